@@ -338,12 +338,13 @@ type callmsg struct {
 	ethereum.CallMsg
 }
 
-func (m callmsg) From() common.Address { return m.CallMsg.From }
-func (m callmsg) Nonce() uint64        { return 0 }
-func (m callmsg) CheckNonce() bool     { return false }
-func (m callmsg) To() *common.Address  { return m.CallMsg.To }
-func (m callmsg) GasPrice() *big.Int   { return m.CallMsg.GasPrice }
-func (m callmsg) Gas() *big.Int        { return m.CallMsg.Gas }
-func (m callmsg) Value() *big.Int      { return m.CallMsg.Value }
-func (m callmsg) Data() []byte         { return m.CallMsg.Data }
-func (m callmsg) TxType() uint64       { return m.CallMsg.TxType }
+func (m callmsg) From() common.Address     { return m.CallMsg.From }
+func (m callmsg) Nonce() uint64            { return 0 }
+func (m callmsg) CheckNonce() bool         { return false }
+func (m callmsg) To() *common.Address      { return m.CallMsg.To }
+func (m callmsg) GasPrice() *big.Int       { return m.CallMsg.GasPrice }
+func (m callmsg) Gas() *big.Int            { return m.CallMsg.Gas }
+func (m callmsg) Value() *big.Int          { return m.CallMsg.Value }
+func (m callmsg) Data() []byte             { return m.CallMsg.Data }
+func (m callmsg) TxType() uint64           { return m.CallMsg.TxType }
+func (m callmsg) Sponsor() *common.Address { return nil }
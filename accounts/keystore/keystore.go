@@ -308,6 +308,74 @@ func (ks *KeyStore) ComputeOTAPPKeys(a accounts.Account, AX, AY, BX, BY string)
 	return []string{pub1X, pub1Y, priv1D, priv2D}, err
 }
 
+// CheckOTAOwners reports which of the one-time-addresses are sent to the unlocked
+// account a, each ota is tested against the view key of the account.
+func (ks *KeyStore) CheckOTAOwners(a accounts.Account, otaWanAddrs [][]byte) ([]bool, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	unlockedKey, found := ks.unlocked[a.Address]
+	if !found {
+		return nil, ErrLocked
+	}
+
+	viewKey := unlockedKey.PrivateKey2.D.Bytes()
+	owned := make([]bool, len(otaWanAddrs))
+	for i, otaWanAddr := range otaWanAddrs {
+		A1, S1, err := GeneratePKPairFromWAddress(otaWanAddr)
+		if err != nil {
+			continue
+		}
+		owned[i] = crypto.CompareA1(viewKey, &unlockedKey.PrivateKey.PublicKey, S1, A1)
+	}
+	return owned, nil
+}
+
+// ComputeOTAImages returns the key images of the one-time-addresses sent to the
+// unlocked account a, they are the key images put in the ring signatures when the
+// otas are spent.
+func (ks *KeyStore) ComputeOTAImages(a accounts.Account, otaWanAddrs [][]byte) ([][]byte, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	unlockedKey, found := ks.unlocked[a.Address]
+	if !found {
+		return nil, ErrLocked
+	}
+
+	images := make([][]byte, len(otaWanAddrs))
+	for i, otaWanAddr := range otaWanAddrs {
+		A1, S1, err := GeneratePKPairFromWAddress(otaWanAddr)
+		if err != nil {
+			return nil, err
+		}
+
+		otaPriv, _, err := crypto.GenerateOneTimePrivateKey2528(unlockedKey.PrivateKey, unlockedKey.PrivateKey2, A1, S1)
+		if err != nil {
+			return nil, err
+		}
+		images[i] = crypto.FromECDSAPub(crypto.ComputeKeyImage(otaPriv.D, A1))
+	}
+	return images, nil
+}
+
+// DecryptOTAMemo decrypts the memo of the one-time-address sent to the unlocked account a
+func (ks *KeyStore) DecryptOTAMemo(a accounts.Account, otaWanAddr []byte, encMemo []byte) ([]byte, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	unlockedKey, found := ks.unlocked[a.Address]
+	if !found {
+		return nil, ErrLocked
+	}
+
+	A1, R, err := GeneratePKPairFromWAddress(otaWanAddr)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.DecryptOTAMemo(unlockedKey.PrivateKey2, A1, R, encMemo)
+}
+
 // SignHashWithPassphrase signs hash if the private key matching the given address
 // can be decrypted with the given passphrase. The produced signature is in the
 // [R || S || V] format where V is 0 or 1.
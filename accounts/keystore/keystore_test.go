@@ -18,7 +18,10 @@
 package keystore
 
 import (
+	"bytes"
+	"crypto/ecdsa"
 	"io/ioutil"
+	"math/big"
 	"math/rand"
 	"os"
 	"runtime"
@@ -534,3 +537,146 @@ func TestComputeOTAPPKeys(t *testing.T) {
 		t.Errorf("invalid ota pk. pk lenght:%d", len(pk))
 	}
 }
+
+// testNewOTA returns a one-time-address sent to a
+func testNewOTA(t *testing.T, ks *KeyStore, a accounts.Account) []byte {
+	wAddr, err := ks.GetWanAddress(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	PK1, PK2, err := GeneratePKPairFromWAddress(wAddr[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	PKPairStr := hexutil.PKPair2HexSlice(PK1, PK2)
+	SKOTA, err := crypto.GenerateOneTimeKey(PKPairStr[0], PKPairStr[1], PKPairStr[2], PKPairStr[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := hexutil.Decode("0x" + strings.Replace(strings.Join(SKOTA, ""), "0x", "", -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ota, err := WaddrFromUncompressedRawBytes(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ota[:]
+}
+
+func TestCheckOTAOwners(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	a, err := ks.NewAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ks.NewAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otas := [][]byte{testNewOTA(t, ks, a), testNewOTA(t, ks, b), testNewOTA(t, ks, a), {1, 2, 3}}
+
+	if _, err := ks.CheckOTAOwners(a, otas); err != ErrLocked {
+		t.Fatal("locked account should not scan otas", err)
+	}
+	if err := ks.Unlock(a, ""); err != nil {
+		t.Fatal(err)
+	}
+	owned, err := ks.CheckOTAOwners(a, otas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bool{true, false, true, false}
+	for i := range want {
+		if owned[i] != want[i] {
+			t.Errorf("ota %d owned: have %v, want %v", i, owned[i], want[i])
+		}
+	}
+}
+
+func TestComputeOTAImages(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	a, err := ks.NewAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ota := testNewOTA(t, ks, a)
+
+	if _, err := ks.ComputeOTAImages(a, [][]byte{ota}); err != ErrLocked {
+		t.Fatal("locked account should not compute key images", err)
+	}
+	if err := ks.Unlock(a, ""); err != nil {
+		t.Fatal(err)
+	}
+	images, err := ks.ComputeOTAImages(a, [][]byte{ota})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the key image must be the one in the ring signature spending the ota
+	raw, err := WaddrToUncompressedRawBytes(ota)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := ks.ComputeOTAPPKeys(a, hexutil.Encode(raw[:32]), hexutil.Encode(raw[32:64]), hexutil.Encode(raw[64:96]), hexutil.Encode(raw[96:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otaPriv := new(big.Int).SetBytes(hexutil.MustDecode(pk[2]))
+	A1, _, err := GeneratePKPairFromWAddress(ota)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, keyImage, _, _, err := crypto.RingSign(testSigData, otaPriv, []*ecdsa.PublicKey{A1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(images[0], crypto.FromECDSAPub(keyImage)) {
+		t.Fatal("key image mismatch")
+	}
+}
+
+func TestDecryptOTAMemo(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	a, err := ks.NewAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wAddr, err := ks.GetWanAddress(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	PK1, PK2, err := GeneratePKPairFromWAddress(wAddr[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	A1, R, encMemo, err := crypto.GenerateOneTimeKeyWithMemo(PK1, PK2, []byte("invoice 42"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ota := GenerateWaddressFromPK(A1, R)
+
+	if _, err := ks.DecryptOTAMemo(a, ota[:], encMemo); err != ErrLocked {
+		t.Fatal("locked account should not decrypt memos", err)
+	}
+	if err := ks.Unlock(a, ""); err != nil {
+		t.Fatal(err)
+	}
+	memo, err := ks.DecryptOTAMemo(a, ota[:], encMemo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(memo) != "invoice 42" {
+		t.Fatal("memo mismatch", string(memo))
+	}
+	if _, err := ks.DecryptOTAMemo(a, testNewOTA(t, ks, a), encMemo); err != crypto.ErrInvalidOTAMemo {
+		t.Fatal("memo of another ota should fail", err)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2018 Wanchain Foundation Ltd
+// This file is part of go-wanchain.
+//
+// go-wanchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-wanchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-wanchain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/wanchain/go-wanchain/cmd/utils"
+	"github.com/wanchain/go-wanchain/pos/incentive"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	incentiveCommand = cli.Command{
+		Name:      "incentive",
+		Usage:     "Pos incentive tools",
+		ArgsUsage: "",
+		Category:  "INCENTIVE COMMANDS",
+		Description: `
+    gwan incentive simulate ./input.json
+
+will run the incentive allocation of one epoch over the staker set in ./input.json.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "simulate",
+				Usage:     "Project the rewards of a hypothetical staker set",
+				ArgsUsage: "<inputfile>",
+				Action:    utils.MigrateFlags(simulateIncentive),
+				Category:  "INCENTIVE COMMANDS",
+				Flags:     []cli.Flag{},
+				Description: `
+    gwan incentive simulate ./input.json
+
+The input file is a json object with the fields:
+  EpochID     the epoch to simulate, decides the foundation subsidy
+  Foundation  optional, overrides the foundation subsidy (wei)
+  GasPool     optional, the tx fee collected in the epoch (wei)
+  Stakers     [{Address, Amount, LockEpochs, FeeRate, Clients: [{Address, Amount}]}]
+  EpLeader, EpActivity, RpLeader, RpActivity, SltLeader, SlBlocks, SlActivity
+              the participants and activity of the epoch
+
+The per address rewards, the foundation share, the remain and an annual rate
+estimate of every staker and client are printed as json.`,
+			},
+		},
+	}
+)
+
+func simulateIncentive(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		return errors.New("need the input file path")
+	}
+
+	data, err := ioutil.ReadFile(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	var input incentive.SimInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		return err
+	}
+
+	result, err := incentive.Simulate(&input)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
@@ -90,6 +90,7 @@ var (
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
 		utils.EtherbaseFlag,
+		utils.PosSignerFlag,
 		utils.GasPriceFlag,
 		utils.MinerThreadsFlag,
 		utils.MiningEnabledFlag,
@@ -169,6 +170,10 @@ func init() {
 		accountCommand,
 		walletCommand,
 		transactionCommand,
+		// See incentivecmd.go:
+		incentiveCommand,
+		// See rbcmd.go:
+		rbCommand,
 		// See consolecmd.go:
 		consoleCommand,
 		attachCommand,
@@ -0,0 +1,132 @@
+// Copyright 2018 Wanchain Foundation Ltd
+// This file is part of go-wanchain.
+//
+// go-wanchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-wanchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-wanchain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/wanchain/go-wanchain/cmd/utils"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/node"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	rbAttachFlag = cli.StringFlag{
+		Name:  "attach",
+		Value: node.DefaultIPCEndpoint(clientIdentifier),
+		Usage: "API endpoint to fetch the random beacon data from",
+	}
+	rbFileFlag = cli.StringFlag{
+		Name:  "file",
+		Usage: "Random beacon data file saved by pos.getRandomBeaconData, no node is needed",
+	}
+	rbOutFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "Save the fetched random beacon data to the file",
+	}
+
+	rbCommand = cli.Command{
+		Name:      "rb",
+		Usage:     "Random beacon tools",
+		ArgsUsage: "",
+		Category:  "RANDOM BEACON COMMANDS",
+		Description: `
+    gwan rb verify 100
+
+will verify the random of epoch 101 generated from the data of epoch 100.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "verify",
+				Usage:     "Verify the random generated from the data of an epoch",
+				ArgsUsage: "<epochId>",
+				Action:    utils.MigrateFlags(verifyRandom),
+				Category:  "RANDOM BEACON COMMANDS",
+				Flags: []cli.Flag{
+					rbAttachFlag,
+					rbFileFlag,
+					rbOutFlag,
+				},
+				Description: `
+    gwan rb verify [--attach endpoint] [--out data.json] <epochId>
+    gwan rb verify --file data.json
+
+The dkg1 commits, dkg2 encrypted shares and signature shares of the epoch are
+fetched from the node by pos_getRandomBeaconData, or read from --file. Every
+share is checked by bn256 pairings locally, the group signature is rebuilt by
+the threshold reconstruction and compared with the random stored on chain.
+The result and the proposers valid or excluded are printed as json.`,
+			},
+		},
+	}
+)
+
+func verifyRandom(ctx *cli.Context) error {
+	var data vm.RbEpochData
+	if file := ctx.String(rbFileFlag.Name); file != "" {
+		buf, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(buf, &data); err != nil {
+			return err
+		}
+	} else {
+		if len(ctx.Args()) < 1 {
+			return errors.New("need the epochId")
+		}
+		epochId, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
+		if err != nil {
+			return err
+		}
+
+		client, err := dialRPC(ctx.String(rbAttachFlag.Name))
+		if err != nil {
+			utils.Fatalf("Unable to attach to gwan node: %v", err)
+		}
+		defer client.Close()
+
+		if err := client.Call(&data, "pos_getRandomBeaconData", epochId); err != nil {
+			return err
+		}
+		if out := ctx.String(rbOutFlag.Name); out != "" {
+			buf, err := json.MarshalIndent(&data, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(out, buf, 0644); err != nil {
+				return err
+			}
+		}
+	}
+
+	result, err := vm.VerifyRandom(&data)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
@@ -0,0 +1,67 @@
+// possigner runs the signer of the validator keys of a pos node in a separate
+// process, the node reaches it on a unix socket with the --possigner flag.
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/cmd/utils"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/possigner"
+)
+
+func main() {
+	var (
+		keyFile      = flag.String("keyfile", "", "keystore file of the validator key")
+		passwordFile = flag.String("password", "", "file containing the password of the key file")
+		socket       = flag.String("socket", "possigner.ipc", "unix socket the signer listens on")
+		protectionDb = flag.String("protectiondb", "possigner-protection", "directory of the slashing protection records")
+		verbosity    = flag.Int("verbosity", int(log.LvlInfo), "log verbosity (0-9)")
+	)
+	flag.Parse()
+
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(*verbosity))
+	log.Root().SetHandler(glogger)
+
+	if *keyFile == "" || *passwordFile == "" {
+		utils.Fatalf("Use -keyfile and -password to specify the validator key")
+	}
+	keyJson, err := ioutil.ReadFile(*keyFile)
+	if err != nil {
+		utils.Fatalf("-keyfile: %v", err)
+	}
+	password, err := ioutil.ReadFile(*passwordFile)
+	if err != nil {
+		utils.Fatalf("-password: %v", err)
+	}
+	key, err := keystore.DecryptKey(keyJson, strings.TrimRight(string(password), "\r\n"))
+	if err != nil {
+		utils.Fatalf("Failed to decrypt the key: %v", err)
+	}
+
+	db, err := ethdb.NewLDBDatabase(*protectionDb, 16, 16)
+	if err != nil {
+		utils.Fatalf("-protectiondb: %v", err)
+	}
+	defer db.Close()
+
+	l, err := possigner.Serve(*socket, possigner.NewProtectedSigner(possigner.NewKeySigner(key), db))
+	if err != nil {
+		utils.Fatalf("Failed to listen on %s: %v", *socket, err)
+	}
+	defer l.Close()
+	log.Info("Validator signer started", "address", key.Address, "socket", *socket)
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	<-sigc
+	log.Info("Validator signer stopped")
+}
@@ -52,6 +52,7 @@ import (
 	"github.com/wanchain/go-wanchain/p2p/nat"
 	"github.com/wanchain/go-wanchain/p2p/netutil"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
 	whisper "github.com/wanchain/go-wanchain/whisper/whisperv5"
 	cli "gopkg.in/urfave/cli.v1"
 )
@@ -312,6 +313,10 @@ var (
 		Name:  "extradata",
 		Usage: "Block extra data set by the miner (default = client version)",
 	}
+	PosSignerFlag = cli.StringFlag{
+		Name:  "possigner",
+		Usage: "Unix socket of the remote signer of the validator keys (default = keystore of the etherbase)",
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -983,6 +988,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
 	setEtherbase(ctx, ks, cfg)
+	if ctx.GlobalIsSet(PosSignerFlag.Name) {
+		cfg.PosSigner = ctx.GlobalString(PosSignerFlag.Name)
+	}
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
 	setEthash(ctx, cfg)
@@ -1170,6 +1178,9 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	if err != nil {
 		Fatalf("%v", err)
 	}
+	if err := posconfig.InitParams(config.PosParams()); err != nil {
+		Fatalf("%v", err)
+	}
 	var engine consensus.Engine
 	if config.Clique != nil {
 		engine = clique.New(config.Clique, chainDb)
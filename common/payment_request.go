@@ -0,0 +1,141 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package common
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net/url"
+	"strings"
+
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/crypto/sha3"
+)
+
+const (
+	// PaymentRequestScheme is the URI scheme of a payment request
+	PaymentRequestScheme = "wan"
+
+	// DenominationWan is the denomination of a payment request of WAN coin
+	DenominationWan = "wan"
+
+	// MaxPaymentMemoLength is the max length of the memo of a payment request, the
+	// memo encrypted to the one-time address fills params.MaxOTAMemoSize then. It's
+	// params.MaxOTAMemoSize - crypto.OTAMemoOverhead, which can't be imported here.
+	MaxPaymentMemoLength = 240
+
+	paymentChecksumLength = 4
+)
+
+var (
+	ErrInvalidPaymentRequest  = errors.New("invalid payment request")
+	ErrPaymentChecksum        = errors.New("payment request checksum mismatch")
+	ErrInvalidPaymentAmount   = errors.New("invalid payment request amount")
+	ErrInvalidPaymentDenom    = errors.New("invalid payment request denomination")
+	ErrPaymentMemoTooLong     = errors.New("payment request memo too long")
+	ErrInvalidPaymentWAddress = errors.New("invalid payment request wan address")
+)
+
+// PaymentRequest is a request of a payment to the one-time addresses of a WAN
+// address. It's encoded in a URI like
+//
+//	wan:0x<wan address>?amount=<amount>&denom=<denomination>&memo=<memo>&checksum=<checksum>
+//
+// where amount is decimal in the smallest unit of the denomination, denomination
+// is "wan" or the address of a privacy token contract, memo is optional, and
+// checksum is the hex of the first 4 bytes of the keccak256 hash of the URI before
+// "&checksum=".
+type PaymentRequest struct {
+	WAddress WAddress
+	Amount   *big.Int
+	Token    *Address // nil if the payment is of WAN coin
+	Memo     string
+}
+
+// Denomination returns the denomination of the payment request in its URI
+func (r *PaymentRequest) Denomination() string {
+	if r.Token == nil {
+		return DenominationWan
+	}
+	return hexutil.Encode(r.Token[:])
+}
+
+// URI returns the URI of the payment request with its checksum
+func (r *PaymentRequest) URI() (string, error) {
+	if err := r.validate(); err != nil {
+		return "", err
+	}
+
+	body := PaymentRequestScheme + ":" + hexutil.Encode(r.WAddress[:]) +
+		"?amount=" + r.Amount.String() + "&denom=" + r.Denomination()
+	if r.Memo != "" {
+		body += "&memo=" + url.QueryEscape(r.Memo)
+	}
+	return body + "&checksum=" + paymentChecksum(body), nil
+}
+
+func (r *PaymentRequest) validate() error {
+	if r.Amount == nil || r.Amount.Sign() <= 0 {
+		return ErrInvalidPaymentAmount
+	}
+	if len(r.Memo) > MaxPaymentMemoLength {
+		return ErrPaymentMemoTooLong
+	}
+	return nil
+}
+
+// ParsePaymentRequest parses and verifies the checksum of the URI of a payment
+// request. The points of the WAN address are not checked on the curve.
+func ParsePaymentRequest(uri string) (*PaymentRequest, error) {
+	i := strings.LastIndex(uri, "&checksum=")
+	if i < 0 {
+		return nil, ErrInvalidPaymentRequest
+	}
+	body, checksum := uri[:i], uri[i+len("&checksum="):]
+	if strings.ToLower(checksum) != paymentChecksum(body) {
+		return nil, ErrPaymentChecksum
+	}
+
+	u, err := url.Parse(body)
+	if err != nil || u.Scheme != PaymentRequestScheme || u.Opaque == "" {
+		return nil, ErrInvalidPaymentRequest
+	}
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, ErrInvalidPaymentRequest
+	}
+
+	r := new(PaymentRequest)
+	wanAddr, err := hexutil.Decode(u.Opaque)
+	if err != nil || len(wanAddr) != WAddressLength {
+		return nil, ErrInvalidPaymentWAddress
+	}
+	copy(r.WAddress[:], wanAddr)
+
+	var ok bool
+	if r.Amount, ok = new(big.Int).SetString(query.Get("amount"), 10); !ok {
+		return nil, ErrInvalidPaymentAmount
+	}
+
+	switch denom := query.Get("denom"); {
+	case denom == DenominationWan:
+	case IsHexAddress(denom) && hexutil.Has0xPrefix(denom):
+		token := HexToAddress(denom)
+		r.Token = &token
+	default:
+		return nil, ErrInvalidPaymentDenom
+	}
+
+	r.Memo = query.Get("memo")
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func paymentChecksum(body string) string {
+	sha := sha3.NewKeccak256()
+	sha.Write([]byte(body))
+	return hex.EncodeToString(sha.Sum(nil)[:paymentChecksumLength])
+}
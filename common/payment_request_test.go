@@ -0,0 +1,61 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package common
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestPaymentRequestURI(t *testing.T) {
+	var wanAddr WAddress
+	for i := range wanAddr {
+		wanAddr[i] = byte(i)
+	}
+	token := HexToAddress("0xa6000c50b8ccf77702c7fde117b02f79f9e1989e")
+
+	for _, r := range []*PaymentRequest{
+		{WAddress: wanAddr, Amount: big.NewInt(1e18)},
+		{WAddress: wanAddr, Amount: big.NewInt(888), Token: &token, Memo: "order #42 & co"},
+	} {
+		uri, err := r.URI()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(uri, "wan:0x0001") {
+			t.Fatal("unexpected uri", uri)
+		}
+		dec, err := ParsePaymentRequest(uri)
+		if err != nil {
+			t.Fatal(err, uri)
+		}
+		if dec.WAddress != r.WAddress || dec.Amount.Cmp(r.Amount) != 0 || dec.Memo != r.Memo ||
+			dec.Denomination() != r.Denomination() {
+			t.Fatal("decoded payment request mismatch", uri)
+		}
+	}
+
+	uri, _ := (&PaymentRequest{WAddress: wanAddr, Amount: big.NewInt(100)}).URI()
+	if _, err := ParsePaymentRequest(strings.Replace(uri, "amount=100", "amount=900", 1)); err != ErrPaymentChecksum {
+		t.Fatal("tampered amount should fail", err)
+	}
+	if _, err := ParsePaymentRequest(uri[:strings.LastIndex(uri, "&checksum=")]); err != ErrInvalidPaymentRequest {
+		t.Fatal("missing checksum should fail", err)
+	}
+	if _, err := (&PaymentRequest{WAddress: wanAddr, Amount: big.NewInt(0)}).URI(); err != ErrInvalidPaymentAmount {
+		t.Fatal("zero amount should fail", err)
+	}
+	if _, err := (&PaymentRequest{WAddress: wanAddr, Amount: big.NewInt(1), Memo: strings.Repeat("m", MaxPaymentMemoLength+1)}).URI(); err != ErrPaymentMemoTooLong {
+		t.Fatal("long memo should fail", err)
+	}
+
+	body := "wan:0x0102?amount=1&denom=wan"
+	if _, err := ParsePaymentRequest(body + "&checksum=" + paymentChecksum(body)); err != ErrInvalidPaymentWAddress {
+		t.Fatal("short wan address should fail", err)
+	}
+	body = strings.Replace(uri[:strings.LastIndex(uri, "&checksum=")], "denom=wan", "denom=btc", 1)
+	if _, err := ParsePaymentRequest(body + "&checksum=" + paymentChecksum(body)); err != ErrInvalidPaymentDenom {
+		t.Fatal("unknown denomination should fail", err)
+	}
+}
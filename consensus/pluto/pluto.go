@@ -27,11 +27,9 @@ import (
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 	"github.com/wanchain/go-wanchain/pos/util"
 
-	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/pos/incentive"
 
 	"github.com/hashicorp/golang-lru"
-	"github.com/wanchain/go-wanchain/accounts"
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/consensus"
@@ -41,13 +39,12 @@ import (
 	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/crypto"
-	"github.com/wanchain/go-wanchain/crypto/sha3"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 	"github.com/wanchain/go-wanchain/pos/slotleader"
-	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/rpc"
 )
 
@@ -142,39 +139,9 @@ var (
 	errWaitTransactions = errors.New("waiting for transactions")
 )
 
-// SignerFn is a signer callback function to request a hash to be signed by a
-// backing account.
-type SignerFn func(accounts.Account, []byte) ([]byte, error)
-
-// sigHash returns the hash which is used as input for the proof-of-authority
-// signing. It is the hash of the entire header apart from the 65 byte signature
-// contained at the end of the extra data.
-//
-// Note, the method requires the extra data to be at least 65 bytes, otherwise it
-// panics. This is done to avoid accidentally using both forms (signature present
-// or not), which could be abused to produce different hashes for the same header.
+// sigHash returns the hash which is used as input for the slot leader seal, see util.SigHash.
 func sigHash(header *types.Header) (hash common.Hash) {
-	hasher := sha3.NewKeccak256()
-
-	rlp.Encode(hasher, []interface{}{
-		header.ParentHash,
-		header.UncleHash,
-		header.Coinbase,
-		header.Root,
-		header.TxHash,
-		header.ReceiptHash,
-		header.Bloom,
-		header.Difficulty,
-		header.Number,
-		header.GasLimit,
-		header.GasUsed,
-		header.Time,
-		header.Extra[:len(header.Extra)-extraSeal], // Yes, this will panic if extra is too short
-		header.MixDigest,
-		header.Nonce,
-	})
-	hasher.Sum(hash[:0])
-	return hash
+	return util.SigHash(header)
 }
 
 // ecrecover extracts the Ethereum account address from a signed header.
@@ -222,11 +189,9 @@ type Pluto struct {
 
 	proposals map[common.Address]bool // Current list of proposals we are pushing
 
-	signer common.Address // Ethereum address of the signing key
-	signFn SignerFn       // Signer function to authorize hashes with
-	lock   sync.RWMutex   // Protects the signer fields
-
-	key *keystore.Key // Unlocked key
+	signer    common.Address   // Ethereum address of the signing key
+	valSigner possigner.Signer // Validator signer of the seals and the slot leader proofs
+	lock      sync.RWMutex     // Protects the signer fields
 }
 
 // New creates a Pluto proof-of-authority consensus engine with the initial
@@ -294,7 +259,7 @@ func (c *Pluto) verifyHeader(chain consensus.ChainReader, header *types.Header,
 	//number := header.Number.Uint64()
 
 	// Don't waste time checking blocks from the future
-	if header.Time.Cmp(big.NewInt(time.Now().Unix())) > 0 {
+	if header.Time.Cmp(new(big.Int).SetUint64(util.NowUnix())) > 0 {
 		return consensus.ErrFutureBlock
 	}
 	// Checkpoint blocks need to enforce zero beneficiary
@@ -676,8 +641,9 @@ func (c *Pluto) Prepare(chain consensus.ChainReader, header *types.Header, minin
 	curEpochId, curSlotId := util.GetEpochSlotID()
 
 	if posconfig.EpochBaseTime == 0 {
-		cur := time.Now().Unix()
-		hcur := cur - (cur % posconfig.SlotTime) + posconfig.SlotTime
+		cur := int64(util.NowUnix())
+		slotTime := int64(posconfig.SlotTime)
+		hcur := cur - (cur % slotTime) + slotTime
 		header.Time = big.NewInt(hcur)
 	} else {
 		header.Time = big.NewInt(int64(posconfig.EpochBaseTime + (curEpochId*posconfig.SlotCount+curSlotId)*posconfig.SlotTime))
@@ -707,7 +673,13 @@ func (c *Pluto) Finalize(chain consensus.ChainReader, header *types.Header, stat
 		}
 
 		snap = state.Snapshot()
-		if !epochLeader.StakeOutRun(state, epochID) {
+		if !incentive.SlashRun(state, epochID-posconfig.IncentiveDelayEpochs) {
+			log.Error("incentive.SlashRun failed")
+			state.RevertToSnapshot(snap)
+		}
+
+		snap = state.Snapshot()
+		if !epochLeader.StakeOutRun(state, epochID, chain.Config().IsStakeOut(header.Number)) {
 			log.Error("Stake Out failed.")
 			state.RevertToSnapshot(snap)
 		}
@@ -722,15 +694,14 @@ func (c *Pluto) Finalize(chain consensus.ChainReader, header *types.Header, stat
 	return types.NewBlock(header, txs, nil, receipts), nil
 }
 
-// Authorize injects a private key into the consensus engine to mint new blocks
-// with.
-func (c *Pluto) Authorize(signer common.Address, signFn SignerFn, key *keystore.Key) {
+// Authorize injects the validator signer into the consensus engine to mint new
+// blocks with.
+func (c *Pluto) Authorize(signer common.Address, valSigner possigner.Signer) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
 	c.signer = signer
-	c.signFn = signFn
-	c.key = key
+	c.valSigner = valSigner
 }
 
 // Seal implements consensus.Engine, attempting to create a sealed block using
@@ -749,7 +720,7 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	}
 	// Don't hold the signer fields for the entire sealing procedure
 	c.lock.RLock()
-	signer, signFn, key := c.signer, c.signFn, c.key
+	signer, valSigner := c.signer, c.valSigner
 	c.lock.RUnlock()
 
 	// Bail out if we're unauthorized to sign a block
@@ -768,7 +739,10 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	if epochSlotId <= lastEpochSlotId {
 		return nil, nil
 	}
-	localPublicKey := hex.EncodeToString(crypto.FromECDSAPub(&c.key.PrivateKey.PublicKey))
+	if valSigner == nil {
+		return nil, errUnauthorized
+	}
+	localPublicKey := hex.EncodeToString(crypto.FromECDSAPub(valSigner.PublicKey()))
 	leaderPub, err := slotleader.GetSlotLeaderSelection().GetSlotLeader(epochId, slotId)
 	if err != nil {
 		return nil, err
@@ -798,7 +772,7 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	header.Coinbase = signer
 
 	s := slotleader.GetSlotLeaderSelection()
-	buf, err := s.PackSlotProof(epochId, slotId, key.PrivateKey)
+	buf, err := s.PackSlotProof(epochId, slotId, valSigner)
 	if err != nil {
 		log.Warn("PackSlotProof failed in Seal", "epochID", epochId, "slotID", slotId, "error", err.Error())
 		return nil, err
@@ -810,7 +784,7 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	copy(header.Extra[:len(buf)], buf)
 	header.Difficulty.SetUint64(epochSlotId)
 
-	sighash, err := signFn(accounts.Account{Address: signer}, sigHash(header).Bytes())
+	sighash, err := valSigner.SignSeal(epochId, slotId, sigHash(header).Bytes())
 	if err != nil {
 		return nil, err
 	}
@@ -818,7 +792,7 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 
 	log.Debug("signature", "hex", hex.EncodeToString(sighash))
 	log.Debug("sigHash(header)", "Bytes", hex.EncodeToString(sigHash(header).Bytes()))
-	log.Debug("Packed slotleader proof info success", "epochID", epochId, "slotID", slotId, "len", len(header.Extra), "pk", hex.EncodeToString(crypto.FromECDSAPub(&key.PrivateKey.PublicKey)))
+	log.Debug("Packed slotleader proof info success", "epochID", epochId, "slotID", slotId, "len", len(header.Extra), "pk", localPublicKey)
 
 	err = c.verifySeal(nil, header, nil, false)
 	if err != nil {
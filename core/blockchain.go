@@ -697,7 +697,7 @@ func SetReceiptsData(config *params.ChainConfig, block *types.Block, receipts ty
 		// The contract address can be derived from the transaction itself
 		if transactions[j].To() == nil {
 			// Deriving the signer is expensive, only do if it's actually needed
-			from, _ := types.Sender(signer, transactions[j])
+			from, _ := TxSender(signer, transactions[j])
 			receipts[j].ContractAddress = crypto.CreateAddress(from, transactions[j].Nonce())
 		}
 		// The used gas can be calculated based on previous receipts
@@ -851,7 +851,7 @@ func (bc *BlockChain) isWriteBlockSecure(block *types.Block) (bool){
 	//because slot index starts from 0
 	totalSlots := epochId * posconfig.SlotCount + slotId + 1
 	if  totalSlots >= posconfig.SlotSecurityParam {
-		return blocksIn2K > posconfig.K
+		return blocksIn2K > int(posconfig.K)
 	} else if totalSlots >= posconfig.K {
 		return blocksIn2K > (int)(totalSlots - posconfig.K)
 	}
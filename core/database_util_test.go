@@ -20,6 +20,7 @@ package core
 import (
 	"bytes"
 	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/wanchain/go-wanchain/common"
@@ -357,6 +358,13 @@ func TestBlockReceiptStorage(t *testing.T) {
 		TxHash:          common.BytesToHash([]byte{0x22, 0x22}),
 		ContractAddress: common.BytesToAddress([]byte{0x02, 0x22, 0x22}),
 		GasUsed:         big.NewInt(222222),
+		PrivacyFee: &types.PrivacyFee{
+			StampValue:   big.NewInt(2222220),
+			StampGas:     big.NewInt(222222),
+			RingSignGas:  big.NewInt(22222),
+			EVMGasUsed:   big.NewInt(2222),
+			GasRemainder: big.NewInt(197778),
+		},
 	}
 	receipts := []*types.Receipt{receipt1, receipt2}
 
@@ -379,6 +387,9 @@ func TestBlockReceiptStorage(t *testing.T) {
 			if !bytes.Equal(rlpHave, rlpWant) {
 				t.Fatalf("receipt #%d: receipt mismatch: have %v, want %v", i, rs[i], receipts[i])
 			}
+			if !reflect.DeepEqual(rs[i].PrivacyFee, receipts[i].PrivacyFee) {
+				t.Fatalf("receipt #%d: privacy fee mismatch: have %v, want %v", i, rs[i].PrivacyFee, receipts[i].PrivacyFee)
+			}
 		}
 	}
 	// Delete the receipt slice and check purge
@@ -40,6 +40,7 @@ import (
 
 //go:generate gencodec -type Genesis -field-override genesisSpecMarshaling -out gen_genesis.go
 //go:generate gencodec -type GenesisAccount -field-override genesisAccountMarshaling -out gen_genesis_account.go
+//go:generate gencodec -type GenesisAccountStaking -field-override genesisAccountStakingMarshaling -out gen_genesis_staking.go
 
 var errGenesisNoConfig = errors.New("genesis has no chain configuration")
 
@@ -114,6 +115,12 @@ type genesisAccountMarshaling struct {
 	PrivateKey hexutil.Bytes
 }
 
+type genesisAccountStakingMarshaling struct {
+	Amount  *math.HexOrDecimal256
+	S256pk  hexutil.Bytes
+	Bn256pk hexutil.Bytes
+}
+
 // storageJSON represents a 256 bit byte array, but allows less than 256 bits when
 // unmarshaling from hex.
 type storageJSON common.Hash
@@ -247,7 +254,7 @@ func (g *Genesis) ToBlock() (*types.Block, *state.StateDB) {
 		statedb.SetCode(addr, account.Code)
 		statedb.SetNonce(addr, account.Nonce)
 
-		if account.Staking.S256pk != nil {
+		if len(account.Staking.S256pk) != 0 {
 			pub := crypto.ToECDSAPub(account.Staking.S256pk)
 			if nil == pub {
 				panic("Invalid genesis.")
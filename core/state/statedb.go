@@ -260,6 +260,34 @@ func (self *StateDB) StorageTrie(a common.Address) Trie {
 	return cpy.updateTrie(self.db)
 }
 
+// proofTrie is implemented by the tries which can construct merkle proofs.
+type proofTrie interface {
+	Prove(key []byte) []rlp.RawValue
+}
+
+// GetProof returns the merkle proof of the account a against the state root.
+func (self *StateDB) GetProof(a common.Address) ([]rlp.RawValue, error) {
+	tr, ok := self.trie.(proofTrie)
+	if !ok {
+		return nil, fmt.Errorf("state trie %T can't construct proof", self.trie)
+	}
+	return tr.Prove(a[:]), nil
+}
+
+// GetStorageProof returns the merkle proof of the storage entry key of the
+// account a against the account's storage root.
+func (self *StateDB) GetStorageProof(a common.Address, key common.Hash) ([]rlp.RawValue, error) {
+	st := self.StorageTrie(a)
+	if st == nil {
+		return nil, fmt.Errorf("account %x doesn't exist", a)
+	}
+	tr, ok := st.(proofTrie)
+	if !ok {
+		return nil, fmt.Errorf("storage trie %T can't construct proof", st)
+	}
+	return tr.Prove(key[:]), nil
+}
+
 func (self *StateDB) HasSuicided(addr common.Address) bool {
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
@@ -32,7 +32,10 @@ import (
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/rlp"
+	"github.com/wanchain/go-wanchain/trie"
 )
 
 // Tests that updating a state trie does not leak any database writes prior to
@@ -393,3 +396,45 @@ func (s *StateSuite) TestTouchDelete(c *check.C) {
 		c.Fatal("expected no dirty state object")
 	}
 }
+
+func TestGetStorageProof(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+
+	addr := common.BytesToAddress([]byte{0x1, 0x2})
+	key := common.BytesToHash([]byte{0x3})
+	value := []byte("staker info")
+	state.AddBalance(addr, big.NewInt(1))
+	state.SetStateByteArray(addr, key, value)
+	root, _ := state.CommitTo(db, false)
+
+	state, _ = New(root, NewDatabase(db))
+	accountProof, err := state.GetProof(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := trie.VerifyProof(root, crypto.Keccak256(addr[:]), accountProof)
+	if err != nil || enc == nil {
+		t.Fatalf("account proof verify failed: %v", err)
+	}
+	var account Account
+	if err := rlp.DecodeBytes(enc, &account); err != nil {
+		t.Fatal(err)
+	}
+
+	storageProof, err := state.GetStorageProof(addr, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := trie.VerifyProof(account.Root, crypto.Keccak256(key[:]), storageProof)
+	if err != nil {
+		t.Fatalf("storage proof verify failed: %v", err)
+	}
+	if !bytes.Equal(got, value) {
+		t.Fatalf("storage value mismatch: have %x, want %x", got, value)
+	}
+
+	if _, err := state.GetStorageProof(common.BytesToAddress([]byte{0x9}), key); err == nil {
+		t.Fatal("proof of non-existent account should fail")
+	}
+}
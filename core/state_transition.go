@@ -67,6 +67,8 @@ type StateTransition struct {
 	data       []byte
 	state      vm.StateDB
 	evm        *vm.EVM
+
+	privacyFee *types.PrivacyFee // fee accounting of a privacy transaction
 }
 
 // Message represents a message sent to a contract.
@@ -84,6 +86,7 @@ type Message interface {
 	Data() []byte
 
 	TxType() uint64
+	Sponsor() *common.Address
 }
 
 // IntrinsicGas computes the 'intrinsic gas' for a message
@@ -144,10 +147,17 @@ func NewStateTransition(evm *vm.EVM, msg Message, gp *GasPool) *StateTransition
 // indicates a core error meaning that the message would always fail for that particular
 // state and would never be accepted within a block.
 func ApplyMessage(evm *vm.EVM, msg Message, gp *GasPool) ([]byte, *big.Int, bool, error) {
+	ret, gasUsed, failed, _, err := applyMessage(evm, msg, gp)
+	return ret, gasUsed, failed, err
+}
+
+// applyMessage is ApplyMessage also returning how the stamp is consumed if msg is
+// a privacy transaction.
+func applyMessage(evm *vm.EVM, msg Message, gp *GasPool) ([]byte, *big.Int, bool, *types.PrivacyFee, error) {
 	st := NewStateTransition(evm, msg, gp)
 
 	ret, _, gasUsed, failed, err := st.TransitionDb()
-	return ret, gasUsed, failed, err
+	return ret, gasUsed, failed, st.privacyFee, err
 }
 
 func (st *StateTransition) from() vm.AccountRef {
@@ -158,6 +168,15 @@ func (st *StateTransition) from() vm.AccountRef {
 	return vm.AccountRef(f)
 }
 
+// gasPayer returns the account buying the gas of the message, the sponsor of a
+// sponsored message or else the sender.
+func (st *StateTransition) gasPayer() vm.AccountRef {
+	if sponsor := st.msg.Sponsor(); sponsor != nil {
+		return vm.AccountRef(*sponsor)
+	}
+	return st.from()
+}
+
 func (st *StateTransition) to() vm.AccountRef {
 	if st.msg == nil {
 		return vm.AccountRef{}
@@ -192,11 +211,11 @@ func (st *StateTransition) buyGas() error {
 	mgval := new(big.Int).Mul(mgas, st.gasPrice)
 
 	var (
-		state  = st.state
-		sender = st.from()
+		state = st.state
+		payer = st.gasPayer()
 	)
 
-	if state.GetBalance(sender.Address()).Cmp(mgval) < 0 {
+	if state.GetBalance(payer.Address()).Cmp(mgval) < 0 {
 		return errInsufficientBalanceForGas
 	}
 
@@ -206,7 +225,7 @@ func (st *StateTransition) buyGas() error {
 	st.gas += mgas.Uint64()
 
 	st.initialGas.Set(mgas)
-	state.SubBalance(sender.Address(), mgval)
+	state.SubBalance(payer.Address(), mgval)
 	return nil
 }
 
@@ -230,11 +249,13 @@ func (st *StateTransition) preCheck() error {
 // including the required gas for the operation as well as the used gas. It returns an error if it
 // failed. An error indicates a consensus issue.
 func (st *StateTransition) TransitionDb() (ret []byte, requiredGas, usedGas *big.Int, failed bool, err error) {
+	handler := TxTypeHandlerOf(st.msg.TxType())
+	if handler == nil {
+		return nil, nil, nil, false, ErrInvalidTxType
+	}
 
-	if types.IsNormalTransaction(st.msg.TxType()) || types.IsPosTransaction(st.msg.TxType()) {
-		if err = st.preCheck(); err != nil {
-			return
-		}
+	if err = handler.PreCheck(st); err != nil {
+		return
 	}
 
 	log.Trace("after preCheck", "txType", st.msg.TxType(), "gas pool", st.gp.String())
@@ -247,30 +268,14 @@ func (st *StateTransition) TransitionDb() (ret []byte, requiredGas, usedGas *big
 
 	// Pay intrinsic gas
 	// TODO convert to uint64
-	intrinsicGas := IntrinsicGas(st.data, msg.To(), true /*homestead*/)
+	intrinsicGas := handler.IntrinsicGas(st.data, msg.To(), true /*homestead*/)
 	log.Trace("get intrinsic gas", "gas", intrinsicGas.String())
 	if intrinsicGas.BitLen() > 64 {
 		return nil, nil, nil, false, vm.ErrOutOfGas
 	}
 
-	var stampTotalGas uint64
-	if types.IsPrivacyTransaction(st.msg.TxType()) {
-		pureCallData, totalUseableGas, evmUseableGas, err := PreProcessPrivacyTx(st.evm.StateDB,
-			sender.Address().Bytes(),
-			st.data, st.gasPrice, st.value)
-		if err != nil {
-			return nil, nil, nil, false, err
-		}
-
-		stampTotalGas = totalUseableGas
-		st.gas = evmUseableGas
-		st.initialGas.SetUint64(evmUseableGas)
-		st.data = pureCallData[:]
-		log.Trace("pre process privacy tx", "stampTotalGas", stampTotalGas, "evmUseableGas", evmUseableGas)
-		//sub gas from total gas of curent block,prevent gas is overhead gaslimit
-		if err := st.gp.SubGas(new(big.Int).SetUint64(totalUseableGas)); err != nil {
-			return nil, nil, nil, false, err
-		}
+	if err = handler.PreTransition(st); err != nil {
+		return nil, nil, nil, false, err
 	}
 
 	if err = st.useGas(intrinsicGas.Uint64()); err != nil {
@@ -306,16 +311,8 @@ func (st *StateTransition) TransitionDb() (ret []byte, requiredGas, usedGas *big
 		}
 	}
 
-	if types.IsNormalTransaction(st.msg.TxType()) || types.IsPosTransaction(st.msg.TxType()) {
-		requiredGas = st.gasUsed()
-		st.refundGas()
-		usedGas = new(big.Int).Set(st.gasUsed())
-		log.Trace("calc used gas, normal tx", "required gas", requiredGas, "used gas", usedGas)
-	} else if types.IsPrivacyTransaction(st.msg.TxType()) {
-		requiredGas = new(big.Int).SetUint64(stampTotalGas)
-		usedGas = requiredGas
-		log.Trace("calc used gas, pos tx", "required gas", requiredGas, "used gas", usedGas)
-	}
+	requiredGas, usedGas = handler.PostTransition(st)
+	log.Trace("calc used gas", "txType", st.msg.TxType(), "required gas", requiredGas, "used gas", usedGas)
 
 	//st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(usedGas, st.gasPrice))
 	epochID := st.evm.Context.Difficulty.Uint64() >> 32
@@ -324,18 +321,18 @@ func (st *StateTransition) TransitionDb() (ret []byte, requiredGas, usedGas *big
 }
 
 func (st *StateTransition) refundGas() {
-	// Return eth for remaining gas to the sender account,
+	// Return eth for remaining gas to the account bought it,
 	// exchanged at the original rate.
-	sender := st.from() // err already checked
+	payer := st.gasPayer()
 	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
-	st.state.AddBalance(sender.Address(), remaining)
+	st.state.AddBalance(payer.Address(), remaining)
 
 	// Apply refund counter, capped to half of the used gas.
 	uhalf := remaining.Div(st.gasUsed(), common.Big2)
 	refund := math.BigMin(uhalf, st.state.GetRefund())
 	st.gas += refund.Uint64()
 
-	st.state.AddBalance(sender.Address(), refund.Mul(refund, st.gasPrice))
+	st.state.AddBalance(payer.Address(), refund.Mul(refund, st.gasPrice))
 
 	// Also return remaining gas to the block gas counter so it is
 	// available for the next transaction.
@@ -372,7 +369,9 @@ type PrivacyTxInfo struct {
 	GasLeftSubRingSign uint64
 }
 
-func FetchPrivacyTxInfo(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *big.Int) (info *PrivacyTxInfo, err error) {
+// FetchPrivacyTxInfo decodes a privacy transaction and verifies its ring signature,
+// ringSignV2 reports whether the ring signature can be of version 2.
+func FetchPrivacyTxInfo(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *big.Int, ringSignV2 bool) (info *PrivacyTxInfo, err error) {
 	if len(in) < 4 {
 		return nil, vm.ErrInvalidRingSigned
 	}
@@ -387,7 +386,7 @@ func FetchPrivacyTxInfo(stateDB vm.StateDB, hashInput []byte, in []byte, gasPric
 		return
 	}
 
-	ringSignInfo, err := vm.FetchRingSignInfo(stateDB, hashInput, TxDataWithRing.RingSignedData)
+	ringSignInfo, err := vm.FetchRingSignInfo(stateDB, hashInput, TxDataWithRing.RingSignedData, ringSignV2)
 	if err != nil {
 		return
 	}
@@ -423,7 +422,7 @@ func FetchPrivacyTxInfo(stateDB vm.StateDB, hashInput []byte, in []byte, gasPric
 }
 
 func ValidPrivacyTx(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *big.Int,
-	intrGas *big.Int, txValue *big.Int, gasLimit *big.Int) error {
+	intrGas *big.Int, txValue *big.Int, gasLimit *big.Int, ringSignV2 bool) error {
 	if intrGas == nil || intrGas.BitLen() > 64 {
 		return vm.ErrOutOfGas
 	}
@@ -436,7 +435,7 @@ func ValidPrivacyTx(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *b
 		return vm.ErrInvalidGasPrice
 	}
 
-	info, err := FetchPrivacyTxInfo(stateDB, hashInput, in, gasPrice)
+	info, err := FetchPrivacyTxInfo(stateDB, hashInput, in, gasPrice, ringSignV2)
 	if err != nil {
 		return err
 	}
@@ -460,23 +459,65 @@ func ValidPrivacyTx(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *b
 	return nil
 }
 
-func PreProcessPrivacyTx(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *big.Int, txValue *big.Int) (callData []byte, totalUseableGas uint64, evmUseableGas uint64, err error) {
+func PreProcessPrivacyTx(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *big.Int, txValue *big.Int, ringSignV2 bool) (callData []byte, totalUseableGas uint64, evmUseableGas uint64, err error) {
+	info, err := preProcessPrivacyTx(stateDB, hashInput, in, gasPrice, txValue, ringSignV2)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	return info.CallData, info.StampTotalGas, info.GasLeftSubRingSign, nil
+}
+
+// preProcessPrivacyTx verifies the ring signature of a privacy transaction and spends
+// its stamp by storing the key image.
+func preProcessPrivacyTx(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *big.Int, txValue *big.Int, ringSignV2 bool) (*PrivacyTxInfo, error) {
 	if txValue.Sign() != 0 {
-		return nil, 0, 0, vm.ErrInvalidPrivacyValue
+		return nil, vm.ErrInvalidPrivacyValue
 	}
 
-	info, err := FetchPrivacyTxInfo(stateDB, hashInput, in, gasPrice)
+	info, err := FetchPrivacyTxInfo(stateDB, hashInput, in, gasPrice, ringSignV2)
 	if err != nil {
-		return nil, 0, 0, err
+		return nil, err
 	}
 
 	kix := crypto.FromECDSAPub(info.KeyImage)
 	exist, _, err := vm.CheckOTAImageExist(stateDB, kix)
-	if err != nil || exist {
-		return nil, 0, 0, err
+	if err != nil {
+		return nil, err
+	} else if exist {
+		// a spent stamp leaves the transaction no gas and no call data instead of
+		// failing it, the blocks including such transactions stay valid
+		return &PrivacyTxInfo{StampBalance: new(big.Int)}, nil
 	}
 
 	vm.AddOTAImage(stateDB, kix, info.StampBalance.Bytes())
 
-	return info.CallData, info.StampTotalGas, info.GasLeftSubRingSign, nil
+	return info, nil
+}
+
+// PrivacyTxExtraGas returns the gas a privacy transaction wrapping callData in a ring
+// signature of ringSize members takes besides executing callData. It's the gas of
+// verifying the ring signature, storing the key image and the intrinsic gas of the
+// ring signed data, which is assumed to be of its longest encoding.
+func PrivacyTxExtraGas(callData []byte, to *common.Address, ringSize int) (uint64, error) {
+	if ringSize <= 0 {
+		return 0, vm.ErrInvalidOTASet
+	}
+
+	pub := "0x" + strings.Repeat("f", 2*65)
+	scalar := "0x" + strings.Repeat("f", 2*32)
+	pubs := make([]string, ringSize)
+	scalars := make([]string, ringSize)
+	for i := 0; i < ringSize; i++ {
+		pubs[i], scalars[i] = pub, scalar
+	}
+	ringSigned := strings.Join([]string{strings.Join(pubs, "&"), pub, strings.Join(scalars, "&"), strings.Join(scalars, "&")}, "+")
+
+	payload, err := utilAbi.Pack("combine", ringSigned, callData)
+	if err != nil {
+		return 0, err
+	}
+
+	ringSignedGas := new(big.Int).Sub(IntrinsicGas(payload, to, true), IntrinsicGas(callData, to, true))
+	return ringSignedGas.Uint64() + params.RequiredGasPerMixPub*uint64(ringSize) + params.SstoreSetGas, nil
 }
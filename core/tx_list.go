@@ -297,8 +297,8 @@ func (l *txList) Filter(costLimit, gasLimit *big.Int) (types.Transactions, types
 
 	// Filter out all the transactions above the account's funds
 	removed := l.txs.Filter(func(tx *types.Transaction) bool {
-		txType := tx.Txtype()
-		return (types.IsNormalTransaction(txType) || types.IsPosTransaction(txType)) && (tx.Cost().Cmp(costLimit) > 0 || tx.Gas().Cmp(gasLimit) > 0)
+		cost := txSenderCost(tx)
+		return cost != nil && (cost.Cmp(costLimit) > 0 || tx.Gas().Cmp(gasLimit) > 0)
 	})
 
 	// If the list was strict, filter anything above the lowest nonce
@@ -316,8 +316,9 @@ func (l *txList) Filter(costLimit, gasLimit *big.Int) (types.Transactions, types
 	return removed, invalids
 }
 
-// InvalidPrivacyTx remove invalidate privacy transactions
-func (l *txList) InvalidPrivacyTx(stateDB vm.StateDB, signer types.Signer, gasLimit *big.Int) types.Transactions {
+// InvalidPrivacyTx remove invalidate privacy transactions, ringSignV2 reports whether
+// the ring signatures can be of version 2 in the pending block.
+func (l *txList) InvalidPrivacyTx(stateDB vm.StateDB, signer types.Signer, gasLimit *big.Int, ringSignV2 bool) types.Transactions {
 	removed := l.txs.Filter(func(tx *types.Transaction) bool {
 		if !types.IsPrivacyTransaction(tx.Txtype()){
 			return false
@@ -329,7 +330,7 @@ func (l *txList) InvalidPrivacyTx(stateDB vm.StateDB, signer types.Signer, gasLi
 		}
 
 		intrGas := IntrinsicGas(tx.Data(), tx.To(), true)
-		err = ValidPrivacyTx(stateDB, from.Bytes(), tx.Data(), tx.GasPrice(), intrGas, tx.Value(), gasLimit)
+		err = ValidPrivacyTx(stateDB, from.Bytes(), tx.Data(), tx.GasPrice(), intrGas, tx.Value(), gasLimit, ringSignV2)
 
 		return err != nil
 	})
@@ -64,6 +64,10 @@ var (
 	// is higher than the balance of the user's account.
 	ErrInsufficientFunds = errors.New("insufficient funds for gas * price + value")
 
+	// ErrInsufficientSponsorFunds is returned if the gas of a sponsored transaction
+	// costs more than the balance of its sponsor.
+	ErrInsufficientSponsorFunds = errors.New("insufficient funds of sponsor for gas * price")
+
 	// ErrIntrinsicGas is returned if the transaction is specified to use less gas
 	// than required to start the invocation.
 	ErrIntrinsicGas = errors.New("intrinsic gas too low")
@@ -203,6 +207,8 @@ type TxPool struct {
 	all     map[common.Hash]*types.Transaction // All transactions to allow lookups
 	priced  *txPricedList                      // All transactions sorted by price
 
+	sponsored map[common.Hash]common.Address // Sponsors of the sponsored transactions, pruned lazily
+
 	wg sync.WaitGroup // for shutdown sync
 
 	homestead bool
@@ -224,6 +230,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		queue:       make(map[common.Address]*txList),
 		beats:       make(map[common.Address]time.Time),
 		all:         make(map[common.Hash]*types.Transaction),
+		sponsored:   make(map[common.Hash]common.Address),
 		chainHeadCh: make(chan ChainHeadEvent, chainHeadChanSize),
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
 	}
@@ -417,6 +424,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// have been invalidated because of another transaction (e.g.
 	// higher gas price)
 	pool.demoteUnexecutables()
+	pool.dropUnsponsored()
 
 	// Update all accounts to the latest known pending nonce
 	for addr, list := range pool.pending {
@@ -551,16 +559,14 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
-	if !types.IsValidTransactionType(tx.Txtype()) {
+	handler := TxTypeHandlerOf(tx.Txtype())
+	if handler == nil {
 		return ErrInvalidTxType
 	}
 
 	// type must match to des address
-	isNormalType := types.IsNormalTransaction(tx.Txtype())
-	isPosType := types.IsPosTransaction(tx.Txtype())
-	isPosAddr := vm.IsPosPrecompiledAddr(tx.To())
-	if isPosType != isPosAddr {
-		return ErrInvalidTxType
+	if err := handler.CheckTx(tx); err != nil {
+		return err
 	}
 
 	// Heuristic limit, reject transactions over 32KB to prevent DOS attacks
@@ -578,7 +584,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return ErrGasLimit
 	}
 	// Make sure the transaction is signed properly
-	from, err := types.Sender(pool.signer, tx)
+	from, err := handler.Sender(pool.signer, tx)
 	if err != nil {
 		return ErrInvalidSender
 	}
@@ -591,29 +597,31 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if pool.currentState.GetNonce(from) > tx.Nonce() {
 		return ErrNonceTooLow
 	}
-	// Transactor should have enough funds to cover the costs
-	// cost == V + GP * GL
-	if (isNormalType || isPosType) && pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
-		return ErrInsufficientFunds
+	// Check the funds, the intrinsic gas and the rest of the transaction type
+	ctx := &TxValidationContext{
+		State:     pool.currentState,
+		Signer:    pool.signer,
+		MaxGas:    pool.currentMaxGas,
+		Homestead: pool.homestead,
+		Config:    pool.chainconfig,
+		Number:    pool.pendingNumber(),
+		SponsorCost: func(sponsor common.Address) *big.Int {
+			return pool.sponsorCost(sponsor, from, tx.Nonce())
+		},
 	}
-
-	intrGas := IntrinsicGas(tx.Data(), tx.To(), pool.homestead)
-	if isNormalType || isPosType {
-		if tx.Gas().Cmp(intrGas) < 0 {
-			return ErrIntrinsicGas
-		}
-
-	} else {
-		err := ValidPrivacyTx(pool.currentState, from.Bytes(), tx.Data(), tx.GasPrice(), intrGas, tx.Value(), pool.currentMaxGas)
-		if err != nil {
-			return err
-		}
+	if err := handler.ValidateTx(ctx, tx, from); err != nil {
+		return err
 	}
 
 	// Check precompile contracts transactions validation
 	if tx.To() != nil {
 		if p := vm.PrecompiledContractsByzantium[*tx.To()]; p != nil {
-			if err = p.ValidTx(pool.currentState, pool.signer, tx); err != nil {
+			if fp, ok := p.(vm.ForkTxValidator); ok {
+				err = fp.ValidTxAt(pool.currentState, pool.signer, tx, ctx.Config, ctx.Number)
+			} else {
+				err = p.ValidTx(pool.currentState, pool.signer, tx)
+			}
+			if err != nil {
 				return err
 			}
 		}
@@ -622,6 +630,76 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	return nil
 }
 
+// sponsorCost returns the gas the sponsor pays for the pooled transactions, but the
+// one of from with nonce which is about to be replaced.
+func (pool *TxPool) sponsorCost(sponsor, from common.Address, nonce uint64) *big.Int {
+	cost := new(big.Int)
+	for hash, addr := range pool.sponsored {
+		tx := pool.all[hash]
+		if tx == nil {
+			delete(pool.sponsored, hash)
+			continue
+		}
+		if addr != sponsor {
+			continue
+		}
+		if sender, _ := types.Sender(pool.signer, tx); sender == from && tx.Nonce() == nonce {
+			continue
+		}
+		cost.Add(cost, new(big.Int).Mul(tx.GasPrice(), tx.Gas()))
+	}
+	return cost
+}
+
+// dropUnsponsored removes the cheapest sponsored transactions of the sponsors who
+// can't afford the gas of all their pooled transactions any more.
+func (pool *TxPool) dropUnsponsored() {
+	bySponsor := make(map[common.Address]types.Transactions)
+	for hash, sponsor := range pool.sponsored {
+		tx := pool.all[hash]
+		if tx == nil {
+			delete(pool.sponsored, hash)
+			continue
+		}
+		bySponsor[sponsor] = append(bySponsor[sponsor], tx)
+	}
+	for sponsor, txs := range bySponsor {
+		cost := new(big.Int)
+		for _, tx := range txs {
+			cost.Add(cost, new(big.Int).Mul(tx.GasPrice(), tx.Gas()))
+		}
+		balance := pool.currentState.GetBalance(sponsor)
+		if cost.Cmp(balance) <= 0 {
+			continue
+		}
+		// Drop the cheapest transactions first, the latest of a sender before the others
+		sort.Slice(txs, func(i, j int) bool {
+			if c := txs[i].GasPrice().Cmp(txs[j].GasPrice()); c != 0 {
+				return c < 0
+			}
+			return txs[i].Nonce() > txs[j].Nonce()
+		})
+		for _, tx := range txs {
+			if cost.Cmp(balance) <= 0 {
+				break
+			}
+			if pool.all[tx.Hash()] == nil {
+				continue // dropped with a former transaction of the sender
+			}
+			log.Trace("Removed unsponsored transaction", "hash", tx.Hash(), "sponsor", sponsor)
+			pool.removeTx(tx.Hash())
+			delete(pool.sponsored, tx.Hash())
+			cost.Sub(cost, new(big.Int).Mul(tx.GasPrice(), tx.Gas()))
+			pendingNofundsCounter.Inc(1)
+		}
+	}
+}
+
+// pendingNumber returns the number of the block the pending transactions go to
+func (pool *TxPool) pendingNumber() *big.Int {
+	return new(big.Int).Add(pool.chain.CurrentBlock().Number(), common.Big1)
+}
+
 // add validates a transaction and inserts it into the non-executable queue for
 // later pending promotion and execution. If the transaction is a replacement for
 // an already pending or queued one, it overwrites the previous and returns this
@@ -659,6 +737,10 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 			pool.removeTx(tx.Hash())
 		}
 	}
+	// Index the sponsored transaction to check its sponsor against the others
+	if sponsor, err := types.Sponsor(pool.signer, tx); err == nil {
+		pool.sponsored[hash] = sponsor
+	}
 	// If the transaction is replacing an already pending one, do directly
 	from, _ := types.Sender(pool.signer, tx) // already validated
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
@@ -825,10 +907,22 @@ func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 
 // addTxs attempts to queue a batch of transactions if they are valid.
 func (pool *TxPool) addTxs(txs []*types.Transaction, local bool) error {
+	// Verify the ring signatures of the refunds in batch out of the lock, validating
+	// the refunds one by one finds the signatures verified then.
+	valid := vm.VerifyRefundRingSigns(pool.signer, txs)
+	verified := make([]*types.Transaction, 0, len(txs))
+	for i, tx := range txs {
+		if valid[i] {
+			verified = append(verified, tx)
+		} else {
+			log.Trace("Discarding refund with invalid ring signature", "hash", tx.Hash())
+		}
+	}
+
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
-	return pool.addTxsLocked(txs, local)
+	return pool.addTxsLocked(verified, local)
 }
 
 // addTxsLocked attempts to queue a batch of transactions if they are valid,
@@ -919,6 +1013,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		}
 	}
 	// Iterate over all accounts and promote any executable transactions
+	ringSignV2 := pool.chainconfig.IsRingSignV2(pool.pendingNumber())
 	for _, addr := range accounts {
 		list := pool.queue[addr]
 		if list == nil {
@@ -934,17 +1029,15 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		// Drop all transactions that are too costly (low balance or out of gas)
 		drops, _ := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
 		for _, tx := range drops {
-			if types.IsNormalTransaction(tx.Txtype()) || types.IsPosTransaction(tx.Txtype()) {
-				hash := tx.Hash()
-				log.Trace("Removed unpayable queued transaction", "hash", hash)
-				delete(pool.all, hash)
-				pool.priced.Removed()
-				queuedNofundsCounter.Inc(1)
-			}
+			hash := tx.Hash()
+			log.Trace("Removed unpayable queued transaction", "hash", hash)
+			delete(pool.all, hash)
+			pool.priced.Removed()
+			queuedNofundsCounter.Inc(1)
 		}
 
 		// Remove all invalid privacy transactions
-		invalidPrivacy := list.InvalidPrivacyTx(pool.currentState, pool.signer, pool.currentMaxGas)
+		invalidPrivacy := list.InvalidPrivacyTx(pool.currentState, pool.signer, pool.currentMaxGas, ringSignV2)
 		for _, tx := range invalidPrivacy {
 			hash := tx.Hash()
 			log.Trace("Removed invalid privacy transaction", "hash", hash)
@@ -1101,6 +1194,8 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 // executable/pending queue and any subsequent transactions that become unexecutable
 // are moved back into the future queue.
 func (pool *TxPool) demoteUnexecutables() {
+	ringSignV2 := pool.chainconfig.IsRingSignV2(pool.pendingNumber())
+
 	// Iterate over all accounts and demote any non-executable transactions
 	for addr, list := range pool.pending {
 		nonce := pool.currentState.GetNonce(addr)
@@ -1115,13 +1210,11 @@ func (pool *TxPool) demoteUnexecutables() {
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 		drops, invalids := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
 		for _, tx := range drops {
-			if types.IsNormalTransaction(tx.Txtype()) || types.IsPosTransaction(tx.Txtype()) {
-				hash := tx.Hash()
-				log.Trace("Removed unpayable pending transaction", "hash", hash)
-				delete(pool.all, hash)
-				pool.priced.Removed()
-				pendingNofundsCounter.Inc(1)
-			}
+			hash := tx.Hash()
+			log.Trace("Removed unpayable pending transaction", "hash", hash)
+			delete(pool.all, hash)
+			pool.priced.Removed()
+			pendingNofundsCounter.Inc(1)
 		}
 		for _, tx := range invalids {
 			hash := tx.Hash()
@@ -1130,7 +1223,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		}
 
 		// Remove all invalid privacy transactions
-		invalidPrivacy := list.InvalidPrivacyTx(pool.currentState, pool.signer, pool.currentMaxGas)
+		invalidPrivacy := list.InvalidPrivacyTx(pool.currentState, pool.signer, pool.currentMaxGas, ringSignV2)
 		for _, tx := range invalidPrivacy {
 			hash := tx.Hash()
 			log.Trace("Removed invalid privacy transaction", "hash", hash)
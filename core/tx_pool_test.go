@@ -23,6 +23,7 @@ import (
 	"io/ioutil"
 	"math/big"
 	"math/rand"
+	"strings"
 	"os"
 	"testing"
 	"time"
@@ -1488,6 +1489,10 @@ var (
 	otaImageStorageAddr = common.BytesToAddress(big.NewInt(301).Bytes())
 )
 
+// stampVerifyData is a privacy transaction of sender 0x36d6780f45c253ba982d41ec17a44b66b890ada9
+// spending a stamp
+var stampVerifyData = "0x0d2897140000000000000000000000000000000000000000000000000000000000000040000000000000000000000000000000000000000000000000000000000000042000000000000000000000000000000000000000000000000000000000000003a530783034623835346663373266623031613065333665653931386230383566663532323830643138343265656232383262333839613166623364333735326564376165643962323536623333303035333932666539343031616539306131393831363463376238346133376236363031306539643065636365623061326361303761663526307830346238353466633732666230316130653336656539313862303835666635323238306431383432656562323832623338396131666233643337353265643761656439623235366233333030353339326665393430316165393061313938313634633762383461333762363630313065396430656363656230613263613037616635263078303462383534666337326662303161306533366565393138623038356666353232383064313834326565623238326233383961316662336433373532656437616564396232353662333330303533393266653934303161653930613139383136346337623834613337623636303130653964306563636562306132636130376166352b3078303438383162636366666631653562636261636234643434356631636531363131623436333932623436383866373261386530346162656562343561633238663634343362646664623233333132316339356439393336363938323363306363393831663665323832363365353234653061613565356537353835366230613763632b30783765353630353965393961373363356362666664313334653835616333636237333366353661373664613635343032356362363662373565666162656465356426307861346631333934333837346430386562313934336438653766323465643366323737613737333832643863623165336134373833626466306338623330653130263078643662633538663363366333383031636132303433633630386532656630613333646563393734366664313064356334653030666366303137383164303337662b307831633333653138323766346663386161333334646430646232656236323638646331343865376534373838363434343063353730356338343963663131626465263078376638396635373966656663363535346533656130333139333462663032333931356531376265336130363462326534333132393836656535373165306339322630783134613764646333326438323233626562643638633862643762626662326132353561323632373431383734333032313230613233343430383438383736383200000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000e4209194e600000000000000000000000001402e3c639c7552e7bf1884a2f4e796a45fbfed0000000000000000000000000000000000000000000000000000000000000060000000000000000000000000000000000000000000000000000000000000037800000000000000000000000000000000000000000000000000000000000000420367ed0938129a574b88badedc025a4ff4be0b543bb4a0fbea493f6ab120c0c02c0354a133436a7dd5354f15722adc95d3f36eefeb2618ef3badbc0976e994fa14a300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"
+
 type CTStateDB struct {
 }
 
@@ -1573,7 +1578,6 @@ func TestStampVerifySuccess(t *testing.T) {
 
 	sender := common.HexToAddress("0x36d6780f45c253ba982d41ec17a44b66b890ada9")
 	WanStamp0dot1 := "1000000000000000"
-	stampVerifyData := "0x0d2897140000000000000000000000000000000000000000000000000000000000000040000000000000000000000000000000000000000000000000000000000000042000000000000000000000000000000000000000000000000000000000000003a530783034623835346663373266623031613065333665653931386230383566663532323830643138343265656232383262333839613166623364333735326564376165643962323536623333303035333932666539343031616539306131393831363463376238346133376236363031306539643065636365623061326361303761663526307830346238353466633732666230316130653336656539313862303835666635323238306431383432656562323832623338396131666233643337353265643761656439623235366233333030353339326665393430316165393061313938313634633762383461333762363630313065396430656363656230613263613037616635263078303462383534666337326662303161306533366565393138623038356666353232383064313834326565623238326233383961316662336433373532656437616564396232353662333330303533393266653934303161653930613139383136346337623834613337623636303130653964306563636562306132636130376166352b3078303438383162636366666631653562636261636234643434356631636531363131623436333932623436383866373261386530346162656562343561633238663634343362646664623233333132316339356439393336363938323363306363393831663665323832363365353234653061613565356537353835366230613763632b30783765353630353965393961373363356362666664313334653835616333636237333366353661373664613635343032356362363662373565666162656465356426307861346631333934333837346430386562313934336438653766323465643366323737613737333832643863623165336134373833626466306338623330653130263078643662633538663363366333383031636132303433633630386532656630613333646563393734366664313064356334653030666366303137383164303337662b307831633333653138323766346663386161333334646430646232656236323638646331343865376534373838363434343063353730356338343963663131626465263078376638396635373966656663363535346533656130333139333462663032333931356531376265336130363462326534333132393836656535373165306339322630783134613764646333326438323233626562643638633862643762626662326132353561323632373431383734333032313230613233343430383438383736383200000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000e4209194e600000000000000000000000001402e3c639c7552e7bf1884a2f4e796a45fbfed0000000000000000000000000000000000000000000000000000000000000060000000000000000000000000000000000000000000000000000000000000037800000000000000000000000000000000000000000000000000000000000000420367ed0938129a574b88badedc025a4ff4be0b543bb4a0fbea493f6ab120c0c02c0354a133436a7dd5354f15722adc95d3f36eefeb2618ef3badbc0976e994fa14a300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"
 	input := common.Hex2Bytes(stampVerifyData[2:])
 
 	ref := &dummyCtRef{}
@@ -1586,7 +1590,7 @@ func TestStampVerifySuccess(t *testing.T) {
 
 	dbMockRetVal, _ = new(big.Int).SetString(WanStamp0dot1, 10)
 
-	_, _, _, err := PreProcessPrivacyTx(st.evm.StateDB, sender.Bytes(), st.data, st.gasPrice, common.Big0)
+	_, _, _, err := PreProcessPrivacyTx(st.evm.StateDB, sender.Bytes(), st.data, st.gasPrice, common.Big0, false)
 	if err != nil {
 		t.Error(err)
 		return
@@ -1598,7 +1602,6 @@ func TestStampVerifyFailWrongSender(t *testing.T) {
 
 	sender := common.HexToAddress("0x11d6780f45c253ba982d41ec17a44b66b890ada9")
 	WanStamp0dot1 := "1000000000000000"
-	stampVerifyData := "0x0d2897140000000000000000000000000000000000000000000000000000000000000040000000000000000000000000000000000000000000000000000000000000042000000000000000000000000000000000000000000000000000000000000003a530783034623835346663373266623031613065333665653931386230383566663532323830643138343265656232383262333839613166623364333735326564376165643962323536623333303035333932666539343031616539306131393831363463376238346133376236363031306539643065636365623061326361303761663526307830346238353466633732666230316130653336656539313862303835666635323238306431383432656562323832623338396131666233643337353265643761656439623235366233333030353339326665393430316165393061313938313634633762383461333762363630313065396430656363656230613263613037616635263078303462383534666337326662303161306533366565393138623038356666353232383064313834326565623238326233383961316662336433373532656437616564396232353662333330303533393266653934303161653930613139383136346337623834613337623636303130653964306563636562306132636130376166352b3078303438383162636366666631653562636261636234643434356631636531363131623436333932623436383866373261386530346162656562343561633238663634343362646664623233333132316339356439393336363938323363306363393831663665323832363365353234653061613565356537353835366230613763632b30783765353630353965393961373363356362666664313334653835616333636237333366353661373664613635343032356362363662373565666162656465356426307861346631333934333837346430386562313934336438653766323465643366323737613737333832643863623165336134373833626466306338623330653130263078643662633538663363366333383031636132303433633630386532656630613333646563393734366664313064356334653030666366303137383164303337662b307831633333653138323766346663386161333334646430646232656236323638646331343865376534373838363434343063353730356338343963663131626465263078376638396635373966656663363535346533656130333139333462663032333931356531376265336130363462326534333132393836656535373165306339322630783134613764646333326438323233626562643638633862643762626662326132353561323632373431383734333032313230613233343430383438383736383200000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000e4209194e600000000000000000000000001402e3c639c7552e7bf1884a2f4e796a45fbfed0000000000000000000000000000000000000000000000000000000000000060000000000000000000000000000000000000000000000000000000000000037800000000000000000000000000000000000000000000000000000000000000420367ed0938129a574b88badedc025a4ff4be0b543bb4a0fbea493f6ab120c0c02c0354a133436a7dd5354f15722adc95d3f36eefeb2618ef3badbc0976e994fa14a300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"
 	input := common.Hex2Bytes(stampVerifyData[2:])
 
 	ref := &dummyCtRef{}
@@ -1611,10 +1614,250 @@ func TestStampVerifyFailWrongSender(t *testing.T) {
 
 	dbMockRetVal, _ = new(big.Int).SetString(WanStamp0dot1, 10)
 
-	_, _, _, err := PreProcessPrivacyTx(st.evm.StateDB, sender.Bytes(), st.data, st.gasPrice, common.Big0)
+	_, _, _, err := PreProcessPrivacyTx(st.evm.StateDB, sender.Bytes(), st.data, st.gasPrice, common.Big0, false)
 	if err == nil {
 		t.Error(err)
 		return
 	}
 
 }
+
+// spentCtDB is a dummyCtDB with every ota image stored
+type spentCtDB struct {
+	dummyCtDB
+}
+
+func (db spentCtDB) GetStateByteArray(addr common.Address, hs common.Hash) []byte {
+	if bytes.Equal(addr.Bytes(), otaImageStorageAddr.Bytes()) {
+		return []byte{1}
+	}
+	return db.dummyCtDB.GetStateByteArray(addr, hs)
+}
+
+func TestStampVerifySpent(t *testing.T) {
+	sender := common.HexToAddress("0x36d6780f45c253ba982d41ec17a44b66b890ada9")
+	input := common.FromHex(stampVerifyData)
+	dbMockRetVal, _ = new(big.Int).SetString("1000000000000000", 10)
+
+	callData, totalGas, evmGas, err := PreProcessPrivacyTx(spentCtDB{dummyCtDB{ref: &dummyCtRef{}}}, sender.Bytes(), input, big.NewInt(10000), common.Big0, false)
+	if err != nil || callData != nil || totalGas != 0 || evmGas != 0 {
+		t.Fatal("spent stamp should leave no gas", err, callData, totalGas, evmGas)
+	}
+}
+
+func TestPrivacyTxExtraGas(t *testing.T) {
+	input := common.Hex2Bytes(stampVerifyData[2:])
+
+	var TxDataWithRing struct {
+		RingSignedData string
+		CxtCallParams  []byte
+	}
+	if err := utilAbi.Unpack(&TxDataWithRing, "combine", input[4:]); err != nil {
+		t.Fatal(err)
+	}
+	ringSize := len(strings.Split(strings.Split(TxDataWithRing.RingSignedData, "+")[0], "&"))
+
+	to := common.HexToAddress("0x01")
+	extra, err := PrivacyTxExtraGas(TxDataWithRing.CxtCallParams, &to, ringSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the ring signed data is estimated not shorter than a real one
+	ringSignGas := params.RequiredGasPerMixPub*uint64(ringSize) + params.SstoreSetGas
+	ringSignedGas := new(big.Int).Sub(IntrinsicGas(input, &to, true), IntrinsicGas(TxDataWithRing.CxtCallParams, &to, true))
+	if extra < ringSignGas+ringSignedGas.Uint64() {
+		t.Fatal("privacy tx extra gas underestimated", extra, ringSignGas, ringSignedGas)
+	}
+
+	more, _ := PrivacyTxExtraGas(TxDataWithRing.CxtCallParams, &to, ringSize+1)
+	if more <= extra {
+		t.Fatal("privacy tx extra gas should grow with ring size", more, extra)
+	}
+	if _, err := PrivacyTxExtraGas(TxDataWithRing.CxtCallParams, &to, 0); err == nil {
+		t.Fatal("empty ring should fail")
+	}
+}
+
+// testTxType is a normal transaction type counting the transactions validated
+type testTxType struct {
+	normalTxType
+	validated *int
+}
+
+func (t testTxType) ValidateTx(ctx *TxValidationContext, tx *types.Transaction, from common.Address) error {
+	*t.validated++
+	return t.normalTxType.ValidateTx(ctx, tx, from)
+}
+
+func TestTxTypeRegistry(t *testing.T) {
+	const testTxTypeId = 100
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(100), big.NewInt(100000), big.NewInt(1), nil)
+	tx.SetTxtype(testTxTypeId)
+	tx, _ = types.SignTx(tx, types.HomesteadSigner{}, key)
+	from, _ := deriveSender(tx)
+	pool.currentState.AddBalance(from, big.NewInt(0xffffffffffffff))
+
+	if err := pool.AddRemote(tx); err != ErrInvalidTxType {
+		t.Fatal("unknown transaction type should fail", err)
+	}
+	if _, err := TxAsMessage(types.HomesteadSigner{}, tx); err != ErrInvalidTxType {
+		t.Fatal("unknown transaction type should have no sender", err)
+	}
+
+	validated := 0
+	if err := RegisterTxType(testTxTypeId, testTxType{validated: &validated}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		txTypesMu.Lock()
+		delete(txTypes, testTxTypeId)
+		txTypesMu.Unlock()
+	}()
+
+	if err := pool.AddRemote(tx); err != nil {
+		t.Fatal(err)
+	}
+	if validated != 1 {
+		t.Fatal("transaction should be validated by its type", validated)
+	}
+	if !txBuysGas(tx) || txSenderCost(tx).Cmp(tx.Cost()) != 0 {
+		t.Fatal("transaction of the registered type should buy its gas")
+	}
+	if msg, err := TxAsMessage(types.HomesteadSigner{}, tx); err != nil || msg.From() != from {
+		t.Fatal("sender of the registered type mismatch", err)
+	}
+	if err := RegisterTxType(testTxTypeId, testTxType{validated: &validated}); err != ErrTxTypeRegistered {
+		t.Fatal("registered type should not be replaced", err)
+	}
+	if err := RegisterTxType(types.NORMAL_TX, testTxType{validated: &validated}); err != ErrTxTypeRegistered {
+		t.Fatal("consensus type should not be replaced", err)
+	}
+
+	// the destination of a type is checked before the state
+	pos, _ := types.SignTx(types.NewTransaction(1, vm.GetRBAddress(), big.NewInt(0), big.NewInt(100000), big.NewInt(1), nil), types.HomesteadSigner{}, key)
+	if err := pool.AddRemote(pos); err != ErrInvalidTxType {
+		t.Fatal("normal transaction to pos contract should fail", err)
+	}
+}
+
+// Tests that the gas of a sponsored transaction is checked against and paid by its
+// sponsor, while the value is paid by the sender.
+func TestSponsoredTransaction(t *testing.T) {
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	sponsorKey, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	sponsor := crypto.PubkeyToAddress(sponsorKey.PublicKey)
+	to := common.Address{1}
+
+	tx := types.NewTransaction(0, to, big.NewInt(100), big.NewInt(100000), big.NewInt(1), nil)
+	tx.SetTxtype(types.SPONSORED_TX)
+	tx, _ = types.SignTx(tx, pool.signer, key)
+	tx, _ = types.SponsorTx(tx, pool.signer, sponsorKey)
+
+	pool.currentState.AddBalance(from, big.NewInt(100))
+	if err := pool.AddRemote(tx); err != ErrInsufficientSponsorFunds {
+		t.Fatal("sponsor can't afford the gas", err)
+	}
+	pool.currentState.AddBalance(sponsor, big.NewInt(100000))
+	if err := pool.AddRemote(tx); err != nil {
+		t.Fatal(err)
+	}
+	if txBuysGas(tx) || txSenderCost(tx).Cmp(tx.Value()) != 0 {
+		t.Fatal("sender of sponsored transaction should pay the value only")
+	}
+
+	msg, err := tx.AsMessage(pool.signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb := pool.currentState.Copy()
+	evm := vm.NewEVM(vm.Context{
+		CanTransfer: CanTransfer,
+		Transfer:    Transfer,
+		GasLimit:    big.NewInt(1000000),
+		BlockNumber: big.NewInt(1),
+		Time:        big.NewInt(0),
+		Difficulty:  big.NewInt(0),
+	}, statedb, params.TestChainConfig, vm.Config{})
+	if _, gas, failed, err := ApplyMessage(evm, msg, new(GasPool).AddGas(big.NewInt(1000000))); err != nil || failed {
+		t.Fatal("sponsored message failed", err, failed)
+	} else if gas.Uint64() != params.TxGas {
+		t.Fatal("sponsored message gas mismatch", gas)
+	}
+
+	if statedb.GetBalance(from).Sign() != 0 || statedb.GetBalance(to).Int64() != 100 {
+		t.Fatal("value should be paid by sender", statedb.GetBalance(from), statedb.GetBalance(to))
+	}
+	if statedb.GetBalance(sponsor).Int64() != 100000-int64(params.TxGas) {
+		t.Fatal("gas should be paid by sponsor", statedb.GetBalance(sponsor))
+	}
+	if statedb.GetNonce(from) != 1 || statedb.GetNonce(sponsor) != 0 {
+		t.Fatal("nonce should be of sender", statedb.GetNonce(from), statedb.GetNonce(sponsor))
+	}
+}
+
+// Tests that the gas of all the pooled transactions of a sponsor is checked against
+// its balance, and that they're dropped once the sponsor is drained.
+func TestSponsoredTransactionDrained(t *testing.T) {
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	otherKey, _ := crypto.GenerateKey()
+	sponsorKey, _ := crypto.GenerateKey()
+	sponsor := crypto.PubkeyToAddress(sponsorKey.PublicKey)
+
+	sponsored := func(nonce uint64, gasprice int64, key *ecdsa.PrivateKey) *types.Transaction {
+		tx := types.NewTransaction(nonce, common.Address{1}, big.NewInt(0), big.NewInt(100000), big.NewInt(gasprice), nil)
+		tx.SetTxtype(types.SPONSORED_TX)
+		tx, _ = types.SignTx(tx, pool.signer, key)
+		tx, _ = types.SponsorTx(tx, pool.signer, sponsorKey)
+		return tx
+	}
+	pool.currentState.AddBalance(sponsor, big.NewInt(200000))
+
+	if err := pool.AddRemote(sponsored(0, 1, key)); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.AddRemote(sponsored(0, 1, otherKey)); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.AddRemote(sponsored(1, 1, key)); err != ErrInsufficientSponsorFunds {
+		t.Fatal("sponsor can't afford the gas of all its transactions", err)
+	}
+	// a replacement is checked against the other transactions only
+	if err := pool.AddRemote(sponsored(0, 2, otherKey)); err != ErrInsufficientSponsorFunds {
+		t.Fatal("sponsor can't afford the replacement", err)
+	}
+	pool.currentState.AddBalance(sponsor, big.NewInt(100000))
+	if err := pool.AddRemote(sponsored(0, 2, otherKey)); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Fatal("pending transactions mismatch", pending)
+	}
+
+	// drain the sponsor, the cheapest transaction is dropped
+	pool.currentState.SubBalance(sponsor, big.NewInt(100000))
+	pool.lockedReset(nil, nil)
+	if pending, queued := pool.Stats(); pending != 1 || queued != 0 {
+		t.Fatal("unsponsored transaction should be dropped", pending, queued)
+	}
+	if pool.pending[crypto.PubkeyToAddress(otherKey.PublicKey)] == nil {
+		t.Fatal("transaction of the higher price should be kept")
+	}
+	pool.currentState.SetBalance(sponsor, new(big.Int))
+	pool.lockedReset(nil, nil)
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatal("unsponsored transactions should be dropped", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+// ErrTxTypeRegistered is returned by RegisterTxType for a type which has a handler
+var ErrTxTypeRegistered = errors.New("transaction type already registered")
+
+// TxValidationContext is the state of the tx pool a transaction is validated against
+type TxValidationContext struct {
+	State     vm.StateDB
+	Signer    types.Signer
+	MaxGas    *big.Int // gas limit of the pending block
+	Homestead bool
+	Config    *params.ChainConfig
+	Number    *big.Int // number of the pending block
+
+	// SponsorCost returns the gas the sponsor pays for the other transactions in the
+	// pool, it's nil if the transaction isn't validated by the pool.
+	SponsorCost func(sponsor common.Address) *big.Int
+}
+
+// TxTypeHandler declares how the transactions of a type are validated by the tx pool
+// and applied by the state transition. A new transaction type is added by registering
+// its handler with RegisterTxType.
+type TxTypeHandler interface {
+	// BuysGas reports whether the sender buys the gas and the value of the transaction
+	// up front, such transactions are dropped from the pool once the sender can't
+	// afford them.
+	BuysGas() bool
+
+	// Sender recovers the account sending the transaction
+	Sender(signer types.Signer, tx *types.Transaction) (common.Address, error)
+
+	// SenderCost returns the funds the sender pays up front, the pool drops the
+	// transaction once the sender can't afford them. It's nil if the sender isn't
+	// charged by the pool.
+	SenderCost(tx *types.Transaction) *big.Int
+
+	// IntrinsicGas returns the gas paid before the transaction is executed
+	IntrinsicGas(data []byte, to *common.Address, homestead bool) *big.Int
+
+	// CheckTx validates the transaction regardless of the state
+	CheckTx(tx *types.Transaction) error
+
+	// ValidateTx validates the transaction of from against the state of the pool
+	ValidateTx(ctx *TxValidationContext, tx *types.Transaction, from common.Address) error
+
+	// PreCheck runs first in the state transition, before the intrinsic gas is paid
+	PreCheck(st *StateTransition) error
+
+	// PreTransition runs after the intrinsic gas is known and before it's paid
+	PreTransition(st *StateTransition) error
+
+	// PostTransition settles the gas once the message is executed, it returns the
+	// gas required by the message and the gas charged for it.
+	PostTransition(st *StateTransition) (requiredGas, usedGas *big.Int)
+}
+
+var (
+	txTypesMu sync.RWMutex
+	txTypes   = map[uint64]TxTypeHandler{
+		types.NORMAL_TX:    normalTxType{},
+		types.PRIVACY_TX:   privacyTxType{},
+		types.POS_TX:       posTxType{},
+		types.SPONSORED_TX: sponsoredTxType{},
+	}
+)
+
+// RegisterTxType registers the handler of the transactions of txType. A handler
+// registered already can't be replaced, the handlers of the consensus types are
+// registered from the start.
+func RegisterTxType(txType uint64, handler TxTypeHandler) error {
+	txTypesMu.Lock()
+	defer txTypesMu.Unlock()
+
+	if _, ok := txTypes[txType]; ok {
+		return ErrTxTypeRegistered
+	}
+	txTypes[txType] = handler
+	return nil
+}
+
+// TxTypeHandlerOf returns the handler of the transactions of txType, nil if the
+// type is unknown.
+func TxTypeHandlerOf(txType uint64) TxTypeHandler {
+	txTypesMu.RLock()
+	defer txTypesMu.RUnlock()
+
+	return txTypes[txType]
+}
+
+// txBuysGas reports whether tx is of a known type which buys its gas up front
+func txBuysGas(tx *types.Transaction) bool {
+	handler := TxTypeHandlerOf(tx.Txtype())
+	return handler != nil && handler.BuysGas()
+}
+
+// txSenderCost returns the funds the sender of tx pays up front, nil if the sender
+// of tx isn't charged by the pool.
+func txSenderCost(tx *types.Transaction) *big.Int {
+	handler := TxTypeHandlerOf(tx.Txtype())
+	if handler == nil {
+		return nil
+	}
+	return handler.SenderCost(tx)
+}
+
+// TxSender recovers the sender of tx by the handler of its type
+func TxSender(signer types.Signer, tx *types.Transaction) (common.Address, error) {
+	handler := TxTypeHandlerOf(tx.Txtype())
+	if handler == nil {
+		return common.Address{}, ErrInvalidTxType
+	}
+	return handler.Sender(signer, tx)
+}
+
+// TxAsMessage returns tx as a message sent by the sender its handler recovers
+func TxAsMessage(signer types.Signer, tx *types.Transaction) (types.Message, error) {
+	from, err := TxSender(signer, tx)
+	if err != nil {
+		return types.Message{}, err
+	}
+	return tx.AsMessageFrom(signer, from)
+}
+
+// baseTxType is the handler of the transactions which buy their gas with the
+// balance of the sender and are signed by the sender.
+type baseTxType struct{}
+
+func (baseTxType) BuysGas() bool { return true }
+
+func (baseTxType) Sender(signer types.Signer, tx *types.Transaction) (common.Address, error) {
+	return types.Sender(signer, tx)
+}
+
+func (baseTxType) SenderCost(tx *types.Transaction) *big.Int { return tx.Cost() }
+
+func (baseTxType) IntrinsicGas(data []byte, to *common.Address, homestead bool) *big.Int {
+	return IntrinsicGas(data, to, homestead)
+}
+
+func (baseTxType) CheckTx(tx *types.Transaction) error {
+	if vm.IsPosPrecompiledAddr(tx.To()) {
+		return ErrInvalidTxType
+	}
+	return nil
+}
+
+func (t baseTxType) ValidateTx(ctx *TxValidationContext, tx *types.Transaction, from common.Address) error {
+	// Transactor should have enough funds to cover the costs
+	// cost == V + GP * GL
+	if ctx.State.GetBalance(from).Cmp(tx.Cost()) < 0 {
+		return ErrInsufficientFunds
+	}
+	if tx.Gas().Cmp(t.IntrinsicGas(tx.Data(), tx.To(), ctx.Homestead)) < 0 {
+		return ErrIntrinsicGas
+	}
+	return nil
+}
+
+func (baseTxType) PreCheck(st *StateTransition) error { return st.preCheck() }
+
+func (baseTxType) PreTransition(st *StateTransition) error { return nil }
+
+func (baseTxType) PostTransition(st *StateTransition) (requiredGas, usedGas *big.Int) {
+	requiredGas = st.gasUsed()
+	st.refundGas()
+	usedGas = new(big.Int).Set(st.gasUsed())
+	return requiredGas, usedGas
+}
+
+// normalTxType is the handler of the normal transactions
+type normalTxType struct {
+	baseTxType
+}
+
+// posTxType is the handler of the transactions to the pos precompiled contracts
+type posTxType struct {
+	baseTxType
+}
+
+func (posTxType) CheckTx(tx *types.Transaction) error {
+	if !vm.IsPosPrecompiledAddr(tx.To()) {
+		return ErrInvalidTxType
+	}
+	return nil
+}
+
+// sponsoredTxType is the handler of the sponsored transactions, the gas of which is
+// bought by the sponsor signing the transaction in addition to the sender. The
+// nonce and the value are of the sender still.
+type sponsoredTxType struct {
+	baseTxType
+}
+
+// BuysGas is false as the sender buys the value only, the gas of all the pooled
+// transactions of a sponsor is checked against its balance by the pool.
+func (sponsoredTxType) BuysGas() bool { return false }
+
+// SenderCost is the value, the gas is paid by the sponsor
+func (sponsoredTxType) SenderCost(tx *types.Transaction) *big.Int { return tx.Value() }
+
+func (t sponsoredTxType) ValidateTx(ctx *TxValidationContext, tx *types.Transaction, from common.Address) error {
+	if ctx.Config == nil || !ctx.Config.IsSponsoredTx(ctx.Number) {
+		return ErrInvalidTxType
+	}
+	sponsor, err := types.Sponsor(ctx.Signer, tx)
+	if err != nil {
+		return err
+	}
+
+	if ctx.State.GetBalance(from).Cmp(tx.Value()) < 0 {
+		return ErrInsufficientFunds
+	}
+	cost := new(big.Int).Mul(tx.GasPrice(), tx.Gas())
+	if ctx.SponsorCost != nil {
+		cost.Add(cost, ctx.SponsorCost(sponsor))
+	}
+	if ctx.State.GetBalance(sponsor).Cmp(cost) < 0 {
+		return ErrInsufficientSponsorFunds
+	}
+	if tx.Gas().Cmp(t.IntrinsicGas(tx.Data(), tx.To(), ctx.Homestead)) < 0 {
+		return ErrIntrinsicGas
+	}
+	return nil
+}
+
+func (sponsoredTxType) PreCheck(st *StateTransition) error {
+	if !st.evm.ChainConfig().IsSponsoredTx(st.evm.BlockNumber) || st.msg.Sponsor() == nil {
+		return ErrInvalidTxType
+	}
+	return st.preCheck()
+}
+
+// privacyTxType is the handler of the privacy transactions, the gas of which is
+// paid by the stamp ring signed in the transaction.
+type privacyTxType struct {
+	baseTxType
+}
+
+func (privacyTxType) BuysGas() bool { return false }
+
+func (privacyTxType) SenderCost(tx *types.Transaction) *big.Int { return nil }
+
+func (t privacyTxType) ValidateTx(ctx *TxValidationContext, tx *types.Transaction, from common.Address) error {
+	intrGas := t.IntrinsicGas(tx.Data(), tx.To(), ctx.Homestead)
+	ringSignV2 := ctx.Config != nil && ctx.Config.IsRingSignV2(ctx.Number)
+	return ValidPrivacyTx(ctx.State, from.Bytes(), tx.Data(), tx.GasPrice(), intrGas, tx.Value(), ctx.MaxGas, ringSignV2)
+}
+
+func (privacyTxType) PreCheck(st *StateTransition) error { return nil }
+
+func (privacyTxType) PreTransition(st *StateTransition) error {
+	info, err := preProcessPrivacyTx(st.evm.StateDB,
+		st.from().Address().Bytes(),
+		st.data, st.gasPrice, st.value,
+		st.evm.ChainConfig().IsRingSignV2(st.evm.BlockNumber))
+	if err != nil {
+		return err
+	}
+
+	st.gas = info.GasLeftSubRingSign
+	st.initialGas.SetUint64(info.GasLeftSubRingSign)
+	st.data = info.CallData[:]
+	st.privacyFee = &types.PrivacyFee{
+		StampValue:  new(big.Int).Set(info.StampBalance),
+		StampGas:    new(big.Int).SetUint64(info.StampTotalGas),
+		RingSignGas: new(big.Int).SetUint64(info.StampTotalGas - info.GasLeftSubRingSign),
+	}
+	log.Trace("pre process privacy tx", "stampTotalGas", info.StampTotalGas, "evmUseableGas", info.GasLeftSubRingSign)
+	//sub gas from total gas of curent block,prevent gas is overhead gaslimit
+	return st.gp.SubGas(new(big.Int).SetUint64(info.StampTotalGas))
+}
+
+func (privacyTxType) PostTransition(st *StateTransition) (requiredGas, usedGas *big.Int) {
+	requiredGas = new(big.Int).Set(st.privacyFee.StampGas)
+	usedGas = requiredGas
+
+	// the stamp is consumed as a whole, the gas left is recorded but not refunded
+	st.privacyFee.EVMGasUsed = st.gasUsed()
+	st.privacyFee.GasRemainder = new(big.Int).SetUint64(st.gas)
+	return requiredGas, usedGas
+}
@@ -26,6 +26,7 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 		R            *hexutil.Big    `json:"r" gencodec:"required"`
 		S            *hexutil.Big    `json:"s" gencodec:"required"`
 		Hash         *common.Hash    `json:"hash" rlp:"-"`
+		Sponsor      []*hexutil.Big  `json:"sponsor,omitempty" rlp:"tail"`
 	}
 
 	var enc txdata
@@ -40,6 +41,12 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 	enc.R = (*hexutil.Big)(t.R)
 	enc.S = (*hexutil.Big)(t.S)
 	enc.Hash = t.Hash
+	if t.Sponsor != nil {
+		enc.Sponsor = make([]*hexutil.Big, len(t.Sponsor))
+		for k, v := range t.Sponsor {
+			enc.Sponsor[k] = (*hexutil.Big)(v)
+		}
+	}
 	return json.Marshal(&enc)
 }
 
@@ -56,6 +63,7 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 		R            *hexutil.Big    `json:"r" gencodec:"required"`
 		S            *hexutil.Big    `json:"s" gencodec:"required"`
 		Hash         *common.Hash    `json:"hash" rlp:"-"`
+		Sponsor      []*hexutil.Big  `json:"sponsor,omitempty" rlp:"tail"`
 	}
 	var dec txdata
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -105,5 +113,11 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 	if dec.Hash != nil {
 		t.Hash = dec.Hash
 	}
+	if dec.Sponsor != nil {
+		t.Sponsor = make([]*big.Int, len(dec.Sponsor))
+		for k, v := range dec.Sponsor {
+			t.Sponsor[k] = (*big.Int)(v)
+		}
+	}
 	return nil
 }
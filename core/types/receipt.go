@@ -28,6 +28,7 @@ import (
 )
 
 //go:generate gencodec -type Receipt -field-override receiptMarshaling -out gen_receipt_json.go
+//go:generate gencodec -type PrivacyFee -field-override privacyFeeMarshaling -out gen_privacy_fee_json.go
 
 var (
 	receiptStatusFailedRLP     = []byte{}
@@ -55,6 +56,9 @@ type Receipt struct {
 	TxHash          common.Hash    `json:"transactionHash" gencodec:"required"`
 	ContractAddress common.Address `json:"contractAddress"`
 	GasUsed         *big.Int       `json:"gasUsed" gencodec:"required"`
+
+	// PrivacyFee is the fee accounting of a privacy transaction, nil for the others
+	PrivacyFee *PrivacyFee `json:"privacyFee,omitempty"`
 }
 
 type receiptMarshaling struct {
@@ -64,6 +68,25 @@ type receiptMarshaling struct {
 	GasUsed           *hexutil.Big
 }
 
+// PrivacyFee is how the stamp of a privacy transaction is consumed. The stamp is
+// spent as a whole, so StampGas is the gas used by the transaction and the
+// remainder is not refunded.
+type PrivacyFee struct {
+	StampValue   *big.Int `json:"stampValue"   gencodec:"required"` // value of the stamp consumed
+	StampGas     *big.Int `json:"stampGas"     gencodec:"required"` // gas the stamp pays for at the gas price
+	RingSignGas  *big.Int `json:"ringSignGas"  gencodec:"required"` // gas of the ring signature and the key image
+	EVMGasUsed   *big.Int `json:"evmGasUsed"   gencodec:"required"` // intrinsic and execution gas used
+	GasRemainder *big.Int `json:"gasRemainder" gencodec:"required"` // gas paid by the stamp but not used
+}
+
+type privacyFeeMarshaling struct {
+	StampValue   *hexutil.Big
+	StampGas     *hexutil.Big
+	RingSignGas  *hexutil.Big
+	EVMGasUsed   *hexutil.Big
+	GasRemainder *hexutil.Big
+}
+
 // receiptRLP is the consensus encoding of a receipt.
 type receiptRLP struct {
 	PostStateOrStatus []byte
@@ -80,6 +103,7 @@ type receiptStorageRLP struct {
 	ContractAddress   common.Address
 	Logs              []*LogForStorage
 	GasUsed           *big.Int
+	PrivacyFee        []*PrivacyFee `rlp:"tail"` // at most one, absent in the receipts of the other transactions
 }
 
 // NewReceipt creates a barebone transaction receipt, copying the init fields.
@@ -164,6 +188,9 @@ func (r *ReceiptForStorage) EncodeRLP(w io.Writer) error {
 	for i, log := range r.Logs {
 		enc.Logs[i] = (*LogForStorage)(log)
 	}
+	if r.PrivacyFee != nil {
+		enc.PrivacyFee = []*PrivacyFee{r.PrivacyFee}
+	}
 	return rlp.Encode(w, enc)
 }
 
@@ -185,6 +212,9 @@ func (r *ReceiptForStorage) DecodeRLP(s *rlp.Stream) error {
 	}
 	// Assign the implementation fields
 	r.TxHash, r.ContractAddress, r.GasUsed = dec.TxHash, dec.ContractAddress, dec.GasUsed
+	if len(dec.PrivacyFee) > 0 {
+		r.PrivacyFee = dec.PrivacyFee[0]
+	}
 	return nil
 }
 
@@ -53,6 +53,8 @@ type Transaction struct {
 	hash atomic.Value
 	size atomic.Value
 	from atomic.Value
+
+	sponsor atomic.Value
 }
 
 type txdata struct {
@@ -72,6 +74,9 @@ type txdata struct {
 
 	// This is only used when marshaling to JSON.
 	Hash *common.Hash `json:"hash" rlp:"-"`
+
+	// Signature values [V, R, S] of the sponsor of a sponsored transaction
+	Sponsor []*big.Int `json:"sponsor,omitempty" rlp:"tail"`
 }
 
 type txdataMarshaling struct {
@@ -85,6 +90,7 @@ type txdataMarshaling struct {
 	V            *hexutil.Big
 	R            *hexutil.Big
 	S            *hexutil.Big
+	Sponsor      []*hexutil.Big
 }
 
 func NewTransaction(nonce uint64, to common.Address, amount, gasLimit, gasPrice *big.Int, data []byte) *Transaction {
@@ -152,6 +158,9 @@ func (tx *Transaction) EncodeRLP(w io.Writer) error {
 func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
 	_, size, _ := s.Kind()
 	err := s.Decode(&tx.data)
+	if err == nil {
+		err = tx.data.validateSponsor()
+	}
 	if err == nil {
 		tx.size.Store(common.StorageSize(rlp.ListSize(size)))
 	}
@@ -159,6 +168,17 @@ func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
 	return err
 }
 
+// validateSponsor checks only the sponsored transactions carry the sponsor signature
+func (d *txdata) validateSponsor() error {
+	if len(d.Sponsor) == 0 {
+		return nil
+	}
+	if !IsSponsoredTransaction(d.Txtype) || len(d.Sponsor) != 3 {
+		return ErrInvalidSponsorSig
+	}
+	return nil
+}
+
 func (tx *Transaction) MarshalJSON() ([]byte, error) {
 	hash := tx.Hash()
 	data := tx.data
@@ -182,6 +202,9 @@ func (tx *Transaction) UnmarshalJSON(input []byte) error {
 	if !crypto.ValidateSignatureValues(V, dec.R, dec.S, false) {
 		return ErrInvalidSig
 	}
+	if err := dec.validateSponsor(); err != nil {
+		return err
+	}
 	*tx = Transaction{data: dec}
 	return nil
 }
@@ -234,7 +257,18 @@ func (tx *Transaction) Size() common.StorageSize {
 //
 // XXX Rename message to something less arbitrary?
 func (tx *Transaction) AsMessage(s Signer) (Message, error) {
+	from, err := Sender(s, tx)
+	if err != nil {
+		return Message{}, err
+	}
+	return tx.AsMessageFrom(s, from)
+}
+
+// AsMessageFrom returns the transaction as a core.Message sent by from, the
+// signer derives the sponsor of a sponsored transaction.
+func (tx *Transaction) AsMessageFrom(s Signer, from common.Address) (Message, error) {
 	msg := Message{
+		from:       from,
 		nonce:      tx.data.AccountNonce,
 		price:      new(big.Int).Set(tx.data.Price),
 		gasLimit:   new(big.Int).Set(tx.data.GasLimit),
@@ -244,9 +278,12 @@ func (tx *Transaction) AsMessage(s Signer) (Message, error) {
 		checkNonce: true,
 		txType:     tx.Txtype(),
 	}
+	if !IsSponsoredTransaction(msg.txType) {
+		return msg, nil
+	}
 
-	var err error
-	msg.from, err = Sender(s, tx)
+	sponsor, err := Sponsor(s, tx)
+	msg.sponsor = &sponsor
 	return msg, err
 }
 
@@ -259,6 +296,23 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 	}
 	cpy := &Transaction{data: tx.data}
 	cpy.data.R, cpy.data.S, cpy.data.V = r, s, v
+	// the sponsor signs the signature of the sender, it's void once re-signed
+	cpy.data.Sponsor = nil
+	return cpy, nil
+}
+
+// WithSponsorSignature returns a new transaction with the given signature of the
+// sponsor. This signature needs to be in the [R || S || V] format where V is 0 or 1.
+func (tx *Transaction) WithSponsorSignature(sig []byte) (*Transaction, error) {
+	if !IsSponsoredTransaction(tx.data.Txtype) {
+		return nil, ErrNotSponsoredTx
+	}
+	r, s, v, err := FrontierSigner{}.SignatureValues(tx, sig)
+	if err != nil {
+		return nil, err
+	}
+	cpy := &Transaction{data: tx.data}
+	cpy.data.Sponsor = []*big.Int{v, r, s}
 	return cpy, nil
 }
 
@@ -457,6 +511,7 @@ type Message struct {
 	data                    []byte
 	checkNonce              bool
 	txType                  uint64
+	sponsor                 *common.Address
 }
 
 func NewMessage(from common.Address, to *common.Address, nonce uint64, amount, gasLimit, price *big.Int, data []byte, checkNonce bool) Message {
@@ -484,6 +539,10 @@ func (m Message) CheckNonce() bool     { return m.checkNonce }
 
 func (m Message) TxType() uint64 { return m.txType }
 
+// Sponsor returns the account paying the gas of a sponsored transaction, nil if
+// the gas is paid by the sender.
+func (m Message) Sponsor() *common.Address { return m.sponsor }
+
 ////////////////////////////////////for privacy tx ///////////////////////
 func NewOTATransaction(nonce uint64, to common.Address, amount, gasLimit, gasPrice *big.Int, data []byte) *Transaction {
 	return newOTATransaction(nonce, &to, amount, gasLimit, gasPrice, data)
@@ -523,9 +582,10 @@ func newOTATransaction(nonce uint64, to *common.Address, amount, gasLimit, gasPr
 }
 
 const (
-	NORMAL_TX  = 1
-	PRIVACY_TX = 6
-	POS_TX     = 7
+	NORMAL_TX    = 1
+	PRIVACY_TX   = 6
+	POS_TX       = 7
+	SPONSORED_TX = 8
 )
 
 func IsNormalTransaction(txType uint64) bool {
@@ -537,6 +597,9 @@ func IsPosTransaction(txType uint64) bool {
 func IsPrivacyTransaction(txType uint64) bool {
 	return txType == PRIVACY_TX
 }
+func IsSponsoredTransaction(txType uint64) bool {
+	return txType == SPONSORED_TX
+}
 func IsValidTransactionType(txType uint64) bool {
-	return (txType == NORMAL_TX || txType == PRIVACY_TX|| txType == POS_TX)
+	return (txType == NORMAL_TX || txType == PRIVACY_TX|| txType == POS_TX || txType == SPONSORED_TX)
 }
@@ -28,7 +28,9 @@ import (
 )
 
 var (
-	ErrInvalidChainId = errors.New("invalid chain id for signer")
+	ErrInvalidChainId    = errors.New("invalid chain id for signer")
+	ErrNotSponsoredTx    = errors.New("not a sponsored transaction")
+	ErrInvalidSponsorSig = errors.New("invalid sponsor v, r, s values")
 )
 
 // sigCache is used to cache the derived sender and contains
@@ -68,6 +70,53 @@ func SignTx(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, err
 	return tx.WithSignature(s, sig)
 }
 
+// SponsorTx signs the gas of the sponsored transaction signed by the sender already,
+// using the given signer and private key of the sponsor.
+func SponsorTx(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, error) {
+	h := SponsorHash(s, tx)
+	sig, err := crypto.Sign(h[:], prv)
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSponsorSignature(sig)
+}
+
+// SponsorHash returns the hash to be signed by the sponsor. It covers the signature
+// of the sender, so the sponsor pays the gas of the very transaction it has seen.
+func SponsorHash(s Signer, tx *Transaction) common.Hash {
+	return rlpHash([]interface{}{
+		s.Hash(tx),
+		tx.data.V,
+		tx.data.R,
+		tx.data.S,
+	})
+}
+
+// Sponsor returns the address of the sponsor derived from the sponsor signature of
+// a sponsored transaction, the derived address is cached like Sender's.
+func Sponsor(signer Signer, tx *Transaction) (common.Address, error) {
+	if sc := tx.sponsor.Load(); sc != nil {
+		sigCache := sc.(sigCache)
+		if sigCache.signer.Equal(signer) {
+			return sigCache.from, nil
+		}
+	}
+
+	if !IsSponsoredTransaction(tx.data.Txtype) {
+		return common.Address{}, ErrNotSponsoredTx
+	}
+	if len(tx.data.Sponsor) != 3 {
+		return common.Address{}, ErrInvalidSponsorSig
+	}
+	V, R, S := tx.data.Sponsor[0], tx.data.Sponsor[1], tx.data.Sponsor[2]
+	addr, err := recoverPlain(SponsorHash(signer, tx), R, S, V, true)
+	if err != nil {
+		return common.Address{}, err
+	}
+	tx.sponsor.Store(sigCache{signer: signer, from: addr})
+	return addr, nil
+}
+
 // Sender returns the address derived from the signature (V, R, S) using secp256k1
 // elliptic curve and an error if it failed deriving or upon an incorrect
 // signature.
@@ -233,3 +233,54 @@ func TestTransactionJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestSponsoredTransaction(t *testing.T) {
+	key, from := defaultTestKey()
+	sponsorKey, _ := crypto.GenerateKey()
+	signer := NewEIP155Signer(common.Big1)
+
+	tx := NewTransaction(0, common.Address{1}, big.NewInt(10), big.NewInt(21000), big.NewInt(1), nil)
+	tx.SetTxtype(SPONSORED_TX)
+	tx, _ = SignTx(tx, signer, key)
+	if _, err := Sponsor(signer, tx); err != ErrInvalidSponsorSig {
+		t.Fatal("transaction should be sponsored before use", err)
+	}
+
+	tx, err := SponsorTx(tx, signer, sponsorKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, _ := rlp.EncodeToBytes(tx)
+	dec, err := decodeTx(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec.Hash() != tx.Hash() {
+		t.Fatal("decoded transaction mismatch")
+	}
+
+	msg, err := dec.AsMessage(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.From() != from || msg.Sponsor() == nil || *msg.Sponsor() != crypto.PubkeyToAddress(sponsorKey.PublicKey) {
+		t.Fatal("sponsored message mismatch", msg.From().Hex(), msg.Sponsor())
+	}
+
+	// the sponsor signature is void once the sender re-signs
+	resigned, _ := SignTx(tx, signer, key)
+	if _, err := Sponsor(signer, resigned); err != ErrInvalidSponsorSig {
+		t.Fatal("sponsor signature should be dropped", err)
+	}
+
+	// other transactions can't carry a sponsor signature
+	normal := NewTransaction(0, common.Address{1}, big.NewInt(10), big.NewInt(21000), big.NewInt(1), nil)
+	if _, err := SponsorTx(normal, signer, sponsorKey); err != ErrNotSponsoredTx {
+		t.Fatal("normal transaction should not be sponsored", err)
+	}
+	tx.data.Txtype = NORMAL_TX
+	enc, _ = rlp.EncodeToBytes(tx)
+	if _, err := decodeTx(enc); err != ErrInvalidSponsorSig {
+		t.Fatal("sponsor signature of normal transaction should fail", err)
+	}
+}
@@ -388,12 +388,14 @@ func (c *bn256Pairing) ValidTx(stateDB StateDB, signer types.Signer, tx *types.T
 
 var (
 	coinSCDefinition = `
-	[{"constant": false,"type": "function","stateMutability": "nonpayable","inputs": [{"name": "OtaAddr","type":"string"},{"name": "Value","type": "uint256"}],"name": "buyCoinNote","outputs": [{"name": "OtaAddr","type":"string"},{"name": "Value","type": "uint256"}]},{"constant": false,"type": "function","inputs": [{"name":"RingSignedData","type": "string"},{"name": "Value","type": "uint256"}],"name": "refundCoin","outputs": [{"name": "RingSignedData","type": "string"},{"name": "Value","type": "uint256"}]},{"constant": false,"type": "function","stateMutability": "nonpayable","inputs": [],"name": "getCoins","outputs": [{"name":"Value","type": "uint256"}]}]`
+	[{"constant": false,"type": "function","stateMutability": "nonpayable","inputs": [{"name": "OtaAddr","type":"string"},{"name": "Value","type": "uint256"}],"name": "buyCoinNote","outputs": [{"name": "OtaAddr","type":"string"},{"name": "Value","type": "uint256"}]},{"constant": false,"type": "function","inputs": [{"name":"RingSignedData","type": "string"},{"name": "Value","type": "uint256"}],"name": "refundCoin","outputs": [{"name": "RingSignedData","type": "string"},{"name": "Value","type": "uint256"}]},{"constant": false,"type": "function","stateMutability": "nonpayable","inputs": [],"name": "getCoins","outputs": [{"name":"Value","type": "uint256"}]},{"constant": false,"type": "function","stateMutability": "nonpayable","inputs": [{"name": "OtaAddrs","type":"string"},{"name": "Values","type": "uint256[]"}],"name": "buyCoinNotes","outputs": [{"name": "OtaAddrs","type":"string"},{"name": "Values","type": "uint256[]"}]},{"constant": false,"type": "function","inputs": [{"name":"RingSignedDatas","type": "string"},{"name": "Values","type": "uint256[]"}],"name": "refundCoins","outputs": [{"name": "RingSignedDatas","type": "string"},{"name": "Values","type": "uint256[]"}]},{"constant": false,"type": "function","stateMutability": "nonpayable","inputs": [{"name": "OtaAddr","type":"string"},{"name": "Value","type": "uint256"},{"name": "Memo","type": "bytes"}],"name": "buyCoinNoteWithMemo","outputs": [{"name": "OtaAddr","type":"string"},{"name": "Value","type": "uint256"},{"name": "Memo","type": "bytes"}]}]`
 
 	stampSCDefinition = `[{"constant": false,"type": "function","stateMutability": "nonpayable","inputs": [{"name":"OtaAddr","type": "string"},{"name": "Value","type": "uint256"}],"name": "buyStamp","outputs": [{"name": "OtaAddr","type": "string"},{"name": "Value","type": "uint256"}]},{"constant": false,"type": "function","inputs": [{"name": "RingSignedData","type": "string"},{"name": "Value","type": "uint256"}],"name": "refundCoin","outputs": [{"name": "RingSignedData","type": "string"},{"name": "Value","type": "uint256"}]},{"constant": false,"type": "function","stateMutability": "nonpayable","inputs": [],"name": "getCoins","outputs": [{"name": "Value","type": "uint256"}]}]`
 
 	coinAbi, errCoinSCInit               = abi.JSON(strings.NewReader(coinSCDefinition))
 	buyIdArr, refundIdArr, getCoinsIdArr [4]byte
+	buyBatchIdArr, refundBatchIdArr      [4]byte
+	buyMemoIdArr                         [4]byte
 
 	stampAbi, errStampSCInit = abi.JSON(strings.NewReader(stampSCDefinition))
 	stBuyId                  [4]byte
@@ -418,6 +420,10 @@ var (
 
 	ErrOTAReused = errors.New("OTA is reused")
 
+	ErrInvalidCoinBatch = errors.New("invalid wancoin batch size")
+
+	ErrOTAMemoTooLong = errors.New("ota memo is too long")
+
 	StampValueSet   = make(map[string]string, 5)
 	WanCoinValueSet = make(map[string]string, 10)
 )
@@ -451,6 +457,10 @@ const (
 
 )
 
+// CoinBatchSeparator separates the otas of a buyCoinNotes call and the ring signed
+// datas of a refundCoins call.
+const CoinBatchSeparator = "|"
+
 func init() {
 	if errCoinSCInit != nil || errStampSCInit != nil {
 		panic("err in coin sc initialize or stamp error initialize ")
@@ -459,6 +469,9 @@ func init() {
 	copy(buyIdArr[:], coinAbi.Methods["buyCoinNote"].Id())
 	copy(refundIdArr[:], coinAbi.Methods["refundCoin"].Id())
 	copy(getCoinsIdArr[:], coinAbi.Methods["getCoins"].Id())
+	copy(buyBatchIdArr[:], coinAbi.Methods["buyCoinNotes"].Id())
+	copy(refundBatchIdArr[:], coinAbi.Methods["refundCoins"].Id())
+	copy(buyMemoIdArr[:], coinAbi.Methods["buyCoinNoteWithMemo"].Id())
 
 	copy(stBuyId[:], stampAbi.Methods["buyStamp"].Id())
 
@@ -657,17 +670,57 @@ func (c *wanCoinSC) RequiredGas(input []byte) uint64 {
 			return params.RequiredGasPerMixPub
 		}
 
-		err, publickeys, _, _, _ := DecodeRingSignOut(RefundStruct.RingSignedData)
+		ringSign, err := DecodeRingSignedData(RefundStruct.RingSignedData, true)
 		if err != nil {
 			return params.RequiredGasPerMixPub
 		}
 
-		mixLen := len(publickeys)
+		mixLen := len(ringSign.PublicKeys)
 		ringSigDiffRequiredGas := params.RequiredGasPerMixPub * (uint64(mixLen))
 
 		// ringsign compute gas + ota image key store setting gas
 		return ringSigDiffRequiredGas + params.SstoreSetGas
 
+	} else if methodIdArr == refundBatchIdArr {
+
+		var RefundStruct struct {
+			RingSignedDatas string
+			Values          []*big.Int
+		}
+
+		err := coinAbi.Unpack(&RefundStruct, "refundCoins", input[4:])
+		if err != nil {
+			return params.RequiredGasPerMixPub
+		}
+
+		gas := uint64(0)
+		for _, ringSignedData := range strings.Split(RefundStruct.RingSignedDatas, CoinBatchSeparator) {
+			ringSign, err := DecodeRingSignedData(ringSignedData, true)
+			if err != nil {
+				return gas + params.RequiredGasPerMixPub
+			}
+
+			// ringsign compute gas + ota image key store setting gas
+			gas += params.RequiredGasPerMixPub*uint64(len(ringSign.PublicKeys)) + params.SstoreSetGas
+		}
+
+		return gas
+
+	} else if methodIdArr == buyBatchIdArr {
+
+		var outStruct struct {
+			OtaAddrs string
+			Values   []*big.Int
+		}
+
+		err := coinAbi.Unpack(&outStruct, "buyCoinNotes", input[4:])
+		if err != nil {
+			return params.SstoreSetGas * 2
+		}
+
+		// ota balance store gas + ota wanaddr store gas of every note
+		return params.SstoreSetGas * 2 * uint64(len(strings.Split(outStruct.OtaAddrs, CoinBatchSeparator)))
+
 	} else {
 		// ota balance store gas + ota wanaddr store gas
 		return params.SstoreSetGas * 2
@@ -683,17 +736,38 @@ func (c *wanCoinSC) Run(in []byte, contract *Contract, evm *EVM) ([]byte, error)
 	var methodIdArr [4]byte
 	copy(methodIdArr[:], in[:4])
 
+	if isCoinNotesMethod(methodIdArr) && !evm.ChainConfig().IsCoinNotes(evm.BlockNumber) {
+		return nil, errMethodId
+	}
+
 	if methodIdArr == buyIdArr {
 		return c.buyCoin(in[4:], contract, evm)
 	} else if methodIdArr == refundIdArr {
 		return c.refund(in[4:], contract, evm)
+	} else if methodIdArr == buyBatchIdArr {
+		return c.buyCoins(in[4:], contract, evm)
+	} else if methodIdArr == refundBatchIdArr {
+		return c.refundCoins(in[4:], contract, evm)
+	} else if methodIdArr == buyMemoIdArr {
+		return c.buyCoinWithMemo(in[4:], contract, evm)
 	}
 
 	return nil, errMethodId
 }
 
+// isCoinNotesMethod reports whether the method is one of the coin notes fork
+func isCoinNotesMethod(methodIdArr [4]byte) bool {
+	return methodIdArr == buyBatchIdArr || methodIdArr == refundBatchIdArr || methodIdArr == buyMemoIdArr
+}
+
+// ValidTx validates tx with every fork active, the tx pool uses ValidTxAt
 func (c *wanCoinSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
-	if stateDB == nil || signer == nil || tx == nil {
+	return c.ValidTxAt(stateDB, signer, tx, params.AllProtocolChanges, new(big.Int))
+}
+
+// ValidTxAt validates tx against the state and the forks of the block number
+func (c *wanCoinSC) ValidTxAt(stateDB StateDB, signer types.Signer, tx *types.Transaction, config *params.ChainConfig, number *big.Int) error {
+	if stateDB == nil || signer == nil || tx == nil || config == nil {
 		return errParameters
 	}
 
@@ -705,6 +779,10 @@ func (c *wanCoinSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tran
 	var methodIdArr [4]byte
 	copy(methodIdArr[:], payload[:4])
 
+	if isCoinNotesMethod(methodIdArr) && !config.IsCoinNotes(number) {
+		return errMethodId
+	}
+
 	if methodIdArr == buyIdArr {
 		_, err := c.ValidBuyCoinReq(stateDB, payload[4:], tx.Value())
 		return err
@@ -715,7 +793,24 @@ func (c *wanCoinSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tran
 			return err
 		}
 
-		_, _, err = c.ValidRefundReq(stateDB, payload[4:], from.Bytes())
+		_, _, err = c.ValidRefundReq(stateDB, payload[4:], from.Bytes(), config.IsRingSignV2(number))
+		return err
+
+	} else if methodIdArr == buyBatchIdArr {
+		_, _, err := c.ValidBuyCoinsReq(stateDB, payload[4:], tx.Value())
+		return err
+
+	} else if methodIdArr == refundBatchIdArr {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return err
+		}
+
+		_, _, err = c.ValidRefundCoinsReq(stateDB, payload[4:], from.Bytes(), config.IsRingSignV2(number))
+		return err
+
+	} else if methodIdArr == buyMemoIdArr {
+		_, _, err := c.ValidBuyCoinWithMemoReq(stateDB, payload[4:], tx.Value())
 		return err
 	}
 
@@ -745,31 +840,128 @@ func (c *wanCoinSC) ValidBuyCoinReq(stateDB StateDB, payload []byte, txValue *bi
 		return nil, ErrMismatchedValue
 	}
 
-	_, ok := WanCoinValueSet[outStruct.Value.Text(16)]
+	wanAddr, _, err := validCoinNote(stateDB, outStruct.OtaAddr, outStruct.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	return wanAddr, nil
+}
+
+// ValidBuyCoinWithMemoReq validates a buyCoinNoteWithMemo call, which buys a coin note
+// like buyCoinNote with a memo encrypted to the receiver of the ota. The memo is
+// only carried by the transaction, it's not stored in the state.
+func (c *wanCoinSC) ValidBuyCoinWithMemoReq(stateDB StateDB, payload []byte, txValue *big.Int) (otaAddr []byte, memo []byte, err error) {
+	if stateDB == nil || len(payload) == 0 || txValue == nil {
+		return nil, nil, errors.New("unknown error")
+	}
+
+	var outStruct struct {
+		OtaAddr string
+		Value   *big.Int
+		Memo    []byte
+	}
+
+	err = coinAbi.Unpack(&outStruct, "buyCoinNoteWithMemo", payload)
+	if err != nil || outStruct.Value == nil {
+		return nil, nil, errBuyCoin
+	}
+
+	if uint64(len(outStruct.Memo)) > params.MaxOTAMemoSize {
+		return nil, nil, ErrOTAMemoTooLong
+	}
+
+	if outStruct.Value.Cmp(txValue) != 0 {
+		return nil, nil, ErrMismatchedValue
+	}
+
+	wanAddr, _, err := validCoinNote(stateDB, outStruct.OtaAddr, outStruct.Value)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return wanAddr, outStruct.Memo, nil
+}
+
+// validCoinNote checks a coin note of value can be bought to the ota otaAddr
+func validCoinNote(stateDB StateDB, otaAddr string, value *big.Int) (wanAddr []byte, ax []byte, err error) {
+	if value == nil {
+		return nil, nil, errBuyCoin
+	}
+
+	_, ok := WanCoinValueSet[value.Text(16)]
 	if !ok {
-		return nil, errCoinValue
+		return nil, nil, errCoinValue
 	}
 
-	wanAddr, err := hexutil.Decode(outStruct.OtaAddr)
+	wanAddr, err = hexutil.Decode(otaAddr)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	ax, err := GetAXFromWanAddr(wanAddr)
+	ax, err = GetAXFromWanAddr(wanAddr)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	exist, _, err := CheckOTAAXExist(stateDB, ax)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if exist {
-		return nil, ErrOTAReused
+		return nil, nil, ErrOTAReused
 	}
 
-	return wanAddr, nil
+	return wanAddr, ax, nil
+}
+
+// ValidBuyCoinsReq validates a buyCoinNotes call, which buys a coin note of Values[i]
+// to the i-th ota of OtaAddrs. Every note keeps its own denomination, so it joins
+// the ring anonymity set of that denomination.
+func (c *wanCoinSC) ValidBuyCoinsReq(stateDB StateDB, payload []byte, txValue *big.Int) (otaAddrs [][]byte, values []*big.Int, err error) {
+	if stateDB == nil || len(payload) == 0 || txValue == nil {
+		return nil, nil, errors.New("unknown error")
+	}
+
+	var outStruct struct {
+		OtaAddrs string
+		Values   []*big.Int
+	}
+
+	err = coinAbi.Unpack(&outStruct, "buyCoinNotes", payload)
+	if err != nil {
+		return nil, nil, errBuyCoin
+	}
+
+	addrs := strings.Split(outStruct.OtaAddrs, CoinBatchSeparator)
+	if len(addrs) != len(outStruct.Values) || uint64(len(addrs)) > params.MaxCoinBatchSize {
+		return nil, nil, ErrInvalidCoinBatch
+	}
+
+	total := new(big.Int)
+	axs := make(map[string]bool, len(addrs))
+	otaAddrs = make([][]byte, len(addrs))
+	for i, addr := range addrs {
+		wanAddr, ax, err := validCoinNote(stateDB, addr, outStruct.Values[i])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if axs[string(ax)] {
+			return nil, nil, ErrOTAReused
+		}
+
+		axs[string(ax)] = true
+		otaAddrs[i] = wanAddr
+		total.Add(total, outStruct.Values[i])
+	}
+
+	if total.Cmp(txValue) != 0 {
+		return nil, nil, ErrMismatchedValue
+	}
+
+	return otaAddrs, outStruct.Values, nil
 }
 
 func (c *wanCoinSC) buyCoin(in []byte, contract *Contract, evm *EVM) ([]byte, error) {
@@ -778,6 +970,20 @@ func (c *wanCoinSC) buyCoin(in []byte, contract *Contract, evm *EVM) ([]byte, er
 		return nil, err
 	}
 
+	return c.buyCoinNote(otaAddr, contract, evm)
+}
+
+func (c *wanCoinSC) buyCoinWithMemo(in []byte, contract *Contract, evm *EVM) ([]byte, error) {
+	otaAddr, _, err := c.ValidBuyCoinWithMemoReq(evm.StateDB, in, contract.value)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.buyCoinNote(otaAddr, contract, evm)
+}
+
+// buyCoinNote stores the coin note of the contract value to the ota otaAddr
+func (c *wanCoinSC) buyCoinNote(otaAddr []byte, contract *Contract, evm *EVM) ([]byte, error) {
 	add, err := AddOTAIfNotExist(evm.StateDB, contract.value, otaAddr)
 	if err != nil || !add {
 		return nil, errBuyCoin
@@ -795,7 +1001,32 @@ func (c *wanCoinSC) buyCoin(in []byte, contract *Contract, evm *EVM) ([]byte, er
 	}
 }
 
-func (c *wanCoinSC) ValidRefundReq(stateDB StateDB, payload []byte, from []byte) (image []byte, value *big.Int, err error) {
+func (c *wanCoinSC) buyCoins(in []byte, contract *Contract, evm *EVM) ([]byte, error) {
+	otaAddrs, values, err := c.ValidBuyCoinsReq(evm.StateDB, in, contract.value)
+	if err != nil {
+		return nil, err
+	}
+
+	addrSrc := contract.CallerAddress
+	balance := evm.StateDB.GetBalance(addrSrc)
+	if balance.Cmp(contract.value) < 0 {
+		return nil, errBalance
+	}
+
+	for i, otaAddr := range otaAddrs {
+		add, err := AddOTAIfNotExist(evm.StateDB, values[i], otaAddr)
+		if err != nil || !add {
+			return nil, errBuyCoin
+		}
+	}
+
+	evm.StateDB.SubBalance(addrSrc, contract.value)
+	return []byte{1}, nil
+}
+
+// ValidRefundReq validates a refundCoin call, ringSignV2 reports whether the ring
+// signature can be of version 2.
+func (c *wanCoinSC) ValidRefundReq(stateDB StateDB, payload []byte, from []byte, ringSignV2 bool) (image []byte, value *big.Int, err error) {
 	if stateDB == nil || len(payload) == 0 || len(from) == 0 {
 		return nil, nil, errors.New("unknown error")
 	}
@@ -810,7 +1041,7 @@ func (c *wanCoinSC) ValidRefundReq(stateDB StateDB, payload []byte, from []byte)
 		return nil, nil, errRefundCoin
 	}
 
-	ringSignInfo, err := FetchRingSignInfo(stateDB, from, RefundStruct.RingSignedData)
+	ringSignInfo, err := FetchRingSignInfo(stateDB, from, RefundStruct.RingSignedData, ringSignV2)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -833,8 +1064,253 @@ func (c *wanCoinSC) ValidRefundReq(stateDB StateDB, payload []byte, from []byte)
 
 }
 
+// ValidRefundCoinsReq validates a refundCoins call, which refunds the coin note of
+// Values[i] by the i-th ring signed data of RingSignedDatas.
+func (c *wanCoinSC) ValidRefundCoinsReq(stateDB StateDB, payload []byte, from []byte, ringSignV2 bool) (images [][]byte, values []*big.Int, err error) {
+	if stateDB == nil || len(payload) == 0 || len(from) == 0 {
+		return nil, nil, errors.New("unknown error")
+	}
+
+	var RefundStruct struct {
+		RingSignedDatas string
+		Values          []*big.Int
+	}
+
+	err = coinAbi.Unpack(&RefundStruct, "refundCoins", payload)
+	if err != nil {
+		return nil, nil, errRefundCoin
+	}
+
+	ringSignedDatas := strings.Split(RefundStruct.RingSignedDatas, CoinBatchSeparator)
+	if len(ringSignedDatas) != len(RefundStruct.Values) || uint64(len(ringSignedDatas)) > params.MaxCoinBatchSize {
+		return nil, nil, ErrInvalidCoinBatch
+	}
+
+	kixs := make(map[string]bool, len(ringSignedDatas))
+	images = make([][]byte, len(ringSignedDatas))
+	for i, ringSignedData := range ringSignedDatas {
+		value := RefundStruct.Values[i]
+		if value == nil {
+			return nil, nil, errRefundCoin
+		}
+
+		ringSignInfo, err := FetchRingSignInfo(stateDB, from, ringSignedData, ringSignV2)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if ringSignInfo.OTABalance.Cmp(value) != 0 {
+			return nil, nil, ErrMismatchedValue
+		}
+
+		kix := crypto.FromECDSAPub(ringSignInfo.KeyImage)
+		exist, _, err := CheckOTAImageExist(stateDB, kix)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if exist || kixs[string(kix)] {
+			return nil, nil, ErrOTAReused
+		}
+
+		kixs[string(kix)] = true
+		images[i] = kix
+	}
+
+	return images, RefundStruct.Values, nil
+}
+
+// RefundKeyImages returns the key images in the ring signatures of a refundCoin or
+// refundCoins transaction, the ring signatures are not verified.
+func RefundKeyImages(tx *types.Transaction) ([][]byte, error) {
+	ringSignedDatas, err := refundRingSignedDatas(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([][]byte, len(ringSignedDatas))
+	for i, ringSignedData := range ringSignedDatas {
+		ringSign, err := DecodeRingSignedData(ringSignedData, true)
+		if err != nil {
+			return nil, err
+		}
+
+		images[i] = crypto.FromECDSAPub(ringSign.KeyImage)
+	}
+
+	return images, nil
+}
+
+// PackBuyCoinNoteWithMemo returns the call data of a buyCoinNoteWithMemo of the
+// coin note of value to otaAddr, memo is encrypted to the ota already.
+func PackBuyCoinNoteWithMemo(otaAddr string, value *big.Int, memo []byte) ([]byte, error) {
+	return coinAbi.Pack("buyCoinNoteWithMemo", otaAddr, value, memo)
+}
+
+// CoinNoteMemo returns the ota and the encrypted memo of a buyCoinNoteWithMemo
+// transaction, the coin note is not validated.
+func CoinNoteMemo(tx *types.Transaction) (otaAddr []byte, memo []byte, err error) {
+	if tx == nil || tx.To() == nil || *tx.To() != wanCoinPrecompileAddr {
+		return nil, nil, errParameters
+	}
+
+	payload := tx.Data()
+	if len(payload) < 4 {
+		return nil, nil, errParameters
+	}
+
+	var methodIdArr [4]byte
+	copy(methodIdArr[:], payload[:4])
+	if methodIdArr != buyMemoIdArr {
+		return nil, nil, errParameters
+	}
+
+	var outStruct struct {
+		OtaAddr string
+		Value   *big.Int
+		Memo    []byte
+	}
+
+	err = coinAbi.Unpack(&outStruct, "buyCoinNoteWithMemo", payload[4:])
+	if err != nil {
+		return nil, nil, errBuyCoin
+	}
+
+	otaAddr, err = hexutil.Decode(outStruct.OtaAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return otaAddr, outStruct.Memo, nil
+}
+
+// CoinNoteOTAs returns the otas bought by a buyCoinNote, buyCoinNotes or buyCoinNoteWithMemo
+// transaction, the coin notes are not validated.
+func CoinNoteOTAs(tx *types.Transaction) ([][]byte, error) {
+	if tx == nil || tx.To() == nil || *tx.To() != wanCoinPrecompileAddr {
+		return nil, errParameters
+	}
+
+	payload := tx.Data()
+	if len(payload) < 4 {
+		return nil, errParameters
+	}
+
+	var methodIdArr [4]byte
+	copy(methodIdArr[:], payload[:4])
+
+	var otaAddrs []string
+	switch methodIdArr {
+	case buyIdArr:
+		var outStruct struct {
+			OtaAddr string
+			Value   *big.Int
+		}
+		if err := coinAbi.Unpack(&outStruct, "buyCoinNote", payload[4:]); err != nil {
+			return nil, errBuyCoin
+		}
+		otaAddrs = []string{outStruct.OtaAddr}
+
+	case buyBatchIdArr:
+		var outStruct struct {
+			OtaAddrs string
+			Values   []*big.Int
+		}
+		if err := coinAbi.Unpack(&outStruct, "buyCoinNotes", payload[4:]); err != nil {
+			return nil, errBuyCoin
+		}
+		otaAddrs = strings.Split(outStruct.OtaAddrs, CoinBatchSeparator)
+
+	case buyMemoIdArr:
+		otaAddr, _, err := CoinNoteMemo(tx)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{otaAddr}, nil
+
+	default:
+		return nil, errParameters
+	}
+
+	otas := make([][]byte, len(otaAddrs))
+	for i, otaAddr := range otaAddrs {
+		ota, err := hexutil.Decode(otaAddr)
+		if err != nil {
+			return nil, err
+		}
+		otas[i] = ota
+	}
+	return otas, nil
+}
+
+// refundRingSignedDatas returns the ring signed datas of a refundCoin or refundCoins transaction
+func refundRingSignedDatas(tx *types.Transaction) ([]string, error) {
+	if tx == nil || tx.To() == nil || *tx.To() != wanCoinPrecompileAddr {
+		return nil, errParameters
+	}
+
+	payload := tx.Data()
+	if len(payload) < 4 {
+		return nil, errParameters
+	}
+
+	var methodIdArr [4]byte
+	copy(methodIdArr[:], payload[:4])
+
+	if methodIdArr == refundIdArr {
+		var RefundStruct struct {
+			RingSignedData string
+			Value          *big.Int
+		}
+
+		err := coinAbi.Unpack(&RefundStruct, "refundCoin", payload[4:])
+		if err != nil {
+			return nil, errRefundCoin
+		}
+
+		return []string{RefundStruct.RingSignedData}, nil
+
+	} else if methodIdArr == refundBatchIdArr {
+		var RefundStruct struct {
+			RingSignedDatas string
+			Values          []*big.Int
+		}
+
+		err := coinAbi.Unpack(&RefundStruct, "refundCoins", payload[4:])
+		if err != nil {
+			return nil, errRefundCoin
+		}
+
+		return strings.Split(RefundStruct.RingSignedDatas, CoinBatchSeparator), nil
+	}
+
+	return nil, errMethodId
+}
+
+func (c *wanCoinSC) refundCoins(all []byte, contract *Contract, evm *EVM) ([]byte, error) {
+	ringSignV2 := evm.ChainConfig().IsRingSignV2(evm.BlockNumber)
+	images, values, err := c.ValidRefundCoinsReq(evm.StateDB, all, contract.CallerAddress.Bytes(), ringSignV2)
+	if err != nil {
+		return nil, err
+	}
+
+	total := new(big.Int)
+	for i, kix := range images {
+		err = AddOTAImage(evm.StateDB, kix, values[i].Bytes())
+		if err != nil {
+			return nil, err
+		}
+
+		total.Add(total, values[i])
+	}
+
+	evm.StateDB.AddBalance(contract.CallerAddress, total)
+	return []byte{1}, nil
+}
+
 func (c *wanCoinSC) refund(all []byte, contract *Contract, evm *EVM) ([]byte, error) {
-	kix, value, err := c.ValidRefundReq(evm.StateDB, all, contract.CallerAddress.Bytes())
+	ringSignV2 := evm.ChainConfig().IsRingSignV2(evm.BlockNumber)
+	kix, value, err := c.ValidRefundReq(evm.StateDB, all, contract.CallerAddress.Bytes(), ringSignV2)
 	if err != nil {
 		fmt.Println("failed refund")
 		fmt.Println(evm.BlockNumber)
@@ -918,18 +1394,25 @@ type RingSignInfo struct {
 	OTABalance *big.Int
 }
 
-func FetchRingSignInfo(stateDB StateDB, hashInput []byte, ringSignedStr string) (info *RingSignInfo, err error) {
+// FetchRingSignInfo decodes and verifies the ring signature ringSignedStr of hashInput,
+// ringSignV2 reports whether it can be of version 2.
+func FetchRingSignInfo(stateDB StateDB, hashInput []byte, ringSignedStr string, ringSignV2 bool) (info *RingSignInfo, err error) {
 	if stateDB == nil || hashInput == nil {
 		return nil, errParameters
 	}
 
-	infoTmp := new(RingSignInfo)
-
-	err, infoTmp.PublicKeys, infoTmp.KeyImage, infoTmp.W_Random, infoTmp.Q_Random = DecodeRingSignOut(ringSignedStr)
+	ringSign, err := DecodeRingSignedData(ringSignedStr, ringSignV2)
 	if err != nil {
 		return nil, err
 	}
 
+	infoTmp := &RingSignInfo{
+		PublicKeys: ringSign.PublicKeys,
+		KeyImage:   ringSign.KeyImage,
+		W_Random:   ringSign.W,
+		Q_Random:   ringSign.Q,
+	}
+
 	otaLongs := make([][]byte, 0, len(infoTmp.PublicKeys))
 	for i := 0; i < len(infoTmp.PublicKeys); i++ {
 		otaLongs = append(otaLongs, keystore.ECDSAPKCompression(infoTmp.PublicKeys[i]))
@@ -948,8 +1431,7 @@ func FetchRingSignInfo(stateDB StateDB, hashInput []byte, ringSignedStr string)
 
 	infoTmp.OTABalance = balanceGet
 
-	valid := crypto.VerifyRingSign(hashInput, infoTmp.PublicKeys, infoTmp.KeyImage, infoTmp.W_Random, infoTmp.Q_Random)
-	if !valid {
+	if !verifyRingSignCached(hashInput, ringSignedStr, ringSign) {
 		return nil, ErrInvalidRingSigned
 	}
 
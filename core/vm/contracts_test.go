@@ -0,0 +1,326 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+type testCoinNote struct {
+	key     *ecdsa.PrivateKey
+	wanAddr []byte
+}
+
+func newTestCoinNote(t *testing.T) *testCoinNote {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key2, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanAddr := append(keystore.ECDSAPKCompression(&key.PublicKey), keystore.ECDSAPKCompression(&key2.PublicKey)...)
+	return &testCoinNote{key, wanAddr}
+}
+
+// testRingSign ring signs from by the note, mixed with the notes in mix
+func testRingSign(t *testing.T, from common.Address, note *testCoinNote, mix ...*testCoinNote) string {
+	publicKeys, keyImage, ws, qs := testRingSignRaw(t, from, note, mix...)
+
+	var pks, wa, qa []string
+	for i := range publicKeys {
+		pks = append(pks, common.ToHex(crypto.FromECDSAPub(publicKeys[i])))
+		wa = append(wa, hexutil.EncodeBig(ws[i]))
+		qa = append(qa, hexutil.EncodeBig(qs[i]))
+	}
+	return strings.Join([]string{strings.Join(pks, "&"), common.ToHex(crypto.FromECDSAPub(keyImage)), strings.Join(wa, "&"), strings.Join(qa, "&")}, "+")
+}
+
+// testRingSignV2 is testRingSign in the encoding of version 2
+func testRingSignV2(t *testing.T, from common.Address, note *testCoinNote, mix ...*testCoinNote) string {
+	publicKeys, keyImage, ws, qs := testRingSignRaw(t, from, note, mix...)
+	s, err := EncodeRingSignedDataV2(&crypto.RingSignature{PublicKeys: publicKeys, KeyImage: keyImage, W: ws, Q: qs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func testRingSignRaw(t *testing.T, from common.Address, note *testCoinNote, mix ...*testCoinNote) ([]*ecdsa.PublicKey, *ecdsa.PublicKey, []*big.Int, []*big.Int) {
+	publicKeys := []*ecdsa.PublicKey{&note.key.PublicKey}
+	for _, m := range mix {
+		publicKeys = append(publicKeys, &m.key.PublicKey)
+	}
+	publicKeys, keyImage, ws, qs, err := crypto.RingSign(from.Bytes(), note.key.D, publicKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return publicKeys, keyImage, ws, qs
+}
+
+func TestWanCoinBatch(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	evm := &EVM{StateDB: statedb, chainConfig: params.TestChainConfig, Context: Context{BlockNumber: big.NewInt(1)}}
+	c := &wanCoinSC{}
+
+	from := common.HexToAddress("0x01")
+	statedb.AddBalance(from, new(big.Int).Mul(big.NewInt(100), ether))
+
+	wan10, _ := new(big.Int).SetString(Wancoin10, 10)
+	wan20, _ := new(big.Int).SetString(Wancoin20, 10)
+	notes := []*testCoinNote{newTestCoinNote(t), newTestCoinNote(t), newTestCoinNote(t), newTestCoinNote(t)}
+	values := []*big.Int{wan10, wan20, wan10, wan20}
+
+	otaAddrs := make([]string, len(notes))
+	for i, note := range notes {
+		otaAddrs[i] = common.ToHex(note.wanAddr)
+	}
+	buy, err := coinAbi.Pack("buyCoinNotes", strings.Join(otaAddrs, CoinBatchSeparator), values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gas := c.RequiredGas(buy); gas != params.SstoreSetGas*2*uint64(len(notes)) {
+		t.Fatal("buy gas mismatch", gas)
+	}
+
+	total := new(big.Int).Mul(big.NewInt(60), ether)
+	if _, _, err := c.ValidBuyCoinsReq(statedb, buy[4:], wan10); err != ErrMismatchedValue {
+		t.Fatal("mismatched value should fail", err)
+	}
+	wan25 := new(big.Int).Mul(big.NewInt(25), ether)
+	invalid, _ := coinAbi.Pack("buyCoinNotes", otaAddrs[0], []*big.Int{wan25})
+	if _, _, err := c.ValidBuyCoinsReq(statedb, invalid[4:], wan25); err != errCoinValue {
+		t.Fatal("unsupported value should fail", err)
+	}
+	invalid, _ = coinAbi.Pack("buyCoinNotes", otaAddrs[0]+CoinBatchSeparator+otaAddrs[0], []*big.Int{wan10, wan10})
+	if _, _, err := c.ValidBuyCoinsReq(statedb, invalid[4:], new(big.Int).Add(wan10, wan10)); err != ErrOTAReused {
+		t.Fatal("duplicated ota should fail", err)
+	}
+	invalid, _ = coinAbi.Pack("buyCoinNotes", otaAddrs[0], values)
+	if _, _, err := c.ValidBuyCoinsReq(statedb, invalid[4:], total); err != ErrInvalidCoinBatch {
+		t.Fatal("mismatched batch should fail", err)
+	}
+
+	contract := NewContract(AccountRef(from), AccountRef(wanCoinPrecompileAddr), total, 0)
+	if _, err := c.Run(buy, contract, evm); err != nil {
+		t.Fatal(err)
+	}
+	for i, note := range notes {
+		ax, _ := GetAXFromWanAddr(note.wanAddr)
+		if balance, _ := GetOtaBalanceFromAX(statedb, ax); balance.Cmp(values[i]) != 0 {
+			t.Fatal("every note should keep its denomination", i, balance)
+		}
+	}
+	if _, _, err := c.ValidBuyCoinsReq(statedb, buy[4:], total); err != ErrOTAReused {
+		t.Fatal("bought ota should not be bought again", err)
+	}
+
+	// refund a note of each denomination in one transaction
+	refundSigns := []string{testRingSign(t, from, notes[0], notes[2]), testRingSign(t, from, notes[1], notes[3])}
+	refund, err := coinAbi.Pack("refundCoins", strings.Join(refundSigns, CoinBatchSeparator), []*big.Int{wan10, wan20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gas := c.RequiredGas(refund); gas != 2*(params.RequiredGasPerMixPub*2+params.SstoreSetGas) {
+		t.Fatal("refund gas mismatch", gas)
+	}
+	invalid, _ = coinAbi.Pack("refundCoins", strings.Join(refundSigns, CoinBatchSeparator), []*big.Int{wan20, wan10})
+	if _, _, err := c.ValidRefundCoinsReq(statedb, invalid[4:], from.Bytes(), true); err != ErrMismatchedValue {
+		t.Fatal("mismatched value should fail", err)
+	}
+	invalid, _ = coinAbi.Pack("refundCoins", refundSigns[0]+CoinBatchSeparator+refundSigns[0], []*big.Int{wan10, wan10})
+	if _, _, err := c.ValidRefundCoinsReq(statedb, invalid[4:], from.Bytes(), true); err != ErrOTAReused {
+		t.Fatal("duplicated key image should fail", err)
+	}
+
+	images, err := RefundKeyImages(types.NewTransaction(0, wanCoinPrecompileAddr, big.NewInt(0), big.NewInt(0), big.NewInt(0), refund))
+	if err != nil || len(images) != 2 {
+		t.Fatal("refund key images mismatch", err, len(images))
+	}
+
+	balance := statedb.GetBalance(from)
+	contract = NewContract(AccountRef(from), AccountRef(wanCoinPrecompileAddr), big.NewInt(0), 0)
+	if _, err := c.Run(refund, contract, evm); err != nil {
+		t.Fatal(err)
+	}
+	if refunded := new(big.Int).Sub(statedb.GetBalance(from), balance); refunded.Cmp(new(big.Int).Add(wan10, wan20)) != 0 {
+		t.Fatal("refunded balance mismatch", refunded)
+	}
+	for _, image := range images {
+		if exist, _, _ := CheckOTAImageExist(statedb, image); !exist {
+			t.Fatal("key image should be stored")
+		}
+	}
+	if _, _, err := c.ValidRefundCoinsReq(statedb, refund[4:], from.Bytes(), true); err != ErrOTAReused {
+		t.Fatal("refunded note should not be refunded again", err)
+	}
+}
+
+func TestWanCoinBatchFork(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	c := &wanCoinSC{}
+
+	from := common.HexToAddress("0x01")
+	statedb.AddBalance(from, new(big.Int).Mul(big.NewInt(100), ether))
+	wan10, _ := new(big.Int).SetString(Wancoin10, 10)
+	note := newTestCoinNote(t)
+	buy, err := coinAbi.Pack("buyCoinNotes", common.ToHex(note.wanAddr), []*big.Int{wan10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &params.ChainConfig{ChainId: big.NewInt(1), CoinNotesBlock: big.NewInt(10)}
+	signer := types.NewEIP155Signer(config.ChainId)
+	tx := types.NewTransaction(0, wanCoinPrecompileAddr, wan10, big.NewInt(100000), big.NewInt(1), buy)
+	if err := c.ValidTxAt(statedb, signer, tx, config, big.NewInt(9)); err != errMethodId {
+		t.Fatal("batch buy should be rejected before the fork", err)
+	}
+	if err := c.ValidTxAt(statedb, signer, tx, config, big.NewInt(10)); err != nil {
+		t.Fatal("batch buy should be accepted from the fork", err)
+	}
+
+	contract := NewContract(AccountRef(from), AccountRef(wanCoinPrecompileAddr), wan10, 0)
+	evm := &EVM{StateDB: statedb, chainConfig: config, Context: Context{BlockNumber: big.NewInt(9)}}
+	if _, err := c.Run(buy, contract, evm); err != errMethodId {
+		t.Fatal("batch buy should fail before the fork", err)
+	}
+	evm.BlockNumber = big.NewInt(10)
+	if _, err := c.Run(buy, contract, evm); err != nil {
+		t.Fatal("batch buy should run from the fork", err)
+	}
+}
+
+func TestRingSignV2Refund(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	c := &wanCoinSC{}
+
+	from := common.HexToAddress("0x01")
+	wan10, _ := new(big.Int).SetString(Wancoin10, 10)
+	notes := []*testCoinNote{newTestCoinNote(t), newTestCoinNote(t), newTestCoinNote(t)}
+	for _, note := range notes {
+		if err := setOTA(statedb, wan10, note.wanAddr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v1 := testRingSign(t, from, notes[0], notes[1], notes[2])
+	v2 := testRingSignV2(t, from, notes[1], notes[0], notes[2])
+	if len(v2) >= len(v1) {
+		t.Fatal("ring signed data of version 2 should be shorter", len(v2), len(v1))
+	}
+
+	refundV1, _ := coinAbi.Pack("refundCoin", v1, wan10)
+	refundV2, _ := coinAbi.Pack("refundCoin", v2, wan10)
+	if c.RequiredGas(refundV1) != c.RequiredGas(refundV2) {
+		t.Fatal("refund gas should not depend on the version")
+	}
+
+	// version 2 is accepted since the fork, version 1 stays valid
+	noFork := &EVM{StateDB: statedb, chainConfig: &params.ChainConfig{ChainId: big.NewInt(1)}, Context: Context{BlockNumber: big.NewInt(1)}}
+	contract := NewContract(AccountRef(from), AccountRef(wanCoinPrecompileAddr), big.NewInt(0), 0)
+	if _, err := c.Run(refundV2, contract, noFork); err != ErrInvalidRingSigned {
+		t.Fatal("ring signature of version 2 should fail before the fork", err)
+	}
+	forked := &EVM{StateDB: statedb, chainConfig: params.TestChainConfig, Context: Context{BlockNumber: big.NewInt(1)}}
+	for _, refund := range [][]byte{refundV1, refundV2} {
+		if _, err := c.Run(refund, contract, forked); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if statedb.GetBalance(from).Cmp(new(big.Int).Add(wan10, wan10)) != 0 {
+		t.Fatal("refunded balance mismatch", statedb.GetBalance(from))
+	}
+
+	// the tx pool verifies the refunds in batch
+	key, _ := crypto.GenerateKey()
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	var txs []*types.Transaction
+	for i, ringSigned := range []string{testRingSignV2(t, sender, notes[2], notes[0]), testRingSign(t, from, notes[2], notes[1])} {
+		data, _ := coinAbi.Pack("refundCoin", ringSigned, wan10)
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), wanCoinPrecompileAddr, big.NewInt(0), big.NewInt(0), big.NewInt(0), data), signer, key)
+		txs = append(txs, tx)
+	}
+
+	// the pool accepts version 2 from the fork only
+	if err := c.ValidTxAt(statedb, signer, txs[0], noFork.ChainConfig(), big.NewInt(1)); err != ErrInvalidRingSigned {
+		t.Fatal("pool should reject the ring signature of version 2 before the fork", err)
+	}
+	if err := c.ValidTxAt(statedb, signer, txs[0], params.TestChainConfig, big.NewInt(1)); err != nil {
+		t.Fatal("pool should accept the ring signature of version 2 since the fork", err)
+	}
+
+	txs = append(txs, types.NewTransaction(2, from, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil))
+	valid := VerifyRefundRingSigns(signer, txs)
+	if !valid[0] || valid[1] || !valid[2] {
+		t.Fatal("batch verified refunds mismatch", valid)
+	}
+}
+
+func TestWanCoinMemo(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	evm := &EVM{StateDB: statedb, chainConfig: params.TestChainConfig, Context: Context{BlockNumber: big.NewInt(1)}}
+	c := &wanCoinSC{}
+
+	from := common.HexToAddress("0x01")
+	statedb.AddBalance(from, new(big.Int).Mul(big.NewInt(100), ether))
+
+	wan10, _ := new(big.Int).SetString(Wancoin10, 10)
+	note := newTestCoinNote(t)
+	memo := []byte("encrypted invoice")
+	buy, err := coinAbi.Pack("buyCoinNoteWithMemo", common.ToHex(note.wanAddr), wan10, memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gas := c.RequiredGas(buy); gas != params.SstoreSetGas*2 {
+		t.Fatal("buy gas mismatch", gas)
+	}
+
+	long, _ := coinAbi.Pack("buyCoinNoteWithMemo", common.ToHex(note.wanAddr), wan10, make([]byte, params.MaxOTAMemoSize+1))
+	if _, _, err := c.ValidBuyCoinWithMemoReq(statedb, long[4:], wan10); err != ErrOTAMemoTooLong {
+		t.Fatal("long memo should fail", err)
+	}
+
+	// the memo shares the fork of the batch methods
+	contract := NewContract(AccountRef(from), AccountRef(wanCoinPrecompileAddr), wan10, 0)
+	noFork := &EVM{StateDB: statedb, chainConfig: &params.ChainConfig{ChainId: big.NewInt(1)}, Context: Context{BlockNumber: big.NewInt(1)}}
+	if _, err := c.Run(buy, contract, noFork); err != errMethodId {
+		t.Fatal("buy with memo should fail before the fork", err)
+	}
+	if _, err := c.Run(buy, contract, evm); err != nil {
+		t.Fatal(err)
+	}
+	ax, _ := GetAXFromWanAddr(note.wanAddr)
+	if balance, _ := GetOtaBalanceFromAX(statedb, ax); balance.Cmp(wan10) != 0 {
+		t.Fatal("coin note balance mismatch", balance)
+	}
+	if _, _, err := c.ValidBuyCoinWithMemoReq(statedb, buy[4:], wan10); err != ErrOTAReused {
+		t.Fatal("bought ota should not be bought again", err)
+	}
+
+	otaAddr, txMemo, err := CoinNoteMemo(types.NewTransaction(0, wanCoinPrecompileAddr, wan10, big.NewInt(0), big.NewInt(0), buy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(otaAddr, note.wanAddr) || !bytes.Equal(txMemo, memo) {
+		t.Fatal("coin note memo mismatch")
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"github.com/wanchain/go-wanchain/common"
+)
+
+// The ways to select the mix otas of a ring
+const (
+	// OTAMixUniform selects every ota of the balance with the same probability
+	OTAMixUniform = iota
+	// OTAMixRecent selects the otas known recently with a higher probability,
+	// the probability grows linearly with the age rank of the ota.
+	OTAMixRecent
+)
+
+var ErrInvalidOTAMixMode = errors.New("invalid ota mix mode")
+
+// OTAMixPolicy is how GetOTAMixSet selects the mix otas of a ring
+type OTAMixPolicy struct {
+	Mode int
+
+	// Rand is the source of the selection, a selection is deterministic
+	// under a seeded source. nil means a source seeded by crypto/rand.
+	Rand *rand.Rand
+
+	// Spent reports whether an ota is known spent, the otas spent are
+	// selected only if there are not enough unspent ones. It can be nil.
+	Spent func(otaWanAddr []byte) bool
+
+	// Age returns the block number an ota is known since, 0 if it's unknown.
+	// It's used by OTAMixRecent, nil means every ota is of the same age.
+	Age func(otaWanAddr []byte) uint64
+}
+
+type otaMixCandidate struct {
+	wanAddr []byte
+	age     uint64
+}
+
+// GetOTAMixSet returns setNum distinct otas of the balance of the ota otaAX, selected by
+// policy. The ota otaAX itself is never selected.
+func GetOTAMixSet(statedb StateDB, otaAX []byte, setNum int, policy *OTAMixPolicy) (otaWanAddrs [][]byte, balance *big.Int, err error) {
+	if statedb == nil || policy == nil || setNum <= 0 {
+		return nil, nil, ErrUnknown
+	}
+	if len(otaAX) != common.HashLength {
+		return nil, nil, ErrInvalidOTAAX
+	}
+	if policy.Mode != OTAMixUniform && policy.Mode != OTAMixRecent {
+		return nil, nil, ErrInvalidOTAMixMode
+	}
+
+	balance, err = GetOtaBalanceFromAX(statedb, otaAX)
+	if err != nil {
+		return nil, nil, err
+	} else if balance == nil || balance.Cmp(common.Big0) == 0 {
+		return nil, nil, errors.New("can't find ota address balance!")
+	}
+
+	var unspent, spent []otaMixCandidate
+	mptEleCount := 0
+	statedb.ForEachStorageByteArray(OTABalance2ContractAddr(balance), func(key common.Hash, value []byte) bool {
+		mptEleCount++
+		if len(value) != common.WAddressLength {
+			err = errors.New("invalid OTA address! balance:" + balance.String() + ", ota:" + common.ToHex(value))
+			return false
+		}
+		if IsAXPointToWanAddr(otaAX, value) {
+			return true
+		}
+
+		candidate := otaMixCandidate{wanAddr: common.CopyBytes(value)}
+		if policy.Age != nil {
+			candidate.age = policy.Age(value)
+		}
+		if policy.Spent != nil && policy.Spent(value) {
+			spent = append(spent, candidate)
+		} else {
+			unspent = append(unspent, candidate)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if mptEleCount == 0 {
+		return nil, balance, errors.New("no ota exist! balance:" + balance.String())
+	}
+	if len(unspent)+len(spent) < setNum {
+		return nil, balance, errors.New("too more required ota number! balance:" + balance.String() +
+			", exist count:" + strconv.Itoa(mptEleCount))
+	}
+
+	rnd := policy.Rand
+	if rnd == nil {
+		var seed [8]byte
+		if _, err := crand.Read(seed[:]); err != nil {
+			return nil, nil, err
+		}
+		rnd = rand.New(rand.NewSource(int64(binary.BigEndian.Uint64(seed[:]))))
+	}
+
+	selected := selectOTAMix(unspent, setNum, policy.Mode, rnd)
+	if len(selected) < setNum {
+		selected = append(selected, selectOTAMix(spent, setNum-len(selected), policy.Mode, rnd)...)
+	}
+
+	otaWanAddrs = make([][]byte, len(selected))
+	for i, candidate := range selected {
+		otaWanAddrs[i] = candidate.wanAddr
+	}
+	return otaWanAddrs, balance, nil
+}
+
+// selectOTAMix selects n distinct candidates, all of them if there are not more than n
+func selectOTAMix(candidates []otaMixCandidate, n int, mode int, rnd *rand.Rand) []otaMixCandidate {
+	if len(candidates) <= n {
+		return candidates
+	}
+
+	// sort by age then address, so the rank of recency is the position and the
+	// selection depends only on the candidates and the source.
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].age != candidates[j].age {
+			return candidates[i].age < candidates[j].age
+		}
+		return bytes.Compare(candidates[i].wanAddr, candidates[j].wanAddr) < 0
+	})
+
+	selected := make([]otaMixCandidate, 0, n)
+	if mode == OTAMixUniform {
+		perm := rnd.Perm(len(candidates))
+		for _, i := range perm[:n] {
+			selected = append(selected, candidates[i])
+		}
+		return selected
+	}
+
+	// weight of a candidate is its age rank, the otas of the same age share a rank
+	weights := make([]int64, len(candidates))
+	total := int64(0)
+	rank := int64(1)
+	for i := range candidates {
+		if i > 0 && candidates[i].age != candidates[i-1].age {
+			rank++
+		}
+		weights[i] = rank
+		total += rank
+	}
+
+	for len(selected) < n {
+		r := rnd.Int63n(total)
+		for i, w := range weights {
+			if r < w {
+				selected = append(selected, candidates[i])
+				total -= w
+				weights[i] = 0
+				break
+			}
+			r -= w
+		}
+	}
+	return selected
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/ethdb"
+)
+
+func testMixOTA(i int) []byte {
+	ota := make([]byte, common.WAddressLength)
+	ota[0] = 2
+	ota[1] = byte(i)
+	return ota
+}
+
+func TestGetOTAMixSet(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	balance := big.NewInt(10)
+	count := 20
+	for i := 1; i <= count; i++ {
+		if err := setOTA(statedb, balance, testMixOTA(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	self := testMixOTA(1)
+	otaAX, _ := GetAXFromWanAddr(self)
+
+	if _, _, err := GetOTAMixSet(statedb, otaAX, 3, &OTAMixPolicy{Mode: 5}); err != ErrInvalidOTAMixMode {
+		t.Fatal("invalid mode should fail", err)
+	}
+	if _, _, err := GetOTAMixSet(statedb, otaAX, count, &OTAMixPolicy{}); err == nil {
+		t.Fatal("mix set should not contain the ota itself")
+	}
+
+	for _, mode := range []int{OTAMixUniform, OTAMixRecent} {
+		// distinct members, deterministic under a seed
+		set1, balanceGet, err := GetOTAMixSet(statedb, otaAX, 8, &OTAMixPolicy{Mode: mode, Rand: rand.New(rand.NewSource(1))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if balanceGet.Cmp(balance) != 0 {
+			t.Fatal("balance mismatch", balanceGet)
+		}
+		set2, _, _ := GetOTAMixSet(statedb, otaAX, 8, &OTAMixPolicy{Mode: mode, Rand: rand.New(rand.NewSource(1))})
+
+		seen := make(map[string]bool)
+		for i, ota := range set1 {
+			if bytes.Equal(ota, self) || seen[string(ota)] {
+				t.Fatal("mix set member should be distinct and not the ota itself", mode, ota)
+			}
+			seen[string(ota)] = true
+			if !bytes.Equal(ota, set2[i]) {
+				t.Fatal("mix set should be deterministic under a seed", mode)
+			}
+		}
+	}
+
+	// the spent otas are used only if there are not enough unspent ones
+	spent := func(ota []byte) bool { return ota[1] > 5 }
+	set, _, err := GetOTAMixSet(statedb, otaAX, 4, &OTAMixPolicy{Spent: spent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ota := range set {
+		if spent(ota) {
+			t.Fatal("spent ota should be excluded", ota)
+		}
+	}
+	set, _, err = GetOTAMixSet(statedb, otaAX, 6, &OTAMixPolicy{Spent: spent})
+	if err != nil || len(set) != 6 {
+		t.Fatal("spent otas should fill the mix set", err, len(set))
+	}
+
+	// the recent otas are selected more often
+	age := func(ota []byte) uint64 { return uint64(ota[1]) }
+	rnd := rand.New(rand.NewSource(1))
+	recent := 0
+	for i := 0; i < 200; i++ {
+		set, _, err := GetOTAMixSet(statedb, otaAX, 1, &OTAMixPolicy{Mode: OTAMixRecent, Rand: rnd, Age: age})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if set[0][1] > byte(count/2) {
+			recent++
+		}
+	}
+	if recent < 120 {
+		t.Fatal("recent otas should be selected more often", recent)
+	}
+}
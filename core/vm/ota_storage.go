@@ -304,6 +304,30 @@ func GetOTAInfoFromAX(statedb StateDB, otaAX []byte) (otaWanAddr []byte, balance
 	return nil, balance, nil
 }
 
+// ForEachOTA calls cb with the WanAddr and balance of every ota stored, the travel stops if cb returns false.
+func ForEachOTA(statedb StateDB, cb func(otaWanAddr []byte, balance *big.Int) bool) error {
+	if statedb == nil || cb == nil {
+		return ErrUnknown
+	}
+
+	statedb.ForEachStorageByteArray(otaBalanceStorageAddr, func(key common.Hash, value []byte) bool {
+		if len(value) == 0 {
+			return true
+		}
+
+		balance := new(big.Int).SetBytes(value)
+		otaWanAddr := statedb.GetStateByteArray(OTABalance2ContractAddr(balance), key)
+		if len(otaWanAddr) != common.WAddressLength {
+			log.Warn("invalid OTA address!", "AX", key.String(), "balance", balance)
+			return true
+		}
+
+		return cb(otaWanAddr, balance)
+	})
+
+	return nil
+}
+
 type GetOTASetEnv struct {
 	otaAX         []byte
 	setNum        int
@@ -5,7 +5,6 @@ import (
 	"math/big"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
@@ -22,6 +21,8 @@ pragma solidity ^0.5.1;
 
 contract posControl {
 	function upgradeWhiteEpochLeader(uint256 EpochId, uint256 wlIndex, uint256 wlCount ) public  {}
+
+	event UpgradeWhiteEpochLeader(address indexed sender, uint256 indexed epochId, uint256 wlIndex, uint256 wlCount);
 }
 */
 
@@ -49,6 +50,33 @@ var (
 		"payable": false,
 		"stateMutability": "nonpayable",
 		"type": "function"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{
+				"indexed": true,
+				"name": "sender",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "epochId",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "wlIndex",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "wlCount",
+				"type": "uint256"
+			}
+		],
+		"name": "UpgradeWhiteEpochLeader",
+		"type": "event"
 	}
 ]
 `
@@ -127,7 +155,7 @@ func (p *PosControl) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tra
 	copy(methodId[:], input[:4])
 
 	if methodId == upgradeWhiteEpochLeaderId {
-		_, err := p.upgradeWhiteEpochLeaderParseAndValid(input[4:], uint64(time.Now().Unix()))
+		_, err := p.upgradeWhiteEpochLeaderParseAndValid(input[4:], util.NowUnix())
 		if err != nil {
 			return errors.New("upgradeWhiteEpochLeaderParseAndValid verify failed")
 		}
@@ -155,6 +183,12 @@ func (p *PosControl) upgradeWhiteEpochLeader(info *UpgradeWhiteEpochLeaderParam,
 		return nil, res
 	}
 
+	err = addPosLog(evm, contract, posControlAbi.Events["UpgradeWhiteEpochLeader"], contract.CallerAddress,
+		info.EpochId, info.WlIndex, info.WlCount)
+	if err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
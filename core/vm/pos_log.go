@@ -0,0 +1,77 @@
+package vm
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/accounts/abi"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+)
+
+var (
+	errPosLogArgs  = errors.New("pos log arguments count mismatch")
+	errPosLogTopic = errors.New("pos log indexed argument type unsupported")
+)
+
+// addPosLog records an event of the pos precompiled contracts as an evm log,
+// so that it can be found by the bloom filtered log pipeline.
+//
+// args should match event.Inputs one by one. The first topic is the event id,
+// the indexed arguments follow as topics, the others are abi packed into data.
+//
+// The logs change the receipts, so they are only recorded from the PosLogBlock
+// fork on.
+func addPosLog(evm *EVM, contract *Contract, event abi.Event, args ...interface{}) error {
+	if !evm.ChainConfig().IsPosLog(evm.BlockNumber) {
+		return nil
+	}
+	if len(args) != len(event.Inputs) {
+		return errPosLogArgs
+	}
+
+	topics := []common.Hash{event.Id()}
+	dataArgs := make([]interface{}, 0, len(args))
+	for i, input := range event.Inputs {
+		if !input.Indexed {
+			dataArgs = append(dataArgs, args[i])
+			continue
+		}
+
+		topic, err := posLogTopic(args[i])
+		if err != nil {
+			return err
+		}
+		topics = append(topics, topic)
+	}
+
+	data, err := event.Inputs.NonIndexed().Pack(dataArgs...)
+	if err != nil {
+		return err
+	}
+
+	posLog := &types.Log{
+		Address: contract.Address(),
+		Topics:  topics,
+		Data:    data,
+	}
+	// This is a non-consensus field, but assigned here because
+	// core/state doesn't know the current block number.
+	if evm.BlockNumber != nil {
+		posLog.BlockNumber = evm.BlockNumber.Uint64()
+	}
+	evm.StateDB.AddLog(posLog)
+	return nil
+}
+
+func posLogTopic(arg interface{}) (common.Hash, error) {
+	switch v := arg.(type) {
+	case common.Address:
+		return common.BytesToHash(v[:]), nil
+	case *big.Int:
+		return common.BigToHash(v), nil
+	case common.Hash:
+		return v, nil
+	}
+	return common.Hash{}, errPosLogTopic
+}
@@ -0,0 +1,80 @@
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+type logStateDB struct {
+	StakerStateDB
+	logs *[]*types.Log
+}
+
+func (db logStateDB) AddLog(l *types.Log) {
+	*db.logs = append(*db.logs, l)
+}
+
+func TestAddPosLog(t *testing.T) {
+	logs := make([]*types.Log, 0)
+	evm := NewEVM(Context{BlockNumber: big.NewInt(10)}, logStateDB{logs: &logs}, params.TestChainConfig, Config{})
+	c := &Contract{self: AccountRef(WanCscPrecompileAddr)}
+
+	sender := common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
+	posAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	event := cscAbi.Events["StakeAppend"]
+	err := addPosLog(evm, c, event, sender, posAddr, big.NewInt(18000), big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logs) != 1 {
+		t.Fatal("log is not added")
+	}
+	l := logs[0]
+	if l.Address != WanCscPrecompileAddr || l.BlockNumber != 10 {
+		t.Fatal("log address or block number wrong")
+	}
+	if len(l.Topics) != 4 ||
+		l.Topics[0] != event.Id() ||
+		l.Topics[1] != common.BytesToHash(sender[:]) ||
+		l.Topics[2] != common.BytesToHash(posAddr[:]) ||
+		l.Topics[3] != common.BigToHash(big.NewInt(18000)) {
+		t.Fatal("log topics wrong")
+	}
+	if common.BytesToHash(l.Data) != common.BigToHash(big.NewInt(100)) {
+		t.Fatal("log data wrong")
+	}
+
+	err = addPosLog(evm, c, event, sender, posAddr)
+	if err == nil {
+		t.Fatal("arguments count mismatch should fail")
+	}
+}
+
+func TestAddPosLogFork(t *testing.T) {
+	config := *params.TestChainConfig
+	config.PosLogBlock = big.NewInt(10)
+	c := &Contract{self: AccountRef(WanCscPrecompileAddr)}
+	sender := common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
+	posAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	event := cscAbi.Events["StakeAppend"]
+
+	for _, tt := range []struct {
+		number int64
+		logs   int
+	}{{9, 0}, {10, 1}, {11, 1}} {
+		logs := make([]*types.Log, 0)
+		evm := NewEVM(Context{BlockNumber: big.NewInt(tt.number)}, logStateDB{logs: &logs}, &config, Config{})
+		err := addPosLog(evm, c, event, sender, posAddr, big.NewInt(18000), big.NewInt(100))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(logs) != tt.logs {
+			t.Errorf("block %d: %d logs, want %d", tt.number, len(logs), tt.logs)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package vm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/uleaderselection"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+//
+// slashing of the random proposers who skip the dkg or signature stage,
+// and of the slot leaders who seal two blocks in a slot
+//
+
+const (
+	// SlashKindDkg means the proposer didn't send a valid dkg2
+	SlashKindDkg = 1
+	// SlashKindSig means the proposer joined dkg2 but didn't send the signature share
+	SlashKindSig = 2
+
+	PSSlashKeyHash     = 701
+	PSSlashPoolKeyHash = 702
+)
+
+var (
+	StakersInfoSlashKeyHash = common.BytesToHash(big.NewInt(PSSlashKeyHash).Bytes())
+	SlashPoolKeyHash        = common.BytesToHash(big.NewInt(PSSlashPoolKeyHash).Bytes())
+
+	kindSlash      = []byte{103}
+	kindDoubleSign = []byte{104}
+
+	errSlashRate          = errors.New("slash rate is out of range")
+	errDoubleSignSlot     = errors.New("headers are not in the same slot")
+	errDoubleSignSame     = errors.New("headers are the same")
+	errDoubleSignEpoch    = errors.New("double sign evidence is not in current epoch")
+	errDoubleSignSealer   = errors.New("header is not sealed by the slot leader")
+	errDoubleSignReported = errors.New("double sign is reported already")
+	errDoubleSignProof    = errors.New("slot leader proof of the header is invalid")
+)
+
+// SlashEvidence is the record of a random proposer slashed in an epoch
+type SlashEvidence struct {
+	EpochId    uint64
+	ProposerId uint32
+	Address    common.Address
+	Kind       uint64
+	Amount     *big.Int // wan deducted from StakerInfo.Amount
+}
+
+// RBSlashKind tells which stage the random proposer skipped in epochId, 0 if none
+func RBSlashKind(stateDB StateDB, epochId uint64, proposerId uint32) uint64 {
+	if !IsJoinDKG2(stateDB, epochId, proposerId) {
+		return SlashKindDkg
+	}
+	if !IsRBActive(stateDB, epochId, proposerId) {
+		return SlashKindSig
+	}
+	return 0
+}
+
+// SlashStaker deducts rate/RBSlashRateBase of the staker amount and records the evidence.
+// The wan deducted is burned from the staking contract, the caller decides where it goes.
+func SlashStaker(stateDB StateDB, epochId uint64, proposerId uint32, addr common.Address, kind uint64, rate uint64) (*big.Int, error) {
+	amount, err := deductStake(stateDB, addr, rate)
+	if err != nil {
+		return nil, err
+	}
+
+	evidence := &SlashEvidence{
+		EpochId:    epochId,
+		ProposerId: proposerId,
+		Address:    addr,
+		Kind:       kind,
+		Amount:     amount,
+	}
+	evidenceBytes, err := rlp.EncodeToBytes(evidence)
+	if err != nil {
+		return nil, err
+	}
+	if err := StoreInfo(stateDB, StakingCommonAddr, *GetRBKeyHash(kindSlash, epochId, proposerId), evidenceBytes); err != nil {
+		return nil, err
+	}
+
+	log.Info("random proposer slashed", "epochId", epochId, "proposerId", proposerId, "address", addr, "kind", kind, "amount", amount)
+	return amount, nil
+}
+
+// deductStake deducts rate/RBSlashRateBase of the staker amount and burns it from the staking contract
+func deductStake(stateDB StateDB, addr common.Address, rate uint64) (*big.Int, error) {
+	if rate > posconfig.RBSlashRateBase {
+		return nil, errSlashRate
+	}
+
+	key := GetStakeInKeyHash(addr)
+	staker, err := getStakerInfo(stateDB, key)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := new(big.Int).Mul(staker.Amount, big.NewInt(int64(rate)))
+	amount.Div(amount, big.NewInt(posconfig.RBSlashRateBase))
+
+	staker.Amount.Sub(staker.Amount, amount)
+	staker.StakeAmount = new(big.Int).Mul(staker.Amount, big.NewInt(int64(CalLocktimeWeight(staker.LockEpochs))))
+	// the partial stake out can't refund more than left
+	if out := staker.GetStakeOut(); out.OutAmount.Cmp(staker.Amount) > 0 {
+		out.OutAmount.Set(staker.Amount)
+		staker.SetStakeOut(out)
+	}
+	if err := storeStakerInfo(stateDB, key, staker); err != nil {
+		return nil, err
+	}
+	stateDB.SubBalance(WanCscPrecompileAddr, amount)
+	return amount, nil
+}
+
+// GetSlashEvidence returns the evidence of the random proposer slashed in epochId, nil if not slashed
+func GetSlashEvidence(stateDB StateDB, epochId uint64, proposerId uint32) (*SlashEvidence, error) {
+	evidenceBytes, err := GetInfo(stateDB, StakingCommonAddr, *GetRBKeyHash(kindSlash, epochId, proposerId))
+	if err != nil {
+		return nil, err
+	}
+	if len(evidenceBytes) == 0 {
+		return nil, nil
+	}
+
+	var evidence SlashEvidence
+	if err := rlp.DecodeBytes(evidenceBytes, &evidence); err != nil {
+		return nil, err
+	}
+	return &evidence, nil
+}
+
+// SlashSetEpoch marks the slashing of epochID finished, epochID+1 is stored so that epoch 0 can be told
+func SlashSetEpoch(stateDb StateDB, epochID uint64) {
+	b := new(big.Int).SetUint64(epochID + 1)
+	StoreInfo(stateDb, StakingCommonAddr, StakersInfoSlashKeyHash, b.Bytes())
+}
+
+// SlashIsFinished tells whether the slashing of epochID is finished
+func SlashIsFinished(stateDb StateDB, epochID uint64) bool {
+	epochByte, err := GetInfo(stateDb, StakingCommonAddr, StakersInfoSlashKeyHash)
+	if err != nil || len(epochByte) == 0 {
+		return false
+	}
+	finishedEpochId := big.NewInt(0).SetBytes(epochByte).Uint64()
+	return finishedEpochId > epochID
+}
+
+// DoubleSignEvidence is the record of a slot leader who sealed two blocks in a slot
+type DoubleSignEvidence struct {
+	EpochId  uint64
+	SlotId   uint64
+	Address  common.Address
+	Header1  common.Hash
+	Header2  common.Hash
+	Reporter common.Address
+	Amount   *big.Int // wan deducted from StakerInfo.Amount
+}
+
+// DoubleSign slashes the slot leader who sealed both headers in a slot. The evidence must be sent
+// in the epoch of the slot, the slot leaders of other epochs are not kept.
+func (p *PosStaking) DoubleSign(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
+	var info DoubleSignParam
+	err := cscAbi.UnpackInput(&info, "doubleSign", payload)
+	if err != nil {
+		return nil, err
+	}
+	if contract.Value().Sign() != 0 {
+		return nil, errors.New("doubleSign should not carry value")
+	}
+
+	eidNow, _ := util.CalEpochSlotID(evm.Time.Uint64())
+	h1, h2, addr, err := checkDoubleSign(evm.StateDB, &info, eidNow)
+	if err != nil {
+		return nil, err
+	}
+	epochId, slotId := headerEpochSlotID(h1)
+
+	amount, err := deductStake(evm.StateDB, addr, posconfig.Cfg().DoubleSignSlashRate)
+	if err != nil {
+		return nil, err
+	}
+	addSlashPool(evm.StateDB, amount)
+
+	evidence := &DoubleSignEvidence{
+		EpochId:  epochId,
+		SlotId:   slotId,
+		Address:  addr,
+		Header1:  h1.Hash(),
+		Header2:  h2.Hash(),
+		Reporter: contract.CallerAddress,
+		Amount:   amount,
+	}
+	evidenceBytes, err := rlp.EncodeToBytes(evidence)
+	if err != nil {
+		return nil, err
+	}
+	err = StoreInfo(evm.StateDB, StakingCommonAddr, *GetRBKeyHash(kindDoubleSign, epochId, uint32(slotId)), evidenceBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = addPosLog(evm, contract, cscAbi.Events["DoubleSign"], contract.CallerAddress, addr,
+		new(big.Int).SetUint64(epochId), new(big.Int).SetUint64(slotId), amount)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("slot leader slashed for double sign", "epochId", epochId, "slotId", slotId, "address", addr, "amount", amount)
+	return nil, nil
+}
+
+func (p *PosStaking) doubleSignParseAndValid(stateDB StateDB, payload []byte) error {
+	var info DoubleSignParam
+	err := cscAbi.UnpackInput(&info, "doubleSign", payload)
+	if err != nil {
+		return err
+	}
+
+	eidNow, _ := util.GetEpochSlotID()
+	_, _, _, err = checkDoubleSign(stateDB, &info, eidNow)
+	return err
+}
+
+// checkDoubleSign verifies the headers are different, in the same slot of epochNow and
+// both sealed by the slot leader. The headers and the slot leader address are returned.
+//
+// The slot leader is told by the headers and the state only, so every node gets the
+// same result: both headers must claim the same key in their slot leader proof, be
+// sealed by it, and the key must be a staker's. An honest slot leader never seals two
+// headers in a slot, so the slot leader sequence of the epoch is not needed.
+func checkDoubleSign(stateDB StateDB, info *DoubleSignParam, epochNow uint64) (*types.Header, *types.Header, common.Address, error) {
+	var h1, h2 types.Header
+	if err := rlp.DecodeBytes(info.Header1, &h1); err != nil {
+		return nil, nil, common.Address{}, err
+	}
+	if err := rlp.DecodeBytes(info.Header2, &h2); err != nil {
+		return nil, nil, common.Address{}, err
+	}
+	if h1.Difficulty == nil || h2.Difficulty == nil || len(h1.Extra) <= util.ExtraSeal || len(h2.Extra) <= util.ExtraSeal {
+		return nil, nil, common.Address{}, errParameters
+	}
+
+	epochId, slotId := headerEpochSlotID(&h1)
+	epochId2, slotId2 := headerEpochSlotID(&h2)
+	if epochId != epochId2 || slotId != slotId2 {
+		return nil, nil, common.Address{}, errDoubleSignSlot
+	}
+	if util.SigHash(&h1) == util.SigHash(&h2) {
+		return nil, nil, common.Address{}, errDoubleSignSame
+	}
+	if epochId != epochNow {
+		return nil, nil, common.Address{}, errDoubleSignEpoch
+	}
+
+	evidence, err := GetInfo(stateDB, StakingCommonAddr, *GetRBKeyHash(kindDoubleSign, epochId, uint32(slotId)))
+	if err != nil {
+		return nil, nil, common.Address{}, err
+	}
+	if len(evidence) != 0 {
+		return nil, nil, common.Address{}, errDoubleSignReported
+	}
+
+	pk, err := headerSlotLeader(&h1)
+	if err != nil {
+		return nil, nil, common.Address{}, err
+	}
+	pk2, err := headerSlotLeader(&h2)
+	if err != nil {
+		return nil, nil, common.Address{}, err
+	}
+	pkBytes := crypto.FromECDSAPub(pk)
+	if !bytes.Equal(pkBytes, crypto.FromECDSAPub(pk2)) {
+		return nil, nil, common.Address{}, errDoubleSignSealer
+	}
+	leader := crypto.PubkeyToAddress(*pk)
+
+	for _, h := range []*types.Header{&h1, &h2} {
+		sealer, err := util.RecoverSealer(h)
+		if err != nil {
+			return nil, nil, common.Address{}, err
+		}
+		if sealer != leader {
+			return nil, nil, common.Address{}, errDoubleSignSealer
+		}
+	}
+
+	staker, err := getStakerInfo(stateDB, GetStakeInKeyHash(leader))
+	if err != nil {
+		return nil, nil, common.Address{}, err
+	}
+	if !bytes.Equal(staker.PubSec256, pkBytes) {
+		return nil, nil, common.Address{}, errDoubleSignSealer
+	}
+	return &h1, &h2, leader, nil
+}
+
+// slotLeaderProof is the slot leader proof packed in the header extra, it has the rlp
+// layout of slotleader.Pack
+type slotLeaderProof struct {
+	Proof    [][]byte
+	ProofMeg [][]byte
+}
+
+// headerSlotLeader returns the public key the header claims as its slot leader, after
+// checking the dleq proof of the claim is made with the private key.
+func headerSlotLeader(header *types.Header) (*ecdsa.PublicKey, error) {
+	var pack slotLeaderProof
+	if err := rlp.DecodeBytes(header.Extra[:len(header.Extra)-util.ExtraSeal], &pack); err != nil {
+		return nil, errDoubleSignProof
+	}
+	if len(pack.Proof) != 2 || len(pack.ProofMeg) != 3 {
+		return nil, errDoubleSignProof
+	}
+
+	proof := convert.ByteArrayToBigIntArray(pack.Proof)
+	proofMeg := convert.ByteArrayToPkArray(pack.ProofMeg)
+	for _, pk := range proofMeg {
+		if pk == nil || pk.X == nil || pk.Y == nil || !crypto.S256().IsOnCurve(pk.X, pk.Y) {
+			return nil, errDoubleSignProof
+		}
+	}
+
+	// ProofMeg is [PK, Gt, skGt], the proof shows log_G(PK) == log_Gt(skGt)
+	base := &ecdsa.PublicKey{Curve: crypto.S256()}
+	base.X, base.Y = crypto.S256().ScalarBaseMult(big.NewInt(1).Bytes())
+	if !uleaderselection.VerifyDleqProof([]*ecdsa.PublicKey{base, proofMeg[1]},
+		[]*ecdsa.PublicKey{proofMeg[0], proofMeg[2]}, proof) {
+		return nil, errDoubleSignProof
+	}
+	return proofMeg[0], nil
+}
+
+// GetDoubleSignEvidence returns the evidence of the slot sealed twice, nil if not reported
+func GetDoubleSignEvidence(stateDB StateDB, epochId uint64, slotId uint64) (*DoubleSignEvidence, error) {
+	evidenceBytes, err := GetInfo(stateDB, StakingCommonAddr, *GetRBKeyHash(kindDoubleSign, epochId, uint32(slotId)))
+	if err != nil {
+		return nil, err
+	}
+	if len(evidenceBytes) == 0 {
+		return nil, nil
+	}
+
+	var evidence DoubleSignEvidence
+	if err := rlp.DecodeBytes(evidenceBytes, &evidence); err != nil {
+		return nil, err
+	}
+	return &evidence, nil
+}
+
+// headerEpochSlotID returns the epoch and slot the header is sealed in, they are kept in the difficulty
+func headerEpochSlotID(header *types.Header) (uint64, uint64) {
+	return header.Difficulty.Uint64() >> 32, (header.Difficulty.Uint64() >> 8) & 0x00FFFFFF
+}
+
+// addSlashPool keeps the wan slashed in a transaction until TakeSlashPool at the epoch end
+func addSlashPool(stateDB StateDB, amount *big.Int) {
+	poolBytes, _ := GetInfo(stateDB, StakingCommonAddr, SlashPoolKeyHash)
+	pool := new(big.Int).SetBytes(poolBytes)
+	pool.Add(pool, amount)
+	StoreInfo(stateDB, StakingCommonAddr, SlashPoolKeyHash, pool.Bytes())
+}
+
+// TakeSlashPool returns the wan slashed by the transactions and empties the pool
+func TakeSlashPool(stateDB StateDB) *big.Int {
+	poolBytes, _ := GetInfo(stateDB, StakingCommonAddr, SlashPoolKeyHash)
+	pool := new(big.Int).SetBytes(poolBytes)
+	if pool.Sign() != 0 {
+		StoreInfo(stateDB, StakingCommonAddr, SlashPoolKeyHash, nil)
+	}
+	return pool
+}
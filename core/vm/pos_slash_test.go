@@ -0,0 +1,178 @@
+package vm
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/uleaderselection"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// testSlotProof returns the packed slot leader proof of key as it is put in the header extra
+func testSlotProof(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	base := &ecdsa.PublicKey{Curve: crypto.S256()}
+	base.X, base.Y = crypto.S256().ScalarBaseMult(big.NewInt(1).Bytes())
+	gt, _ := crypto.GenerateKey()
+	skGt := &ecdsa.PublicKey{Curve: crypto.S256()}
+	skGt.X, skGt.Y = crypto.S256().ScalarMult(gt.PublicKey.X, gt.PublicKey.Y, key.D.Bytes())
+
+	proof, err := uleaderselection.DleqProofGeneration([]*ecdsa.PublicKey{base, &gt.PublicKey},
+		[]*ecdsa.PublicKey{&key.PublicKey, skGt}, key.D)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := rlp.EncodeToBytes(&slotLeaderProof{
+		Proof:    convert.BigIntArrayToByteArray(proof),
+		ProofMeg: convert.PkArrayToByteArray([]*ecdsa.PublicKey{&key.PublicKey, &gt.PublicKey, skGt}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+// testSealedHeader returns the rlp of a header in epochID/slotID with the slot leader proof
+// of prover and sealed by key
+func testSealedHeader(t *testing.T, key, prover *ecdsa.PrivateKey, epochID, slotID uint64, root common.Hash) []byte {
+	proof := testSlotProof(t, prover)
+	header := &types.Header{
+		Number:     big.NewInt(1),
+		Root:       root,
+		Difficulty: new(big.Int).SetUint64(epochID<<32 | slotID<<8 | 1),
+		Time:       big.NewInt(0),
+		Extra:      append(proof, make([]byte, util.ExtraSeal)...),
+	}
+	sig, err := crypto.Sign(util.SigHash(header).Bytes(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(header.Extra[len(proof):], sig)
+
+	buf, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+// testUnprovedHeader returns the rlp of a header sealed by key without a slot leader proof
+func testUnprovedHeader(t *testing.T, key *ecdsa.PrivateKey, epochID, slotID uint64) []byte {
+	header := &types.Header{
+		Number:     big.NewInt(1),
+		Difficulty: new(big.Int).SetUint64(epochID<<32 | slotID<<8 | 1),
+		Time:       big.NewInt(0),
+		Extra:      make([]byte, 1+util.ExtraSeal),
+	}
+	sig, err := crypto.Sign(util.SigHash(header).Bytes(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(header.Extra[1:], sig)
+
+	buf, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestDoubleSign(t *testing.T) {
+	if !reset() {
+		t.Fatal("pos staking db init error")
+	}
+	defer clearDb()
+
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	leader := crypto.PubkeyToAddress(key.PublicKey)
+	amount := new(big.Int).Mul(big.NewInt(100000), ether)
+	staker := &StakerInfo{Address: leader, PubSec256: crypto.FromECDSAPub(&key.PublicKey), Amount: new(big.Int).Set(amount), StakeAmount: new(big.Int).Set(amount), LockEpochs: 10}
+	if err := storeStakerInfo(stakerevm.StateDB, GetStakeInKeyHash(leader), staker); err != nil {
+		t.Fatal(err)
+	}
+	stakerevm.StateDB.AddBalance(WanCscPrecompileAddr, amount)
+
+	stakerevm.Time = big.NewInt(time.Now().Unix())
+	contract.CallerAddress = common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
+	contract.Value().SetUint64(0)
+	eidNow, _ := util.CalEpochSlotID(stakerevm.Time.Uint64())
+	h1 := testSealedHeader(t, key, key, eidNow, 5, common.HexToHash("0x01"))
+
+	// the evidences are refused before the double sign fork
+	bytes, _ := cscAbi.Pack("doubleSign", h1, testSealedHeader(t, key, key, eidNow, 5, common.HexToHash("0x02")))
+	stakerevm.BlockNumber = nil
+	_, err := stakercontract.Run(bytes, contract, stakerevm)
+	stakerevm.BlockNumber = big.NewInt(1)
+	if err != errMethodId {
+		t.Fatal("double sign should be refused before the fork", err)
+	}
+
+	cases := []struct {
+		h2  []byte
+		err error
+	}{
+		{h1, errDoubleSignSame},
+		{testSealedHeader(t, key, key, eidNow, 6, common.HexToHash("0x02")), errDoubleSignSlot},
+		{testSealedHeader(t, key, key, eidNow+1, 5, common.HexToHash("0x02")), errDoubleSignSlot},
+		{testSealedHeader(t, other, key, eidNow, 5, common.HexToHash("0x02")), errDoubleSignSealer},
+		{testSealedHeader(t, other, other, eidNow, 5, common.HexToHash("0x02")), errDoubleSignSealer},
+		{testUnprovedHeader(t, key, eidNow, 5), errDoubleSignProof},
+	}
+	for i, c := range cases {
+		bytes, _ := cscAbi.Pack("doubleSign", h1, c.h2)
+		if _, err := stakercontract.Run(bytes, contract, stakerevm); err != c.err {
+			t.Fatal("case", i, "should fail", c.err, err)
+		}
+	}
+
+	// a slot leader which is not a staker can't be slashed
+	bytes, _ = cscAbi.Pack("doubleSign", testSealedHeader(t, other, other, eidNow, 5, common.HexToHash("0x01")),
+		testSealedHeader(t, other, other, eidNow, 5, common.HexToHash("0x02")))
+	if _, err := stakercontract.Run(bytes, contract, stakerevm); err == nil {
+		t.Fatal("double sign of a non staker should fail")
+	}
+
+	h2 := testSealedHeader(t, key, key, eidNow, 5, common.HexToHash("0x02"))
+	bytes, _ = cscAbi.Pack("doubleSign", h1, h2)
+	if _, err := stakercontract.Run(bytes, contract, stakerevm); err != nil {
+		t.Fatal(err)
+	}
+
+	slash := new(big.Int).Mul(amount, big.NewInt(int64(posconfig.Cfg().DoubleSignSlashRate)))
+	slash.Div(slash, big.NewInt(posconfig.RBSlashRateBase))
+	info, err := getStakerInfo(stakerevm.StateDB, GetStakeInKeyHash(leader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if new(big.Int).Add(info.Amount, slash).Cmp(amount) != 0 {
+		t.Fatal("staker should be slashed", info.Amount)
+	}
+
+	evidence, err := GetDoubleSignEvidence(stakerevm.StateDB, eidNow, 5)
+	if err != nil || evidence == nil {
+		t.Fatal("double sign evidence should be saved", err)
+	}
+	if evidence.Address != leader || evidence.Reporter != contract.CallerAddress || evidence.Amount.Cmp(slash) != 0 {
+		t.Fatal("double sign evidence mismatch", evidence)
+	}
+
+	// a slot is slashed once
+	if _, err := stakercontract.Run(bytes, contract, stakerevm); err != errDoubleSignReported {
+		t.Fatal("double sign should be reported once", err)
+	}
+
+	if pool := TakeSlashPool(stakerevm.StateDB); pool.Cmp(slash) != 0 {
+		t.Fatal("slashed wan should be kept in the pool", pool)
+	}
+	if pool := TakeSlashPool(stakerevm.StateDB); pool.Sign() != 0 {
+		t.Fatal("pool should be empty after taken", pool)
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 )
@@ -24,19 +26,22 @@ contract stake {
 	function stakeAppend(address addr) public payable {}
 	function delegateIn(address delegateAddress) public payable {}
 	function delegateOut(address delegateAddress) public {}
+	function stakeOut(address addr) public {}
+	function stakeOutPartial(address addr, uint256 amount) public {}
+	function doubleSign(bytes memory header1, bytes memory header2) public {}
+
+	event StakeIn(address indexed sender, address indexed posAddress, uint256 indexed epochId, uint256 value, uint256 lockEpochs, uint256 feeRate);
+	event StakeUpdate(address indexed sender, address indexed posAddress, uint256 indexed epochId, uint256 lockEpochs, uint256 feeRate);
+	event StakeAppend(address indexed sender, address indexed posAddress, uint256 indexed epochId, uint256 value);
+	event DelegateIn(address indexed sender, address indexed posAddress, uint256 indexed epochId, uint256 value);
+	event DelegateOut(address indexed sender, address indexed posAddress, uint256 indexed epochId, uint256 quitEpoch);
+	event StakeOut(address indexed sender, address indexed posAddress, uint256 indexed epochId, uint256 quitEpoch);
+	event StakeOutPartial(address indexed sender, address indexed posAddress, uint256 indexed epochId, uint256 amount, uint256 outEpoch);
+	event DoubleSign(address indexed sender, address indexed posAddress, uint256 indexed epochId, uint256 slotId, uint256 amount);
 }
 
 */
 const (
-	PSMinEpochNum = 7
-	PSEpochNum_1 = 15 // 1.1 times
-	PSEpochNum_2 = 45 // 1.3 times
-	PSEpochNum_3 = 90 // 1.5 times
-
-	PSMaxEpochNum = 90
-	PSMinStakeholderStake = 10000
-	PSMinValidatorStake = 100000
-	PSMinDelegatorStake = 100
 	PSMinFeeRate = 0
 	PSMaxFeeRate = 100
 	PSNodeleFeeRate = 100
@@ -138,6 +143,297 @@ var (
 		"payable": false,
 		"stateMutability": "nonpayable",
 		"type": "function"
+	},
+	{
+		"constant": false,
+		"inputs": [
+			{
+				"name": "addr",
+				"type": "address"
+			}
+		],
+		"name": "stakeOut",
+		"outputs": [],
+		"payable": false,
+		"stateMutability": "nonpayable",
+		"type": "function"
+	},
+	{
+		"constant": false,
+		"inputs": [
+			{
+				"name": "addr",
+				"type": "address"
+			},
+			{
+				"name": "amount",
+				"type": "uint256"
+			}
+		],
+		"name": "stakeOutPartial",
+		"outputs": [],
+		"payable": false,
+		"stateMutability": "nonpayable",
+		"type": "function"
+	},
+	{
+		"constant": false,
+		"inputs": [
+			{
+				"name": "header1",
+				"type": "bytes"
+			},
+			{
+				"name": "header2",
+				"type": "bytes"
+			}
+		],
+		"name": "doubleSign",
+		"outputs": [],
+		"payable": false,
+		"stateMutability": "nonpayable",
+		"type": "function"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{
+				"indexed": true,
+				"name": "sender",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "posAddress",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "epochId",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "value",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "lockEpochs",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "feeRate",
+				"type": "uint256"
+			}
+		],
+		"name": "StakeIn",
+		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{
+				"indexed": true,
+				"name": "sender",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "posAddress",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "epochId",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "lockEpochs",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "feeRate",
+				"type": "uint256"
+			}
+		],
+		"name": "StakeUpdate",
+		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{
+				"indexed": true,
+				"name": "sender",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "posAddress",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "epochId",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "value",
+				"type": "uint256"
+			}
+		],
+		"name": "StakeAppend",
+		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{
+				"indexed": true,
+				"name": "sender",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "posAddress",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "epochId",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "value",
+				"type": "uint256"
+			}
+		],
+		"name": "DelegateIn",
+		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{
+				"indexed": true,
+				"name": "sender",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "posAddress",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "epochId",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "quitEpoch",
+				"type": "uint256"
+			}
+		],
+		"name": "DelegateOut",
+		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{
+				"indexed": true,
+				"name": "sender",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "posAddress",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "epochId",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "quitEpoch",
+				"type": "uint256"
+			}
+		],
+		"name": "StakeOut",
+		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{
+				"indexed": true,
+				"name": "sender",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "posAddress",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "epochId",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "amount",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "outEpoch",
+				"type": "uint256"
+			}
+		],
+		"name": "StakeOutPartial",
+		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{
+				"indexed": true,
+				"name": "sender",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "posAddress",
+				"type": "address"
+			},
+			{
+				"indexed": true,
+				"name": "epochId",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "slotId",
+				"type": "uint256"
+			},
+			{
+				"indexed": false,
+				"name": "amount",
+				"type": "uint256"
+			}
+		],
+		"name": "DoubleSign",
+		"type": "event"
 	}
 ]
 `
@@ -150,12 +446,10 @@ var (
 	stakeAppendId [4]byte
 	delegateInId [4]byte
 	delegateOutId [4]byte
+	stakeOutId [4]byte
+	stakeOutPartialId [4]byte
+	doubleSignId [4]byte
 
-	maxEpochNum         = big.NewInt(PSMaxEpochNum)
-	minEpochNum         = big.NewInt(PSMinEpochNum)
-	minStakeholderStake = new(big.Int).Mul(big.NewInt(PSMinStakeholderStake), ether)
-	minValidatorStake 	= new(big.Int).Mul(big.NewInt(PSMinValidatorStake), ether)
-	minDelegatorStake = new(big.Int).Mul(big.NewInt(PSMinDelegatorStake), ether)
 	minFeeRate = big.NewInt(PSMinFeeRate)
 	maxFeeRate = big.NewInt(PSMaxFeeRate)
 	noDelegateFeeRate = big.NewInt(PSNodeleFeeRate)
@@ -179,6 +473,14 @@ type StakeUpdateParam struct {
 type DelegateParam struct {
 	DelegateAddress common.Address //delegation’s address
 }
+type StakeOutPartialParam struct {
+	Addr   common.Address //stakeholder’s address
+	Amount *big.Int       //wan value to withdraw
+}
+type DoubleSignParam struct {
+	Header1 []byte //rlp encoded header sealed by the slot leader
+	Header2 []byte //rlp encoded header sealed in the same slot
+}
 
 //
 // storage structures
@@ -198,6 +500,44 @@ type StakerInfo struct {
 	FeeRate      uint64
 	NextFeeRate  uint64
 	Clients      []ClientInfo
+
+	// the stake out state, at most one item which is absent until the staker
+	// stakes out. it is a tail list so the records stored before still decode.
+	Out []*StakeOutInfo `rlp:"tail"`
+}
+
+// StakeOutInfo is the pending stake out of a stakeholder
+type StakeOutInfo struct {
+	QuitEpoch uint64   //the epoch in which the stakeholder quits. 0 means not quit.
+	OutAmount *big.Int //wan value waiting to be withdrawn by stakeOutPartial
+	OutEpoch  uint64   //the epoch in which OutAmount is refunded.
+}
+
+// GetStakeOut returns a copy of the stake out state, the zero state if the
+// staker never staked out
+func (s *StakerInfo) GetStakeOut() StakeOutInfo {
+	if len(s.Out) == 0 || s.Out[0] == nil {
+		return StakeOutInfo{OutAmount: big.NewInt(0)}
+	}
+	out := *s.Out[0]
+	if out.OutAmount == nil {
+		out.OutAmount = big.NewInt(0)
+	} else {
+		out.OutAmount = new(big.Int).Set(out.OutAmount)
+	}
+	return out
+}
+
+// SetStakeOut sets the stake out state, the zero state is dropped from the record
+func (s *StakerInfo) SetStakeOut(out StakeOutInfo) {
+	if out.QuitEpoch == 0 && (out.OutAmount == nil || out.OutAmount.Sign() == 0) {
+		s.Out = nil
+		return
+	}
+	if out.OutAmount == nil {
+		out.OutAmount = big.NewInt(0)
+	}
+	s.Out = []*StakeOutInfo{&out}
 }
 
 type ClientInfo struct {
@@ -239,6 +579,9 @@ func init() {
 	copy(stakeUpdateId[:], cscAbi.Methods["stakeUpdate"].Id())
 	copy(delegateInId[:], cscAbi.Methods["delegateIn"].Id())
 	copy(delegateOutId[:], cscAbi.Methods["delegateOut"].Id())
+	copy(stakeOutId[:], cscAbi.Methods["stakeOut"].Id())
+	copy(stakeOutPartialId[:], cscAbi.Methods["stakeOutPartial"].Id())
+	copy(doubleSignId[:], cscAbi.Methods["doubleSign"].Id())
 }
 
 /////////////////////////////
@@ -277,11 +620,32 @@ func (p *PosStaking) Run(input []byte, contract *Contract, evm *EVM) ([]byte, er
 		return p.DelegateIn(input[4:], contract, evm)
 	} else if methodId == delegateOutId {
 		return p.DelegateOut(input[4:], contract, evm)
+	} else if methodId == stakeOutId {
+		if !evm.ChainConfig().IsStakeOut(evm.BlockNumber) {
+			return nil, errMethodId
+		}
+		return p.StakeOut(input[4:], contract, evm)
+	} else if methodId == stakeOutPartialId {
+		if !evm.ChainConfig().IsStakeOut(evm.BlockNumber) {
+			return nil, errMethodId
+		}
+		return p.StakeOutPartial(input[4:], contract, evm)
+	} else if methodId == doubleSignId {
+		if !evm.ChainConfig().IsDoubleSign(evm.BlockNumber) {
+			return nil, errMethodId
+		}
+		return p.DoubleSign(input[4:], contract, evm)
 	}
 	return nil, errMethodId
 }
 
+// ValidTx validates tx with every fork active, the tx pool uses ValidTxAt
 func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+	return p.ValidTxAt(stateDB, signer, tx, params.AllProtocolChanges, new(big.Int))
+}
+
+// ValidTxAt validates tx against the state and the forks of the block number
+func (p *PosStaking) ValidTxAt(stateDB StateDB, signer types.Signer, tx *types.Transaction, config *params.ChainConfig, number *big.Int) error {
 	input := tx.Data()
 	if len(input) < 4 {
 		return errors.New("parameter is too short")
@@ -290,6 +654,13 @@ func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tra
 	var methodId [4]byte
 	copy(methodId[:], input[:4])
 
+	if methodId == doubleSignId && !config.IsDoubleSign(number) {
+		return errMethodId
+	}
+	if (methodId == stakeOutId || methodId == stakeOutPartialId) && !config.IsStakeOut(number) {
+		return errMethodId
+	}
+
 	if methodId == stakeInId {
 		err := p.stakeInParseAndValid(input[4:])
 		if err != nil {
@@ -320,6 +691,24 @@ func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tra
 			return errors.New("delegateOut verify failed")
 		}
 		return nil
+	} else if methodId == stakeOutId {
+		err := p.stakeOutParseAndValid(stateDB, signer, tx, input[4:])
+		if err != nil {
+			return errors.New("stakeOut verify failed " + err.Error())
+		}
+		return nil
+	} else if methodId == stakeOutPartialId {
+		err := p.stakeOutPartialParseAndValid(stateDB, signer, tx, input[4:])
+		if err != nil {
+			return errors.New("stakeOutPartial verify failed " + err.Error())
+		}
+		return nil
+	} else if methodId == doubleSignId {
+		err := p.doubleSignParseAndValid(stateDB, input[4:])
+		if err != nil {
+			return errors.New("doubleSign verify failed " + err.Error())
+		}
+		return nil
 	}
 
 	return errParameters
@@ -336,7 +725,7 @@ func (p *PosStaking) StakeUpdate(payload []byte, contract *Contract, evm *EVM) (
 	}
 
 	//  Lock time >= min epoch, <= max epoch
-	if info.LockEpochs.Cmp(minEpochNum) < 0 || info.LockEpochs.Cmp(maxEpochNum) > 0 {
+	if info.LockEpochs.Cmp(minEpochNum()) < 0 || info.LockEpochs.Cmp(maxEpochNum()) > 0 {
 		return nil, errors.New("invalid lock time")
 	}
 
@@ -360,7 +749,7 @@ func (p *PosStaking) StakeUpdate(payload []byte, contract *Contract, evm *EVM) (
 		return nil, errors.New("cannot change at the last 3 epoch.")
 	}
 
-	if info.FeeRate.Cmp(noDelegateFeeRate) != 0 &&  stakerInfo.Amount.Cmp(minValidatorStake) < 0 {
+	if info.FeeRate.Cmp(noDelegateFeeRate) != 0 &&  stakerInfo.Amount.Cmp(minValidatorStake()) < 0 {
 		return nil, errors.New("need more Wan to be a validator")
 	}
 	stakerInfo.NextLockEpochs = info.LockEpochs.Uint64()
@@ -374,6 +763,12 @@ func (p *PosStaking) StakeUpdate(payload []byte, contract *Contract, evm *EVM) (
 		return nil, res
 	}
 
+	err = addPosLog(evm, contract, cscAbi.Events["StakeUpdate"], contract.CallerAddress, info.Addr,
+		new(big.Int).SetUint64(eidNow), info.LockEpochs, info.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 func (p *PosStaking) StakeAppend(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
@@ -408,6 +803,12 @@ func (p *PosStaking) StakeAppend(payload []byte, contract *Contract, evm *EVM) (
 		return nil, res
 	}
 
+	err = addPosLog(evm, contract, cscAbi.Events["StakeAppend"], contract.CallerAddress, addr,
+		new(big.Int).SetUint64(eidNow), contract.Value())
+	if err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 func (p *PosStaking) StakeIn(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
@@ -437,7 +838,7 @@ func (p *PosStaking) StakeIn(payload []byte, contract *Contract, evm *EVM) ([]by
 	}
 
 	// 3. Lock time >= min epoch, <= max epoch
-	if info.LockEpochs.Cmp(minEpochNum) < 0 || info.LockEpochs.Cmp(maxEpochNum) > 0 {
+	if info.LockEpochs.Cmp(minEpochNum()) < 0 || info.LockEpochs.Cmp(maxEpochNum()) > 0 {
 		return nil, errors.New("invalid lock time")
 	}
 
@@ -447,12 +848,12 @@ func (p *PosStaking) StakeIn(payload []byte, contract *Contract, evm *EVM) ([]by
 	}
 
 	// TODO: need max?
-	// 5. amount >= PSMinStakeholderStake,
-	if contract.value.Cmp(minStakeholderStake) < 0 {
+	// 5. amount >= minStakeholderStake,
+	if contract.value.Cmp(minStakeholderStake()) < 0 {
 		return nil, errors.New("need more Wan to be a stake holder")
 	}
 
-	if info.FeeRate.Cmp(noDelegateFeeRate) != 0 &&  contract.value.Cmp(minValidatorStake) < 0 {
+	if info.FeeRate.Cmp(noDelegateFeeRate) != 0 &&  contract.value.Cmp(minValidatorStake()) < 0 {
 		return nil, errors.New("need more Wan to be a validator")
 	}
 
@@ -494,6 +895,12 @@ func (p *PosStaking) StakeIn(payload []byte, contract *Contract, evm *EVM) ([]by
 		return nil, res
 	}
 
+	err = addPosLog(evm, contract, cscAbi.Events["StakeIn"], contract.CallerAddress, secAddr,
+		new(big.Int).SetUint64(eidNow), contract.value, info.LockEpochs, info.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
@@ -537,7 +944,7 @@ func (p *PosStaking) DelegateIn(payload []byte, contract *Contract, evm *EVM) ([
 	}
 	if info == nil {
 		// only first delegatein check amount is valid.
-		if contract.value.Cmp(minDelegatorStake) < 0 {
+		if contract.value.Cmp(minDelegatorStake()) < 0 {
 			return nil, errors.New("low amount")
 		}
 		// save
@@ -561,6 +968,13 @@ func (p *PosStaking) DelegateIn(payload []byte, contract *Contract, evm *EVM) ([
 		return nil, res
 	}
 
+	eidNow, _ := util.CalEpochSlotID(evm.Time.Uint64())
+	err = addPosLog(evm, contract, cscAbi.Events["DelegateIn"], contract.CallerAddress, addr,
+		new(big.Int).SetUint64(eidNow), contract.Value())
+	if err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
@@ -595,7 +1009,7 @@ func (p *PosStaking) DelegateOut(payload []byte, contract *Contract, evm *EVM) (
 			if stakerInfo.Clients[i].QuitEpoch != 0 {
 				return nil,  errors.New("delegater has existed")
 			}
-			stakerInfo.Clients[i].QuitEpoch = eidNow+3
+			stakerInfo.Clients[i].QuitEpoch = eidNow + posconfig.StakeOutDelayEpochs
 			found = true
 			break
 		}
@@ -614,16 +1028,187 @@ func (p *PosStaking) DelegateOut(payload []byte, contract *Contract, evm *EVM) (
 		return nil, res
 	}
 
+	err = addPosLog(evm, contract, cscAbi.Events["DelegateOut"], contract.CallerAddress, addr,
+		new(big.Int).SetUint64(eidNow), new(big.Int).SetUint64(eidNow+posconfig.StakeOutDelayEpochs))
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// the stakeholder quits the pos before its lock time expires.
+// the stake and all delegated clients are refunded by StakeOutRun at QuitEpoch.
+func (p *PosStaking) StakeOut(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
+	var addr common.Address
+	err := cscAbi.UnpackInput(&addr, "stakeOut", payload)
+	if err != nil {
+		return nil, err
+	}
+
+	key := GetStakeInKeyHash(addr)
+	stakerInfo, err := getStakerInfo(evm.StateDB, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if stakerInfo.From != contract.CallerAddress {
+		return nil, errors.New("cannot stake out from other address")
+	}
+	out := stakerInfo.GetStakeOut()
+	if out.QuitEpoch != 0 {
+		return nil, errors.New("staker has quit")
+	}
+	if contract.Value().Sign() != 0 {
+		return nil, errors.New("stakeOut should not carry value")
+	}
+
+	eidNow, _ := util.CalEpochSlotID(evm.Time.Uint64())
+	out.QuitEpoch = eidNow + posconfig.StakeOutDelayEpochs
+	stakerInfo.SetStakeOut(out)
+	// no renew after quit.
+	stakerInfo.NextLockEpochs = 0
+
+	err = storeStakerInfo(evm.StateDB, key, stakerInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	err = addPosLog(evm, contract, cscAbi.Events["StakeOut"], contract.CallerAddress, addr,
+		new(big.Int).SetUint64(eidNow), new(big.Int).SetUint64(out.QuitEpoch))
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// the stakeholder withdraws part of its stake.
+// the amount is refunded by StakeOutRun at OutEpoch, the left stake must keep the minimum stake.
+func (p *PosStaking) StakeOutPartial(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
+	var info StakeOutPartialParam
+	err := cscAbi.UnpackInput(&info, "stakeOutPartial", payload)
+	if err != nil {
+		return nil, err
+	}
+
+	key := GetStakeInKeyHash(info.Addr)
+	stakerInfo, err := getStakerInfo(evm.StateDB, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if stakerInfo.From != contract.CallerAddress {
+		return nil, errors.New("cannot stake out from other address")
+	}
+	if stakerInfo.GetStakeOut().QuitEpoch != 0 {
+		return nil, errors.New("staker has quit")
+	}
+	if contract.Value().Sign() != 0 {
+		return nil, errors.New("stakeOutPartial should not carry value")
+	}
+
+	eidNow, _ := util.CalEpochSlotID(evm.Time.Uint64())
+	err = checkStakeOutAmount(stakerInfo, info.Amount, eidNow)
+	if err != nil {
+		return nil, err
+	}
+
+	out := stakerInfo.GetStakeOut()
+	out.OutAmount.Add(out.OutAmount, info.Amount)
+	out.OutEpoch = eidNow + posconfig.StakeOutDelayEpochs
+	stakerInfo.SetStakeOut(out)
+
+	err = storeStakerInfo(evm.StateDB, key, stakerInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	err = addPosLog(evm, contract, cscAbi.Events["StakeOutPartial"], contract.CallerAddress, info.Addr,
+		new(big.Int).SetUint64(eidNow), info.Amount, new(big.Int).SetUint64(out.OutEpoch))
+	if err != nil {
+		return nil, err
+	}
 
 	return nil, nil
 }
 
+// check the amount is positive and the stake left after withdrawing is still enough.
+// a pending stake out of an earlier epoch must be refunded first, merging it would
+// postpone its refund.
+func checkStakeOutAmount(stakerInfo *StakerInfo, amount *big.Int, eidNow uint64) error {
+	if amount == nil || amount.Sign() <= 0 {
+		return errors.New("invalid stake out amount")
+	}
+
+	out := stakerInfo.GetStakeOut()
+	if out.OutAmount.Sign() != 0 && out.OutEpoch != eidNow+posconfig.StakeOutDelayEpochs {
+		return errors.New("previous stake out is pending")
+	}
+
+	left := new(big.Int).Sub(stakerInfo.Amount, amount)
+	left.Sub(left, out.OutAmount)
+	if left.Cmp(minStakeholderStake()) < 0 {
+		return errors.New("left stake is lower than the minimum")
+	}
+	if stakerInfo.FeeRate != noDelegateFeeRate.Uint64() && left.Cmp(minValidatorStake()) < 0 {
+		return errors.New("left stake is lower than the validator minimum")
+	}
+
+	totalDelegated := big.NewInt(0)
+	for i := 0; i < len(stakerInfo.Clients); i++ {
+		totalDelegated.Add(totalDelegated, stakerInfo.Clients[i].Amount)
+	}
+	if totalDelegated.Cmp(big.NewInt(0).Mul(left, big.NewInt(maxTimeDelegate))) > 0 {
+		return errors.New("over delegate limitation")
+	}
+	return nil
+}
+
+func getStakerInfo(stateDB StateDB, key common.Hash) (*StakerInfo, error) {
+	stakerBytes, err := GetInfo(stateDB, StakersInfoAddr, key)
+	if err != nil {
+		return nil, err
+	}
+	if stakerBytes == nil {
+		return nil, errors.New("item doesn't exist")
+	}
+	var stakerInfo StakerInfo
+	err = rlp.DecodeBytes(stakerBytes, &stakerInfo)
+	if err != nil {
+		return nil, errors.New("parse staker info error")
+	}
+	return &stakerInfo, nil
+}
+
+func storeStakerInfo(stateDB StateDB, key common.Hash, stakerInfo *StakerInfo) error {
+	infoBytes, err := rlp.EncodeToBytes(stakerInfo)
+	if err != nil {
+		return err
+	}
+	return StoreInfo(stateDB, StakersInfoAddr, key, infoBytes)
+}
+
+// the staking limits come from the pos section of the genesis config, see posconfig.InitParams
+func maxEpochNum() *big.Int { return new(big.Int).SetUint64(posconfig.MaxLockEpochs) }
+func minEpochNum() *big.Int { return new(big.Int).SetUint64(posconfig.MinLockEpochs) }
+func minStakeholderStake() *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(posconfig.MinStakeholderStake), ether)
+}
+func minValidatorStake() *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(posconfig.MinValidatorStake), ether)
+}
+func minDelegatorStake() *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(posconfig.MinDelegatorStake), ether)
+}
+
 func CalLocktimeWeight(lockEpoch uint64) uint64 {
-	if lockEpoch < PSEpochNum_1 {
+	// 1.1, 1.3 and 1.5 times
+	if lockEpoch < posconfig.LockWeightEpochs[0] {
 		return 10
-	} else if lockEpoch < PSEpochNum_2 {
+	} else if lockEpoch < posconfig.LockWeightEpochs[1] {
 		return 11
-	} else if lockEpoch < PSEpochNum_3 {
+	} else if lockEpoch < posconfig.LockWeightEpochs[2] {
 		return 13
 	} else {
 		return 15
@@ -710,3 +1295,52 @@ func (p *PosStaking) delegateOutParseAndValid(payload []byte) error {
 
 	return nil
 }
+func (p *PosStaking) stakeOutParseAndValid(stateDB StateDB, signer types.Signer, tx *types.Transaction, payload []byte) error {
+	var addr common.Address
+	err := cscAbi.UnpackInput(&addr, "stakeOut", payload)
+	if err != nil {
+		return err
+	}
+
+	stakerInfo, err := getStakerInfo(stateDB, GetStakeInKeyHash(addr))
+	if err != nil {
+		return err
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return err
+	}
+	if stakerInfo.From != from {
+		return errors.New("cannot stake out from other address")
+	}
+	if stakerInfo.GetStakeOut().QuitEpoch != 0 {
+		return errors.New("staker has quit")
+	}
+
+	return nil
+}
+func (p *PosStaking) stakeOutPartialParseAndValid(stateDB StateDB, signer types.Signer, tx *types.Transaction, payload []byte) error {
+	var info StakeOutPartialParam
+	err := cscAbi.UnpackInput(&info, "stakeOutPartial", payload)
+	if err != nil {
+		return err
+	}
+
+	stakerInfo, err := getStakerInfo(stateDB, GetStakeInKeyHash(info.Addr))
+	if err != nil {
+		return err
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return err
+	}
+	if stakerInfo.From != from {
+		return errors.New("cannot stake out from other address")
+	}
+	if stakerInfo.GetStakeOut().QuitEpoch != 0 {
+		return errors.New("staker has quit")
+	}
+
+	eidNow, _ := util.GetEpochSlotID()
+	return checkStakeOutAmount(stakerInfo, info.Amount, eidNow)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 	"io/ioutil"
@@ -135,9 +136,9 @@ var (
 	stakerAddr = crypto.PubkeyToAddress(*pb)
 
 	stakerref = &dummyStakerRef{}
-	stakerevm = NewEVM(Context{}, dummyStakerDB{ref: stakerref}, params.TestChainConfig, Config{EnableJit: false, ForceJit: false})
+	stakerevm = NewEVM(Context{BlockNumber: big.NewInt(1)}, dummyStakerDB{ref: stakerref}, params.TestChainConfig, Config{EnableJit: false, ForceJit: false})
 
-	contract       = &Contract{value: big.NewInt(0).Mul(big.NewInt(10), ether), CallerAddress: stakerAddr}
+	contract       = &Contract{value: big.NewInt(0).Mul(big.NewInt(10), ether), CallerAddress: stakerAddr, self: AccountRef(WanCscPrecompileAddr)}
 	stakercontract = &PosStaking{}
 )
 
@@ -167,6 +168,145 @@ func TestDelegateIn(t *testing.T) {
 	clearDb()
 }
 
+func TestStakeOutPartial(t *testing.T) {
+	if !reset() {
+		t.Fatal("pos staking db init error")
+	}
+	err := doStakeIn()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	contract.CallerAddress = common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
+	contract.Value().SetUint64(0)
+	eidNow, _ := util.CalEpochSlotID(stakerevm.Time.Uint64())
+
+	// left stake lower than the stakeholder minimum
+	bytes, _ := cscAbi.Pack("stakeOutPartial", stakerAddr, new(big.Int).Mul(big.NewInt(195000), ether))
+	if _, err = stakercontract.Run(bytes, contract, stakerevm); err == nil {
+		t.Fatal("stakeOutPartial should fail when left stake is too low")
+	}
+
+	a := new(big.Int).Mul(big.NewInt(50000), ether)
+	bytes, _ = cscAbi.Pack("stakeOutPartial", stakerAddr, a)
+	if _, err = stakercontract.Run(bytes, contract, stakerevm); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var info StakerInfo
+	err = rlp.DecodeBytes(stakerevm.StateDB.GetStateByteArray(StakersInfoAddr, GetStakeInKeyHash(stakerAddr)), &info)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if out := info.GetStakeOut(); out.OutAmount.Cmp(a) != 0 || out.OutEpoch != eidNow+posconfig.StakeOutDelayEpochs {
+		t.Fatal("stakeOutPartial saved wrong")
+	}
+
+	// a stake out pending from an earlier epoch is not postponed
+	out := info.GetStakeOut()
+	out.OutEpoch--
+	info.SetStakeOut(out)
+	if err = storeStakerInfo(stakerevm.StateDB, GetStakeInKeyHash(stakerAddr), &info); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err = stakercontract.Run(bytes, contract, stakerevm); err == nil {
+		t.Fatal("stakeOutPartial should fail when an earlier stake out is pending")
+	}
+	clearDb()
+}
+
+func TestStakerInfoLegacyDecode(t *testing.T) {
+	// the staker records stored before stake out have no stake out state
+	type legacyStakerInfo struct {
+		Address        common.Address
+		PubSec256      []byte
+		PubBn256       []byte
+		Amount         *big.Int
+		StakeAmount    *big.Int
+		LockEpochs     uint64
+		NextLockEpochs uint64
+		From           common.Address
+		StakingEpoch   uint64
+		FeeRate        uint64
+		NextFeeRate    uint64
+		Clients        []ClientInfo
+	}
+	legacy := legacyStakerInfo{Address: stakerAddr, Amount: big.NewInt(100), StakeAmount: big.NewInt(1000), LockEpochs: 10, StakingEpoch: 2}
+	enc, err := rlp.EncodeToBytes(&legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info StakerInfo
+	if err := rlp.DecodeBytes(enc, &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Address != stakerAddr || info.Amount.Cmp(legacy.Amount) != 0 || info.StakingEpoch != 2 {
+		t.Fatal("legacy staker decoded wrong")
+	}
+	if out := info.GetStakeOut(); out.QuitEpoch != 0 || out.OutAmount.Sign() != 0 {
+		t.Fatal("legacy staker has stake out state")
+	}
+
+	// a staker without stake out state is stored as before
+	if reenc, _ := rlp.EncodeToBytes(&info); !reflect.DeepEqual(reenc, enc) {
+		t.Fatal("staker encoding changed")
+	}
+
+	info.SetStakeOut(StakeOutInfo{QuitEpoch: 5, OutAmount: big.NewInt(0)})
+	enc, _ = rlp.EncodeToBytes(&info)
+	var dec StakerInfo
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if dec.GetStakeOut().QuitEpoch != 5 {
+		t.Fatal("stake out state lost")
+	}
+}
+
+func TestStakeOut(t *testing.T) {
+	if !reset() {
+		t.Fatal("pos staking db init error")
+	}
+	err := doStakeIn()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	contract.Value().SetUint64(0)
+	eidNow, _ := util.CalEpochSlotID(stakerevm.Time.Uint64())
+
+	bytes, _ := cscAbi.Pack("stakeOut", stakerAddr)
+	contract.CallerAddress = common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
+	stakerevm.BlockNumber = nil
+	_, err = stakercontract.Run(bytes, contract, stakerevm)
+	stakerevm.BlockNumber = big.NewInt(1)
+	if err != errMethodId {
+		t.Fatal("stakeOut should be refused before the fork", err)
+	}
+
+	contract.CallerAddress = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	if _, err = stakercontract.Run(bytes, contract, stakerevm); err == nil {
+		t.Fatal("stakeOut from other address should fail")
+	}
+
+	contract.CallerAddress = common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
+	if _, err = stakercontract.Run(bytes, contract, stakerevm); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err = stakercontract.Run(bytes, contract, stakerevm); err == nil {
+		t.Fatal("stakeOut twice should fail")
+	}
+
+	var info StakerInfo
+	err = rlp.DecodeBytes(stakerevm.StateDB.GetStateByteArray(StakersInfoAddr, GetStakeInKeyHash(stakerAddr)), &info)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if info.GetStakeOut().QuitEpoch != eidNow+posconfig.StakeOutDelayEpochs || info.NextLockEpochs != 0 {
+		t.Fatal("stakeOut saved wrong")
+	}
+	clearDb()
+}
+
 // go test -test.bench=“.×”
 func TestMultiDelegateIn(b *testing.T) {
 	if !reset() {
@@ -241,7 +381,7 @@ func doStakeIn() error {
 	if info.Address != secAddr ||
 		info.From != contract.CallerAddress ||
 		info.Amount.Cmp(a) != 0 ||
-		info.StakingEpoch != eidNow {
+		info.StakingEpoch != eidNow+2 {
 		return errors.New("stakeIn from amount epoch address saved wrong")
 	}
 	return nil
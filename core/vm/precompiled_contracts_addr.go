@@ -7,6 +7,7 @@ import (
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/params"
 )
 
 // Precompiled contracts address or
@@ -68,6 +69,7 @@ var (
 	// TODO: remove one?
 	RandomBeaconPrecompileAddr = randomBeaconPrecompileAddr
 	SlotLeaderPrecompileAddr   = slotLeaderPrecompileAddr
+	WanCoinPrecompileAddr      = wanCoinPrecompileAddr
 )
 
 // PrecompiledContract is the basic interface for native Go contracts. The implementation
@@ -79,6 +81,13 @@ type PrecompiledContract interface {
 	ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error
 }
 
+// ForkTxValidator is implemented by the precompiled contracts whose methods depend on
+// the forks, the tx pool validates their transactions with ValidTxAt against the forks
+// of the pending block instead of ValidTx.
+type ForkTxValidator interface {
+	ValidTxAt(stateDB StateDB, signer types.Signer, tx *types.Transaction, config *params.ChainConfig, number *big.Int) error
+}
+
 // PrecompiledContractsHomestead contains the default set of pre-compiled Ethereum
 // contracts used in the Frontier and Homestead releases.
 var PrecompiledContractsHomestead = map[common.Address]PrecompiledContract{
@@ -11,7 +11,6 @@ import (
 	"math/big"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/wanchain/go-wanchain/accounts/abi"
 	"github.com/wanchain/go-wanchain/common"
@@ -79,6 +78,89 @@ var (
     ],
     "payable": false,
     "type": "function"
+  },
+  {
+    "anonymous": false,
+    "inputs": [
+      {
+        "indexed": true,
+        "name": "sender",
+        "type": "address"
+      },
+      {
+        "indexed": true,
+        "name": "epochId",
+        "type": "uint256"
+      },
+      {
+        "indexed": true,
+        "name": "proposerId",
+        "type": "uint256"
+      }
+    ],
+    "name": "Dkg1",
+    "type": "event"
+  },
+  {
+    "anonymous": false,
+    "inputs": [
+      {
+        "indexed": true,
+        "name": "sender",
+        "type": "address"
+      },
+      {
+        "indexed": true,
+        "name": "epochId",
+        "type": "uint256"
+      },
+      {
+        "indexed": true,
+        "name": "proposerId",
+        "type": "uint256"
+      }
+    ],
+    "name": "Dkg2",
+    "type": "event"
+  },
+  {
+    "anonymous": false,
+    "inputs": [
+      {
+        "indexed": true,
+        "name": "sender",
+        "type": "address"
+      },
+      {
+        "indexed": true,
+        "name": "epochId",
+        "type": "uint256"
+      },
+      {
+        "indexed": true,
+        "name": "proposerId",
+        "type": "uint256"
+      }
+    ],
+    "name": "SigShare",
+    "type": "event"
+  },
+  {
+    "anonymous": false,
+    "inputs": [
+      {
+        "indexed": true,
+        "name": "epochId",
+        "type": "uint256"
+      },
+      {
+        "indexed": false,
+        "name": "random",
+        "type": "uint256"
+      }
+    ],
+    "name": "RandomGenerated",
+    "type": "event"
   }
 ]`
 	// random beacon smart contract abi object
@@ -194,13 +276,13 @@ func ValidPosRBTx(stateDB StateDB, from common.Address, payload []byte) error {
 	copy(methodId[:], payload[:4])
 
 	if methodId == dkg1Id {
-		_, err := validDkg1(stateDB, uint64(time.Now().Unix()), from, payload[4:])
+		_, err := validDkg1(stateDB, util.NowUnix(), from, payload[4:])
 		return err
 	} else if methodId == dkg2Id {
-		_, err := validDkg2(stateDB, uint64(time.Now().Unix()), from, payload[4:])
+		_, err := validDkg2(stateDB, util.NowUnix(), from, payload[4:])
 		return err
 	} else if methodId == sigShareId {
-		_, _, _, err := validSigShare(stateDB, uint64(time.Now().Unix()), from, payload[4:])
+		_, _, _, err := validSigShare(stateDB, util.NowUnix(), from, payload[4:])
 		return err
 	} else {
 		return errParameters
@@ -856,6 +938,12 @@ func (c *RandomBeaconContract) dkg1(payload []byte, contract *Contract, evm *EVM
 	}
 	evm.StateDB.SetStateByteArray(randomBeaconPrecompileAddr, *hash, cijBytes)
 
+	err = addPosLog(evm, contract, rbSCAbi.Events["Dkg1"], contract.CallerAddress,
+		new(big.Int).SetUint64(eid), big.NewInt(int64(pid)))
+	if err != nil {
+		return nil, logError(err)
+	}
+
 	log.Debug("vm.dkg1", "dkg1Id", dkg1Id, "epochID", eid, "proposerId", pid, "hash", hash.Hex())
 	return nil, nil
 }
@@ -879,6 +967,12 @@ func (c *RandomBeaconContract) dkg2(payload []byte, contract *Contract, evm *EVM
 	}
 	evm.StateDB.SetStateByteArray(randomBeaconPrecompileAddr, *hash, encryptShareBytes)
 
+	err = addPosLog(evm, contract, rbSCAbi.Events["Dkg2"], contract.CallerAddress,
+		new(big.Int).SetUint64(eid), big.NewInt(int64(pid)))
+	if err != nil {
+		return nil, logError(err)
+	}
+
 	log.Debug("vm.dkg2", "dkgId", dkg2Id, "epochID", eid, "proposerId", pid, "hash", hash.Hex())
 	return nil, nil
 }
@@ -898,6 +992,12 @@ func (c *RandomBeaconContract) sigShare(payload []byte, contract *Contract, evm
 	hash := GetRBKeyHash(sigShareId[:], eid, pid)
 	evm.StateDB.SetStateByteArray(randomBeaconPrecompileAddr, *hash, payload)
 
+	err = addPosLog(evm, contract, rbSCAbi.Events["SigShare"], contract.CallerAddress,
+		new(big.Int).SetUint64(eid), big.NewInt(int64(pid)))
+	if err != nil {
+		return nil, logError(err)
+	}
+
 	/////////////////
 	// calc r if not exist
 	sigNum := getSignorsNum(eid, evm) + 1
@@ -907,6 +1007,10 @@ func (c *RandomBeaconContract) sigShare(payload []byte, contract *Contract, evm
 		if r != nil && err == nil {
 			hashR := GetRBRKeyHash(eid + 1)
 			evm.StateDB.SetStateByteArray(randomBeaconPrecompileAddr, *hashR, r.Bytes())
+			err = addPosLog(evm, contract, rbSCAbi.Events["RandomGenerated"], new(big.Int).SetUint64(eid+1), r)
+			if err != nil {
+				return nil, logError(err)
+			}
 			log.SyslogInfo("generate random, epochId:%d, r:%s", eid+1, common.ToHex(r.Bytes()))
 		}
 	}
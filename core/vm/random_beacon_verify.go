@@ -0,0 +1,291 @@
+package vm
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/rbselection"
+)
+
+//
+// random beacon verification, re-check the random generated by computeRandom
+// from the data stored by the contract
+//
+
+// RbProposerData is the data a random proposer stored in an epoch,
+// the points are marshaled and empty if the proposer didn't send the stage.
+type RbProposerData struct {
+	ProposerId uint32
+	Pk         hexutil.Bytes   // bn256 G1
+	Commit     []hexutil.Bytes // dkg1, bn256 G2 for every proposer
+	EnShare    []hexutil.Bytes // dkg2, bn256 G1 for every proposer
+	SigShare   hexutil.Bytes   // bn256 G1
+}
+
+// RbEpochData is all the data needed to verify the random of EpochId+1
+type RbEpochData struct {
+	EpochId   uint64
+	M         hexutil.Bytes
+	Random    *hexutil.Big // nil if the random is not generated
+	Proposers []RbProposerData
+}
+
+// RbProposerResult tells how a proposer took part in the random
+type RbProposerResult struct {
+	ProposerId uint32
+	Dkg1       bool   // commit passes the Reed-Solomon check
+	Dkg2       bool   // encrypted shares match the commit of the receivers
+	Sig        bool   // signature share matches the group public key share
+	InGroup    bool   // counted in the group public key
+	Included   bool   // counted in the group signature
+	Reason     string `json:",omitempty"`
+}
+
+// RbVerifyResult is the result of VerifyRandom
+type RbVerifyResult struct {
+	EpochId   uint64
+	Random    *hexutil.Big // random of EpochId+1 stored in the state
+	Computed  *hexutil.Big // random rebuilt from the signature shares
+	Valid     bool
+	Reason    string `json:",omitempty"`
+	Proposers []RbProposerResult
+}
+
+var (
+	errRbVerifyNoGroup = errors.New("can't find random beacon proposer group")
+	errRbVerifyNoM     = errors.New("random beacon message is empty")
+)
+
+// GetRbEpochData collects the data of epochId stored by the random beacon contract,
+// pks is the random proposer group of the epoch.
+func GetRbEpochData(stateDB StateDB, epochId uint64, pks []bn256.G1) (*RbEpochData, error) {
+	if len(pks) == 0 {
+		return nil, errRbVerifyNoGroup
+	}
+
+	m, err := GetRBM(stateDB, epochId)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &RbEpochData{
+		EpochId:   epochId,
+		M:         m,
+		Proposers: make([]RbProposerData, len(pks)),
+	}
+	if r := GetStateR(stateDB, epochId+1); r != nil {
+		data.Random = (*hexutil.Big)(r)
+	}
+
+	for id := range pks {
+		pid := uint32(id)
+		p := &data.Proposers[id]
+		p.ProposerId = pid
+		p.Pk = pks[id].Marshal()
+
+		commit, err := GetCji(stateDB, epochId, pid)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range commit {
+			p.Commit = append(p.Commit, c.Marshal())
+		}
+
+		enShare, err := GetEncryptShare(stateDB, epochId, pid)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range enShare {
+			p.EnShare = append(p.EnShare, e.Marshal())
+		}
+
+		sig, err := GetSig(stateDB, epochId, pid)
+		if err != nil {
+			return nil, err
+		}
+		if sig != nil && sig.GSignShare != nil {
+			p.SigShare = sig.GSignShare.Marshal()
+		}
+	}
+
+	return data, nil
+}
+
+// VerifyRandom re-runs the threshold reconstruction of computeRandom over data,
+// every share is checked by pairings so the proposers excluded can be told.
+func VerifyRandom(data *RbEpochData) (*RbVerifyResult, error) {
+	nr := len(data.Proposers)
+	if nr == 0 {
+		return nil, errRbVerifyNoGroup
+	}
+	if len(data.M) == 0 {
+		return nil, errRbVerifyNoM
+	}
+
+	result := &RbVerifyResult{
+		EpochId:   data.EpochId,
+		Random:    data.Random,
+		Proposers: make([]RbProposerResult, nr),
+	}
+
+	pks := make([]bn256.G1, nr)
+	for i := range data.Proposers {
+		if _, err := pks[i].Unmarshal(data.Proposers[i].Pk); err != nil {
+			return nil, err
+		}
+	}
+
+	xAll := make([]big.Int, nr)
+	for i := 0; i < nr; i++ {
+		xAll[i].SetBytes(GetPolynomialX(&pks[i], uint32(i)))
+		xAll[i].Mod(&xAll[i], bn256.Order)
+	}
+
+	// dkg1 and dkg2, the group public key is built from every proposer joined dkg2
+	commits := make([][]*bn256.G2, nr)
+	for i := range data.Proposers {
+		p := &data.Proposers[i]
+		res := &result.Proposers[i]
+		res.ProposerId = p.ProposerId
+
+		commit, err := unmarshalG2s(p.Commit)
+		if err != nil || len(commit) != nr {
+			res.Reason = "no valid dkg1 commit"
+			continue
+		}
+		commits[i] = commit
+
+		cs := make([]bn256.G2, nr)
+		for j := 0; j < nr; j++ {
+			cs[j] = *commit[j]
+		}
+		res.Dkg1 = rbselection.RScodeVerify(cs, xAll, int(posconfig.Cfg().PolymDegree))
+		if !res.Dkg1 {
+			res.Reason = "dkg1 commit fails Reed-Solomon check"
+		}
+
+		enShare, err := unmarshalG1s(p.EnShare)
+		if err != nil || len(enShare) != nr {
+			if res.Reason == "" {
+				res.Reason = "no valid dkg2 encrypted share"
+			}
+			continue
+		}
+		res.InGroup = true
+
+		res.Dkg2 = true
+		for j := 0; j < nr; j++ {
+			// EnShare[j] = s*pk[j] and Commit[j] = s*hBase
+			if bn256.Pair(enShare[j], hBase).String() != bn256.Pair(&pks[j], commit[j]).String() {
+				res.Dkg2 = false
+				if res.Reason == "" {
+					res.Reason = "dkg2 encrypted share mismatches commit"
+				}
+				break
+			}
+		}
+	}
+
+	m := new(big.Int).SetBytes(data.M)
+	mG := new(bn256.G1).ScalarBaseMult(m)
+
+	// signature shares
+	sigs := make([]bn256.G1, 0)
+	xSig := make([]big.Int, 0)
+	for i := range data.Proposers {
+		p := &data.Proposers[i]
+		res := &result.Proposers[i]
+		if len(p.SigShare) == 0 {
+			if res.Reason == "" {
+				res.Reason = "no signature share"
+			}
+			continue
+		}
+
+		sig := new(bn256.G1)
+		if _, err := sig.Unmarshal(p.SigShare); err != nil {
+			if res.Reason == "" {
+				res.Reason = "signature share can't be parsed"
+			}
+			continue
+		}
+
+		var gPKShare bn256.G2
+		for j := range data.Proposers {
+			if result.Proposers[j].InGroup {
+				gPKShare.Add(&gPKShare, commits[j][i])
+			}
+		}
+		res.Sig = bn256.Pair(sig, hBase).String() == bn256.Pair(mG, &gPKShare).String()
+		if !res.Sig {
+			if res.Reason == "" {
+				res.Reason = "signature share mismatches group public key share"
+			}
+			continue
+		}
+
+		res.Included = true
+		sigs = append(sigs, *sig)
+		x := new(big.Int).SetBytes(GetPolynomialX(&pks[i], p.ProposerId))
+		xSig = append(xSig, *x)
+	}
+
+	if uint(len(sigs)) < posconfig.Cfg().RBThres {
+		result.Reason = "insufficient valid signature share"
+		return result, nil
+	}
+
+	gSignature := rbselection.LagrangeSig(sigs, xSig, int(posconfig.Cfg().PolymDegree))
+	result.Computed = (*hexutil.Big)(new(big.Int).SetBytes(crypto.Keccak256(gSignature.Marshal())))
+
+	c := make([]bn256.G2, nr)
+	for i := 0; i < nr; i++ {
+		c[i].ScalarBaseMult(big.NewInt(int64(0)))
+		for j := 0; j < nr; j++ {
+			if result.Proposers[j].InGroup {
+				c[i].Add(&c[i], commits[j][i])
+			}
+		}
+	}
+	gPub := rbselection.LagrangePub(c, xAll, int(posconfig.Cfg().PolymDegree))
+	if bn256.Pair(&gSignature, rbselection.Hbase).String() != bn256.Pair(mG, &gPub).String() {
+		result.Reason = "final pairing check failed"
+		return result, nil
+	}
+
+	switch {
+	case result.Random == nil:
+		result.Reason = "random is not generated"
+	case (*big.Int)(result.Random).Cmp((*big.Int)(result.Computed)) != 0:
+		result.Reason = "random mismatches the computed one"
+	default:
+		result.Valid = true
+	}
+	return result, nil
+}
+
+func unmarshalG1s(data []hexutil.Bytes) ([]*bn256.G1, error) {
+	points := make([]*bn256.G1, len(data))
+	for i := range data {
+		points[i] = new(bn256.G1)
+		if _, err := points[i].Unmarshal(data[i]); err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
+
+func unmarshalG2s(data []hexutil.Bytes) ([]*bn256.G2, error) {
+	points := make([]*bn256.G2, len(data))
+	for i := range data {
+		points[i] = new(bn256.G2)
+		if _, err := points[i].Unmarshal(data[i]); err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
@@ -0,0 +1,112 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// prepareRbEpoch stores the data of every proposer as the contract does, and the random computed from it
+func prepareRbEpoch(t *testing.T) {
+	clearDB()
+	sigs := prepareSig(pris, enshareA)
+
+	dkgData := make([]RbCijDataCollector, 0)
+	for i := 0; i < nr; i++ {
+		pid := uint32(i)
+		dkg1 := Dkg1ToDkg1Flat(&RbDKG1TxPayload{EpochId: rbepochId, ProposerId: pid, Commit: commitA[i]})
+		cijBytes, _ := rlp.EncodeToBytes(dkg1.Commit)
+		evm.StateDB.SetStateByteArray(randomBeaconPrecompileAddr, *GetRBKeyHash(kindCij, rbepochId, pid), cijBytes)
+
+		dkg2 := Dkg2ToDkg2Flat(&RbDKG2TxPayload{EpochId: rbepochId, ProposerId: pid, EnShare: enshareA[i], Proof: proofA[i]})
+		ensBytes, _ := rlp.EncodeToBytes(dkg2.EnShare)
+		evm.StateDB.SetStateByteArray(randomBeaconPrecompileAddr, *GetRBKeyHash(kindEns, rbepochId, pid), ensBytes)
+
+		sigBytes, _ := rlp.EncodeToBytes(&RbSIGTxPayload{EpochId: rbepochId, ProposerId: pid, GSignShare: sigs[i]})
+		evm.StateDB.SetStateByteArray(randomBeaconPrecompileAddr, *GetRBKeyHash(sigShareId[:], rbepochId, pid), sigBytes)
+
+		dkgData = append(dkgData, RbCijDataCollector{commitA[i], &pubs[i]})
+	}
+
+	r, err := computeRandom(evm.StateDB, rbepochId, dkgData, pubs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evm.StateDB.SetStateByteArray(randomBeaconPrecompileAddr, *GetRBRKeyHash(rbepochId + 1), r.Bytes())
+}
+
+func TestVerifyRandom(t *testing.T) {
+	prepareRbEpoch(t)
+
+	data, err := GetRbEpochData(evm.StateDB, rbepochId, pubs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Random == nil {
+		t.Fatal("random should be generated")
+	}
+
+	result, err := VerifyRandom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Computed.String() != data.Random.String() {
+		t.Fatal("random should be verified", result.Reason)
+	}
+	for _, p := range result.Proposers {
+		if !p.Dkg1 || !p.Dkg2 || !p.Sig || !p.InGroup || !p.Included {
+			t.Fatal("proposer should be valid", p.ProposerId, p.Reason)
+		}
+	}
+}
+
+func TestVerifyRandomExclude(t *testing.T) {
+	prepareRbEpoch(t)
+
+	data, err := GetRbEpochData(evm.StateDB, rbepochId, pubs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// proposer 0 didn't sign, proposer 1 sent a wrong share
+	data.Proposers[0].SigShare = nil
+	data.Proposers[1].SigShare = new(bn256.G1).ScalarBaseMult(hexutil.MustDecodeBig("0x1234")).Marshal()
+
+	result, err := VerifyRandom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid {
+		t.Fatal("random should be rebuilt from the others", result.Reason)
+	}
+	if result.Proposers[0].Included || result.Proposers[0].Reason == "" {
+		t.Fatal("proposer 0 should be excluded")
+	}
+	if result.Proposers[1].Sig || result.Proposers[1].Included {
+		t.Fatal("proposer 1 should be excluded")
+	}
+
+	// not enough shares left
+	for i := 0; i < nr-int(posconfig.Cfg().RBThres)+1; i++ {
+		data.Proposers[i].SigShare = nil
+	}
+	result, err = VerifyRandom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.Computed != nil {
+		t.Fatal("random should not be rebuilt without enough shares")
+	}
+}
+
+func TestVerifyRandomInvalid(t *testing.T) {
+	if _, err := GetRbEpochData(evm.StateDB, 0, nil); err != errRbVerifyNoGroup {
+		t.Fatal("empty group should fail", err)
+	}
+	if _, err := VerifyRandom(&RbEpochData{}); err != errRbVerifyNoGroup {
+		t.Fatal("empty data should fail", err)
+	}
+}
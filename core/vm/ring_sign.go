@@ -0,0 +1,125 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"strings"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+// ringSignCacheSize is the number of the valid ring signatures remembered, so the
+// signatures verified by the tx pool are not verified again in a block.
+const ringSignCacheSize = 4096
+
+var ringSignCache, _ = lru.New(ringSignCacheSize)
+
+// DecodeRingSignedData decodes a ring signed data of a refund or a privacy transaction.
+// A ring signed data of version 1 is the "+" joined string of DecodeRingSignOut, a ring
+// signed data of version 2 is the hex of the RLP encoding of crypto.EncodeRingSignV2,
+// which is accepted only if ringSignV2 is true.
+func DecodeRingSignedData(s string, ringSignV2 bool) (*crypto.RingSignature, error) {
+	if strings.Contains(s, "+") {
+		err, publicKeys, keyImage, w, q := DecodeRingSignOut(s)
+		if err != nil {
+			return nil, err
+		}
+		return &crypto.RingSignature{PublicKeys: publicKeys, KeyImage: keyImage, W: w, Q: q}, nil
+	}
+
+	if !ringSignV2 {
+		return nil, ErrInvalidRingSigned
+	}
+
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return nil, ErrInvalidRingSigned
+	}
+	return crypto.DecodeRingSignV2(b, int(params.MaxRingSignSize))
+}
+
+// EncodeRingSignedDataV2 returns the ring signed data of version 2 of the ring signature
+func EncodeRingSignedDataV2(ringSign *crypto.RingSignature) (string, error) {
+	b, err := crypto.EncodeRingSignV2(ringSign)
+	if err != nil {
+		return "", err
+	}
+	return hexutil.Encode(b), nil
+}
+
+func ringSignCacheKey(hashInput []byte, ringSignedStr string) common.Hash {
+	return crypto.Keccak256Hash(hashInput, []byte(ringSignedStr))
+}
+
+// verifyRingSignCached verifies the ring signature of hashInput decoded from
+// ringSignedStr, unless it's verified already.
+func verifyRingSignCached(hashInput []byte, ringSignedStr string, ringSign *crypto.RingSignature) bool {
+	key := ringSignCacheKey(hashInput, ringSignedStr)
+	if ringSignCache.Contains(key) {
+		return true
+	}
+
+	if !ringSign.Verify(hashInput) {
+		return false
+	}
+
+	ringSignCache.Add(key, struct{}{})
+	return true
+}
+
+// VerifyRefundRingSigns verifies the ring signatures of the refundCoin and refundCoins
+// transactions in txs in batch. The valid signatures are remembered, so validating
+// and applying the transactions later don't verify them again. valid[i] is false if
+// txs[i] is a refund with an invalid ring signature.
+func VerifyRefundRingSigns(signer types.Signer, txs []*types.Transaction) (valid []bool) {
+	var (
+		msgs      [][]byte
+		sigs      []*crypto.RingSignature
+		keys      []common.Hash
+		sigOwners []int
+	)
+
+	valid = make([]bool, len(txs))
+	for i, tx := range txs {
+		valid[i] = true
+
+		ringSignedDatas, err := refundRingSignedDatas(tx)
+		if err != nil {
+			continue
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			continue
+		}
+
+		for _, ringSignedData := range ringSignedDatas {
+			key := ringSignCacheKey(from.Bytes(), ringSignedData)
+			if ringSignCache.Contains(key) {
+				continue
+			}
+			ringSign, err := DecodeRingSignedData(ringSignedData, true)
+			if err != nil {
+				valid[i] = false
+				break
+			}
+			msgs = append(msgs, from.Bytes())
+			sigs = append(sigs, ringSign)
+			keys = append(keys, key)
+			sigOwners = append(sigOwners, i)
+		}
+	}
+
+	for j, ok := range crypto.VerifyRingSignBatch(msgs, sigs) {
+		if ok {
+			ringSignCache.Add(keys[j], struct{}{})
+		} else {
+			valid[sigOwners[j]] = false
+		}
+	}
+	return valid
+}
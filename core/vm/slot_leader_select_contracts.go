@@ -69,6 +69,50 @@ var (
 						"type": "string"
 					}
 				]
+			},
+			{
+				"anonymous": false,
+				"inputs": [
+					{
+						"indexed": true,
+						"name": "sender",
+						"type": "address"
+					},
+					{
+						"indexed": true,
+						"name": "epochId",
+						"type": "uint256"
+					},
+					{
+						"indexed": true,
+						"name": "selfIndex",
+						"type": "uint256"
+					}
+				],
+				"name": "SlotLeaderStage1",
+				"type": "event"
+			},
+			{
+				"anonymous": false,
+				"inputs": [
+					{
+						"indexed": true,
+						"name": "sender",
+						"type": "address"
+					},
+					{
+						"indexed": true,
+						"name": "epochId",
+						"type": "uint256"
+					},
+					{
+						"indexed": true,
+						"name": "selfIndex",
+						"type": "uint256"
+					}
+				],
+				"name": "SlotLeaderStage2",
+				"type": "event"
 			}
 		]`
 	slotLeaderAbi, errSlotLeaderSCInit = abi.JSON(strings.NewReader(slotLeaderSCDef))
@@ -191,6 +235,12 @@ func (c *slotLeaderSC) handleStgOne(in []byte, contract *Contract, evm *EVM) ([]
 
 	addSlotScCallTimes(convert.BytesToUint64(epochIDBuf))
 
+	err = addPosLog(evm, contract, slotLeaderAbi.Events["SlotLeaderStage1"], contract.CallerAddress,
+		new(big.Int).SetUint64(convert.BytesToUint64(epochIDBuf)), new(big.Int).SetUint64(convert.BytesToUint64(selfIndexBuf)))
+	if err != nil {
+		return nil, err
+	}
+
 	log.Debug(fmt.Sprintf("handleStgOne save data addr:%s, key:%s, data len:%d", slotLeaderPrecompileAddr.Hex(),
 		keyHash.Hex(), len(in)))
 	log.Debug("handleStgOne save", "epochID", convert.BytesToUint64(epochIDBuf), "selfIndex",
@@ -222,6 +272,12 @@ func (c *slotLeaderSC) handleStgTwo(in []byte, contract *Contract, evm *EVM) ([]
 	}
 	addSlotScCallTimes(convert.BytesToUint64(epochIDBuf))
 
+	err = addPosLog(evm, contract, slotLeaderAbi.Events["SlotLeaderStage2"], contract.CallerAddress,
+		new(big.Int).SetUint64(convert.BytesToUint64(epochIDBuf)), new(big.Int).SetUint64(convert.BytesToUint64(selfIndexBuf)))
+	if err != nil {
+		return nil, err
+	}
+
 	log.Debug(fmt.Sprintf("handleStgTwo save data addr:%s, key:%s, data len:%d", slotLeaderPrecompileAddr.Hex(),
 		keyHash.Hex(), len(in)))
 	log.Debug("handleStgTwo save", "epochID", convert.BytesToUint64(epochIDBuf), "selfIndex",
@@ -646,8 +702,11 @@ func isInValidStage(epochID uint64, evm *EVM, kStart uint64, kEnd uint64) bool {
 }
 
 func updateSlotLeaderStageIndex(evm *EVM, epochID []byte, slotLeaderStageIndexes string, index uint64) error {
-	var sendtrans [posconfig.EpochLeaderCount]bool
-	var sendtransGet [posconfig.EpochLeaderCount]bool
+	if index >= uint64(posconfig.EpochLeaderCount) {
+		return ErrIllegalSender
+	}
+	sendtrans := make([]bool, posconfig.EpochLeaderCount)
+	sendtransGet := make([]bool, 0, posconfig.EpochLeaderCount)
 
 	key := getSlotLeaderStageIndexesKeyHash(epochID, slotLeaderStageIndexes)
 	bytes := evm.StateDB.GetStateByteArray(slotLeaderPrecompileAddr, key)
@@ -666,6 +725,9 @@ func updateSlotLeaderStageIndex(evm *EVM, epochID []byte, slotLeaderStageIndexes
 		if err != nil {
 			return err
 		}
+		if len(sendtransGet) != posconfig.EpochLeaderCount {
+			return ErrIllegalSender
+		}
 
 		sendtransGet[index] = true
 		value, err := rlp.EncodeToBytes(sendtransGet)
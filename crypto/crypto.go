@@ -398,6 +398,12 @@ func xScalarHashP(x []byte, pub *ecdsa.PublicKey) (I *ecdsa.PublicKey) {
 	return
 }
 
+// ComputeKeyImage returns the key image of the one-time private key x of pub,
+// it is the key image RingSign puts in the signature when x signs.
+func ComputeKeyImage(x *big.Int, pub *ecdsa.PublicKey) *ecdsa.PublicKey {
+	return xScalarHashP(x.Bytes(), pub)
+}
+
 var (
 	ErrInvalidRingSignParams = errors.New("invalid ring sign params")
 	ErrRingSignFail          = errors.New("ring sign fail")
@@ -618,14 +624,28 @@ func CompareA1(b []byte, A *ecdsa.PublicKey, S1 *ecdsa.PublicKey, A1 *ecdsa.Publ
 // generateOneTimeKey2528 generates an OTA account for receiver using receiver's publickey
 // Pengbo added, TeemoGuo revised
 func generateOneTimeKey2528(A *ecdsa.PublicKey, B *ecdsa.PublicKey) (A1 *ecdsa.PublicKey, R *ecdsa.PublicKey, err error) {
+	A1, R, _, err = generateOneTimeKeyWithSecret2528(A, B)
+	return A1, R, err
+}
+
+// generateOneTimeKeyWithSecret2528 generates an OTA account like generateOneTimeKey2528,
+// and returns the shared secret [r]B of the sender, which is [b]R of the receiver.
+func generateOneTimeKeyWithSecret2528(A *ecdsa.PublicKey, B *ecdsa.PublicKey) (A1 *ecdsa.PublicKey, R *ecdsa.PublicKey, secret []byte, err error) {
 	RPrivateKey, err := GenerateKey()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	R = &RPrivateKey.PublicKey
 	A1 = new(ecdsa.PublicKey)
 	*A1 = generateA1(RPrivateKey.D.Bytes(), A, B)
-	return A1, R, err
+	return A1, R, otaSharedSecret(RPrivateKey.D.Bytes(), B), err
+}
+
+// otaSharedSecret returns the encoding of [k]P
+func otaSharedSecret(k []byte, P *ecdsa.PublicKey) []byte {
+	S := &ecdsa.PublicKey{Curve: S256()}
+	S.X, S.Y = S256().ScalarMult(P.X, P.Y, k)
+	return FromECDSAPub(S)
 }
 
 // Generate OTA account interface
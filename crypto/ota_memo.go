@@ -0,0 +1,64 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"errors"
+)
+
+// OTAMemoOverhead is the size the encryption adds to a memo
+const OTAMemoOverhead = 16
+
+var (
+	ErrInvalidOTAMemo = errors.New("invalid ota memo")
+
+	otaMemoKeyPrefix = []byte("wanchain ota memo")
+)
+
+// GenerateOneTimeKeyWithMemo generates an OTA account for the receiver of the wan
+// address (A, B) like GenerateOneTimeKey, and encrypts memo to the receiver with the
+// shared secret of the OTA. Only the receiver with the private key b of B decrypts
+// the memo by DecryptOTAMemo.
+func GenerateOneTimeKeyWithMemo(A *ecdsa.PublicKey, B *ecdsa.PublicKey, memo []byte) (A1 *ecdsa.PublicKey, R *ecdsa.PublicKey, encMemo []byte, err error) {
+	A1, R, secret, err := generateOneTimeKeyWithSecret2528(A, B)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	aead, err := otaMemoCipher(secret)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return A1, R, aead.Seal(nil, make([]byte, aead.NonceSize()), memo, FromECDSAPub(A1)), nil
+}
+
+// DecryptOTAMemo decrypts the memo of the OTA (A1, R) with the private key b of the
+// receiver, it fails if the OTA is not sent to the receiver.
+func DecryptOTAMemo(privateKey2 *ecdsa.PrivateKey, A1 *ecdsa.PublicKey, R *ecdsa.PublicKey, encMemo []byte) ([]byte, error) {
+	if privateKey2 == nil || A1 == nil || R == nil || len(encMemo) < OTAMemoOverhead {
+		return nil, ErrInvalidOTAMemo
+	}
+
+	aead, err := otaMemoCipher(otaSharedSecret(privateKey2.D.Bytes(), R))
+	if err != nil {
+		return nil, err
+	}
+	memo, err := aead.Open(nil, make([]byte, aead.NonceSize()), encMemo, FromECDSAPub(A1))
+	if err != nil {
+		return nil, ErrInvalidOTAMemo
+	}
+	return memo, nil
+}
+
+// otaMemoCipher returns the AES-GCM cipher keyed by the shared secret of an OTA.
+// Every OTA has a fresh secret and encrypts a single memo, so the nonce is zero.
+func otaMemoCipher(secret []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(Keccak256(otaMemoKeyPrefix, secret))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,46 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOTAMemo(t *testing.T) {
+	keyA, _ := GenerateKey()
+	keyB, _ := GenerateKey()
+	memo := []byte("invoice 42")
+
+	A1, R, encMemo, err := GenerateOneTimeKeyWithMemo(&keyA.PublicKey, &keyB.PublicKey, memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(encMemo) != len(memo)+OTAMemoOverhead {
+		t.Fatal("encrypted memo length mismatch", len(encMemo))
+	}
+	if !CompareA1(keyB.D.Bytes(), &keyA.PublicKey, R, A1) {
+		t.Fatal("ota should be sent to the receiver")
+	}
+
+	dec, err := DecryptOTAMemo(keyB, A1, R, encMemo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dec, memo) {
+		t.Fatal("decrypted memo mismatch", string(dec))
+	}
+
+	other, _ := GenerateKey()
+	if _, err := DecryptOTAMemo(other, A1, R, encMemo); err != ErrInvalidOTAMemo {
+		t.Fatal("memo should not be decrypted by others", err)
+	}
+	A2, _, _, _ := GenerateOneTimeKeyWithMemo(&keyA.PublicKey, &keyB.PublicKey, nil)
+	if _, err := DecryptOTAMemo(keyB, A2, R, encMemo); err != ErrInvalidOTAMemo {
+		t.Fatal("memo should be bound to its ota", err)
+	}
+	encMemo[0] ^= 1
+	if _, err := DecryptOTAMemo(keyB, A1, R, encMemo); err != ErrInvalidOTAMemo {
+		t.Fatal("tampered memo should fail", err)
+	}
+}
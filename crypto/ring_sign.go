@@ -0,0 +1,170 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"runtime"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/wanchain/go-wanchain/common/math"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// RingSignVersion2 is the version of the RLP encoding of ring signatures
+const RingSignVersion2 = 2
+
+var ErrInvalidRingSignEncoding = errors.New("invalid ring signature encoding")
+
+// RingSignature is a linkable ring signature made by RingSign
+type RingSignature struct {
+	PublicKeys []*ecdsa.PublicKey
+	KeyImage   *ecdsa.PublicKey
+	W          []*big.Int
+	Q          []*big.Int
+}
+
+// ringSignatureRLP is the version 2 encoding of a ring signature, the points are
+// compressed and the scalars are 32 bytes.
+type ringSignatureRLP struct {
+	Version    uint
+	PublicKeys [][]byte
+	KeyImage   []byte
+	W          [][]byte
+	Q          [][]byte
+}
+
+// Verify reports whether the ring signature of M is valid
+func (s *RingSignature) Verify(M []byte) bool {
+	return VerifyRingSign(M, s.PublicKeys, s.KeyImage, s.W, s.Q)
+}
+
+// EncodeRingSignV2 encodes the ring signature in the version 2 encoding
+func EncodeRingSignV2(s *RingSignature) ([]byte, error) {
+	if s == nil || s.KeyImage == nil || len(s.PublicKeys) == 0 ||
+		len(s.PublicKeys) != len(s.W) || len(s.PublicKeys) != len(s.Q) {
+		return nil, ErrInvalidRingSignParams
+	}
+
+	enc := ringSignatureRLP{
+		Version:    RingSignVersion2,
+		PublicKeys: make([][]byte, len(s.PublicKeys)),
+		KeyImage:   compressPoint(s.KeyImage),
+		W:          make([][]byte, len(s.W)),
+		Q:          make([][]byte, len(s.Q)),
+	}
+	for i := range s.PublicKeys {
+		if s.PublicKeys[i] == nil || s.W[i] == nil || s.Q[i] == nil {
+			return nil, ErrInvalidRingSignParams
+		}
+		enc.PublicKeys[i] = compressPoint(s.PublicKeys[i])
+		enc.W[i] = math.PaddedBigBytes(s.W[i], 32)
+		enc.Q[i] = math.PaddedBigBytes(s.Q[i], 32)
+	}
+	return rlp.EncodeToBytes(&enc)
+}
+
+// DecodeRingSignV2 decodes a ring signature of the version 2 encoding with at most
+// maxRingSize public keys. Every point is checked on the curve and every scalar is
+// checked in the field, the signature itself is not verified.
+func DecodeRingSignV2(b []byte, maxRingSize int) (*RingSignature, error) {
+	var dec ringSignatureRLP
+	if err := rlp.DecodeBytes(b, &dec); err != nil {
+		return nil, err
+	}
+	if dec.Version != RingSignVersion2 {
+		return nil, ErrInvalidRingSignEncoding
+	}
+
+	n := len(dec.PublicKeys)
+	if n == 0 || n > maxRingSize || len(dec.W) != n || len(dec.Q) != n {
+		return nil, ErrInvalidRingSignEncoding
+	}
+
+	s := &RingSignature{
+		PublicKeys: make([]*ecdsa.PublicKey, n),
+		W:          make([]*big.Int, n),
+		Q:          make([]*big.Int, n),
+	}
+
+	var err error
+	if s.KeyImage, err = decompressPoint(dec.KeyImage); err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		if s.PublicKeys[i], err = decompressPoint(dec.PublicKeys[i]); err != nil {
+			return nil, err
+		}
+		if s.W[i], err = decodeScalar(dec.W[i]); err != nil {
+			return nil, err
+		}
+		if s.Q[i], err = decodeScalar(dec.Q[i]); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// VerifyRingSignBatch verifies the ring signature sigs[i] of msgs[i] concurrently,
+// valid[i] reports whether sigs[i] is valid.
+func VerifyRingSignBatch(msgs [][]byte, sigs []*RingSignature) (valid []bool) {
+	valid = make([]bool, len(sigs))
+	if len(msgs) != len(sigs) {
+		return valid
+	}
+
+	workers := runtime.NumCPU()
+	if workers > len(sigs) {
+		workers = len(sigs)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		jobs = make(chan int, len(sigs))
+	)
+	for i := range sigs {
+		jobs <- i
+	}
+	close(jobs)
+
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				valid[i] = sigs[i] != nil && sigs[i].Verify(msgs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	return valid
+}
+
+func compressPoint(p *ecdsa.PublicKey) []byte {
+	return (*btcec.PublicKey)(&ecdsa.PublicKey{Curve: btcec.S256(), X: p.X, Y: p.Y}).SerializeCompressed()
+}
+
+func decompressPoint(b []byte) (*ecdsa.PublicKey, error) {
+	if len(b) != btcec.PubKeyBytesLenCompressed {
+		return nil, ErrInvalidRingSignEncoding
+	}
+	p, err := btcec.ParsePubKey(b, btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{Curve: S256(), X: p.X, Y: p.Y}, nil
+}
+
+func decodeScalar(b []byte) (*big.Int, error) {
+	if len(b) != 32 {
+		return nil, ErrInvalidRingSignEncoding
+	}
+	x := new(big.Int).SetBytes(b)
+	if x.Cmp(secp256k1_N) >= 0 {
+		return nil, ErrInvalidRingSignEncoding
+	}
+	return x, nil
+}
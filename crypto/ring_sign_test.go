@@ -0,0 +1,105 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package crypto
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+func testRingSign(t *testing.T, M []byte, ringSize int) *RingSignature {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeys := []*ecdsa.PublicKey{&key.PublicKey}
+	for i := 1; i < ringSize; i++ {
+		mix, _ := GenerateKey()
+		publicKeys = append(publicKeys, &mix.PublicKey)
+	}
+
+	publicKeys, keyImage, w, q, err := RingSign(M, key.D, publicKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &RingSignature{publicKeys, keyImage, w, q}
+}
+
+func TestRingSignV2Encoding(t *testing.T) {
+	M := []byte("ring signed message")
+	sig := testRingSign(t, M, 3)
+
+	enc, err := EncodeRingSignV2(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := DecodeRingSignV2(enc, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dec.Verify(M) {
+		t.Fatal("decoded ring signature should be valid")
+	}
+	if dec.Verify([]byte("another message")) {
+		t.Fatal("ring signature of another message should be invalid")
+	}
+	if !bytes.Equal(FromECDSAPub(dec.KeyImage), FromECDSAPub(sig.KeyImage)) {
+		t.Fatal("key image mismatch")
+	}
+
+	if _, err := DecodeRingSignV2(enc, 2); err != ErrInvalidRingSignEncoding {
+		t.Fatal("ring larger than max size should fail", err)
+	}
+
+	var raw ringSignatureRLP
+	rlp.DecodeBytes(enc, &raw)
+	raw.Version = 1
+	b, _ := rlp.EncodeToBytes(&raw)
+	if _, err := DecodeRingSignV2(b, 3); err != ErrInvalidRingSignEncoding {
+		t.Fatal("unknown version should fail", err)
+	}
+
+	rlp.DecodeBytes(enc, &raw)
+	raw.W[1] = raw.W[1][1:]
+	b, _ = rlp.EncodeToBytes(&raw)
+	if _, err := DecodeRingSignV2(b, 3); err != ErrInvalidRingSignEncoding {
+		t.Fatal("short scalar should fail", err)
+	}
+
+	rlp.DecodeBytes(enc, &raw)
+	raw.Q = raw.Q[:2]
+	b, _ = rlp.EncodeToBytes(&raw)
+	if _, err := DecodeRingSignV2(b, 3); err != ErrInvalidRingSignEncoding {
+		t.Fatal("mismatched ring size should fail", err)
+	}
+
+	rlp.DecodeBytes(enc, &raw)
+	raw.PublicKeys[0] = append([]byte{5}, raw.PublicKeys[0][1:]...)
+	b, _ = rlp.EncodeToBytes(&raw)
+	if _, err := DecodeRingSignV2(b, 3); err == nil {
+		t.Fatal("invalid point should fail")
+	}
+}
+
+func TestVerifyRingSignBatch(t *testing.T) {
+	var (
+		msgs [][]byte
+		sigs []*RingSignature
+	)
+	for i := 0; i < 8; i++ {
+		M := []byte{byte(i)}
+		msgs = append(msgs, M)
+		sigs = append(sigs, testRingSign(t, M, i%3+1))
+	}
+	msgs[3] = []byte("tampered")
+	sigs[5] = nil
+
+	for i, valid := range VerifyRingSignBatch(msgs, sigs) {
+		if valid != (i != 3 && i != 5) {
+			t.Fatal("batch verification mismatch", i, valid)
+		}
+	}
+}
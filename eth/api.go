@@ -564,7 +564,7 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int) (co
 	signer := types.MakeSigner(api.config, block.Number())
 	for idx, tx := range txs {
 		// Assemble the transaction call message
-		msg, _ := tx.AsMessage(signer)
+		msg, _ := core.TxAsMessage(signer, tx)
 		context := core.NewEVMContext(msg, block.Header(), api.eth.BlockChain(), nil)
 		if idx == txIndex {
 			return msg, context, statedb, nil
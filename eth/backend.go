@@ -18,10 +18,14 @@
 package eth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/pos/cfm"
 	"github.com/wanchain/go-wanchain/pos/posapi"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 	"math/big"
 	"runtime"
 	"sync"
@@ -83,12 +87,14 @@ type Ethereum struct {
 
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
+	finality      *cfm.Finality                  // Safe and finalized block tracker of pos
 
 	ApiBackend *EthApiBackend
 
 	miner     *miner.Miner
 	gasPrice  *big.Int
 	etherbase common.Address
+	valSigner possigner.Signer // Signer of the validator keys of the etherbase
 
 	networkId     uint64
 	netRPCService *ethapi.PublicNetAPI
@@ -119,6 +125,9 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		return nil, genesisErr
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
+	if err := posconfig.InitParams(chainConfig.PosParams()); err != nil {
+		return nil, err
+	}
 
 	eth := &Ethereum{
 		config:         config,
@@ -162,6 +171,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 
 	if chainConfig.Pluto != nil {
 		miner.PosInit(eth)
+		eth.finality = cfm.NewFinality(eth.blockchain)
 	}
 
 	if config.TxPool.Journal != "" {
@@ -344,21 +354,13 @@ func (s *Ethereum) StartMining(local bool) error {
 		clique.Authorize(eb, wallet.SignHash)
 	}
 	if pluto, ok := s.engine.(*pluto.Pluto); ok {
-		wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
-		if wallet == nil || err != nil {
-			log.Error("Etherbase account unavailable locally", "err", err)
+		valSigner, err := s.validatorSigner(eb)
+		if err != nil {
+			log.Error("Validator signer unavailable", "err", err)
 			return fmt.Errorf("signer missing: %v", err)
 		}
-		//------------Get local unlock publicKey
-		type getKey interface {
-			GetUnlockedKey(address common.Address) (*keystore.Key, error)
-		}
-		key, err := wallet.(getKey).GetUnlockedKey(eb)
-		if key == nil || err != nil {
-			panic(err)
-		}
-		//------------
-		pluto.Authorize(eb, wallet.SignHash, key)
+		posconfig.Cfg().MinerSigner = valSigner
+		pluto.Authorize(eb, valSigner)
 	}
 
 	if ethash, ok := s.engine.(*ethash.Ethash); ok {
@@ -380,6 +382,60 @@ func (s *Ethereum) StartMining(local bool) error {
 	return nil
 }
 
+// validatorSigner returns the signer of the validator keys of eb, the pos protocol
+// transactions of eb are signed by it too. It is the remote signer on the PosSigner
+// socket if one is configured, else the unlocked key of the keystore with its
+// slashing protection records kept in the chain database.
+func (s *Ethereum) validatorSigner(eb common.Address) (possigner.Signer, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.valSigner != nil && s.valSigner.Address() == eb {
+		return s.valSigner, nil
+	}
+	s.closeValidatorSigner()
+
+	if s.config.PosSigner != "" {
+		remote, err := possigner.DialRemote(s.config.PosSigner)
+		if err != nil {
+			return nil, err
+		}
+		if remote.Address() != eb {
+			remote.Close()
+			return nil, fmt.Errorf("remote signer holds the keys of %x instead of %x", remote.Address(), eb)
+		}
+		log.Info("Connected to the remote validator signer", "endpoint", s.config.PosSigner, "address", eb)
+		s.valSigner = remote
+		return remote, nil
+	}
+
+	wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+	if wallet == nil || err != nil {
+		return nil, err
+	}
+	type getKey interface {
+		GetUnlockedKey(address common.Address) (*keystore.Key, error)
+	}
+	ks, ok := wallet.(getKey)
+	if !ok {
+		return nil, errors.New("etherbase is not a keystore account")
+	}
+	key, err := ks.GetUnlockedKey(eb)
+	if err != nil {
+		return nil, err
+	}
+	s.valSigner = possigner.NewProtectedSigner(possigner.NewKeySigner(key), s.chainDb)
+	return s.valSigner, nil
+}
+
+// closeValidatorSigner disconnects from the remote validator signer, if any
+func (s *Ethereum) closeValidatorSigner() {
+	if remote, ok := s.valSigner.(*possigner.RemoteSigner); ok {
+		remote.Close()
+	}
+	s.valSigner = nil
+}
+
 func (s *Ethereum) StopMining()         { s.miner.Stop() }
 func (s *Ethereum) IsMining() bool      { return s.miner.Mining() }
 func (s *Ethereum) Miner() *miner.Miner { return s.miner }
@@ -395,6 +451,12 @@ func (s *Ethereum) EthVersion() int                    { return int(s.protocolMa
 func (s *Ethereum) NetVersion() uint64                 { return s.networkId }
 func (s *Ethereum) Downloader() *downloader.Downloader { return s.protocolManager.downloader }
 
+// SuggestPrice returns the gas price suggested by the oracle, the pos protocol
+// transactions of the miner are priced by it.
+func (s *Ethereum) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	return s.ApiBackend.SuggestPrice(ctx)
+}
+
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *Ethereum) Protocols() []p2p.Protocol {
@@ -410,6 +472,12 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 	// Start the bloom bits servicing goroutines
 	s.startBloomHandlers()
 
+	// Start tracking the safe and finalized blocks
+	if s.finality != nil {
+		s.finality.Start()
+		cfm.SetFinalityGadget(s.finality)
+	}
+
 	// Start the RPC service
 	s.netRPCService = ethapi.NewPublicNetAPI(srvr, s.NetVersion())
 
@@ -436,6 +504,10 @@ func (s *Ethereum) Stop() error {
 		s.stopDbUpgrade()
 	}
 	s.bloomIndexer.Close()
+	if s.finality != nil {
+		cfm.SetFinalityGadget(nil)
+		s.finality.Stop()
+	}
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
@@ -445,6 +517,10 @@ func (s *Ethereum) Stop() error {
 	s.miner.Stop()
 	s.eventMux.Stop()
 
+	s.lock.Lock()
+	s.closeValidatorSigner()
+	s.lock.Unlock()
+
 	s.chainDb.Close()
 	close(s.shutdownChan)
 
@@ -90,6 +90,7 @@ type Config struct {
 	MinerThreads int            `toml:",omitempty"`
 	ExtraData    []byte         `toml:",omitempty"`
 	GasPrice     *big.Int
+	PosSigner    string `toml:",omitempty"` // Unix socket of the remote signer of the validator keys
 
 	// Ethash options
 	EthashCacheDir       string
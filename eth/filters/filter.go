@@ -18,6 +18,7 @@ package filters
 
 import (
 	"context"
+	"errors"
 	"math/big"
 	"time"
 
@@ -102,6 +103,10 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	}
 	head := header.Number.Uint64()
 
+	// Resolve the safe and finalized tags to the blocks they point to
+	if err := f.resolveTags(ctx); err != nil {
+		return nil, err
+	}
 	if f.begin == -1 {
 		f.begin = int64(head)
 	}
@@ -130,6 +135,24 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	return logs, err
 }
 
+// resolveTags replaces the safe and finalized tags of the range with block numbers.
+func (f *Filter) resolveTags(ctx context.Context) error {
+	for _, number := range []*int64{&f.begin, &f.end} {
+		if *number != rpc.SafeBlockNumber.Int64() && *number != rpc.FinalizedBlockNumber.Int64() {
+			continue
+		}
+		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(*number))
+		if header == nil {
+			if err == nil {
+				err = errors.New("unknown block")
+			}
+			return err
+		}
+		*number = header.Number.Int64()
+	}
+	return nil
+}
+
 // indexedLogs returns the logs matching the filter criteria based on the bloom
 // bits indexed available locally or via the network.
 func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
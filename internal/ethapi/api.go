@@ -26,6 +26,7 @@ import (
 	"fmt"
 	"github.com/wanchain/go-wanchain/accounts/keystore/bn256"
 	"math/big"
+	mathrand "math/rand"
 	"strings"
 	"time"
 
@@ -66,6 +67,8 @@ var (
 	ErrReqTooManyOTAMix                 = errors.New("Require too many OTA mix address")
 	ErrInvalidOTAMixNum                 = errors.New("Invalid required OTA mix address number")
 	ErrInvalidInput                     = errors.New("Invalid input")
+	ErrInvalidRingSize                  = errors.New("Invalid ring size of privacy transaction")
+	ErrNoStampForGas                    = errors.New("No stamp pays for the gas of privacy transaction")
 )
 
 // PublicEthereumAPI provides an API to access Ethereum related information.
@@ -228,17 +231,21 @@ func (s *PublicAccountAPI) Accounts() []common.Address {
 // It offers methods to create, (un)lock en list accounts. Some methods accept
 // passwords and are therefore considered private by default.
 type PrivateAccountAPI struct {
-	am        *accounts.Manager
-	nonceLock *AddrLocker
-	b         Backend
+	am         *accounts.Manager
+	nonceLock  *AddrLocker
+	otaScanner *OTAScanner
+	otaBuys    *OTABuyIndex
+	b          Backend
 }
 
 // NewPrivateAccountAPI create a new PrivateAccountAPI.
-func NewPrivateAccountAPI(b Backend, nonceLock *AddrLocker) *PrivateAccountAPI {
+func NewPrivateAccountAPI(b Backend, nonceLock *AddrLocker, otaScanner *OTAScanner, otaBuys *OTABuyIndex) *PrivateAccountAPI {
 	return &PrivateAccountAPI{
-		am:        b.AccountManager(),
-		nonceLock: nonceLock,
-		b:         b,
+		am:         b.AccountManager(),
+		nonceLock:  nonceLock,
+		otaScanner: otaScanner,
+		otaBuys:    otaBuys,
+		b:          b,
 	}
 }
 
@@ -825,6 +832,10 @@ type CallArgs struct {
 	GasPrice hexutil.Big     `json:"gasPrice"`
 	Value    hexutil.Big     `json:"value"`
 	Data     hexutil.Bytes   `json:"data"`
+
+	// RingSize is the ring size of a privacy transaction whose call data is Data,
+	// nil if the call isn't made by a privacy transaction.
+	RingSize *hexutil.Uint64 `json:"ringSize"`
 }
 
 func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config) ([]byte, *big.Int, bool, error) {
@@ -898,7 +909,73 @@ func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr r
 }
 
 // EstimateGas returns an estimate of the amount of gas needed to execute the given transaction.
+// The gas of a privacy transaction is the one paid by the stamp EstimateStamp recommends.
 func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (*hexutil.Big, error) {
+	if args.RingSize != nil {
+		estimate, err := s.EstimateStamp(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		return estimate.StampGas, nil
+	}
+	return s.estimateCallGas(ctx, args)
+}
+
+// StampEstimate is the stamp recommended to pay for a privacy transaction
+type StampEstimate struct {
+	Gas         *hexutil.Big `json:"gas"`         // gas the privacy transaction takes
+	RingSignGas *hexutil.Big `json:"ringSignGas"` // part of the gas taken by the ring signature
+	GasPrice    *hexutil.Big `json:"gasPrice"`
+	Stamp       *hexutil.Big `json:"stamp"`    // the smallest stamp denomination paying for the gas
+	StampGas    *hexutil.Big `json:"stampGas"` // gas the stamp pays for, the gas limit of the transaction
+}
+
+// EstimateStamp estimates the gas of a privacy transaction making the call args with a ring
+// signature of args.RingSize members, and recommends the smallest stamp denomination of
+// GetSupportStampOTABalances paying for it at the gas price.
+func (s *PublicBlockChainAPI) EstimateStamp(ctx context.Context, args CallArgs) (*StampEstimate, error) {
+	// a ring is the stamp itself and its mix set
+	if args.RingSize == nil || *args.RingSize == 0 || uint64(*args.RingSize) > params.GetOTAMixSetMaxSize+1 {
+		return nil, ErrInvalidRingSize
+	}
+
+	ringSignGas, err := core.PrivacyTxExtraGas(args.Data, args.To, int(*args.RingSize))
+	if err != nil {
+		return nil, err
+	}
+
+	gasPrice := args.GasPrice.ToInt()
+	if gasPrice.Sign() == 0 {
+		if gasPrice, err = s.b.SuggestPrice(ctx); err != nil {
+			return nil, err
+		}
+		args.GasPrice = hexutil.Big(*gasPrice)
+	}
+
+	callGas, err := s.estimateCallGas(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	gas := new(big.Int).Add(callGas.ToInt(), new(big.Int).SetUint64(ringSignGas))
+	for _, stamp := range vm.GetSupportStampOTABalances() {
+		stampGas := new(big.Int).Div(stamp, gasPrice)
+		if stampGas.Cmp(gas) >= 0 {
+			return &StampEstimate{
+				Gas:         (*hexutil.Big)(gas),
+				RingSignGas: (*hexutil.Big)(new(big.Int).SetUint64(ringSignGas)),
+				GasPrice:    (*hexutil.Big)(gasPrice),
+				Stamp:       (*hexutil.Big)(stamp),
+				StampGas:    (*hexutil.Big)(stampGas),
+			}, nil
+		}
+	}
+
+	return nil, ErrNoStampForGas
+}
+
+// estimateCallGas returns an estimate of the amount of gas needed to execute the given call.
+func (s *PublicBlockChainAPI) estimateCallGas(ctx context.Context, args CallArgs) (*hexutil.Big, error) {
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
 		lo uint64 = params.TxGas - 1
@@ -1134,11 +1211,13 @@ func newRPCTransactionFromBlockHash(b *types.Block, hash common.Hash) *RPCTransa
 type PublicTransactionPoolAPI struct {
 	b         Backend
 	nonceLock *AddrLocker
+	otaBuys   *OTABuyIndex
 }
 
 // NewPublicTransactionPoolAPI creates a new RPC service with methods specific for the transaction pool.
-func NewPublicTransactionPoolAPI(b Backend, nonceLock *AddrLocker) *PublicTransactionPoolAPI {
-	return &PublicTransactionPoolAPI{b, nonceLock}
+// otaBuys provides the ota ages to select the ota mix sets, it can be nil.
+func NewPublicTransactionPoolAPI(b Backend, nonceLock *AddrLocker, otaBuys *OTABuyIndex) *PublicTransactionPoolAPI {
+	return &PublicTransactionPoolAPI{b, nonceLock, otaBuys}
 }
 
 // GetBlockTransactionCountByNumber returns the number of transactions in the block with the given block number.
@@ -1271,6 +1350,9 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
+	if receipt.PrivacyFee != nil {
+		fields["privacyFee"] = receipt.PrivacyFee
+	}
 	return fields, nil
 }
 
@@ -1432,7 +1514,24 @@ func (s *PublicTransactionPoolAPI) SendPosTransaction(ctx context.Context, args
 	return submitTransaction(ctx, s.b, signed)
 }
 
-func (s *PublicTransactionPoolAPI) GetOTAMixSet(ctx context.Context, otaAddr string, setLen int) ([]string, error) {
+// OTAMixPolicyArgs is how GetOTAMixSet selects the mix otas
+type OTAMixPolicyArgs struct {
+	Mode string          `json:"mode"` // "uniform" or "recent", "uniform" by default
+	Seed *hexutil.Uint64 `json:"seed"` // seed of the selection for deterministic results, random if nil
+}
+
+// GetOTAMixSet returns setLen mix otas of the same balance as otaAddr. They are selected
+// uniformly by default, or weighted by how recent their buy blocks are with the "recent"
+// mode. The selection is of the chain data only, so it tells nothing of the accounts of
+// the node.
+func (s *PublicTransactionPoolAPI) GetOTAMixSet(ctx context.Context, otaAddr string, setLen int, policy *OTAMixPolicyArgs) ([]string, error) {
+	return otaMixSet(ctx, s.b, otaAddr, setLen, policy, s.otaBuys, nil)
+}
+
+// otaMixSet selects the mix otas of otaAddr by policy in the head state, the otas
+// spent reports are selected only if there are not enough others.
+func otaMixSet(ctx context.Context, b Backend, otaAddr string, setLen int, policy *OTAMixPolicyArgs,
+	buys *OTABuyIndex, spent func(otaWanAddr []byte) bool) ([]string, error) {
 	if setLen <= 0 {
 		return []string{}, ErrInvalidOTAMixNum
 	}
@@ -1450,7 +1549,7 @@ func (s *PublicTransactionPoolAPI) GetOTAMixSet(ctx context.Context, otaAddr str
 		return []string{}, ErrInvalidOTAAddr
 	}
 
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.BlockNumber(-1))
+	state, header, err := b.StateAndHeaderByNumber(ctx, rpc.BlockNumber(-1))
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -1460,7 +1559,32 @@ func (s *PublicTransactionPoolAPI) GetOTAMixSet(ctx context.Context, otaAddr str
 		otaAX, _ = vm.GetAXFromWanAddr(orgOtaAddr)
 	}
 
-	otaByteSet, _, err := vm.GetOTASet(state, otaAX, setLen)
+	mode := vm.OTAMixUniform
+	var rnd *mathrand.Rand
+	if policy != nil {
+		switch policy.Mode {
+		case "", "uniform":
+		case "recent":
+			mode = vm.OTAMixRecent
+		default:
+			return nil, vm.ErrInvalidOTAMixMode
+		}
+		if policy.Seed != nil {
+			rnd = mathrand.New(mathrand.NewSource(int64(*policy.Seed)))
+		}
+	}
+
+	mixPolicy := &vm.OTAMixPolicy{Mode: mode, Rand: rnd, Spent: spent}
+	if mode == vm.OTAMixRecent {
+		if buys == nil {
+			return nil, errOTABuyIndexSyncing
+		}
+		if mixPolicy.Age, err = buys.Age(header.Number.Uint64()); err != nil {
+			return nil, err
+		}
+	}
+
+	otaByteSet, _, err := vm.GetOTAMixSet(state, otaAX, setLen, mixPolicy)
 	if err != nil {
 		return nil, err
 	}
@@ -1805,30 +1929,79 @@ func (s *PublicTransactionPoolAPI) GenerateOneTimeAddress(ctx context.Context, w
 		return "", err
 	}
 
-	PK1, PK2, err := keystore.GeneratePKPairFromWAddress(PKBytesSlice)
+	rawWanAddr, err := generateOneTimeAddress(PKBytesSlice)
+	if err != nil {
+		return "", err
+	}
+
+	return hexutil.Encode(rawWanAddr), nil
+}
+
+// OTAWithMemo is a One-Time-Address with the memo encrypted to its receiver
+type OTAWithMemo struct {
+	Address hexutil.Bytes `json:"otaAddress"`
+	Memo    hexutil.Bytes `json:"memo"`
+}
+
+// GenerateOneTimeAddressWithMemo returns a One-Time-Address for a given WanAddress and
+// the memo encrypted to the receiver, which are the arguments of buyCoinNoteWithMemo.
+func (s *PublicTransactionPoolAPI) GenerateOneTimeAddressWithMemo(ctx context.Context, wAddr hexutil.Bytes, memo string) (*OTAWithMemo, error) {
+	return generateOneTimeAddressWithMemo(wAddr, memo)
+}
+
+// generateOneTimeAddressWithMemo returns a new One-Time-Address of the raw WanAddress
+// and the memo encrypted to it
+func generateOneTimeAddressWithMemo(wAddr []byte, memo string) (*OTAWithMemo, error) {
+	if len(wAddr) != common.WAddressLength {
+		return nil, ErrInvalidWAddress
+	}
+	if uint64(len(memo)+crypto.OTAMemoOverhead) > params.MaxOTAMemoSize {
+		return nil, vm.ErrOTAMemoTooLong
+	}
+
+	PK1, PK2, err := keystore.GeneratePKPairFromWAddress(wAddr)
+	if err != nil {
+		return nil, ErrFailToGeneratePKPairFromWAddress
+	}
+
+	A1, R, encMemo, err := crypto.GenerateOneTimeKeyWithMemo(PK1, PK2, []byte(memo))
+	if err != nil {
+		return nil, err
+	}
+
+	ota := keystore.GenerateWaddressFromPK(A1, R)
+	return &OTAWithMemo{Address: ota[:], Memo: encMemo}, nil
+}
+
+// generateOneTimeAddress returns a new One-Time-Address of the raw WanAddress
+func generateOneTimeAddress(wanAddr []byte) ([]byte, error) {
+	PK1, PK2, err := keystore.GeneratePKPairFromWAddress(wanAddr)
 	if err != nil {
-		return "", ErrFailToGeneratePKPairFromWAddress
+		return nil, ErrFailToGeneratePKPairFromWAddress
 	}
 
 	PKPairSlice := hexutil.PKPair2HexSlice(PK1, PK2)
 
 	SKOTA, err := crypto.GenerateOneTimeKey(PKPairSlice[0], PKPairSlice[1], PKPairSlice[2], PKPairSlice[3])
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	otaStr := strings.Replace(strings.Join(SKOTA, ""), "0x", "", -1)
 	raw, err := hexutil.Decode("0x" + otaStr)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	rawWanAddr, err := keystore.WaddrFromUncompressedRawBytes(raw)
-	if err != nil || rawWanAddr == nil {
-		return "", err
+	if err != nil {
+		return nil, err
+	}
+	if rawWanAddr == nil {
+		return nil, ErrInvalidOTAAddr
 	}
 
-	return hexutil.Encode(rawWanAddr[:]), nil
+	return rawWanAddr[:], nil
 }
 
 func (args *SendTxArgs) toOTATransaction() *types.Transaction {
@@ -1850,6 +2023,45 @@ func (s *PrivateAccountAPI) GetOTABalance(ctx context.Context, blockNr rpc.Block
 
 }
 
+// GetOTAStatus returns the otas sent to the unlocked account with their key images,
+// spent status and refund transactions. The account is added to the ota scanner
+// if it is not scanned yet.
+func (s *PrivateAccountAPI) GetOTAStatus(addr common.Address) ([]*OwnedOTA, error) {
+	if err := s.otaScanner.Add(addr); err != nil {
+		return nil, err
+	}
+	return s.otaScanner.AllOTAs(addr)
+}
+
+// GetOTAMixSet returns setLen mix otas of the same balance as otaAddr like the public
+// one, but the otas the accounts of the ota scanner have spent are selected only if
+// there are not enough others.
+func (s *PrivateAccountAPI) GetOTAMixSet(ctx context.Context, otaAddr string, setLen int, policy *OTAMixPolicyArgs) ([]string, error) {
+	return otaMixSet(ctx, s.b, otaAddr, setLen, policy, s.otaBuys, s.otaScanner.Spent())
+}
+
+// GetOTAMemo returns the memo of the buyCoinNoteWithMemo transaction decrypted by the
+// unlocked account, which is the receiver of the ota bought.
+func (s *PrivateAccountAPI) GetOTAMemo(ctx context.Context, addr common.Address, hash common.Hash) (string, error) {
+	tx, _, _, _ := core.GetTransaction(s.b.ChainDb(), hash)
+	if tx == nil {
+		if tx = s.b.GetPoolTransaction(hash); tx == nil {
+			return "", fmt.Errorf("transaction %x not found", hash)
+		}
+	}
+
+	otaAddr, encMemo, err := vm.CoinNoteMemo(tx)
+	if err != nil {
+		return "", err
+	}
+
+	memo, err := fetchKeystore(s.am).DecryptOTAMemo(accounts.Account{Address: addr}, otaAddr, encMemo)
+	if err != nil {
+		return "", err
+	}
+	return string(memo), nil
+}
+
 func (s *PrivateAccountAPI) ShowPublicKey(addr common.Address, passwd string) ([]string,error) {
 
 	if len(addr) == 0 {
@@ -72,6 +72,8 @@ type Backend interface {
 
 func GetAPIs(apiBackend Backend) []rpc.API {
 	nonceLock := new(AddrLocker)
+	otaScanner := NewOTAScanner(apiBackend)
+	otaBuys := NewOTABuyIndex(apiBackend)
 	return []rpc.API{
 		{
 			Namespace: "eth",
@@ -91,12 +93,12 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   NewPublicTransactionPoolAPI(apiBackend, nonceLock),
+			Service:   NewPublicTransactionPoolAPI(apiBackend, nonceLock, otaBuys),
 			Public:    true,
 		}, {
 			Namespace: "wan",
 			Version:   "1.0",
-			Service:   NewPublicTransactionPoolAPI(apiBackend, nonceLock),
+			Service:   NewPublicTransactionPoolAPI(apiBackend, nonceLock, otaBuys),
 			Public:    true,
 		}, {
 			Namespace: "txpool",
@@ -120,7 +122,12 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 		}, {
 			Namespace: "personal",
 			Version:   "1.0",
-			Service:   NewPrivateAccountAPI(apiBackend, nonceLock),
+			Service:   NewPrivateAccountAPI(apiBackend, nonceLock, otaScanner, otaBuys),
+			Public:    false,
+		}, {
+			Namespace: "wan",
+			Version:   "1.0",
+			Service:   NewPrivateOTAScannerAPI(otaScanner),
 			Public:    false,
 		},
 	}
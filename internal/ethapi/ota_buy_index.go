@@ -0,0 +1,150 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// otaBuyIndexReorg is the count of the last blocks indexed whose hashes are kept to
+// find the fork block of a reorg
+const otaBuyIndexReorg = 1024
+
+var errOTABuyIndexSyncing = errors.New("ota buy blocks are being indexed, retry later")
+
+// otaBlockReader returns the block of the canonical chain of number, nil if there's none
+type otaBlockReader func(number uint64) *types.Block
+
+// OTABuyIndex is the index of the blocks the otas are bought in. It's built from
+// the buy transactions of the canonical chain from the genesis on, so it's the
+// same on every node and whatever the accounts of the node. The index is built
+// once it's first used, and follows the chain head then.
+type OTABuyIndex struct {
+	b     Backend
+	block otaBlockReader
+
+	mu      sync.Mutex
+	blocks  map[string]uint64      // buy blocks of the otas indexed
+	hashes  map[uint64]common.Hash // hashes of the last blocks indexed
+	next    uint64                 // the next block to index
+	started bool
+}
+
+// NewOTABuyIndex creates the index of the buy blocks of the chain of the backend
+func NewOTABuyIndex(b Backend) *OTABuyIndex {
+	idx := &OTABuyIndex{
+		b:      b,
+		blocks: make(map[string]uint64),
+		hashes: make(map[uint64]common.Hash),
+		next:   1,
+	}
+	idx.block = func(number uint64) *types.Block {
+		block, err := b.BlockByNumber(context.Background(), rpc.BlockNumber(number))
+		if err != nil {
+			return nil
+		}
+		return block
+	}
+	return idx
+}
+
+// Age returns the buy blocks of the otas stored in the state of the block number,
+// 0 for the otas not bought by a transaction. It fails while the index is behind
+// the block.
+func (idx *OTABuyIndex) Age(number uint64) (func(otaWanAddr []byte) uint64, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if !idx.started {
+		idx.started = true
+		go idx.loop()
+	}
+	if idx.next <= number {
+		return nil, errOTABuyIndexSyncing
+	}
+	return func(otaWanAddr []byte) uint64 {
+		idx.mu.Lock()
+		defer idx.mu.Unlock()
+		return idx.blocks[string(otaWanAddr)]
+	}, nil
+}
+
+// loop indexes the chain up to every new head
+func (idx *OTABuyIndex) loop() {
+	heads := make(chan core.ChainHeadEvent, 10)
+	sub := idx.b.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	idx.update(idx.b.CurrentBlock().NumberU64())
+	for {
+		select {
+		case head := <-heads:
+			idx.update(head.Block.NumberU64())
+		case <-sub.Err():
+			return
+		}
+	}
+}
+
+// update rewinds the index to the fork block of a reorg, and indexes the blocks
+// after it up to head. It's only run by loop, so the fields are written by it only.
+func (idx *OTABuyIndex) update(head uint64) {
+	next := idx.next
+	for next > 1 {
+		hash, ok := idx.hashes[next-1]
+		if !ok {
+			break
+		}
+		if block := idx.block(next - 1); block != nil && block.Hash() == hash {
+			break
+		}
+		next--
+	}
+	if next < idx.next {
+		idx.mu.Lock()
+		for ota, number := range idx.blocks {
+			if number >= next {
+				delete(idx.blocks, ota)
+			}
+		}
+		for number := next; number < idx.next; number++ {
+			delete(idx.hashes, number)
+		}
+		idx.next = next
+		idx.mu.Unlock()
+	}
+
+	for number := next; number <= head; number++ {
+		block := idx.block(number)
+		if block == nil {
+			log.Warn("ota buy index failed to get block", "number", number)
+			return
+		}
+
+		idx.mu.Lock()
+		for _, tx := range block.Transactions() {
+			otaAddrs, err := vm.CoinNoteOTAs(tx)
+			if err != nil {
+				continue
+			}
+			for _, otaAddr := range otaAddrs {
+				if _, ok := idx.blocks[string(otaAddr)]; !ok {
+					idx.blocks[string(otaAddr)] = number
+				}
+			}
+		}
+		idx.hashes[number] = block.Hash()
+		delete(idx.hashes, number-otaBuyIndexReorg)
+		idx.next = number + 1
+		idx.mu.Unlock()
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethapi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+)
+
+func TestOTABuyIndex(t *testing.T) {
+	chain := types.Blocks{
+		testBlock(0),
+		testBlock(1, testBuyTx(t, testOTA(1))),
+		testBlock(2),
+		testBlock(3, testBuyTx(t, testOTA(2)), testBuyTx(t, testOTA(3))),
+	}
+	idx := &OTABuyIndex{blocks: make(map[string]uint64), hashes: make(map[uint64]common.Hash), next: 1, started: true}
+	idx.block = func(number uint64) *types.Block {
+		if number >= uint64(len(chain)) {
+			return nil
+		}
+		return chain[number]
+	}
+
+	if _, err := idx.Age(1); err != errOTABuyIndexSyncing {
+		t.Fatal("index behind the block should fail", err)
+	}
+	idx.update(3)
+	age, err := idx.Age(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ages := map[byte]uint64{1: 1, 2: 3, 3: 3, 4: 0}
+	for b, want := range ages {
+		if got := age(testOTA(b)); got != want {
+			t.Errorf("buy block of ota %d: got %d, want %d", b, got, want)
+		}
+	}
+
+	// the blocks 2 and 3 are replaced by a reorg, ota 3 is bought in the new block 2
+	chain = append(chain[:2], types.NewBlock(&types.Header{Number: big.NewInt(2), Extra: []byte{1}},
+		types.Transactions{testBuyTx(t, testOTA(3))}, nil, nil))
+	idx.update(2)
+	if _, err := idx.Age(3); err != errOTABuyIndexSyncing {
+		t.Fatal("index should be rewound to the new head", err)
+	}
+	age, _ = idx.Age(2)
+	ages = map[byte]uint64{1: 1, 2: 0, 3: 2}
+	for b, want := range ages {
+		if got := age(testOTA(b)); got != want {
+			t.Errorf("buy block of ota %d after the reorg: got %d, want %d", b, got, want)
+		}
+	}
+}
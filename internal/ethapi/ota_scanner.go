@@ -0,0 +1,523 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/event"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// maxOTAScanGap is the most blocks before a new head read for the refunds and memos in them,
+// when the heads between it and the last block scanned are not notified
+const maxOTAScanGap = 1024
+
+var (
+	errNoKeyStore    = errors.New("no keystore to scan otas")
+	errOTANotScanned = errors.New("account is not scanned for otas")
+)
+
+// OwnedOTA is a one-time-address sent to a scanned account
+type OwnedOTA struct {
+	Address     hexutil.Bytes  `json:"address"`        // Wan address of the ota
+	Balance     *hexutil.Big   `json:"balance"`        // Wan coins bought to the ota
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`    // Head block when the ota is found
+	KeyImage    hexutil.Bytes  `json:"keyImage"`       // Key image of the ring signature spending the ota
+	Spent       bool           `json:"spent"`          // Whether the key image is stored in the head state
+	RefundTx    *common.Hash   `json:"refundTx"`       // Refund transaction, nil if it's not seen by the scanner
+	Memo        string         `json:"memo,omitempty"` // Decrypted memo of the buyCoinNoteWithMemo transaction
+}
+
+// OTAScanEvent is posted when otas of a scanned account are found or spent
+type OTAScanEvent struct {
+	Account     common.Address `json:"account"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	OTAs        []*OwnedOTA    `json:"otas"`
+	Spent       []*OwnedOTA    `json:"spent"`
+}
+
+// otaOwnerChecker reports which of the otas are sent to the account
+type otaOwnerChecker func(account common.Address, otaWanAddrs [][]byte) ([]bool, error)
+
+// otaImageComputer returns the key images of the otas sent to the account
+type otaImageComputer func(account common.Address, otaWanAddrs [][]byte) ([][]byte, error)
+
+// otaMemoDecrypter decrypts the memo of an ota sent to the account
+type otaMemoDecrypter func(account common.Address, otaWanAddr []byte, encMemo []byte) ([]byte, error)
+
+type storedOTA struct {
+	wanAddr []byte
+	balance *big.Int
+	memo    []byte // encrypted memo, nil if the ota is not bought with a memo in a block scanned
+}
+
+type otaIndex struct {
+	next int // count of the stored otas checked for the account
+	otas []*OwnedOTA
+}
+
+func (index *otaIndex) unspent() []*OwnedOTA {
+	otas := make([]*OwnedOTA, 0, len(index.otas))
+	for _, ota := range index.otas {
+		if !ota.Spent {
+			otas = append(otas, ota)
+		}
+	}
+	return copyOTAs(otas)
+}
+
+// OTAScanner walks the ota storage of the chain head and keeps an index of the
+// otas sent to the accounts added. The view key of an account is only available
+// when it is unlocked, the otas stored meanwhile are checked once it's unlocked.
+// An ota is spent once the key image of it is stored, the refund transactions
+// in the blocks scanned are recorded, so are the memos of the otas bought in them.
+// The blocks since the last block scanned are read at a new head.
+type OTAScanner struct {
+	b      Backend
+	check  otaOwnerChecker
+	images otaImageComputer
+	memos  otaMemoDecrypter
+
+	scanMu   sync.Mutex // serializes the scans
+	scanned  uint64     // the last block scanned, protected by scanMu
+	mu       sync.Mutex
+	stored   []storedOTA         // otas in the order they are found
+	known    map[string]struct{} // wan addresses of stored
+	accounts map[common.Address]*otaIndex
+	started  bool
+
+	feed  event.Feed
+	scope event.SubscriptionScope
+}
+
+// NewOTAScanner creates a scanner checking the otas with the keystore of the backend
+func NewOTAScanner(b Backend) *OTAScanner {
+	s := &OTAScanner{
+		b:        b,
+		known:    make(map[string]struct{}),
+		accounts: make(map[common.Address]*otaIndex),
+	}
+	s.check = s.checkWithKeyStore
+	s.images = s.imagesWithKeyStore
+	s.memos = s.memoWithKeyStore
+	return s
+}
+
+func (s *OTAScanner) keyStore() (*keystore.KeyStore, error) {
+	backends := s.b.AccountManager().Backends(keystore.KeyStoreType)
+	if len(backends) == 0 {
+		return nil, errNoKeyStore
+	}
+	return backends[0].(*keystore.KeyStore), nil
+}
+
+func (s *OTAScanner) checkWithKeyStore(account common.Address, otaWanAddrs [][]byte) ([]bool, error) {
+	ks, err := s.keyStore()
+	if err != nil {
+		return nil, err
+	}
+	return ks.CheckOTAOwners(accounts.Account{Address: account}, otaWanAddrs)
+}
+
+func (s *OTAScanner) imagesWithKeyStore(account common.Address, otaWanAddrs [][]byte) ([][]byte, error) {
+	ks, err := s.keyStore()
+	if err != nil {
+		return nil, err
+	}
+	return ks.ComputeOTAImages(accounts.Account{Address: account}, otaWanAddrs)
+}
+
+func (s *OTAScanner) memoWithKeyStore(account common.Address, otaWanAddr []byte, encMemo []byte) ([]byte, error) {
+	ks, err := s.keyStore()
+	if err != nil {
+		return nil, err
+	}
+	return ks.DecryptOTAMemo(accounts.Account{Address: account}, otaWanAddr, encMemo)
+}
+
+// Add starts scanning the otas of the unlocked account, the head state is scanned at once
+func (s *OTAScanner) Add(account common.Address) error {
+	if _, err := s.check(account, nil); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if _, ok := s.accounts[account]; !ok {
+		s.accounts[account] = &otaIndex{}
+	}
+	if !s.started {
+		s.started = true
+		go s.loop()
+	}
+	s.mu.Unlock()
+
+	statedb, header, err := s.b.StateAndHeaderByNumber(context.Background(), rpc.LatestBlockNumber)
+	if statedb == nil || err != nil {
+		return err
+	}
+	s.scan(statedb, header.Number.Uint64(), nil)
+	return nil
+}
+
+// Remove stops scanning the otas of the account and drops its index
+func (s *OTAScanner) Remove(account common.Address) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.accounts[account]
+	delete(s.accounts, account)
+	return ok
+}
+
+// OTAs returns the unspent otas found for the account
+func (s *OTAScanner) OTAs(account common.Address) ([]*OwnedOTA, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, ok := s.accounts[account]
+	if !ok {
+		return nil, errOTANotScanned
+	}
+	return index.unspent(), nil
+}
+
+// AllOTAs returns the otas found for the account, both spent and unspent
+func (s *OTAScanner) AllOTAs(account common.Address) ([]*OwnedOTA, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, ok := s.accounts[account]
+	if !ok {
+		return nil, errOTANotScanned
+	}
+	return copyOTAs(index.otas), nil
+}
+
+// Spent returns whether an ota is spent by an account scanned, as of the last scan
+func (s *OTAScanner) Spent() func(otaWanAddr []byte) bool {
+	s.mu.Lock()
+	spent := make(map[string]struct{})
+	for _, index := range s.accounts {
+		for _, ota := range index.otas {
+			if ota.Spent {
+				spent[string(ota.Address)] = struct{}{}
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	return func(otaWanAddr []byte) bool {
+		_, ok := spent[string(otaWanAddr)]
+		return ok
+	}
+}
+
+// SubscribeScanEvent registers a subscription of OTAScanEvent
+func (s *OTAScanner) SubscribeScanEvent(ch chan<- OTAScanEvent) event.Subscription {
+	return s.scope.Track(s.feed.Subscribe(ch))
+}
+
+// loop scans the state of every new head
+func (s *OTAScanner) loop() {
+	heads := make(chan core.ChainHeadEvent, 10)
+	sub := s.b.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case head := <-heads:
+			number := head.Block.NumberU64()
+			statedb, _, err := s.b.StateAndHeaderByNumber(context.Background(), rpc.BlockNumber(number))
+			if statedb == nil || err != nil {
+				log.Warn("ota scanner failed to get state", "number", number, "err", err)
+				continue
+			}
+			s.scan(statedb, number, s.blocksSince(head.Block))
+		case <-sub.Err():
+			return
+		}
+	}
+}
+
+// accountScan is the part of a scan done for an account without holding the lock
+type accountScan struct {
+	next   int             // count of the stored otas checked before the scan
+	images [][]byte        // key images of the otas found before the scan
+	spent  map[string]bool // whether the key images are stored
+	found  []*OwnedOTA     // otas found in the stored otas after next
+	err    error           // error checking the otas, the account is scanned again at next block
+}
+
+// blocksSince returns the blocks after the last block scanned up to head, in ascending order
+func (s *OTAScanner) blocksSince(head *types.Block) types.Blocks {
+	s.scanMu.Lock()
+	scanned := s.scanned
+	s.scanMu.Unlock()
+
+	number := head.NumberU64()
+	from := scanned + 1
+	if scanned == 0 || from > number {
+		return types.Blocks{head}
+	}
+	if number-from > maxOTAScanGap {
+		from = number - maxOTAScanGap
+	}
+
+	var blocks types.Blocks
+	for n := from; n < number; n++ {
+		block, err := s.b.BlockByNumber(context.Background(), rpc.BlockNumber(n))
+		if block == nil || err != nil {
+			log.Warn("ota scanner failed to get block", "number", n, "err", err)
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	return append(blocks, head)
+}
+
+// scan collects the otas not stored yet, updates the spent status of the otas
+// found and checks the otas new to every account. blocks are the blocks scanned in
+// ascending order, the refund transactions and the memos in them are recorded.
+//
+// The ota storage walk and the keystore work are done without holding mu, so the
+// queries are not blocked by them, mu is only held to take and merge the results.
+func (s *OTAScanner) scan(statedb vm.StateDB, number uint64, blocks types.Blocks) {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	s.scanned = number
+
+	refunds := make(map[string]common.Hash)
+	memos := make(map[string][]byte)
+	for _, block := range blocks {
+		for _, tx := range block.Transactions() {
+			if otaAddr, memo, err := vm.CoinNoteMemo(tx); err == nil {
+				memos[string(otaAddr)] = memo
+				continue
+			}
+
+			images, err := vm.RefundKeyImages(tx)
+			if err != nil {
+				continue
+			}
+			for _, image := range images {
+				refunds[string(image)] = tx.Hash()
+			}
+		}
+	}
+
+	// known is only written by scan, so it's read without mu under scanMu
+	var added []storedOTA
+	vm.ForEachOTA(statedb, func(otaWanAddr []byte, balance *big.Int) bool {
+		if _, ok := s.known[string(otaWanAddr)]; !ok {
+			added = append(added, storedOTA{common.CopyBytes(otaWanAddr), balance, memos[string(otaWanAddr)]})
+		}
+		return true
+	})
+
+	s.mu.Lock()
+	for _, ota := range added {
+		s.known[string(ota.wanAddr)] = struct{}{}
+		s.stored = append(s.stored, ota)
+	}
+	stored := s.stored
+	scans := make(map[common.Address]*accountScan, len(s.accounts))
+	for account, index := range s.accounts {
+		scan := &accountScan{next: index.next, images: make([][]byte, len(index.otas))}
+		for i, ota := range index.otas {
+			scan.images[i] = ota.KeyImage
+		}
+		scans[account] = scan
+	}
+	s.mu.Unlock()
+
+	for account, scan := range scans {
+		scan.spent = make(map[string]bool, len(scan.images))
+		for _, image := range scan.images {
+			scan.spent[string(image)], _, _ = vm.CheckOTAImageExist(statedb, image)
+		}
+		scan.found, scan.err = s.scanNew(statedb, number, account, stored[scan.next:])
+	}
+
+	var events []OTAScanEvent
+
+	s.mu.Lock()
+	for account, scan := range scans {
+		index, ok := s.accounts[account]
+		if !ok || index.next != scan.next {
+			// the account is removed or added again meanwhile
+			continue
+		}
+
+		var spent []*OwnedOTA
+		for _, ota := range index.otas {
+			if hash, ok := refunds[string(ota.KeyImage)]; ok {
+				ota.RefundTx = &hash
+			}
+
+			exist := scan.spent[string(ota.KeyImage)]
+			if exist && !ota.Spent {
+				spent = append(spent, ota)
+			}
+			ota.Spent = exist
+		}
+
+		if scan.err == nil {
+			index.next = len(stored)
+			index.otas = append(index.otas, scan.found...)
+		}
+		if len(scan.found) > 0 || len(spent) > 0 {
+			events = append(events, OTAScanEvent{account, hexutil.Uint64(number), copyOTAs(scan.found), copyOTAs(spent)})
+		}
+	}
+	s.mu.Unlock()
+
+	for _, ev := range events {
+		s.feed.Send(ev)
+	}
+}
+
+// scanNew checks the otas stored after the last scan of the account, returns the otas owned
+func (s *OTAScanner) scanNew(statedb vm.StateDB, number uint64, account common.Address, pending []storedOTA) ([]*OwnedOTA, error) {
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	otaWanAddrs := make([][]byte, len(pending))
+	for i, ota := range pending {
+		otaWanAddrs[i] = ota.wanAddr
+	}
+	owned, err := s.check(account, otaWanAddrs)
+	if err != nil {
+		// the account is locked, check again at next block
+		log.Debug("ota scanner skipped account", "account", account, "err", err)
+		return nil, err
+	}
+
+	var ownedOTAs []storedOTA
+	var ownedWanAddrs [][]byte
+	for i, ota := range pending {
+		if owned[i] {
+			ownedOTAs = append(ownedOTAs, ota)
+			ownedWanAddrs = append(ownedWanAddrs, ota.wanAddr)
+		}
+	}
+	images, err := s.images(account, ownedWanAddrs)
+	if err != nil {
+		log.Debug("ota scanner skipped account", "account", account, "err", err)
+		return nil, err
+	}
+
+	found := make([]*OwnedOTA, len(ownedOTAs))
+	for i, ota := range ownedOTAs {
+		spent, _, _ := vm.CheckOTAImageExist(statedb, images[i])
+		found[i] = &OwnedOTA{
+			Address:     ota.wanAddr,
+			Balance:     (*hexutil.Big)(ota.balance),
+			BlockNumber: hexutil.Uint64(number),
+			KeyImage:    images[i],
+			Spent:       spent,
+		}
+		if ota.memo != nil {
+			memo, err := s.memos(account, ota.wanAddr, ota.memo)
+			if err != nil {
+				log.Debug("ota scanner failed to decrypt memo", "account", account, "err", err)
+				continue
+			}
+			found[i].Memo = string(memo)
+		}
+	}
+	return found, nil
+}
+
+func copyOTAs(otas []*OwnedOTA) []*OwnedOTA {
+	cpy := make([]*OwnedOTA, len(otas))
+	for i, ota := range otas {
+		o := *ota
+		cpy[i] = &o
+	}
+	return cpy
+}
+
+// PrivateOTAScannerAPI provides an API to find the otas sent to the accounts of the node.
+type PrivateOTAScannerAPI struct {
+	scanner *OTAScanner
+}
+
+// NewPrivateOTAScannerAPI creates a new ota scanner API.
+func NewPrivateOTAScannerAPI(scanner *OTAScanner) *PrivateOTAScannerAPI {
+	return &PrivateOTAScannerAPI{scanner}
+}
+
+// StartOTAScan starts finding the otas sent to the unlocked account, in the head
+// state at once and in every new block after.
+func (api *PrivateOTAScannerAPI) StartOTAScan(address common.Address) error {
+	return api.scanner.Add(address)
+}
+
+// StopOTAScan stops finding the otas of the account, returns false if it is not scanned.
+func (api *PrivateOTAScannerAPI) StopOTAScan(address common.Address) bool {
+	return api.scanner.Remove(address)
+}
+
+// GetScannedOTAs returns the unspent otas found for the account.
+func (api *PrivateOTAScannerAPI) GetScannedOTAs(address common.Address) ([]*OwnedOTA, error) {
+	return api.scanner.OTAs(address)
+}
+
+// GetScannedOTABalance returns the total balance of the unspent otas found for the account.
+func (api *PrivateOTAScannerAPI) GetScannedOTABalance(address common.Address) (*hexutil.Big, error) {
+	otas, err := api.scanner.OTAs(address)
+	if err != nil {
+		return nil, err
+	}
+
+	total := new(big.Int)
+	for _, ota := range otas {
+		total.Add(total, ota.Balance.ToInt())
+	}
+	return (*hexutil.Big)(total), nil
+}
+
+// ScannedOTAs sends a notification each time otas of the account are found or spent in a new block.
+func (api *PrivateOTAScannerAPI) ScannedOTAs(ctx context.Context, address common.Address) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan OTAScanEvent)
+		eventsSub := api.scanner.SubscribeScanEvent(events)
+
+		for {
+			select {
+			case ev := <-events:
+				if ev.Account == address {
+					notifier.Notify(rpcSub.ID, ev)
+				}
+			case <-rpcSub.Err():
+				eventsSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				eventsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
@@ -0,0 +1,281 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wanchain/go-wanchain/accounts/abi"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+)
+
+func testOTA(b byte) []byte {
+	ota := make([]byte, common.WAddressLength)
+	ota[0] = 2
+	ota[1] = b
+	return ota
+}
+
+var testOTAKey, _ = crypto.GenerateKey()
+
+func testOTAImage(ota []byte) []byte {
+	return crypto.FromECDSAPub(crypto.ComputeKeyImage(big.NewInt(int64(ota[1])), &testOTAKey.PublicKey))
+}
+
+// testRefundTx returns a refundCoin transaction spending ota, the ring signature is not valid
+func testRefundTx(t *testing.T, ota []byte) *types.Transaction {
+	keyImage := crypto.ComputeKeyImage(big.NewInt(int64(ota[1])), &testOTAKey.PublicKey)
+	ringSigned, _ := encodeRingSignOut([]*ecdsa.PublicKey{&testOTAKey.PublicKey}, keyImage, []*big.Int{big.NewInt(1)}, []*big.Int{big.NewInt(1)})
+
+	coinAbi, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"refundCoin","inputs":[{"name":"RingSignedData","type":"string"},{"name":"Value","type":"uint256"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := coinAbi.Pack("refundCoin", ringSigned, big.NewInt(int64(ota[1])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return types.NewTransaction(0, common.BytesToAddress([]byte{100}), big.NewInt(0), big.NewInt(100000), big.NewInt(1), data)
+}
+
+func testBlock(number int64, txs ...*types.Transaction) *types.Block {
+	return types.NewBlock(&types.Header{Number: big.NewInt(number)}, txs, nil, nil)
+}
+
+// testBuyTx returns a buyCoinNote transaction of ota
+func testBuyTx(t *testing.T, ota []byte) *types.Transaction {
+	coinAbi, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"buyCoinNote","inputs":[{"name":"OtaAddr","type":"string"},{"name":"Value","type":"uint256"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := coinAbi.Pack("buyCoinNote", hexutil.Encode(ota), big.NewInt(int64(ota[1])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return types.NewTransaction(0, common.BytesToAddress([]byte{100}), big.NewInt(int64(ota[1])), big.NewInt(100000), big.NewInt(1), data)
+}
+
+func TestOTAScanner(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	account := common.HexToAddress("0x01")
+	locked := true
+	s := NewOTAScanner(nil)
+	s.check = func(addr common.Address, otaWanAddrs [][]byte) ([]bool, error) {
+		if locked {
+			return nil, keystore.ErrLocked
+		}
+		owned := make([]bool, len(otaWanAddrs))
+		for i, ota := range otaWanAddrs {
+			owned[i] = addr == account && ota[1]%2 == 1
+		}
+		return owned, nil
+	}
+	s.images = func(addr common.Address, otaWanAddrs [][]byte) ([][]byte, error) {
+		images := make([][]byte, len(otaWanAddrs))
+		for i, ota := range otaWanAddrs {
+			images[i] = testOTAImage(ota)
+		}
+		return images, nil
+	}
+	s.accounts[account] = &otaIndex{}
+
+	for i := byte(1); i <= 4; i++ {
+		if _, err := vm.AddOTAIfNotExist(statedb, big.NewInt(int64(i)), testOTA(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events := make(chan OTAScanEvent, 10)
+	sub := s.SubscribeScanEvent(events)
+	defer sub.Unsubscribe()
+
+	// otas stored while the account is locked are checked after it is unlocked
+	s.scan(statedb, 1, nil)
+	if otas, _ := s.OTAs(account); len(otas) != 0 {
+		t.Fatal("locked account should not be scanned", len(otas))
+	}
+	locked = false
+	vm.AddOTAIfNotExist(statedb, big.NewInt(5), testOTA(5))
+	s.scan(statedb, 2, nil)
+
+	otas, err := s.OTAs(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(otas) != 3 {
+		t.Fatal("owned otas mismatch", len(otas))
+	}
+	total := new(big.Int)
+	for _, ota := range otas {
+		if ota.Address[1]%2 != 1 || ota.Balance.ToInt().Int64() != int64(ota.Address[1]) || ota.BlockNumber != 2 {
+			t.Fatal("owned ota mismatch", ota.Address, ota.Balance, ota.BlockNumber)
+		}
+		total.Add(total, ota.Balance.ToInt())
+	}
+	if total.Int64() != 9 {
+		t.Fatal("owned balance mismatch", total)
+	}
+	if ev := <-events; ev.Account != account || len(ev.OTAs) != 3 || ev.BlockNumber != 2 {
+		t.Fatal("scan event mismatch", ev)
+	}
+
+	// an ota is indexed once
+	vm.AddOTAIfNotExist(statedb, big.NewInt(7), testOTA(7))
+	s.scan(statedb, 3, nil)
+	ev := <-events
+	if len(ev.OTAs) != 1 || !bytes.Equal(ev.OTAs[0].Address, testOTA(7)) {
+		t.Fatal("only new ota should be found", ev)
+	}
+	if otas, _ := s.OTAs(account); len(otas) != 4 {
+		t.Fatal("owned otas mismatch", len(otas))
+	}
+
+	// ota 3 is refunded, ota 5 is spent before the block scanned
+	vm.AddOTAImage(statedb, testOTAImage(testOTA(3)), big.NewInt(3).Bytes())
+	vm.AddOTAImage(statedb, testOTAImage(testOTA(5)), big.NewInt(5).Bytes())
+	refund := testRefundTx(t, testOTA(3))
+	s.scan(statedb, 4, types.Blocks{testBlock(4, refund)})
+	ev = <-events
+	if len(ev.OTAs) != 0 || len(ev.Spent) != 2 {
+		t.Fatal("spent otas should be notified", ev)
+	}
+	if otas, _ := s.OTAs(account); len(otas) != 2 {
+		t.Fatal("spent otas should not be listed", len(otas))
+	}
+	all, _ := s.AllOTAs(account)
+	for _, ota := range all {
+		switch ota.Address[1] {
+		case 3:
+			if !ota.Spent || ota.RefundTx == nil || *ota.RefundTx != refund.Hash() {
+				t.Fatal("ota should be refunded", ota.Spent, ota.RefundTx)
+			}
+		case 5:
+			if !ota.Spent || ota.RefundTx != nil {
+				t.Fatal("ota should be spent without refund transaction", ota.Spent, ota.RefundTx)
+			}
+		default:
+			if ota.Spent {
+				t.Fatal("ota should be unspent", ota.Address)
+			}
+		}
+	}
+
+	// the otas spent by the accounts are known to the wallet mix sets
+	spent := s.Spent()
+	if !spent(testOTA(3)) || !spent(testOTA(5)) || spent(testOTA(1)) {
+		t.Fatal("spent otas mismatch")
+	}
+
+	if !s.Remove(account) {
+		t.Fatal("account should be removed")
+	}
+	if _, err := s.OTAs(account); err != errOTANotScanned {
+		t.Fatal("removed account should not be scanned", err)
+	}
+}
+
+func TestOTAScannerUnlocked(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	account := common.HexToAddress("0x01")
+	s := NewOTAScanner(nil)
+	s.accounts[account] = &otaIndex{}
+
+	// the queries are served while the keystore checks the otas
+	s.check = func(addr common.Address, otaWanAddrs [][]byte) ([]bool, error) {
+		done := make(chan struct{})
+		go func() {
+			s.OTAs(account)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Error("scanner is locked while checking the otas")
+		}
+		return make([]bool, len(otaWanAddrs)), nil
+	}
+	s.images = func(addr common.Address, otaWanAddrs [][]byte) ([][]byte, error) {
+		return make([][]byte, len(otaWanAddrs)), nil
+	}
+
+	vm.AddOTAIfNotExist(statedb, big.NewInt(1), testOTA(1))
+	s.scan(statedb, 1, nil)
+	if s.accounts[account].next != 1 {
+		t.Fatal("stored otas should be checked", s.accounts[account].next)
+	}
+}
+
+func TestOTAScannerMemo(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	wanAddr := append(keystore.ECDSAPKCompression(&keyA.PublicKey), keystore.ECDSAPKCompression(&keyB.PublicKey)...)
+	ota, err := (&PublicTransactionPoolAPI{}).GenerateOneTimeAddressWithMemo(context.Background(), wanAddr, "invoice 42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account := common.HexToAddress("0x01")
+	s := NewOTAScanner(nil)
+	s.check = func(addr common.Address, otaWanAddrs [][]byte) ([]bool, error) {
+		owned := make([]bool, len(otaWanAddrs))
+		for i := range owned {
+			owned[i] = true
+		}
+		return owned, nil
+	}
+	s.images = func(addr common.Address, otaWanAddrs [][]byte) ([][]byte, error) {
+		return make([][]byte, len(otaWanAddrs)), nil
+	}
+	s.memos = func(addr common.Address, otaWanAddr []byte, encMemo []byte) ([]byte, error) {
+		A1, R, err := keystore.GeneratePKPairFromWAddress(otaWanAddr)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.DecryptOTAMemo(keyB, A1, R, encMemo)
+	}
+	s.accounts[account] = &otaIndex{}
+
+	coinAbi, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"buyCoinNoteWithMemo","inputs":[{"name":"OtaAddr","type":"string"},{"name":"Value","type":"uint256"},{"name":"Memo","type":"bytes"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := coinAbi.Pack("buyCoinNoteWithMemo", hexutil.Encode(ota.Address), big.NewInt(10), []byte(ota.Memo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buy := types.NewTransaction(0, common.BytesToAddress([]byte{100}), big.NewInt(10), big.NewInt(100000), big.NewInt(1), data)
+
+	vm.AddOTAIfNotExist(statedb, big.NewInt(10), ota.Address)
+	vm.AddOTAIfNotExist(statedb, big.NewInt(20), testOTA(1))
+	s.scan(statedb, 1, types.Blocks{testBlock(1, buy)})
+
+	otas, _ := s.OTAs(account)
+	if len(otas) != 2 {
+		t.Fatal("owned otas mismatch", len(otas))
+	}
+	for _, o := range otas {
+		if bytes.Equal(o.Address, ota.Address) != (o.Memo == "invoice 42") {
+			t.Fatal("ota memo mismatch", o.Address, o.Memo)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/wanchain/go-wanchain/accounts/abi"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+// privacyTokenAbiDefinition is the ota transfer of the privacy token contracts
+const privacyTokenAbiDefinition = `[{"constant":false,"type":"function","inputs":[{"name":"_to","type":"address"},{"name":"_toKey","type":"bytes"},{"name":"_value","type":"uint256"}],"name":"otatransfer","outputs":[{"name":"","type":"string"}]}]`
+
+var privacyTokenAbi, _ = abi.JSON(strings.NewReader(privacyTokenAbiDefinition))
+
+var (
+	ErrPaymentNotToken = errors.New("privacy payment request should be of a privacy token, pay a wan payment request with payWanPaymentRequest")
+	ErrPaymentNotWan   = errors.New("wan payment request should be of wan coin")
+	ErrPaymentMemo     = errors.New("memo of a privacy token payment request can't be sent with the transfer")
+)
+
+// PaymentRequestArgs is a payment request to the one-time addresses of a WanAddress,
+// Token is nil if the payment is of WAN coin.
+type PaymentRequestArgs struct {
+	WAddress hexutil.Bytes   `json:"wanAddress"`
+	Amount   *hexutil.Big    `json:"amount"`
+	Token    *common.Address `json:"token,omitempty"`
+	Memo     string          `json:"memo,omitempty"`
+}
+
+// EncodePaymentRequest returns the wan URI of the payment request
+func (s *PublicTransactionPoolAPI) EncodePaymentRequest(ctx context.Context, args PaymentRequestArgs) (string, error) {
+	if len(args.WAddress) != common.WAddressLength {
+		return "", ErrInvalidWAddress
+	}
+	if _, _, err := keystore.GeneratePKPairFromWAddress(args.WAddress); err != nil {
+		return "", ErrFailToGeneratePKPairFromWAddress
+	}
+
+	r := &common.PaymentRequest{Amount: args.Amount.ToInt(), Token: args.Token, Memo: args.Memo}
+	copy(r.WAddress[:], args.WAddress)
+	return r.URI()
+}
+
+// DecodePaymentRequest verifies and decodes the wan URI of a payment request
+func (s *PublicTransactionPoolAPI) DecodePaymentRequest(ctx context.Context, uri string) (*PaymentRequestArgs, error) {
+	r, err := parsePaymentRequest(uri)
+	if err != nil {
+		return nil, err
+	}
+	return &PaymentRequestArgs{
+		WAddress: r.WAddress[:],
+		Amount:   (*hexutil.Big)(r.Amount),
+		Token:    r.Token,
+		Memo:     r.Memo,
+	}, nil
+}
+
+func parsePaymentRequest(uri string) (*common.PaymentRequest, error) {
+	r, err := common.ParsePaymentRequest(uri)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := keystore.GeneratePKPairFromWAddress(r.WAddress[:]); err != nil {
+		return nil, ErrFailToGeneratePKPairFromWAddress
+	}
+	return r, nil
+}
+
+// PayPaymentRequest pays the privacy token payment request of the wan URI with a
+// privacy transaction. The tokens are transferred from the OTA of holderKey to a new
+// One-Time-Address of the requested WanAddress, the gas is paid by the stamp of
+// stampKey ring signed with the "+" joined mixWanAdresses.
+//
+// The ota transfer of the privacy tokens has no memo, so a request with a memo is
+// rejected rather than paid without it. The requests of wan coin are paid by
+// PayWanPaymentRequest.
+func (s *PrivateAccountAPI) PayPaymentRequest(ctx context.Context, uri string, holderKey string, stampKey string, mixWanAdresses string) (common.Hash, error) {
+	r, err := parsePaymentRequest(uri)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if r.Token == nil {
+		return common.Hash{}, ErrPaymentNotToken
+	}
+	if r.Memo != "" {
+		return common.Hash{}, ErrPaymentMemo
+	}
+
+	callData, err := paymentRequestCallData(r)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	if !hexutil.Has0xPrefix(holderKey) || !hexutil.Has0xPrefix(stampKey) {
+		return common.Hash{}, ErrInvalidPrivateKey
+	}
+	holder, err := crypto.HexToECDSA(holderKey[2:])
+	if err != nil {
+		return common.Hash{}, err
+	}
+	stamp, err := crypto.HexToECDSA(stampKey[2:])
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	if len(mixWanAdresses) == 0 {
+		return common.Hash{}, ErrInvalidOTAMixSet
+	}
+	wanAddresses := strings.Split(mixWanAdresses, "+")
+
+	// the stamp signs the address of the holder sending the privacy transaction
+	from := crypto.PubkeyToAddress(holder.PublicKey)
+	ringSigned, err := genRingSignData(from.Bytes(), stamp.D.Bytes(), &stamp.PublicKey, wanAddresses)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	data, err := core.TokenAbi.Pack("combine", ringSigned, callData)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	args := SendTxArgs{From: from, To: r.Token, Data: data}
+	return s.SendPrivacyCxtTransaction(ctx, args, holderKey)
+}
+
+// PayWanPaymentRequest pays the wan coin payment request of the wan URI from the
+// account from unlocked by passwd. The coins are bought to a new One-Time-Address of
+// the requested WanAddress with buyCoinNoteWithMemo, which carries the memo of the
+// request encrypted to the receiver. The amount should be a coin note denomination.
+func (s *PrivateAccountAPI) PayWanPaymentRequest(ctx context.Context, uri string, from common.Address, passwd string) (common.Hash, error) {
+	r, err := parsePaymentRequest(uri)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if r.Token != nil {
+		return common.Hash{}, ErrPaymentNotWan
+	}
+
+	data, err := wanPaymentRequestCallData(r)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	// the coin contract takes the gas of storing the ota and its balance
+	to := vm.WanCoinPrecompileAddr
+	gas := new(big.Int).Add(core.IntrinsicGas(data, &to, true), new(big.Int).SetUint64(2*params.SstoreSetGas))
+	args := SendTxArgs{From: from, To: &to, Gas: (*hexutil.Big)(gas), Value: (*hexutil.Big)(r.Amount), Data: data}
+	return s.SendTransaction(ctx, args, passwd)
+}
+
+// wanPaymentRequestCallData returns the call data of the coin contract which buys the
+// requested amount to a new One-Time-Address of the requested WanAddress with the memo
+func wanPaymentRequestCallData(r *common.PaymentRequest) ([]byte, error) {
+	ota, err := generateOneTimeAddressWithMemo(r.WAddress[:], r.Memo)
+	if err != nil {
+		return nil, err
+	}
+	return vm.PackBuyCoinNoteWithMemo(hexutil.Encode(ota.Address), r.Amount, []byte(ota.Memo))
+}
+
+// paymentRequestCallData returns the call data of the privacy token contract which
+// transfers the requested amount to a new One-Time-Address of the requested WanAddress
+func paymentRequestCallData(r *common.PaymentRequest) ([]byte, error) {
+	ota, err := generateOneTimeAddress(r.WAddress[:])
+	if err != nil {
+		return nil, err
+	}
+	otaPub, _, err := keystore.GeneratePKPairFromWAddress(ota)
+	if err != nil {
+		return nil, ErrInvalidOTAAddr
+	}
+	return privacyTokenAbi.Pack("otatransfer", crypto.PubkeyToAddress(*otaPub), ota, r.Amount)
+}
@@ -0,0 +1,130 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+func TestPaymentRequest(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	wanAddr := append(keystore.ECDSAPKCompression(&keyA.PublicKey), keystore.ECDSAPKCompression(&keyB.PublicKey)...)
+	token := common.HexToAddress("0xa6000c50b8ccf77702c7fde117b02f79f9e1989e")
+
+	api := &PublicTransactionPoolAPI{}
+	uri, err := api.EncodePaymentRequest(context.Background(), PaymentRequestArgs{
+		WAddress: wanAddr,
+		Amount:   (*hexutil.Big)(big.NewInt(888)),
+		Token:    &token,
+		Memo:     "invoice 42",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.EncodePaymentRequest(context.Background(), PaymentRequestArgs{WAddress: make([]byte, common.WAddressLength), Amount: (*hexutil.Big)(big.NewInt(1))}); err != ErrFailToGeneratePKPairFromWAddress {
+		t.Fatal("invalid wan address should fail", err)
+	}
+
+	args, err := api.DecodePaymentRequest(context.Background(), uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(args.WAddress, wanAddr) || args.Amount.ToInt().Int64() != 888 || *args.Token != token || args.Memo != "invoice 42" {
+		t.Fatal("decoded payment request mismatch", args)
+	}
+
+	// the tokens are transferred to a new ota of the wan address
+	r, _ := parsePaymentRequest(uri)
+	callData, err := paymentRequestCallData(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// otatransfer(address _to, bytes _toKey, uint256 _value), _toKey is after the head
+	to := common.BytesToAddress(callData[4:36])
+	value := new(big.Int).SetBytes(callData[68:100])
+	toKey := callData[4+32*4 : 4+32*4+common.WAddressLength]
+	otaPub, _, err := keystore.GeneratePKPairFromWAddress(toKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to != crypto.PubkeyToAddress(*otaPub) || value.Int64() != 888 {
+		t.Fatal("otatransfer mismatch", to, value)
+	}
+}
+
+func TestWanPaymentRequest(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	wanAddr := append(keystore.ECDSAPKCompression(&keyA.PublicKey), keystore.ECDSAPKCompression(&keyB.PublicKey)...)
+	token := common.HexToAddress("0xa6000c50b8ccf77702c7fde117b02f79f9e1989e")
+	amount, _ := new(big.Int).SetString(vm.Wancoin10, 10)
+
+	api := &PublicTransactionPoolAPI{}
+	uri, err := api.EncodePaymentRequest(context.Background(), PaymentRequestArgs{WAddress: wanAddr, Amount: (*hexutil.Big)(amount), Memo: "invoice 42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenURI, err := api.EncodePaymentRequest(context.Background(), PaymentRequestArgs{WAddress: wanAddr, Amount: (*hexutil.Big)(amount), Token: &token, Memo: "invoice 42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the denomination and the memo select how a request can be paid
+	private := &PrivateAccountAPI{}
+	if _, err := private.PayPaymentRequest(context.Background(), uri, "0x01", "0x01", "0x01"); err != ErrPaymentNotToken {
+		t.Fatal("wan payment request should not be paid with privacy tokens", err)
+	}
+	if _, err := private.PayPaymentRequest(context.Background(), tokenURI, "0x01", "0x01", "0x01"); err != ErrPaymentMemo {
+		t.Fatal("privacy token payment request with a memo should be rejected", err)
+	}
+	if _, err := private.PayWanPaymentRequest(context.Background(), tokenURI, common.Address{}, ""); err != ErrPaymentNotWan {
+		t.Fatal("privacy token payment request should not be paid with wan", err)
+	}
+
+	// the coins are bought to a new ota of the wan address with the memo encrypted to it
+	r, _ := parsePaymentRequest(uri)
+	data, err := wanPaymentRequestCallData(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := types.NewTransaction(0, vm.WanCoinPrecompileAddr, amount, big.NewInt(100000), big.NewInt(1), data)
+	ota, encMemo, err := vm.CoinNoteMemo(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	A1, R, err := keystore.GeneratePKPairFromWAddress(ota)
+	if err != nil {
+		t.Fatal(err)
+	}
+	memo, err := crypto.DecryptOTAMemo(keyB, A1, R, encMemo)
+	if err != nil || string(memo) != "invoice 42" {
+		t.Fatal("payment memo mismatch", err, string(memo))
+	}
+
+	// the longest memo of a request fits the coin note once encrypted
+	if common.MaxPaymentMemoLength != params.MaxOTAMemoSize-crypto.OTAMemoOverhead {
+		t.Fatal("max payment memo length mismatch", common.MaxPaymentMemoLength)
+	}
+	r.Memo = strings.Repeat("m", common.MaxPaymentMemoLength)
+	data, err = wanPaymentRequestCallData(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx = types.NewTransaction(0, vm.WanCoinPrecompileAddr, amount, big.NewInt(100000), big.NewInt(1), data)
+	if _, encMemo, err = vm.CoinNoteMemo(tx); err != nil || uint64(len(encMemo)) != params.MaxOTAMemoSize {
+		t.Fatal("encrypted max memo size mismatch", err, len(encMemo))
+	}
+}
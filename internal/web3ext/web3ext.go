@@ -161,6 +161,16 @@ web3._extend({
 			call: 'pos_random',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'getRandomBeaconData',
+			call: 'pos_getRandomBeaconData',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'verifyRandom',
+			call: 'pos_verifyRandom',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'getSijCount',
 			call: 'pos_getSijCount',
@@ -241,6 +251,11 @@ web3._extend({
 			call: 'pos_getStakerInfo',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getStakerProof',
+			call: 'pos_getStakerProof',
+			params: 2
+		}),
 		new web3._extend.Method({
 			name: 'getRBAddress',
 			call: 'pos_getRBAddress',
@@ -257,6 +272,16 @@ web3._extend({
 			call: 'pos_getActivity',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getValidatorSchedule',
+			call: 'pos_getValidatorSchedule',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getValidatorPerformance',
+			call: 'pos_getValidatorPerformance',
+			params: 3
+		}),
 		new web3._extend.Method({
 			name: 'getEpochID',
 			call: 'pos_getEpochID',
@@ -41,6 +41,7 @@ import (
 	"github.com/wanchain/go-wanchain/p2p"
 	"github.com/wanchain/go-wanchain/p2p/discv5"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
 	rpc "github.com/wanchain/go-wanchain/rpc"
 	"math/big"
 )
@@ -87,6 +88,9 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightEthereum, error) {
 		return nil, genesisErr
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
+	if err := posconfig.InitParams(chainConfig.PosParams()); err != nil {
+		return nil, err
+	}
 
 	peers := newPeerSet()
 	quitSync := make(chan struct{})
@@ -2,10 +2,6 @@ package miner
 
 import (
 	"encoding/hex"
-	"fmt"
-	"github.com/wanchain/go-wanchain/accounts"
-	"github.com/wanchain/go-wanchain/accounts/keystore"
-	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/log"
@@ -16,7 +12,6 @@ import (
 	"github.com/wanchain/go-wanchain/pos/randombeacon"
 	"github.com/wanchain/go-wanchain/pos/slotleader"
 	"github.com/wanchain/go-wanchain/pos/util"
-	"github.com/wanchain/go-wanchain/rpc"
 	"time"
 )
 
@@ -59,13 +54,9 @@ func PosInit(s Backend) *epochLeader.Epocher {
 
 	return epochSelector
 }
-func posInitMiner(s Backend, key *keystore.Key) {
+func posInitMiner(s Backend) {
 	log.Debug("posInitMiner is running")
 
-	// config
-	if key != nil {
-		posconfig.Cfg().MinerKey = key
-	}
 	epochSelector := epochLeader.NewEpocher(s.BlockChain())
 	randombeacon.GetRandonBeaconInst().Init(epochSelector)
 	if posconfig.EpochBaseTime == 0 {
@@ -80,32 +71,17 @@ func posInitMiner(s Backend, key *keystore.Key) {
 // backendTimerLoop is pos main time loop
 func (self *Miner) backendTimerLoop(s Backend) {
 	log.Debug("backendTimerLoop is running")
-	// get wallet
-	eb, errb := s.Etherbase()
-	if errb != nil {
-		panic(errb)
-	}
-	wallet, errf := s.AccountManager().Find(accounts.Account{Address: eb})
-	if wallet == nil || errf != nil {
-		panic(errf)
-	}
-	type getKey interface {
-		GetUnlockedKey(address common.Address) (*keystore.Key, error)
-	}
-	key, err := wallet.(getKey).GetUnlockedKey(eb)
-	if key == nil || err != nil {
-		panic(err)
-	}
-	log.Debug("Get unlocked key success address:" + eb.Hex())
-	localPublicKey := hex.EncodeToString(crypto.FromECDSAPub(&key.PrivateKey.PublicKey))
-	posInitMiner(s, key)
-	// get rpcClient
-	url := posconfig.Cfg().NodeCfg.IPCEndpoint()
-	rc, err := rpc.Dial(url)
-	if err != nil {
-		fmt.Println("err:", err)
-		panic(err)
+	// the validator signer is set up by the backend before the miner is started
+	valSigner := posconfig.Cfg().MinerSigner
+	if valSigner == nil {
+		panic("validator signer is missing")
 	}
+	log.Debug("Get validator signer success address:" + valSigner.Address().Hex())
+	localPublicKey := hex.EncodeToString(crypto.FromECDSAPub(valSigner.PublicKey()))
+	posInitMiner(s)
+	clock := util.GetClock()
+	// the protocol transactions are submitted to the tx pool in process
+	sender := NewProtocolTxSender(s, valSigner)
 
 	//todo:`switch pos from pow,the time is not 1?
 	h := s.BlockChain().GetHeaderByNumber(1)
@@ -129,16 +105,16 @@ func (self *Miner) backendTimerLoop(s Backend) {
 			case <-self.timerStop:
 				randombeacon.GetRandonBeaconInst().Stop()
 				return
-			case <-time.After(time.Duration(time.Second)):
+			case <-clock.After(time.Duration(time.Second)):
 				continue
 			}
 			//todo,this is unnessessary?
 			continue
 		} else {
 			posconfig.EpochBaseTime = h.Time.Uint64()
-			cur := uint64(time.Now().Unix())
+			cur := uint64(clock.Now().Unix())
 			if cur < posconfig.EpochBaseTime+posconfig.SlotTime {
-				time.Sleep(time.Duration((posconfig.EpochBaseTime + posconfig.SlotTime - cur)) * time.Second)
+				clock.Sleep(time.Duration((posconfig.EpochBaseTime + posconfig.SlotTime - cur)) * time.Second)
 			}
 		}
 
@@ -146,7 +122,7 @@ func (self *Miner) backendTimerLoop(s Backend) {
 		epochid, slotid := util.GetEpochSlotID()
 		log.Debug("get current period", "epochid", epochid, "slotid", slotid)
 
-		slotleader.GetSlotLeaderSelection().Loop(rc, key, epochid, slotid)
+		slotleader.GetSlotLeaderSelection().Loop(sender, valSigner, epochid, slotid)
 
 		leaderPub, err := slotleader.GetSlotLeaderSelection().GetSlotLeader(epochid, slotid)
 		if err == nil {
@@ -160,12 +136,12 @@ func (self *Miner) backendTimerLoop(s Backend) {
 		stateDb, err := s.BlockChain().State()
 		if err == nil {
 			// random beacon loop
-			randombeacon.GetRandonBeaconInst().Loop(stateDb, rc, epochid, slotid)
+			randombeacon.GetRandonBeaconInst().Loop(stateDb, sender, epochid, slotid)
 		} else {
 			log.SyslogErr("Failed to get stateDb", "err", err)
 		}
 
-		cur := uint64(time.Now().Unix())
+		cur := uint64(clock.Now().Unix())
 		sleepTime := posconfig.SlotTime - (cur - posconfig.EpochBaseTime - (epochid*posconfig.SlotCount+slotid)*posconfig.SlotTime)
 		log.Debug("timeloop sleep", "sleepTime", sleepTime)
 		if sleepTime < 0 {
@@ -175,7 +151,7 @@ func (self *Miner) backendTimerLoop(s Backend) {
 		case <-self.timerStop:
 			randombeacon.GetRandonBeaconInst().Stop()
 			return
-		case <-time.After(time.Duration(time.Second * time.Duration(sleepTime))):
+		case <-clock.After(time.Duration(time.Second * time.Duration(sleepTime))):
 			continue
 		}
 	}
@@ -0,0 +1,105 @@
+package miner
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/possigner"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+// protocolTxRetries is how many times a protocol transaction is resubmitted with
+// a fresh nonce once its nonce is taken by another transaction of the sender
+const protocolTxRetries = 3
+
+var errProtocolTxSender = errors.New("protocol transaction is not of the validator")
+
+// gasPriceSuggester is implemented by the backends with a gas price oracle
+type gasPriceSuggester interface {
+	SuggestPrice(ctx context.Context) (*big.Int, error)
+}
+
+// poolTxSender is the ProtocolTxSender adding the transactions to the tx pool of
+// the node, they are signed by the validator signer like the other pos messages.
+type poolTxSender struct {
+	signer  possigner.Signer
+	pool    *core.TxPool
+	chainId *big.Int
+	gpo     gasPriceSuggester // nil if the backend has no gas price oracle
+
+	mu sync.Mutex // serialises the nonce assignment of the protocol transactions
+}
+
+// NewProtocolTxSender creates the ProtocolTxSender submitting to the tx pool of the
+// backend in process the transactions signed by valSigner
+func NewProtocolTxSender(s Backend, valSigner possigner.Signer) util.ProtocolTxSender {
+	gpo, _ := s.(gasPriceSuggester)
+	return &poolTxSender{
+		signer:  valSigner,
+		pool:    s.TxPool(),
+		chainId: s.BlockChain().Config().ChainId,
+		gpo:     gpo,
+	}
+}
+
+// gasPrice returns the price suggested by the gas price oracle like the transactions
+// sent through the rpc, or else the minimal price of the tx pool. It is capped by
+// the highest price the validator signer signs.
+func (p *poolTxSender) gasPrice() *big.Int {
+	price := p.pool.GasPrice()
+	if p.gpo != nil {
+		suggested, err := p.gpo.SuggestPrice(context.Background())
+		if err == nil {
+			price = suggested
+		} else {
+			log.Warn("Failed to suggest gas price of pos protocol transaction", "err", err)
+		}
+	}
+	if price.Cmp(possigner.MaxProtocolTxGasPrice) > 0 {
+		price = new(big.Int).Set(possigner.MaxProtocolTxGasPrice)
+	}
+	return price
+}
+
+func (p *poolTxSender) SendProtocolTx(from common.Address, to common.Address, data []byte) (common.Hash, error) {
+	if from != p.signer.Address() {
+		return common.Hash{}, errProtocolTxSender
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	gas := core.IntrinsicGas(data, &to, true)
+	gasPrice := p.gasPrice()
+	var err error
+	for i := 0; i <= protocolTxRetries; i++ {
+		nonce := p.pool.State().GetNonce(from)
+		tx := types.NewTransaction(nonce, to, new(big.Int), gas, gasPrice, data)
+		tx.SetTxtype(types.POS_TX)
+
+		var signed *types.Transaction
+		signed, err = p.signer.SignTx(tx, p.chainId)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if err = p.pool.AddLocal(signed); err == nil {
+			log.Info("Submitted pos protocol transaction", "fullhash", signed.Hash().Hex(), "recipient", to, "nonce", nonce)
+			return signed.Hash(), nil
+		}
+
+		// the nonce is taken by a transaction sent meanwhile, retry with the next one
+		if err != core.ErrNonceTooLow && err != core.ErrReplaceUnderpriced {
+			break
+		}
+		log.Warn("Retry pos protocol transaction", "nonce", nonce, "err", err)
+	}
+
+	log.Error("Failed to submit pos protocol transaction", "recipient", to, "err", err)
+	return common.Hash{}, err
+}
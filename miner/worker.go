@@ -35,6 +35,7 @@ import (
 	"github.com/wanchain/go-wanchain/event"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/util"
 	set "gopkg.in/fatih/set.v0"
 )
 
@@ -412,7 +413,8 @@ func (self *worker) commitNewWork(isPos bool) {
 	self.currentMu.Lock()
 	defer self.currentMu.Unlock()
 
-	tstart := time.Now()
+	clock := util.GetClock()
+	tstart := clock.Now()
 	parent := self.chain.CurrentBlock()
 
 	tstamp := tstart.Unix()
@@ -420,10 +422,10 @@ func (self *worker) commitNewWork(isPos bool) {
 		tstamp = parent.Time().Int64() + 1
 	}
 	// this will ensure we're not going off too far in the future
-	if now := time.Now().Unix(); tstamp > now+1 {
+	if now := clock.Now().Unix(); tstamp > now+1 {
 		wait := time.Duration(tstamp-now) * time.Second
 		log.Info("Mining too far in the future", "wait", common.PrettyDuration(wait))
-		time.Sleep(wait)
+		clock.Sleep(wait)
 	}
 
 	num := parent.Number()
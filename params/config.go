@@ -118,12 +118,19 @@ var (
 	// means that all fields must be set at all times. This forces
 	// anyone adding flags to the config to also have to set these
 	// fields.
-	AllProtocolChanges = &ChainConfig{big.NewInt(1337) /* big.NewInt(0),*/ /*nil, false,*/ /* big.NewInt(0), common.Hash{},*/ /*big.NewInt(0),*/ /*big.NewInt(0),*/, big.NewInt(0), new(EthashConfig), nil, nil}
+	AllProtocolChanges = &ChainConfig{big.NewInt(1337) /* big.NewInt(0),*/ /*nil, false,*/ /* big.NewInt(0), common.Hash{},*/ /*big.NewInt(0),*/ /*big.NewInt(0),*/, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), new(EthashConfig), nil, nil, nil}
 
 	TestChainConfig = &ChainConfig{
-		ChainId:        big.NewInt(1),
-		ByzantiumBlock: big.NewInt(0),
-		Ethash:         new(EthashConfig),
+		ChainId:               big.NewInt(1),
+		ByzantiumBlock:        big.NewInt(0),
+		RingSignV2Block:       big.NewInt(0),
+		SponsoredTxBlock:      big.NewInt(0),
+		PosLogBlock:           big.NewInt(0),
+		IncentiveHistoryBlock: big.NewInt(0),
+		CoinNotesBlock:        big.NewInt(0),
+		DoubleSignBlock:       big.NewInt(0),
+		StakeOutBlock:         big.NewInt(0),
+		Ethash:                new(EthashConfig),
 	}
 
 	TestRules = TestChainConfig.Rules(new(big.Int))
@@ -151,10 +158,21 @@ type ChainConfig struct {
 
 	ByzantiumBlock *big.Int `json:"byzantiumBlock,omitempty"` // Byzantium switch block (nil = no fork, 0 = already on byzantium)
 
+	RingSignV2Block       *big.Int `json:"ringSignV2Block,omitempty"`       // Ring signature v2 encoding switch block (nil = no fork)
+	SponsoredTxBlock      *big.Int `json:"sponsoredTxBlock,omitempty"`      // Sponsored transaction switch block (nil = no fork)
+	PosLogBlock           *big.Int `json:"posLogBlock,omitempty"`           // Logs of the pos precompiled contracts switch block (nil = no fork)
+	IncentiveHistoryBlock *big.Int `json:"incentiveHistoryBlock,omitempty"` // Incentive totals in the state switch block (nil = no fork)
+	CoinNotesBlock        *big.Int `json:"coinNotesBlock,omitempty"`        // Batch and memo coin note methods switch block (nil = no fork)
+	DoubleSignBlock       *big.Int `json:"doubleSignBlock,omitempty"`       // Double sign evidences of slot leaders switch block (nil = no fork)
+	StakeOutBlock         *big.Int `json:"stakeOutBlock,omitempty"`         // Stake out methods of the pos staking contract switch block (nil = no fork)
+
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
 	Pluto  *PlutoConfig  `json:"pluto,omitempty"`
+
+	// Proof-of-stake network parameters, nil means DefaultPosConfig
+	Pos *PosConfig `json:"pos,omitempty"`
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -187,6 +205,68 @@ func (c *PlutoConfig) String() string {
 	return "pluto"
 }
 
+// PosConfig is the timing and economics of a proof-of-stake network. Every field
+// must be set, an epoch has 12*K slots and a stage of the protocols lasts K slots.
+type PosConfig struct {
+	SlotTime          uint64 `json:"slotTime"`          // Seconds of a slot
+	K                 uint64 `json:"k"`                 // Slots of a stage
+	EpochLeaderCount  uint64 `json:"epochLeaderCount"`  // Count of epoch leaders selected by stake
+	RandomProperCount uint64 `json:"randomProperCount"` // Count of random proposers selected by stake
+
+	MinLockEpochs       uint64    `json:"minLockEpochs"`       // Minimum lock epochs of a staker
+	MaxLockEpochs       uint64    `json:"maxLockEpochs"`       // Maximum lock epochs of a staker
+	LockWeightEpochs    [3]uint64 `json:"lockWeightEpochs"`    // Lock epochs the stake weight grows to 1.1, 1.3 and 1.5 times
+	MinStakeholderStake uint64    `json:"minStakeholderStake"` // Minimum stake of a staker in wan
+	MinValidatorStake   uint64    `json:"minValidatorStake"`   // Minimum stake of a staker accepting delegation in wan
+	MinDelegatorStake   uint64    `json:"minDelegatorStake"`   // Minimum stake of a delegation in wan
+
+	RBSlashEpoch        uint64 `json:"rbSlashEpoch,omitempty"`        // First epoch whose random proposers are slashed for a skipped stage (0 = no slashing)
+	RBSlashRate         uint64 `json:"rbSlashRate,omitempty"`         // Per ten thousand of the stake slashed for a skipped random beacon stage (0 = no slashing)
+	DoubleSignSlashRate uint64 `json:"doubleSignSlashRate,omitempty"` // Per ten thousand of the stake slashed for a double sign (0 = no slashing)
+}
+
+// DefaultPosConfig is the parameters of the wanchain proof-of-stake networks
+var DefaultPosConfig = &PosConfig{
+	SlotTime:          10,
+	K:                 10,
+	EpochLeaderCount:  50,
+	RandomProperCount: 25,
+
+	MinLockEpochs:       7,
+	MaxLockEpochs:       90,
+	LockWeightEpochs:    [3]uint64{15, 45, 90},
+	MinStakeholderStake: 10000,
+	MinValidatorStake:   100000,
+	MinDelegatorStake:   100,
+
+	RBSlashRate:         100,
+	DoubleSignSlashRate: 1000,
+}
+
+// String implements the stringer interface, returning the pos parameters.
+func (c *PosConfig) String() string {
+	return fmt.Sprintf("{SlotTime: %v K: %v EpochLeaders: %v RandomProposers: %v}",
+		c.SlotTime, c.K, c.EpochLeaderCount, c.RandomProperCount)
+}
+
+// compatible returns whether the blocks after genesis are valid under both c and
+// newcfg. The parameters, the slash rates too, take effect from the genesis, except
+// RBSlashEpoch which is scheduled like a fork and so can be set on a running chain
+// before it is reached.
+func (c *PosConfig) compatible(newcfg *PosConfig) bool {
+	stored, changed := *c, *newcfg
+	stored.RBSlashEpoch, changed.RBSlashEpoch = 0, 0
+	return stored == changed
+}
+
+// PosParams returns the proof-of-stake parameters of the chain
+func (c *ChainConfig) PosParams() *PosConfig {
+	if c == nil || c.Pos == nil {
+		return DefaultPosConfig
+	}
+	return c.Pos
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -201,7 +281,7 @@ func (c *ChainConfig) String() string {
 		engine = "unknown"
 	}
 	//return fmt.Sprintf("{ChainID: %v Homestead: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Engine: %v}",
-	return fmt.Sprintf("{ChainID: %v Byzantium: %v Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Byzantium: %v RingSignV2: %v SponsoredTx: %v PosLog: %v IncentiveHistory: %v CoinNotes: %v DoubleSign: %v StakeOut: %v Engine: %v}",
 		c.ChainId,
 		//c.HomesteadBlock,
 		//c.DAOForkBlock,
@@ -211,6 +291,13 @@ func (c *ChainConfig) String() string {
 		//c.EIP158Block,
 
 		c.ByzantiumBlock,
+		c.RingSignV2Block,
+		c.SponsoredTxBlock,
+		c.PosLogBlock,
+		c.IncentiveHistoryBlock,
+		c.CoinNotesBlock,
+		c.DoubleSignBlock,
+		c.StakeOutBlock,
 		engine,
 	)
 }
@@ -241,6 +328,41 @@ func (c *ChainConfig) String() string {
 //	return isForked(c.ByzantiumBlock, num)
 //}
 
+// IsRingSignV2 returns whether num is either equal to the ring signature v2 fork block or greater.
+func (c *ChainConfig) IsRingSignV2(num *big.Int) bool {
+	return isForked(c.RingSignV2Block, num)
+}
+
+// IsSponsoredTx returns whether num is either equal to the sponsored transaction fork block or greater.
+func (c *ChainConfig) IsSponsoredTx(num *big.Int) bool {
+	return isForked(c.SponsoredTxBlock, num)
+}
+
+// IsPosLog returns whether num is either equal to the pos contract logs fork block or greater.
+func (c *ChainConfig) IsPosLog(num *big.Int) bool {
+	return isForked(c.PosLogBlock, num)
+}
+
+// IsIncentiveHistory returns whether num is either equal to the incentive history fork block or greater.
+func (c *ChainConfig) IsIncentiveHistory(num *big.Int) bool {
+	return isForked(c.IncentiveHistoryBlock, num)
+}
+
+// IsCoinNotes returns whether num is either equal to the coin notes fork block or greater.
+func (c *ChainConfig) IsCoinNotes(num *big.Int) bool {
+	return isForked(c.CoinNotesBlock, num)
+}
+
+// IsDoubleSign returns whether num is either equal to the double sign fork block or greater.
+func (c *ChainConfig) IsDoubleSign(num *big.Int) bool {
+	return isForked(c.DoubleSignBlock, num)
+}
+
+// IsStakeOut returns whether num is either equal to the stake out fork block or greater.
+func (c *ChainConfig) IsStakeOut(num *big.Int) bool {
+	return isForked(c.StakeOutBlock, num)
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -314,6 +436,31 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	//	return newCompatError("Byzantium fork block", c.ByzantiumBlock, newcfg.ByzantiumBlock)
 	//}
 
+	if isForkIncompatible(c.RingSignV2Block, newcfg.RingSignV2Block, head) {
+		return newCompatError("Ring signature v2 fork block", c.RingSignV2Block, newcfg.RingSignV2Block)
+	}
+	if isForkIncompatible(c.SponsoredTxBlock, newcfg.SponsoredTxBlock, head) {
+		return newCompatError("Sponsored transaction fork block", c.SponsoredTxBlock, newcfg.SponsoredTxBlock)
+	}
+	if isForkIncompatible(c.PosLogBlock, newcfg.PosLogBlock, head) {
+		return newCompatError("Pos contract logs fork block", c.PosLogBlock, newcfg.PosLogBlock)
+	}
+	if isForkIncompatible(c.IncentiveHistoryBlock, newcfg.IncentiveHistoryBlock, head) {
+		return newCompatError("Incentive history fork block", c.IncentiveHistoryBlock, newcfg.IncentiveHistoryBlock)
+	}
+	if isForkIncompatible(c.CoinNotesBlock, newcfg.CoinNotesBlock, head) {
+		return newCompatError("Coin notes fork block", c.CoinNotesBlock, newcfg.CoinNotesBlock)
+	}
+	if isForkIncompatible(c.DoubleSignBlock, newcfg.DoubleSignBlock, head) {
+		return newCompatError("Double sign fork block", c.DoubleSignBlock, newcfg.DoubleSignBlock)
+	}
+	if isForkIncompatible(c.StakeOutBlock, newcfg.StakeOutBlock, head) {
+		return newCompatError("Stake out fork block", c.StakeOutBlock, newcfg.StakeOutBlock)
+	}
+	if head.Sign() > 0 && !c.PosParams().compatible(newcfg.PosParams()) {
+		return newCompatError("Pos parameters", common.Big0, common.Big0)
+	}
+
 	return nil
 }
 
@@ -17,6 +17,7 @@
 package params
 
 import (
+	"encoding/json"
 	"math/big"
 	"reflect"
 	"testing"
@@ -37,6 +38,40 @@ func TestCheckCompatible(t *testing.T) {
 			head:    9,
 			wantErr: nil,
 		},
+		{
+			stored:  &ChainConfig{},
+			new:     &ChainConfig{Pos: &PosConfig{SlotTime: 5, K: 2, EpochLeaderCount: 4, RandomProperCount: 3}},
+			head:    0,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{},
+			new:    &ChainConfig{Pos: &PosConfig{SlotTime: 5, K: 2, EpochLeaderCount: 4, RandomProperCount: 3}},
+			head:   100,
+			wantErr: &ConfigCompatError{
+				What:         "Pos parameters",
+				StoredConfig: big.NewInt(0),
+				NewConfig:    big.NewInt(0),
+				RewindTo:     0,
+			},
+		},
+		{
+			stored:  &ChainConfig{Pos: &PosConfig{SlotTime: 5, K: 2, EpochLeaderCount: 4, RandomProperCount: 3}},
+			new:     &ChainConfig{Pos: &PosConfig{SlotTime: 5, K: 2, EpochLeaderCount: 4, RandomProperCount: 3, RBSlashEpoch: 10}},
+			head:    100,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{Pos: &PosConfig{SlotTime: 5, K: 2, EpochLeaderCount: 4, RandomProperCount: 3, RBSlashRate: 100}},
+			new:    &ChainConfig{Pos: &PosConfig{SlotTime: 5, K: 2, EpochLeaderCount: 4, RandomProperCount: 3, RBSlashRate: 200}},
+			head:   100,
+			wantErr: &ConfigCompatError{
+				What:         "Pos parameters",
+				StoredConfig: big.NewInt(0),
+				NewConfig:    big.NewInt(0),
+				RewindTo:     0,
+			},
+		},
 		//{
 		//	stored: AllProtocolChanges,
 		//	new:    &ChainConfig{ByzantiumBlock: nil},
@@ -79,3 +114,19 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestPosParams(t *testing.T) {
+	if (*ChainConfig)(nil).PosParams() != DefaultPosConfig || TestChainConfig.PosParams() != DefaultPosConfig {
+		t.Fatal("chain without pos section should use the default parameters")
+	}
+
+	var config ChainConfig
+	data := `{"chainId": 3, "pos": {"slotTime": 5, "k": 2, "epochLeaderCount": 4, "randomProperCount": 3, "lockWeightEpochs": [1, 2, 3]}}`
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatal(err)
+	}
+	want := &PosConfig{SlotTime: 5, K: 2, EpochLeaderCount: 4, RandomProperCount: 3, LockWeightEpochs: [3]uint64{1, 2, 3}}
+	if !reflect.DeepEqual(config.PosParams(), want) {
+		t.Errorf("pos params mismatch: have %v, want %v", config.PosParams(), want)
+	}
+}
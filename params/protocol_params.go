@@ -72,6 +72,9 @@ const (
 
 	RequiredGasPerMixPub uint64 = 4000 // ring signature mix difficulty gas
 	GetOTAMixSetMaxSize  uint64 = 20   // Max number of mix ota set size from once getting
+	MaxCoinBatchSize     uint64 = 10   // Max number of coin notes bought or refunded in one transaction
+	MaxRingSignSize      uint64 = 32   // Max number of public keys in a ring signature of version 2
+	MaxOTAMemoSize       uint64 = 256  // Max size of the encrypted memo of a coin note
 
 	//SlsStgOnePerByteGas		uint64 = 20      // per byte gas for SlsStgOnePerByteGas
 	SlsStgTwoPerByteGas		uint64 = 20      // per byte gas for SlsStgOnePerByteGas
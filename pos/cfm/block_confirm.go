@@ -6,7 +6,7 @@ import (
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"time"
+	"github.com/wanchain/go-wanchain/pos/util"
 )
 
 const (
@@ -36,13 +36,18 @@ var c *CFM
 func InitCFM(bc *core.BlockChain) {
 	c = &CFM{}
 	c.bc = bc
-	c.whiteList = make(map[common.Address]int, 0)
+	c.whiteList = loadWhiteList()
+}
+
+func loadWhiteList() map[common.Address]int {
+	whiteList := make(map[common.Address]int, 0)
 	for _, value := range posconfig.WhiteList {
 
 		b := hexutil.MustDecode(value)
 		address := crypto.PubkeyToAddress(*(crypto.ToECDSAPub(b)))
-		c.whiteList[address] = 1
+		whiteList[address] = 1
 	}
+	return whiteList
 }
 
 func GetCFM() *CFM {
@@ -51,7 +56,7 @@ func GetCFM() *CFM {
 
 func (c *CFM) GetMaxStableBlkNumber() uint64 {
 
-	timeNow := uint64(time.Now().Unix())
+	timeNow := util.NowUnix()
 	blkStatusArr := c.scanAllBlockStatus(timeNow)
 	return c.getMaxStableBlkNumber(blkStatusArr)
 }
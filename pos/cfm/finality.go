@@ -0,0 +1,251 @@
+package cfm
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/event"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// FinalityGadget resolves the "safe" and "finalized" block tags of the rpc.
+// A safe block is unlikely to be reorganized, a finalized block never is.
+type FinalityGadget interface {
+	SafeBlockNumber() uint64
+	FinalizedBlockNumber() uint64
+}
+
+var (
+	gadget   FinalityGadget
+	gadgetMu sync.RWMutex
+)
+
+// SetFinalityGadget sets the gadget used to resolve the block tags, nil disables the tags
+func SetFinalityGadget(g FinalityGadget) {
+	gadgetMu.Lock()
+	defer gadgetMu.Unlock()
+	gadget = g
+}
+
+// GetFinalityGadget returns the gadget set by SetFinalityGadget, nil if there is none
+func GetFinalityGadget() FinalityGadget {
+	gadgetMu.RLock()
+	defer gadgetMu.RUnlock()
+	return gadget
+}
+
+// ErrNoFinality is returned when a block tag is asked without a gadget
+var ErrNoFinality = errors.New("safe and finalized blocks are not available")
+
+// TagBlockNumber returns the block number the safe or finalized tag points to,
+// blockNr which is not one of the tags is returned as is.
+func TagBlockNumber(blockNr rpc.BlockNumber) (rpc.BlockNumber, error) {
+	if blockNr != rpc.SafeBlockNumber && blockNr != rpc.FinalizedBlockNumber {
+		return blockNr, nil
+	}
+
+	g := GetFinalityGadget()
+	if g == nil {
+		return blockNr, ErrNoFinality
+	}
+	if blockNr == rpc.SafeBlockNumber {
+		return rpc.BlockNumber(g.SafeBlockNumber()), nil
+	}
+	return rpc.BlockNumber(g.FinalizedBlockNumber()), nil
+}
+
+// FinalityChain is the part of the chain Finality follows
+type FinalityChain interface {
+	CurrentHeader() *types.Header
+	GetHeader(hash common.Hash, number uint64) *types.Header
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+type finalityBlk struct {
+	number  uint64
+	hash    common.Hash
+	time    uint64
+	trusted bool
+}
+
+// Finality is the default FinalityGadget. It applies the confirmation rule of
+// CFM on every chain head event, but keeps the unconfirmed blocks in between so
+// that only the new blocks are read. A block once safe stays safe unless a reorg
+// replaces it, a block is finalized when it is safe and SlotSecurityParam deep.
+type Finality struct {
+	chain     FinalityChain
+	whiteList map[common.Address]int
+	depth     uint64 // blocks deeper than depth are safe
+	finDepth  uint64 // safe blocks deeper than finDepth are finalized
+	secDiff   int64
+	now       func() uint64
+
+	mu        sync.RWMutex
+	window    []finalityBlk // unconfirmed blocks ascending, the last one is the head
+	safe      uint64
+	finalized uint64
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewFinality creates a Finality following chain, Start should be called to track the head
+func NewFinality(chain FinalityChain) *Finality {
+	return &Finality{
+		chain:     chain,
+		whiteList: loadWhiteList(),
+		depth:     posconfig.K,
+		finDepth:  posconfig.SlotSecurityParam,
+		secDiff:   SecBlkDiff,
+		now:       util.NowUnix,
+		quit:      make(chan struct{}),
+	}
+}
+
+// Start loads the current head and tracks the chain head events
+func (f *Finality) Start() {
+	if head := f.chain.CurrentHeader(); head != nil {
+		f.update(head)
+	}
+
+	headCh := make(chan core.ChainHeadEvent, 16)
+	sub := f.chain.SubscribeChainHeadEvent(headCh)
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case ev := <-headCh:
+				f.update(ev.Block.Header())
+			case <-sub.Err():
+				return
+			case <-f.quit:
+				return
+			}
+		}
+	}()
+	log.Info("Finality gadget started", "safe", f.SafeBlockNumber(), "finalized", f.FinalizedBlockNumber())
+}
+
+// Stop stops tracking the chain head events
+func (f *Finality) Stop() {
+	close(f.quit)
+	f.wg.Wait()
+}
+
+// SafeBlockNumber implements FinalityGadget
+func (f *Finality) SafeBlockNumber() uint64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.safe
+}
+
+// FinalizedBlockNumber implements FinalityGadget
+func (f *Finality) FinalizedBlockNumber() uint64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.finalized
+}
+
+func (f *Finality) update(head *types.Header) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := len(f.window)
+	switch {
+	case n > 0 && f.window[n-1].hash == head.Hash():
+	case n > 0 && f.window[n-1].hash == head.ParentHash:
+		f.window = append(f.window, f.newBlk(head))
+	default:
+		f.window = f.load(head)
+	}
+	f.confirm(head.Number.Uint64(), f.now())
+}
+
+// load reads the blocks above the finalized one from head, it is used at start and on reorg
+func (f *Finality) load(head *types.Header) []finalityBlk {
+	number := head.Number.Uint64()
+	if f.finalized > number {
+		log.Warn("Finality finalized block is reorganized", "finalized", f.finalized, "head", number)
+		f.finalized = number
+	}
+	bottom := f.finalized + 1
+	if number > f.depth && number-f.depth > bottom {
+		bottom = number - f.depth
+	}
+
+	blks := []finalityBlk{f.newBlk(head)}
+	for h := head; h.Number.Uint64() > bottom; {
+		if h = f.chain.GetHeader(h.ParentHash, h.Number.Uint64()-1); h == nil {
+			break
+		}
+		blks = append(blks, f.newBlk(h))
+	}
+	for i, j := 0, len(blks)-1; i < j; i, j = i+1, j-1 {
+		blks[i], blks[j] = blks[j], blks[i]
+	}
+	return blks
+}
+
+// confirm moves the safe and finalized numbers up by the same rule as CFM.GetMaxStableBlkNumber
+func (f *Finality) confirm(head uint64, timeNow uint64) {
+	// blocks deeper than depth are safe without check
+	start := 0
+	for start < len(f.window)-1 && f.window[start].number+f.depth < head {
+		start++
+	}
+	f.window = f.window[start:]
+
+	// the first block from the bottom not stable, the head never is
+	unstable := len(f.window) - 1
+	var trusted, nonTrusted int64
+	for i := len(f.window) - 2; i >= 0; i-- {
+		if f.window[i+1].trusted {
+			trusted++
+		} else {
+			nonTrusted++
+		}
+		var slots int64
+		if timeNow > f.window[i].time {
+			slots = int64((timeNow - f.window[i].time) / posconfig.SlotTime)
+		}
+		if 2*trusted+nonTrusted-slots <= f.secDiff {
+			unstable = i
+		}
+	}
+	f.window = f.window[unstable:]
+
+	if number := f.window[0].number; number > 0 {
+		f.safe = number - 1
+	} else {
+		f.safe = 0
+	}
+
+	if head > f.finDepth {
+		fin := head - f.finDepth
+		if fin > f.safe {
+			fin = f.safe
+		}
+		if fin > f.finalized {
+			f.finalized = fin
+		}
+	}
+}
+
+func (f *Finality) newBlk(h *types.Header) finalityBlk {
+	_, trusted := f.whiteList[h.Coinbase]
+	return finalityBlk{
+		number:  h.Number.Uint64(),
+		hash:    h.Hash(),
+		time:    h.Time.Uint64(),
+		trusted: trusted,
+	}
+}
@@ -0,0 +1,171 @@
+package cfm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/event"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+type testFinalityChain struct {
+	headers map[common.Hash]*types.Header
+	head    *types.Header
+	feed    event.Feed
+}
+
+func (tc *testFinalityChain) CurrentHeader() *types.Header { return tc.head }
+
+func (tc *testFinalityChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return tc.headers[hash]
+}
+
+func (tc *testFinalityChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return tc.feed.Subscribe(ch)
+}
+
+// extend adds n headers on parent, one per slot, mined by coinbase
+func (tc *testFinalityChain) extend(parent *types.Header, n int, coinbase common.Address) []*types.Header {
+	headers := make([]*types.Header, 0, n)
+	for i := 0; i < n; i++ {
+		h := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(0).Add(parent.Number, big.NewInt(1)),
+			Time:       big.NewInt(0).Add(parent.Time, new(big.Int).SetUint64(posconfig.SlotTime)),
+			Coinbase:   coinbase,
+		}
+		tc.headers[h.Hash()] = h
+		headers = append(headers, h)
+		parent = h
+	}
+	return headers
+}
+
+func newTestFinality(depth, finDepth uint64) (*Finality, *testFinalityChain, *types.Header, common.Address) {
+	genesis := &types.Header{Number: big.NewInt(0), Time: big.NewInt(0)}
+	tc := &testFinalityChain{headers: map[common.Hash]*types.Header{genesis.Hash(): genesis}, head: genesis}
+
+	trusted := common.HexToAddress("0x01")
+	f := NewFinality(tc)
+	f.whiteList = map[common.Address]int{trusted: 1}
+	f.depth = depth
+	f.finDepth = finDepth
+	return f, tc, genesis, trusted
+}
+
+func TestFinalityDepth(t *testing.T) {
+	f, tc, genesis, _ := newTestFinality(posconfig.K, posconfig.SlotSecurityParam)
+
+	// untrusted blocks never pass the check, only the depth makes them safe
+	for _, h := range tc.extend(genesis, 50, common.HexToAddress("0x02")) {
+		f.now = func() uint64 { return h.Time.Uint64() }
+		f.update(h)
+
+		head := h.Number.Uint64()
+		var safe, finalized uint64
+		if head > posconfig.K {
+			safe = head - posconfig.K - 1
+		}
+		if head > posconfig.SlotSecurityParam {
+			finalized = head - posconfig.SlotSecurityParam
+		}
+		if f.SafeBlockNumber() != safe || f.FinalizedBlockNumber() != finalized {
+			t.Fatalf("head %d: safe %d finalized %d, want %d %d", head, f.SafeBlockNumber(), f.FinalizedBlockNumber(), safe, finalized)
+		}
+		if uint64(len(f.window)) > posconfig.K+1 {
+			t.Fatalf("head %d: window should not exceed depth, got %d", head, len(f.window))
+		}
+	}
+}
+
+func TestFinalityTrusted(t *testing.T) {
+	f, tc, genesis, trusted := newTestFinality(200, 200)
+
+	// a block of trusted suffix t at slot distance t is stable when 2t-t > SecBlkDiff
+	for _, h := range tc.extend(genesis, 120, trusted) {
+		f.now = func() uint64 { return h.Time.Uint64() }
+		f.update(h)
+
+		head := h.Number.Uint64()
+		var safe uint64
+		if head > SecBlkDiff {
+			safe = head - SecBlkDiff - 1
+		}
+		if f.SafeBlockNumber() != safe {
+			t.Fatalf("head %d: safe %d, want %d", head, f.SafeBlockNumber(), safe)
+		}
+	}
+
+	// the chain stalls, the safe block is kept
+	head := tc.headers[f.window[len(f.window)-1].hash]
+	f.now = func() uint64 { return head.Time.Uint64() + 100*posconfig.SlotTime }
+	f.update(head)
+	if f.SafeBlockNumber() != head.Number.Uint64()-SecBlkDiff-1 {
+		t.Fatal("safe block should not move back", f.SafeBlockNumber())
+	}
+}
+
+func TestFinalityReorg(t *testing.T) {
+	f, tc, genesis, _ := newTestFinality(posconfig.K, posconfig.SlotSecurityParam)
+	untrusted := common.HexToAddress("0x02")
+
+	main := tc.extend(genesis, 40, untrusted)
+	for _, h := range main {
+		f.now = func() uint64 { return h.Time.Uint64() }
+		f.update(h)
+	}
+	finalized := f.FinalizedBlockNumber()
+
+	// a shorter side chain from block 35 becomes the head
+	side := tc.extend(main[34], 2, common.HexToAddress("0x03"))
+	f.update(side[1])
+	if f.SafeBlockNumber() != 37-posconfig.K-1 {
+		t.Fatal("safe block should follow the new head", f.SafeBlockNumber())
+	}
+	if f.FinalizedBlockNumber() != finalized {
+		t.Fatal("finalized block should not move back", f.FinalizedBlockNumber())
+	}
+	if f.window[len(f.window)-1].hash != side[1].Hash() || f.window[0].number != f.SafeBlockNumber()+1 {
+		t.Fatal("window should be reloaded from the new head")
+	}
+}
+
+func TestFinalityStart(t *testing.T) {
+	f, tc, genesis, _ := newTestFinality(posconfig.K, posconfig.SlotSecurityParam)
+	headers := tc.extend(genesis, 30, common.HexToAddress("0x02"))
+	tc.head = headers[19]
+
+	f.Start()
+	defer f.Stop()
+	if f.SafeBlockNumber() != 20-posconfig.K-1 {
+		t.Fatal("safe block should be loaded at start", f.SafeBlockNumber())
+	}
+
+	SetFinalityGadget(f)
+	defer SetFinalityGadget(nil)
+	number, err := TagBlockNumber(rpc.SafeBlockNumber)
+	if err != nil || uint64(number) != f.SafeBlockNumber() {
+		t.Fatal("safe tag mismatch", number, err)
+	}
+	number, err = TagBlockNumber(rpc.FinalizedBlockNumber)
+	if err != nil || uint64(number) != f.FinalizedBlockNumber() {
+		t.Fatal("finalized tag mismatch", number, err)
+	}
+	number, err = TagBlockNumber(rpc.LatestBlockNumber)
+	if err != nil || number != rpc.LatestBlockNumber {
+		t.Fatal("latest tag should not be resolved", number, err)
+	}
+}
+
+func TestTagBlockNumberNoGadget(t *testing.T) {
+	if _, err := TagBlockNumber(rpc.SafeBlockNumber); err != ErrNoFinality {
+		t.Fatal("safe tag without gadget should fail", err)
+	}
+	if number, err := TagBlockNumber(rpc.BlockNumber(5)); err != nil || number != 5 {
+		t.Fatal("block number should be returned as is", number, err)
+	}
+}
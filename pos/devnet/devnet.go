@@ -0,0 +1,368 @@
+// Package devnet runs a local pos network with each validator in a separate
+// process, or a single validator in process on a simulated clock, started from
+// a generated genesis in which all the validators are registered stakers. It is
+// the harness of the regression tests of the pos protocols.
+package devnet
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	accBn256 "github.com/wanchain/go-wanchain/accounts/keystore/bn256"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/p2p/simulations"
+	"github.com/wanchain/go-wanchain/p2p/simulations/adapters"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// DefaultPosConfig has short epochs for the tests, an epoch lasts 72 seconds
+var DefaultPosConfig = &params.PosConfig{
+	SlotTime:          2,
+	K:                 3,
+	EpochLeaderCount:  10,
+	RandomProperCount: 6,
+
+	MinLockEpochs:       1,
+	MaxLockEpochs:       90,
+	LockWeightEpochs:    [3]uint64{15, 45, 90},
+	MinStakeholderStake: 10000,
+	MinValidatorStake:   100000,
+	MinDelegatorStake:   100,
+
+	RBSlashRate:         100,
+	DoubleSignSlashRate: 1000,
+}
+
+// Config is the configuration of a devnet
+type Config struct {
+	Validators int               // count of the validators, each one runs a node
+	Stake      *big.Int          // wei staked by each validator in the genesis
+	Pos        *params.PosConfig // nil means DefaultPosConfig
+	BaseDir    string            // directory of the node data, a temporary one if empty
+
+	// Clock runs the devnet in process on the simulated clock the caller moves, the
+	// genesis is of its time. The pos engine has a single instance in a process, so
+	// the devnet has a single validator then and a process runs one such devnet.
+	Clock *util.SimulatedClock
+}
+
+// ErrSimulatedRun is returned by Start of a devnet on a simulated clock once the
+// process has run one, whose pos engine state can't be reset.
+var ErrSimulatedRun = errors.New("process already ran a devnet on a simulated clock")
+
+var simulatedRun int32
+
+var (
+	genesisBalance = new(big.Int).Mul(big.NewInt(1e9), big.NewInt(params.Wan))
+	defaultStake   = new(big.Int).Mul(big.NewInt(1e6), big.NewInt(params.Wan))
+)
+
+// Devnet is a pos network of validator nodes run by the exec adapter of the p2p
+// simulations, or by its sim adapter on a simulated clock. The nodes are queried through their rpc clients.
+type Devnet struct {
+	Genesis    *core.Genesis
+	Validators []*keystore.Key
+
+	dir       string
+	tempDir   bool
+	clock     *util.SimulatedClock
+	prevClock util.Clock
+	network   *simulations.Network
+	nodes     []*adapters.NodeConfig
+}
+
+// New generates the keys of the validators and the genesis of a devnet, the
+// nodes are not started until Start is called.
+func New(config *Config) (*Devnet, error) {
+	if config.Validators < 1 {
+		return nil, errors.New("devnet needs a validator")
+	}
+	if config.Clock != nil && config.Validators > 1 {
+		return nil, errors.New("devnet on a simulated clock has a single validator")
+	}
+	stake := config.Stake
+	if stake == nil {
+		stake = defaultStake
+	}
+	pos := config.Pos
+	if pos == nil {
+		pos = DefaultPosConfig
+	}
+
+	d := &Devnet{Validators: make([]*keystore.Key, config.Validators), dir: config.BaseDir, clock: config.Clock}
+	for i := range d.Validators {
+		key, err := newValidatorKey()
+		if err != nil {
+			return nil, err
+		}
+		d.Validators[i] = key
+	}
+	d.Genesis = NewGenesis(d.Validators, stake, pos)
+	if d.clock != nil {
+		d.Genesis.Timestamp = uint64(d.clock.Now().Unix())
+	}
+	return d, nil
+}
+
+func newValidatorKey() (*keystore.Key, error) {
+	sk, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	sk2, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	sk3, err := accBn256.GenerateBn256()
+	if err != nil {
+		return nil, err
+	}
+	return &keystore.Key{Address: crypto.PubkeyToAddress(sk.PublicKey), PrivateKey: sk, PrivateKey2: sk2, PrivateKey3: sk3}, nil
+}
+
+// NewGenesis returns the genesis of a pluto network with the pos parameters pos,
+// in which the validators are stakers of stake wei which never expire. The
+// first validator is the leader of all the slots of epoch 0.
+func NewGenesis(validators []*keystore.Key, stake *big.Int, pos *params.PosConfig) *core.Genesis {
+	config := *params.PlutoChainConfig
+	config.Pos = pos
+
+	alloc := make(core.GenesisAlloc, len(validators))
+	for _, key := range validators {
+		alloc[key.Address] = core.GenesisAccount{
+			Balance: genesisBalance,
+			Staking: core.GenesisAccountStaking{
+				Amount:  stake,
+				S256pk:  crypto.FromECDSAPub(&key.PrivateKey.PublicKey),
+				Bn256pk: key.PrivateKey3.PublicKeyBn256.G1.Marshal(),
+			},
+		}
+	}
+	return &core.Genesis{
+		Config:     &config,
+		Timestamp:  uint64(time.Now().Unix()),
+		ExtraData:  crypto.FromECDSAPub(&validators[0].PrivateKey.PublicKey),
+		GasLimit:   0x47b760, // 4700000
+		Difficulty: big.NewInt(1),
+		Alloc:      alloc,
+	}
+}
+
+// Start starts a node for each validator and connects all of them
+func (d *Devnet) Start() error {
+	if d.network != nil {
+		return errors.New("devnet already started")
+	}
+	if d.clock != nil && !atomic.CompareAndSwapInt32(&simulatedRun, 0, 1) {
+		return ErrSimulatedRun
+	}
+	if d.dir == "" {
+		dir, err := ioutil.TempDir("", "pos-devnet")
+		if err != nil {
+			return err
+		}
+		d.dir, d.tempDir = dir, true
+	}
+
+	snap := &simulations.Snapshot{}
+	d.nodes = make([]*adapters.NodeConfig, len(d.Validators))
+	for i, key := range d.Validators {
+		name := fmt.Sprintf("validator%02d", i)
+		conf, err := newValidatorConfig(d.Genesis, key, filepath.Join(d.dir, name))
+		if err != nil {
+			return err
+		}
+		d.nodes[i] = adapters.RandomNodeConfig()
+		d.nodes[i].Name = name
+		d.nodes[i].Services = []string{serviceName}
+		snap.Nodes = append(snap.Nodes, simulations.NodeSnapshot{
+			Node:      simulations.Node{Config: d.nodes[i], Up: true},
+			Snapshots: map[string][]byte{serviceName: conf},
+		})
+		for _, other := range d.nodes[:i] {
+			snap.Conns = append(snap.Conns, simulations.Conn{One: d.nodes[i].ID, Other: other.ID})
+		}
+	}
+
+	var adapter adapters.NodeAdapter = adapters.NewExecAdapter(d.dir)
+	if d.clock != nil {
+		adapter = adapters.NewSimAdapter(adapters.Services{serviceName: newValidatorService})
+		d.prevClock = util.SetClock(d.clock)
+	}
+	d.network = simulations.NewNetwork(adapter, &simulations.NetworkConfig{DefaultService: serviceName})
+	if err := d.network.Load(snap); err != nil {
+		d.Shutdown()
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the nodes, the temporary directory of the node data is removed
+func (d *Devnet) Shutdown() {
+	if d.network != nil {
+		d.network.Shutdown()
+		d.network = nil
+	}
+	if d.prevClock != nil {
+		util.SetClock(d.prevClock)
+		d.prevClock = nil
+	}
+	if d.tempDir {
+		os.RemoveAll(d.dir)
+		d.dir, d.tempDir = "", false
+	}
+}
+
+// Client returns the rpc client of the node of validator i
+func (d *Devnet) Client(i int) (*rpc.Client, error) {
+	if d.network == nil {
+		return nil, errors.New("devnet not started")
+	}
+	node := d.network.GetNode(d.nodes[i].ID)
+	if node == nil || !node.Up {
+		return nil, fmt.Errorf("node of validator %d is down", i)
+	}
+	return node.Client()
+}
+
+// WaitEpoch waits until the chain of validator i has a block of epochID or a
+// later epoch
+func (d *Devnet) WaitEpoch(ctx context.Context, i int, epochID uint64) error {
+	slotTime := time.Duration(d.Genesis.Config.PosParams().SlotTime) * time.Second
+	for {
+		cur, _, err := d.HeadEpoch(ctx, i)
+		if err != nil {
+			return err
+		}
+		if cur >= epochID {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(slotTime):
+		}
+	}
+}
+
+// HeadEpoch returns the epoch and the slot of the head block of validator i, zeros
+// at the genesis.
+func (d *Devnet) HeadEpoch(ctx context.Context, i int) (epochID uint64, slotID uint64, err error) {
+	client, err := d.Client(i)
+	if err != nil {
+		return 0, 0, err
+	}
+	var head struct {
+		Number     *hexutil.Big `json:"number"`
+		Difficulty *hexutil.Big `json:"difficulty"`
+	}
+	if err := client.CallContext(ctx, &head, "eth_getBlockByNumber", "latest", false); err != nil {
+		return 0, 0, err
+	}
+	// the difficulty of a pos block encodes its epoch and slot
+	if head.Number == nil || head.Number.ToInt().Sign() == 0 {
+		return 0, 0, nil
+	}
+	epochID, slotID = util.CalEpSlbyTd(head.Difficulty.ToInt().Uint64())
+	return epochID, slotID, nil
+}
+
+// EpochLeaders returns the addresses of the epoch leaders of epochID known by
+// validator i, in the order of the group
+func (d *Devnet) EpochLeaders(ctx context.Context, i int, epochID uint64) ([]common.Address, error) {
+	client, err := d.Client(i)
+	if err != nil {
+		return nil, err
+	}
+	var leaders map[string]string
+	if err := client.CallContext(ctx, &leaders, "pos_getEpochLeadersByEpochID", epochID); err != nil {
+		return nil, err
+	}
+
+	// the group is keyed by the zero padded index of the leaders
+	index := make([]string, 0, len(leaders))
+	for k := range leaders {
+		index = append(index, k)
+	}
+	sort.Strings(index)
+	addrs := make([]common.Address, len(index))
+	for j, k := range index {
+		buf, err := hex.DecodeString(leaders[k])
+		if err != nil {
+			return nil, err
+		}
+		pk := crypto.ToECDSAPub(buf)
+		if pk == nil {
+			return nil, fmt.Errorf("invalid epoch leader %s", leaders[k])
+		}
+		addrs[j] = crypto.PubkeyToAddress(*pk)
+	}
+	return addrs, nil
+}
+
+// Random returns the random of epochID in the latest state of validator i
+func (d *Devnet) Random(ctx context.Context, i int, epochID uint64) (*big.Int, error) {
+	client, err := d.Client(i)
+	if err != nil {
+		return nil, err
+	}
+	var r hexutil.Big
+	if err := client.CallContext(ctx, &r, "pos_random", epochID, int64(rpc.LatestBlockNumber)); err != nil {
+		return nil, err
+	}
+	return r.ToInt(), nil
+}
+
+// VerifyRandom re-checks on validator i the random beacon run in epochID
+func (d *Devnet) VerifyRandom(ctx context.Context, i int, epochID uint64) (*vm.RbVerifyResult, error) {
+	client, err := d.Client(i)
+	if err != nil {
+		return nil, err
+	}
+	var res vm.RbVerifyResult
+	if err := client.CallContext(ctx, &res, "pos_verifyRandom", epochID); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// IncentivePayDetail returns the incentives paid for epochID in the latest state
+// of validator i, summed by address
+func (d *Devnet) IncentivePayDetail(ctx context.Context, i int, epochID uint64) (map[common.Address]*big.Int, error) {
+	client, err := d.Client(i)
+	if err != nil {
+		return nil, err
+	}
+	var detail [][]vm.ClientIncentive
+	if err := client.CallContext(ctx, &detail, "pos_getEpochIncentivePayDetail", epochID); err != nil {
+		return nil, err
+	}
+
+	paid := make(map[common.Address]*big.Int)
+	for _, group := range detail {
+		for _, client := range group {
+			if paid[client.Addr] == nil {
+				paid[client.Addr] = new(big.Int)
+			}
+			paid[client.Addr].Add(paid[client.Addr], client.Incentive)
+		}
+	}
+	return paid, nil
+}
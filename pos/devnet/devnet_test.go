@@ -0,0 +1,216 @@
+package devnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+var runDevnet = flag.Bool("devnet", false, "run a pos devnet of validator processes through several epochs")
+
+func TestGenesisJSON(t *testing.T) {
+	d, err := New(&Config{Validators: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the genesis reaches the nodes encoded in their snapshot
+	conf, err := newValidatorConfig(d.Genesis, d.Validators[1], "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dec validatorConfig
+	if err := json.Unmarshal(conf, &dec); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := d.Genesis.ToBlock()
+	if got, _ := dec.Genesis.ToBlock(); got.Hash() != want.Hash() {
+		t.Fatalf("genesis hash mismatch: got %x, want %x", got.Hash(), want.Hash())
+	}
+	if dec.Genesis.Config.Pos.K != DefaultPosConfig.K {
+		t.Fatal("pos parameters are lost")
+	}
+
+	if !bytes.Equal(dec.PrivateKey2, crypto.FromECDSA(d.Validators[1].PrivateKey2)) ||
+		new(big.Int).SetBytes(dec.Bn256Key).Cmp(d.Validators[1].PrivateKey3.D) != 0 {
+		t.Fatal("validator keys mismatch")
+	}
+}
+
+// TestDevnetSimulated runs a validator in process on a simulated clock until
+// epoch 2, the short variant of TestDevnet which runs by default.
+func TestDevnetSimulated(t *testing.T) {
+	clock := util.NewSimulatedClock(time.Now())
+	d, err := New(&Config{Validators: 1, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Start(); err == ErrSimulatedRun {
+		t.Skip(err)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Shutdown()
+
+	// the clock moves a second at a time, the node catches up in between
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	var blocks int
+	for prevSlot := uint64(0); ; {
+		epochID, slotID, err := d.HeadEpoch(ctx, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if epochID >= 2 {
+			break
+		}
+		if slotID != prevSlot {
+			blocks++
+			prevSlot = slotID
+		}
+		clock.Run(time.Second)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if blocks == 0 {
+		t.Fatal("no block produced")
+	}
+
+	leaders, err := d.EpochLeaders(ctx, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaders) != int(DefaultPosConfig.EpochLeaderCount) {
+		t.Errorf("%d epoch leaders, want %d", len(leaders), DefaultPosConfig.EpochLeaderCount)
+	}
+	for _, addr := range leaders {
+		if addr != d.Validators[0].Address {
+			t.Errorf("epoch leader %x is not the validator", addr)
+		}
+	}
+}
+
+// TestDevnet runs three validators until epoch 3 and checks the epoch leaders,
+// the random beacon and the incentives of the first epochs. It takes several
+// minutes, run it with: go test ./pos/devnet -devnet -v
+func TestDevnet(t *testing.T) {
+	if !*runDevnet {
+		t.Skip("run with -devnet")
+	}
+
+	d, err := New(&Config{Validators: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer d.Shutdown()
+
+	validators := make(map[common.Address]bool)
+	for _, key := range d.Validators {
+		validators[key.Address] = true
+	}
+
+	epochTime := time.Duration(DefaultPosConfig.SlotTime*DefaultPosConfig.K*12) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), 5*epochTime)
+	defer cancel()
+	for i := range d.Validators {
+		if err := d.WaitEpoch(ctx, i, 3); err != nil {
+			t.Fatalf("validator %d: %v", i, err)
+		}
+	}
+
+	for epochID := uint64(1); epochID <= 2; epochID++ {
+		want, err := d.EpochLeaders(ctx, 0, epochID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(want) != int(DefaultPosConfig.EpochLeaderCount) {
+			t.Errorf("epoch %d: %d epoch leaders, want %d", epochID, len(want), DefaultPosConfig.EpochLeaderCount)
+		}
+		for _, addr := range want {
+			if !validators[addr] {
+				t.Errorf("epoch %d: epoch leader %x is not a validator", epochID, addr)
+			}
+		}
+		for i := 1; i < len(d.Validators); i++ {
+			leaders, err := d.EpochLeaders(ctx, i, epochID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !addressesEqual(leaders, want) {
+				t.Errorf("epoch %d: epoch leaders of validator %d differ", epochID, i)
+			}
+		}
+	}
+
+	// the random of epoch 2 is generated by the random beacon of epoch 1
+	res, err := d.VerifyRandom(ctx, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Valid {
+		t.Errorf("random beacon of epoch 1 is invalid: %s", res.Reason)
+	}
+	want, err := d.Random(ctx, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(d.Validators); i++ {
+		if r, err := d.Random(ctx, i, 2); err != nil || r.Cmp(want) != 0 {
+			t.Errorf("random of epoch 2 of validator %d differs: %v %v", i, r, err)
+		}
+	}
+
+	// the incentives of epoch 1 are paid in epoch 2
+	paid, err := d.IncentivePayDetail(ctx, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paid) == 0 {
+		t.Error("no incentive paid for epoch 1")
+	}
+	for addr, amount := range paid {
+		if !validators[addr] {
+			t.Errorf("incentive paid to %x which is not a validator", addr)
+		}
+		if amount.Sign() <= 0 {
+			t.Errorf("incentive of %x is %v", addr, amount)
+		}
+	}
+	for i := 1; i < len(d.Validators); i++ {
+		other, err := d.IncentivePayDetail(ctx, i, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(other) != len(paid) {
+			t.Errorf("incentives of epoch 1 of validator %d differ", i)
+			continue
+		}
+		for addr, amount := range paid {
+			if other[addr] == nil || other[addr].Cmp(amount) != 0 {
+				t.Errorf("incentive of %x of validator %d differs", addr, i)
+			}
+		}
+	}
+}
+
+func addressesEqual(a, b []common.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package devnet
+
+import (
+	"encoding/json"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	accBn256 "github.com/wanchain/go-wanchain/accounts/keystore/bn256"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/common/math"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/eth"
+	"github.com/wanchain/go-wanchain/node"
+	"github.com/wanchain/go-wanchain/p2p"
+	"github.com/wanchain/go-wanchain/p2p/simulations/adapters"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+)
+
+// serviceName is the simulation service of a validator node
+const serviceName = "pos-validator"
+
+func init() {
+	// the validator nodes run in child processes of the test binary, which
+	// start the service here instead of running the tests
+	adapters.RegisterServices(adapters.Services{serviceName: newValidatorService})
+}
+
+// validatorConfig is the snapshot a validator node is started from
+type validatorConfig struct {
+	Genesis     *core.Genesis `json:"genesis"`
+	PrivateKey  hexutil.Bytes `json:"privateKey"`
+	PrivateKey2 hexutil.Bytes `json:"privateKey2"`
+	Bn256Key    hexutil.Bytes `json:"bn256Key"`
+	PosDir      string        `json:"posDir"` // of the pos databases if the node has no data directory
+}
+
+func newValidatorConfig(genesis *core.Genesis, key *keystore.Key, posDir string) ([]byte, error) {
+	return json.Marshal(&validatorConfig{
+		Genesis:     genesis,
+		PrivateKey:  crypto.FromECDSA(key.PrivateKey),
+		PrivateKey2: crypto.FromECDSA(key.PrivateKey2),
+		Bn256Key:    math.PaddedBigBytes(key.PrivateKey3.D, 32),
+		PosDir:      posDir,
+	})
+}
+
+// validatorService runs the wanchain protocol of a validator and mines from the
+// start of the node
+type validatorService struct {
+	*eth.Ethereum
+}
+
+func newValidatorService(ctx *adapters.ServiceContext) (node.Service, error) {
+	var conf validatorConfig
+	if err := json.Unmarshal(ctx.Snapshot, &conf); err != nil {
+		return nil, err
+	}
+	sk, err := crypto.ToECDSA(conf.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	sk2, err := crypto.ToECDSA(conf.PrivateKey2)
+	if err != nil {
+		return nil, err
+	}
+	sk3, err := accBn256.ToBn256(conf.Bn256Key)
+	if err != nil {
+		return nil, err
+	}
+
+	// the validator account is unlocked in the keystore of the node, like gwan
+	// does with --unlock
+	ks := ctx.NodeContext.AccountManager.Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	account, err := ks.ImportECDSA(sk, sk2, sk3, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := ks.Unlock(account, ""); err != nil {
+		return nil, err
+	}
+
+	// the pos modules keep their databases in the data directory like gwan does,
+	// the in process nodes have none
+	posDir := ctx.NodeContext.ResolvePath("")
+	if posDir == "" {
+		posDir = conf.PosDir
+	}
+	posdb.DbInitAll(posDir)
+	posconfig.Init(nil)
+
+	config := eth.DefaultConfig
+	config.Genesis = conf.Genesis
+	config.NetworkId = conf.Genesis.Config.ChainId.Uint64()
+	config.Etherbase = account.Address
+	ethereum, err := eth.New(ctx.NodeContext, &config)
+	if err != nil {
+		return nil, err
+	}
+	return &validatorService{ethereum}, nil
+}
+
+func (s *validatorService) Start(srvr *p2p.Server) error {
+	if err := s.Ethereum.Start(srvr); err != nil {
+		return err
+	}
+	return s.StartMining(true)
+}
@@ -495,7 +495,9 @@ func (e *Epocher) SetEpochIncentive(epochId uint64, infors [][]vm.ClientIncentiv
 	return nil
 }
 
-func StakeOutRun(stateDb *state.StateDB, epochID uint64) bool {
+// StakeOutRun refunds the stakers and the clients who quit at epochID, and renews the lock of the stakers.
+// The stake outs of the stakeOut and stakeOutPartial methods are only refunded from the stake out fork.
+func StakeOutRun(stateDb *state.StateDB, epochID uint64, stakeOutFork bool) bool {
 	if vm.StakeoutIsFinished(stateDb, epochID) {
 		return true
 	}
@@ -506,17 +508,31 @@ func StakeOutRun(stateDb *state.StateDB, epochID uint64) bool {
 		// stakeout delegated client. client will expire at the same time with delegate node
 		staker := stakers[i]
 		var changed = false
+		out := staker.GetStakeOut()
+		// the staker quits by stakeOut.
+		if stakeOutFork && out.QuitEpoch != 0 && epochID >= out.QuitEpoch {
+			quitStaker(stateDb, &staker)
+			continue
+		}
+		// refund the partial stake out.
+		if stakeOutFork && out.OutAmount.Sign() != 0 && epochID >= out.OutEpoch {
+			core.Transfer(stateDb, vm.WanCscPrecompileAddr, staker.From, out.OutAmount)
+			staker.Amount.Sub(staker.Amount, out.OutAmount)
+			staker.StakeAmount.Mul(staker.Amount, big.NewInt(int64(vm.CalLocktimeWeight(staker.LockEpochs))))
+			out.OutAmount.SetUint64(0)
+			out.OutEpoch = 0
+			staker.SetStakeOut(out)
+			changed = true
+		}
 		// LockEpochs==0 means NO expire
 		if staker.LockEpochs==0 {
+			if changed {
+				updateStaker(stateDb, &staker)
+			}
 			continue
 		}
 		if epochID >= staker.StakingEpoch+staker.LockEpochs {
-			for j := 0; j < len(staker.Clients); j++ {
-					core.Transfer(stateDb, vm.WanCscPrecompileAddr, staker.Clients[j].Address, staker.Clients[j].Amount)
-			}
-			// quit the validator
-			core.Transfer(stateDb, vm.WanCscPrecompileAddr, staker.From, staker.Amount)
-			vm.UpdateInfo(stateDb, vm.StakersInfoAddr, vm.GetStakeInKeyHash(staker.Address), nil)
+			quitStaker(stateDb, &staker)
 			continue
 		}
 
@@ -548,13 +564,27 @@ func StakeOutRun(stateDb *state.StateDB, epochID uint64) bool {
 		}
 		if changed {
 			staker.Clients = newClients
-			stakerBytes, err := rlp.EncodeToBytes(staker)
-			if err != nil {
-				log.Error("StakeOutRun Failed: ", "err", err)
-				continue
-			}
-			vm.UpdateInfo(stateDb, vm.StakersInfoAddr, vm.GetStakeInKeyHash(staker.Address), stakerBytes)
+			updateStaker(stateDb, &staker)
 		}
 	}
 	return true
 }
+
+// refund the staker and all its clients, then remove the staker.
+func quitStaker(stateDb *state.StateDB, staker *vm.StakerInfo) {
+	for j := 0; j < len(staker.Clients); j++ {
+		core.Transfer(stateDb, vm.WanCscPrecompileAddr, staker.Clients[j].Address, staker.Clients[j].Amount)
+	}
+	// quit the validator
+	core.Transfer(stateDb, vm.WanCscPrecompileAddr, staker.From, staker.Amount)
+	vm.UpdateInfo(stateDb, vm.StakersInfoAddr, vm.GetStakeInKeyHash(staker.Address), nil)
+}
+
+func updateStaker(stateDb *state.StateDB, staker *vm.StakerInfo) {
+	stakerBytes, err := rlp.EncodeToBytes(staker)
+	if err != nil {
+		log.Error("StakeOutRun Failed: ", "err", err)
+		return
+	}
+	vm.UpdateInfo(stateDb, vm.StakersInfoAddr, vm.GetStakeInKeyHash(staker.Address), stakerBytes)
+}
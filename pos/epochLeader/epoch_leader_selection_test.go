@@ -380,14 +380,12 @@ func TestCalProbability(t *testing.T) {
 	addrc.SetString("0x6e6f37b8463b541fd6d07082f30f0296c5ac2118")
 	c.Address = addrc
 	c.Amount = math.MustParseBig256("1000000000000000000000000")
-	c.StakingEpoch = item.StakingEpoch+1
 	item.Clients = append(item.Clients, c)
 	for epochid := uint64(1); epochid < 11; epochid++ {
-		pb := epocherInst.CalProbability(epochid, item.Amount, item.LockEpochs, item.StakingEpoch)
+		pb := epocherInst.CalProbability(item.Amount, item.LockEpochs)
 		t.Log("pb: ", epochid, pb)
 		for i := 0; i < len(item.Clients); i++ {
-			lockEpoch := item.LockEpochs - (item.Clients[i].StakingEpoch - item.StakingEpoch)
-			cp := epocherInst.CalProbability(epochid, item.Clients[i].Amount, lockEpoch, item.Clients[i].StakingEpoch)
+			cp := epocherInst.CalProbability(item.Clients[i].Amount, item.LockEpochs)
 			t.Log("cp: ", epochid, cp)
 			pb.Add(pb, cp)
 		}
@@ -0,0 +1,100 @@
+package epochLeader
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+func storeTestStaker(t *testing.T, stateDb *state.StateDB, staker *vm.StakerInfo) {
+	stakerBytes, err := rlp.EncodeToBytes(staker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm.UpdateInfo(stateDb, vm.StakersInfoAddr, vm.GetStakeInKeyHash(staker.Address), stakerBytes)
+}
+
+func loadTestStaker(t *testing.T, stateDb *state.StateDB, addr common.Address) *vm.StakerInfo {
+	stakerBytes, err := vm.GetInfo(stateDb, vm.StakersInfoAddr, vm.GetStakeInKeyHash(addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stakerBytes) == 0 {
+		return nil
+	}
+	var staker vm.StakerInfo
+	if err := rlp.DecodeBytes(stakerBytes, &staker); err != nil {
+		t.Fatal(err)
+	}
+	return &staker
+}
+
+func TestStakeOutRunRefund(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	stateDb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	stateDb.AddBalance(vm.WanCscPrecompileAddr, big.NewInt(1000))
+
+	partial := &vm.StakerInfo{
+		Address:     common.HexToAddress("0x01"),
+		From:        common.HexToAddress("0x11"),
+		Amount:      big.NewInt(100),
+		StakeAmount: big.NewInt(1000),
+	}
+	partial.SetStakeOut(vm.StakeOutInfo{OutAmount: big.NewInt(30), OutEpoch: 5})
+	storeTestStaker(t, stateDb, partial)
+
+	quit := &vm.StakerInfo{
+		Address:     common.HexToAddress("0x02"),
+		From:        common.HexToAddress("0x12"),
+		Amount:      big.NewInt(200),
+		StakeAmount: big.NewInt(2000),
+		Clients:     []vm.ClientInfo{{Address: common.HexToAddress("0x22"), Amount: big.NewInt(50), StakeAmount: big.NewInt(500)}},
+	}
+	quit.SetStakeOut(vm.StakeOutInfo{QuitEpoch: 5})
+	storeTestStaker(t, stateDb, quit)
+
+	// nothing is refunded before the stake out epochs
+	StakeOutRun(stateDb, 4, true)
+	if stateDb.GetBalance(partial.From).Sign() != 0 || stateDb.GetBalance(quit.From).Sign() != 0 {
+		t.Fatal("stake out refunded too early")
+	}
+	if staker := loadTestStaker(t, stateDb, partial.Address); staker == nil || staker.GetStakeOut().OutAmount.Cmp(big.NewInt(30)) != 0 {
+		t.Fatal("pending stake out lost")
+	}
+
+	// nor before the stake out fork
+	snap := stateDb.Snapshot()
+	StakeOutRun(stateDb, 5, false)
+	if stateDb.GetBalance(partial.From).Sign() != 0 || stateDb.GetBalance(quit.From).Sign() != 0 {
+		t.Fatal("stake out refunded before the fork")
+	}
+	stateDb.RevertToSnapshot(snap)
+
+	StakeOutRun(stateDb, 5, true)
+	if stateDb.GetBalance(partial.From).Cmp(big.NewInt(30)) != 0 {
+		t.Fatal("partial stake out refunded", stateDb.GetBalance(partial.From))
+	}
+	staker := loadTestStaker(t, stateDb, partial.Address)
+	if staker == nil || staker.Amount.Cmp(big.NewInt(70)) != 0 || staker.StakeAmount.Cmp(big.NewInt(700)) != 0 {
+		t.Fatal("stake left after the partial stake out is wrong")
+	}
+	if len(staker.Out) != 0 {
+		t.Fatal("refunded stake out is still stored")
+	}
+
+	if stateDb.GetBalance(quit.From).Cmp(big.NewInt(200)) != 0 ||
+		stateDb.GetBalance(quit.Clients[0].Address).Cmp(big.NewInt(50)) != 0 {
+		t.Fatal("quit staker and its clients are not refunded")
+	}
+	if loadTestStaker(t, stateDb, quit.Address) != nil {
+		t.Fatal("quit staker is not removed")
+	}
+	if stateDb.GetBalance(vm.WanCscPrecompileAddr).Cmp(big.NewInt(1000-30-200-50)) != 0 {
+		t.Fatal("refunds are not paid by the staking contract")
+	}
+}
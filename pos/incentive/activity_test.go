@@ -28,7 +28,7 @@ func (t *TestChainReader) GetHeaderByNumber(number uint64) *types.Header {
 	return &types.Header{Number: big.NewInt(int64(100)), Difficulty: big.NewInt(0), Coinbase: slAddrs[int(number)%len(slAddrs)]}
 }
 
-func (t *TestChainReader) Config() *params.ChainConfig                             { return nil }
+func (t *TestChainReader) Config() *params.ChainConfig                             { return params.TestChainConfig }
 func (t *TestChainReader) GetHeader(hash common.Hash, number uint64) *types.Header { return nil }
 func (t *TestChainReader) GetHeaderByHash(hash common.Hash) *types.Header          { return nil }
 func (t *TestChainReader) GetBlock(hash common.Hash, number uint64) *types.Block   { return nil }
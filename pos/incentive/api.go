@@ -1,6 +1,7 @@
 package incentive
 
 import (
+	"errors"
 	"math/big"
 
 	"github.com/wanchain/go-wanchain/consensus"
@@ -8,155 +9,203 @@ import (
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 
 	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
 
 	"github.com/wanchain/go-wanchain/common"
 
 	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/pos/posdb"
-	"github.com/wanchain/go-wanchain/rlp"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
 )
 
-var localDb *posdb.Db
+// localDbName is the local db of the incentive totals before the fork
+var localDbName = "incentive"
 
 var (
-	dictAllTotal       = "all_total"
-	dictEpochTotal     = "epoch_total"
-	dictTotalRemain    = "total_remain"
-	dictEpochRemain    = "epoch_remain"
-	dictRunTimes       = "run_times"
-	dictEpochGasPool   = "epoch_gas_pool"
-	dictAllGasPool     = "all_gas_pool"
-	dictAddressAll     = "address_all"
-	dictAddressEpoch   = "address_epoch"
-	dictCurrentEpoch   = "current_epoch"
-	dictAddressCnt     = "address_count"
-	dictEpochPayDetail = "epoch_pay_detail"
+	dictAllTotal    = "all_total"
+	dictEpochTotal  = "epoch_total"
+	dictTotalRemain = "total_remain"
+	dictEpochRemain = "epoch_remain"
+	dictRunTimes    = "run_times"
 )
 
-func initLocalDb(dbName string) {
-	localDb = posdb.NewDb(dbName)
+var errNotPaid = errors.New("incentive of the epoch has not been paid")
+
+// The payment detail of an epoch is too large for the state, it is replayed from
+// the state before the block which paid it. The totals are saved in the storage of
+// the incentive precompile address from the IncentiveHistoryBlock fork on, so
+// every node which has synced the chain gets the same answer, and in the local db
+// before the fork. The history functions below are given a nil stateDb before the
+// fork.
+func getHistoryHashKey(epochID uint64, key string) common.Hash {
+	return crypto.Keccak256Hash(convert.Uint64ToBytes(epochID), []byte(key))
 }
 
-func saveIncentiveHistory(epochID uint64, payments [][]vm.ClientIncentive) {
-	if payments == nil {
+func historyGet(stateDb vm.StateDB, epochID uint64, key string) ([]byte, error) {
+	if stateDb != nil {
+		return stateDb.GetStateByteArray(getIncentivePrecompileAddress(), getHistoryHashKey(epochID, key)), nil
+	}
+
+	buf, err := posdb.NewDb(localDbName).Get(epochID, key)
+	if err != nil && err.Error() != "leveldb: not found" {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func historyPut(stateDb vm.StateDB, epochID uint64, key string, value []byte) {
+	if stateDb != nil {
+		stateDb.SetStateByteArray(getIncentivePrecompileAddress(), getHistoryHashKey(epochID, key), value)
 		return
 	}
 
-	buf, err := rlp.EncodeToBytes(payments)
-	if err != nil {
+	if _, err := posdb.NewDb(localDbName).Put(epochID, key, value); err != nil {
 		log.SyslogErr(err.Error())
-		return
 	}
-	localDb.Put(epochID, dictEpochPayDetail, buf)
+}
 
-	saveOtherInfomation(epochID, payments)
+func saveIncentiveHistory(stateDb vm.StateDB, epochID uint64, payments [][]vm.ClientIncentive) {
+	if payments == nil {
+		return
+	}
+	saveOtherInfomation(stateDb, epochID, payments)
 }
 
-func saveTotalIncentive(epochID uint64, incentives [][]vm.ClientIncentive) {
+func saveTotalIncentive(stateDb vm.StateDB, epochID uint64, incentives [][]vm.ClientIncentive) {
 	if incentives == nil {
 		return
 	}
 	totalIncome := sumIncentive(incentives)
-	localDbAddValue(0, dictAllTotal, totalIncome)
+	historyAddValue(stateDb, 0, dictAllTotal, totalIncome)
 }
 
-func saveEpochTotalIncentive(epochID uint64, incentives [][]vm.ClientIncentive) {
+func saveEpochTotalIncentive(stateDb vm.StateDB, epochID uint64, incentives [][]vm.ClientIncentive) {
 	if incentives == nil {
 		return
 	}
 	totalIncome := sumIncentive(incentives)
-	localDb.Put(epochID, dictEpochTotal, totalIncome.Bytes())
+	historyPut(stateDb, epochID, dictEpochTotal, totalIncome.Bytes())
 }
 
-func saveRemain(epochID uint64, remain *big.Int) {
+func saveRemain(stateDb vm.StateDB, epochID uint64, remain *big.Int) {
 	if remain == nil {
 		return
 	}
-	localDb.Put(epochID, dictEpochRemain, remain.Bytes())
-	localDbAddValue(0, dictTotalRemain, remain)
+	historyPut(stateDb, epochID, dictEpochRemain, remain.Bytes())
+	historyAddValue(stateDb, 0, dictTotalRemain, remain)
 }
 
-func addRunTimes() {
-	localDbAddValue(0, dictRunTimes, big.NewInt(1))
+func addRunTimes(stateDb vm.StateDB) {
+	historyAddValue(stateDb, 0, dictRunTimes, big.NewInt(1))
 }
 
-func saveOtherInfomation(epochID uint64, incentives [][]vm.ClientIncentive) {
-	saveTotalIncentive(epochID, incentives)
-	saveEpochTotalIncentive(epochID, incentives)
-	addRunTimes()
+func saveOtherInfomation(stateDb vm.StateDB, epochID uint64, incentives [][]vm.ClientIncentive) {
+	saveTotalIncentive(stateDb, epochID, incentives)
+	saveEpochTotalIncentive(stateDb, epochID, incentives)
+	addRunTimes(stateDb)
 }
 
-func localDbGetValue(epochID uint64, key string) (*big.Int, error) {
-	total, err := localDb.Get(epochID, key)
-	if err != nil && err.Error() != "leveldb: not found" {
+func historyAddValue(stateDb vm.StateDB, epochID uint64, key string, value *big.Int) {
+	buf, err := historyGet(stateDb, epochID, key)
+	if err != nil {
 		log.SyslogErr(err.Error())
-		return nil, err
-	}
-
-	if total == nil || len(total) == 0 {
-		return big.NewInt(0), nil
+		return
 	}
-
-	return big.NewInt(0).SetBytes(total), nil
+	total := big.NewInt(0).SetBytes(buf)
+	total.Add(total, value)
+	historyPut(stateDb, epochID, key, total.Bytes())
 }
 
-func localDbAddValue(epochID uint64, key string, value *big.Int) {
-	total, err := localDb.Get(epochID, key)
-	if err != nil && err.Error() != "leveldb: not found" {
-		log.SyslogErr(err.Error())
-		return
-	}
-	totalNum := big.NewInt(0)
-	if total != nil && len(total) != 0 {
-		totalNum.SetBytes(total)
+// historyGetValue returns a total of the state, or of the local db if the state
+// has not recorded it, which is the case before the fork. The totals of all the
+// epochs in the state only count from the fork on.
+func historyGetValue(stateDb vm.StateDB, epochID uint64, key string) (*big.Int, error) {
+	if stateDb == nil {
+		return nil, errors.New("historyGetValue with an empty stateDb")
 	}
-	totalNum.Add(totalNum, value)
-	localDb.Put(0, key, totalNum.Bytes())
-}
 
-// GetEpochPayDetail use to get detail payment array
-func GetEpochPayDetail(epochID uint64) ([][]vm.ClientIncentive, error) {
-	buf, err := localDb.Get(epochID, dictEpochPayDetail)
+	buf, err := historyGet(stateDb, epochID, key)
+	if err == nil && len(buf) == 0 {
+		buf, err = historyGet(nil, epochID, key)
+	}
 	if err != nil {
 		log.SyslogErr(err.Error())
 		return nil, err
 	}
+	return big.NewInt(0).SetBytes(buf), nil
+}
+
+// StateAtFn returns the state of the block of number
+type StateAtFn func(number uint64) (*state.StateDB, error)
+
+// chainAt is the chain up to head, as read by the block after head
+type chainAt struct {
+	consensus.ChainReader
+	head *types.Header
+}
+
+func (c *chainAt) CurrentHeader() *types.Header {
+	return c.head
+}
+
+// GetEpochPayDetail returns the payments of epochID. They are replayed on the state
+// before the block which paid them, the first block of the next epochs to run the
+// incentive, so it needs the state of that block.
+func GetEpochPayDetail(chain consensus.ChainReader, stateAt StateAtFn, epochID uint64) ([][]vm.ClientIncentive, error) {
+	payEpochID := epochID + posconfig.IncentiveDelayEpochs
+	number := util.FirstBlockAfter(chain, payEpochID, posconfig.IncentiveStartStage)
+	// a failed run is reverted, the next block of the epoch runs it again
+	for ; ; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, errNotPaid
+		}
+		if ep, _ := util.CalEpSlbyTd(header.Difficulty.Uint64()); ep != payEpochID {
+			return nil, errNotPaid
+		}
+		stateDb, err := stateAt(number)
+		if err != nil {
+			return nil, err
+		}
+		if isFinished(stateDb, epochID, number) {
+			break
+		}
+	}
 
-	var payment [][]vm.ClientIncentive
-
-	err = rlp.DecodeBytes(buf, &payment)
+	stateDb, err := stateAt(number - 1)
 	if err != nil {
-		log.SyslogErr(err.Error())
 		return nil, err
 	}
-
-	return payment, nil
+	payments, _, err := epochIncentive(&chainAt{chain, chain.GetHeaderByNumber(number - 1)}, stateDb, epochID)
+	return payments, err
 }
 
 // GetTotalIncentive get total incentive of all epoch
-func GetTotalIncentive() (*big.Int, error) {
-	return localDbGetValue(0, dictAllTotal)
+func GetTotalIncentive(stateDb vm.StateDB) (*big.Int, error) {
+	return historyGetValue(stateDb, 0, dictAllTotal)
 }
 
 // GetEpochIncentive get total incentive of all epoch
-func GetEpochIncentive(epochID uint64) (*big.Int, error) {
-	return localDbGetValue(epochID, dictEpochTotal)
+func GetEpochIncentive(stateDb vm.StateDB, epochID uint64) (*big.Int, error) {
+	return historyGetValue(stateDb, epochID, dictEpochTotal)
 }
 
 // GetEpochRemain get remain of epoch input
-func GetEpochRemain(epochID uint64) (*big.Int, error) {
-	return localDbGetValue(epochID, dictEpochRemain)
+func GetEpochRemain(stateDb vm.StateDB, epochID uint64) (*big.Int, error) {
+	return historyGetValue(stateDb, epochID, dictEpochRemain)
 }
 
 // GetTotalRemain get remain of epoch input
-func GetTotalRemain() (*big.Int, error) {
-	return localDbGetValue(0, dictTotalRemain)
+func GetTotalRemain(stateDb vm.StateDB) (*big.Int, error) {
+	return historyGetValue(stateDb, 0, dictTotalRemain)
 }
 
 // GetRunTimes returns incentive run times
-func GetRunTimes() (*big.Int, error) {
-	return localDbGetValue(0, dictRunTimes)
+func GetRunTimes(stateDb vm.StateDB) (*big.Int, error) {
+	return historyGetValue(stateDb, 0, dictRunTimes)
 }
 
 // GetEpochGasPool use to get epoch gas pool
@@ -196,5 +245,5 @@ func GetEpochRBLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Addr
 
 // GetSlotLeaderActivity can get the address, blockCnt, and activity of slotleader
 func GetSlotLeaderActivity(chain consensus.ChainReader, epochID uint64) ([]common.Address, []int, float64) {
-	return getSlotLeaderActivity(chain, epochID, posconfig.SlotCount)
+	return getSlotLeaderActivity(chain, epochID, int(posconfig.SlotCount))
 }
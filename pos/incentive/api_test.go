@@ -1,26 +1,108 @@
 package incentive
 
 import (
+	"io/ioutil"
 	"math/big"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
 )
 
-func testInitDb() {
-	os.RemoveAll("/tmp/pluto/gwan/incentive")
-	initLocalDb("/tmp/pluto/gwan/incentive")
+func testHistoryDb(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "incentive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	localDbName = filepath.Join(dir, "incentive")
+	return func() {
+		localDbName = "incentive"
+		os.RemoveAll(dir)
+	}
 }
 
-func TestInitLocalDB(t *testing.T) {
-	testInitDb()
+// testPayChain is the chain reader of the headers, the header of number n is the n-th
+type testPayChain struct {
+	consensus.ChainReader
+	headers []*types.Header
+}
+
+func (c *testPayChain) Config() *params.ChainConfig  { return params.TestChainConfig }
+func (c *testPayChain) CurrentHeader() *types.Header { return c.headers[len(c.headers)-1] }
+
+func (c *testPayChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(c.headers)) {
+		return nil
+	}
+	return c.headers[number]
 }
 
 func TestGetEpochPayDetail(t *testing.T) {
-	epochID := uint64(0)
+	TestSetActivityInterface(t)
+	TestSetStakerInterface(t)
+
+	chain := &testPayChain{}
+	for _, es := range [][2]uint64{{0, 0}, {0, 3}, {1, posconfig.IncentiveStartStage + 1}, {1, posconfig.IncentiveStartStage + 2}} {
+		chain.headers = append(chain.headers, &types.Header{
+			Number:     big.NewInt(int64(len(chain.headers))),
+			Difficulty: new(big.Int).SetUint64(es[0]<<32 | es[1]<<8 | 1),
+		})
+	}
+
+	// the run of the block 2 is reverted, the block 3 pays the epoch 0
+	db, _ := ethdb.NewMemDatabase()
+	stateDb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	parentRoot, _ := stateDb.CommitTo(db, false)
+	stateDb, _ = state.New(parentRoot, state.NewDatabase(db))
+	if !Run(chain, stateDb, 0, 3) {
+		t.Fatal("incentive run failed")
+	}
+	paidRoot, _ := stateDb.CommitTo(db, false)
+	roots := []common.Hash{parentRoot, parentRoot, parentRoot, paidRoot}
+	stateAt := func(number uint64) (*state.StateDB, error) {
+		return state.New(roots[number], state.NewDatabase(db))
+	}
+
+	payments, err := GetEpochPayDetail(chain, stateAt, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paid := make(map[common.Address]*big.Int)
+	for _, group := range payments {
+		for _, client := range group {
+			if paid[client.Addr] == nil {
+				paid[client.Addr] = new(big.Int)
+			}
+			paid[client.Addr].Add(paid[client.Addr], client.Incentive)
+		}
+	}
+	if len(paid) == 0 {
+		t.Fatal("no payment replayed")
+	}
+	for addr, amount := range paid {
+		if balance := stateDb.GetBalance(addr); balance.Cmp(amount) != 0 {
+			t.Fatalf("replayed payment of %x is %v, paid %v", addr, amount, balance)
+		}
+	}
+
+	if _, err := GetEpochPayDetail(chain, stateAt, 1); err != errNotPaid {
+		t.Fatal("epoch 1 should not be paid", err)
+	}
+}
+
+func TestIncentiveHistory(t *testing.T) {
+	defer testHistoryDb(t)()
 	generateTestAddrs()
-	testInitDb()
+	db, _ := ethdb.NewMemDatabase()
+	stateDb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 
 	payExample := [][]vm.ClientIncentive{
 		{
@@ -34,50 +116,74 @@ func TestGetEpochPayDetail(t *testing.T) {
 		},
 	}
 
-	saveIncentiveHistory(epochID, nil)
-	saveIncentiveHistory(epochID, payExample)
-	pay, err := GetEpochPayDetail(epochID)
-	if err != nil {
-		t.FailNow()
-	}
-
-	for i := 0; i < len(pay); i++ {
-		for m := 0; m < len(pay[i]); m++ {
-			if pay[i][m].Addr.Hex() != payExample[i][m].Addr.Hex() || pay[i][m].Incentive.String() != payExample[i][m].Incentive.String() {
-				t.FailNow()
-			}
-		}
-	}
-
-	saveIncentiveHistory(1, payExample)
+	saveIncentiveHistory(stateDb, 0, nil)
+	saveIncentiveHistory(stateDb, 0, payExample)
+	saveIncentiveHistory(stateDb, 1, payExample)
 
-	total, err := GetTotalIncentive()
+	total, err := GetTotalIncentive(stateDb)
 	if total.Uint64() != 3000 || err != nil {
 		t.FailNow()
 	}
 
-	total, err = GetEpochIncentive(1)
+	total, err = GetEpochIncentive(stateDb, 1)
 	if total.Uint64() != 1500 || err != nil {
 		t.FailNow()
 	}
 
-	saveRemain(0, big.NewInt(100))
-	saveRemain(1, big.NewInt(300))
+	saveRemain(stateDb, 0, big.NewInt(100))
+	saveRemain(stateDb, 1, big.NewInt(300))
 
-	epRemain, err := GetEpochRemain(1)
+	epRemain, err := GetEpochRemain(stateDb, 1)
 	if err != nil || epRemain.Uint64() != 300 {
 		t.FailNow()
 	}
-	epRemain, err = GetTotalRemain()
+	epRemain, err = GetTotalRemain(stateDb)
 	if err != nil || epRemain.Uint64() != 400 {
 		t.FailNow()
 	}
 
-	value, err := GetRunTimes()
+	value, err := GetRunTimes(stateDb)
 	if err != nil || value.Uint64() != 2 {
 		t.FailNow()
 	}
 
+	// the totals are a part of the state, so they can be read back from the state root.
+	root, _ := stateDb.CommitTo(db, false)
+	stateDb, _ = state.New(root, state.NewDatabase(db))
+	value, err = GetRunTimes(stateDb)
+	if err != nil || value.Uint64() != 2 {
+		t.FailNow()
+	}
+}
+
+func TestIncentiveHistoryBeforeFork(t *testing.T) {
+	defer testHistoryDb(t)()
+	generateTestAddrs()
+	db, _ := ethdb.NewMemDatabase()
+	stateDb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	root := stateDb.IntermediateRoot(false)
+
+	payExample := [][]vm.ClientIncentive{{{Addr: epAddrs[0], Incentive: big.NewInt(100)}}}
+
+	// before the fork the totals are saved in the local db only
+	saveIncentiveHistory(nil, 2, payExample)
+	saveRemain(nil, 2, big.NewInt(50))
+	if stateDb.IntermediateRoot(false) != root {
+		t.Fatal("history before the fork changed the state")
+	}
+
+	if total, err := GetEpochIncentive(stateDb, 2); err != nil || total.Uint64() != 100 {
+		t.Fatal("epoch total is not read from the local db", total, err)
+	}
+	if remain, err := GetTotalRemain(stateDb); err != nil || remain.Uint64() != 50 {
+		t.Fatal("total remain is not read from the local db", remain, err)
+	}
+
+	// from the fork on the totals of the state are returned
+	saveIncentiveHistory(stateDb, 3, payExample)
+	if value, err := GetRunTimes(stateDb); err != nil || value.Uint64() != 1 {
+		t.Fatal("run times of the state", value, err)
+	}
 }
 
 func TestOtherApiSuccess(t *testing.T) {
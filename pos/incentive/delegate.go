@@ -9,12 +9,13 @@ import (
 	"github.com/wanchain/go-wanchain/log"
 )
 
-// delegate can calc the delegate division
-func delegate(addrs []common.Address, values []*big.Int, epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
+// delegate can calc the delegate division, getInfo is used to get the staker info of addrs
+func delegate(addrs []common.Address, values []*big.Int, epochID uint64,
+	getInfo GetStakerInfoFn) ([][]vm.ClientIncentive, *big.Int, error) {
 	finalIncentive := make([][]vm.ClientIncentive, 0)
 	remain := big.NewInt(0)
 	for i := 0; i < len(addrs); i++ {
-		stakers, division, totalProbility, err := getStakerInfoAndCheck(epochID, addrs[i])
+		stakers, division, totalProbility, err := getStakerInfoAndCheck(epochID, addrs[i], getInfo)
 		if err != nil {
 			log.SyslogErr(err.Error())
 			continue
@@ -27,8 +28,9 @@ func delegate(addrs []common.Address, values []*big.Int, epochID uint64) ([][]vm
 	return finalIncentive, remain, nil
 }
 
-func getStakerInfoAndCheck(epochID uint64, addr common.Address) ([]vm.ClientProbability, uint64, *big.Int, error) {
-	stakers, division, totalProbility, err := getStakerInfo(epochID, addr)
+func getStakerInfoAndCheck(epochID uint64, addr common.Address,
+	getInfo GetStakerInfoFn) ([]vm.ClientProbability, uint64, *big.Int, error) {
+	stakers, division, totalProbility, err := getInfo(epochID, addr)
 	if err != nil {
 		log.Error("getStakerInfo error", "error", err.Error())
 		return nil, 0, nil, err
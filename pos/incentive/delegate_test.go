@@ -21,7 +21,7 @@ func TestDelegate(t *testing.T) {
 		values[i] = big.NewInt(1e18)
 	}
 
-	finalIncentive, remain, err := delegate(epAddrs, values, 0)
+	finalIncentive, remain, err := delegate(epAddrs, values, 0, getStakerInfo)
 
 	if err != nil {
 		t.FailNow()
@@ -2,7 +2,6 @@ package incentive
 
 import (
 	"errors"
-	"fmt"
 	"math"
 	"math/big"
 
@@ -21,16 +20,20 @@ import (
 )
 
 var (
-	redutionYears            = 1
-	redutionRateBase         = 0.88                                                                                   //88% redution for every year
-	subsidyReductionInterval = uint64((365 * 24 * 3600 * redutionYears) / (posconfig.SlotTime * posconfig.SlotCount)) // Epoch count in 1 years
-	percentOfEpochLeader     = 12.0 / 49.0                                                                            //24.4898%
-	percentOfRandomProposer  = 25.0 / 49.0                                                                            //51.0204%
-	percentOfSlotLeader      = 12.0 / 49.0                                                                            //24.4898%
-	ceilingPercentS0         = 100.0                                                                                  //100% Turn off in current version.
-	openIncentive            = true                                                                                   //If the incentive function is open
+	redutionYears           = 1
+	redutionRateBase        = 0.88        //88% redution for every year
+	percentOfEpochLeader    = 12.0 / 49.0 //24.4898%
+	percentOfRandomProposer = 25.0 / 49.0 //51.0204%
+	percentOfSlotLeader     = 12.0 / 49.0 //24.4898%
+	ceilingPercentS0        = 100.0       //100% Turn off in current version.
+	openIncentive           = true        //If the incentive function is open
 )
 
+// subsidyReductionInterval is the epoch count in 1 years
+func subsidyReductionInterval() uint64 {
+	return uint64(365*24*3600*redutionYears) / (posconfig.SlotTime * posconfig.SlotCount)
+}
+
 const (
 	dictGasCollection = "gas_collection"
 	dictEpochRun      = "epoch_run"
@@ -49,7 +52,6 @@ func Init(get GetStakerInfoFn, set SetStakerInfoFn, getRbAddr GetRandomProposerA
 	setActivityInterface(getEpochLeaderActivity, getRandomProposerActivity, getSlotLeaderActivity)
 	setRBAddressInterface(getRbAddr)
 
-	initLocalDb("incentive")
 	log.Info("--------Incentive Init Finish----------")
 }
 
@@ -64,15 +66,62 @@ func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64, bl
 		return true
 	}
 	log.Info("--------Incentive Run Start----------", "epochID", epochID)
-	finalIncentive := make([][]vm.ClientIncentive, 0)
-	remainsAll := big.NewInt(0)
 
+	finalIncentive, remainsAll, err := epochIncentive(chain, stateDb, epochID)
+	if err != nil {
+		return false
+	}
+
+	addRemainIncentivePool(stateDb, epochID, remainsAll)
+
+	pay(finalIncentive, stateDb)
+
+	setStakerInfo(epochID, finalIncentive)
+
+	// the history totals are in the state only from the fork on
+	var historyDb vm.StateDB
+	if chain.Config().IsIncentiveHistory(new(big.Int).SetUint64(blockNumber)) {
+		historyDb = stateDb
+	}
+	saveRemain(historyDb, epochID, remainsAll)
+	saveIncentiveHistory(historyDb, epochID, finalIncentive)
+
+	finished(stateDb, epochID)
+	log.Info("--------Incentive Run Success Finish----------", "epochID", epochID)
+	return true
+}
+
+// epochIncentive computes the payments of epochID and their remains from the
+// state of the block paying them, the chain is read up to its parent.
+func epochIncentive(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
 	total, foundation, gasPool := calculateIncentivePool(stateDb, epochID)
 	saveIncentiveIncome(total, foundation, gasPool)
 
-	epAddrs, epAct := getEpochLeaderInfo(stateDb, epochID)
-	rpAddrs, rpAct := getRandomProposerInfo(stateDb, epochID)
-	slAddrs, slBlk, slAct := getSlotLeaderInfo(chain, epochID, posconfig.SlotCount)
+	act := &epochActivity{}
+	act.epAddrs, act.epAct = getEpochLeaderInfo(stateDb, epochID)
+	act.rpAddrs, act.rpAct = getRandomProposerInfo(stateDb, epochID)
+	act.slAddrs, act.slBlk, act.slAct = getSlotLeaderInfo(chain, epochID, int(posconfig.SlotCount))
+
+	return allocate(total, act, epochID, getStakerInfo)
+}
+
+// epochActivity is the addresses and activity of the protocol participants in an epoch
+type epochActivity struct {
+	epAddrs []common.Address
+	epAct   []int
+	rpAddrs []common.Address
+	rpAct   []int
+	slAddrs []common.Address
+	slBlk   []int
+	slAct   float64
+}
+
+// allocate divides the total incentive of an epoch to the epoch leaders, random proposers
+// and slot leaders by their activity, returns the payments and the remains.
+func allocate(total *big.Int, act *epochActivity, epochID uint64,
+	getInfo GetStakerInfoFn) ([][]vm.ClientIncentive, *big.Int, error) {
+	finalIncentive := make([][]vm.ClientIncentive, 0)
+	remainsAll := big.NewInt(0)
 
 	epochLeaderSubsidy := calcPercent(total, float64(percentOfEpochLeader*100.0))
 	randomProposerSubsidy := calcPercent(total, float64(percentOfRandomProposer*100.0))
@@ -86,29 +135,29 @@ func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64, bl
 	sumRemain := big.NewInt(0).Sub(total, sum)
 	remainsAll.Add(remainsAll, sumRemain)
 
-	incentives, remains, err := epochLeaderAllocate(epochLeaderSubsidy, epAddrs, epAct, epochID)
+	incentives, remains, err := epochLeaderAllocate(epochLeaderSubsidy, act.epAddrs, act.epAct, epochID, getInfo)
 	if err != nil {
-		log.Error("Incentive epochLeaderAllocate error", "error", err.Error(), "epochLeaderSubsidy", epochLeaderSubsidy.String(), "epAddrs", epAddrs)
+		log.Error("Incentive epochLeaderAllocate error", "error", err.Error(), "epochLeaderSubsidy", epochLeaderSubsidy.String(), "epAddrs", act.epAddrs)
 		log.SyslogErr("Incentive epochLeaderAllocate error")
-		return false
+		return nil, nil, err
 	}
 	finalIncentive = append(finalIncentive, incentives...)
 	remainsAll.Add(remainsAll, remains)
 
-	incentives, remains, err = randomProposerAllocate(randomProposerSubsidy, rpAddrs, rpAct, epochID)
+	incentives, remains, err = randomProposerAllocate(randomProposerSubsidy, act.rpAddrs, act.rpAct, epochID, getInfo)
 	if err != nil {
-		log.Error("Incentive randomProposerAllocate error", "error", err.Error(), "randomProposerSubsidy", randomProposerSubsidy.String(), "rpAddrs", rpAddrs)
+		log.Error("Incentive randomProposerAllocate error", "error", err.Error(), "randomProposerSubsidy", randomProposerSubsidy.String(), "rpAddrs", act.rpAddrs)
 		log.SyslogErr("Incentive randomProposerAllocate error")
-		return false
+		return nil, nil, err
 	}
 	finalIncentive = append(finalIncentive, incentives...)
 	remainsAll.Add(remainsAll, remains)
 
-	incentives, remains, err = slotLeaderAllocate(slotLeaderSubsidy, slAddrs, slBlk, slAct, posconfig.SlotCount, epochID)
+	incentives, remains, err = slotLeaderAllocate(slotLeaderSubsidy, act.slAddrs, act.slBlk, act.slAct, int(posconfig.SlotCount), epochID, getInfo)
 	if err != nil {
-		log.Error("Incentive slotLeaderAllocate error", "slotLeaderSubsidy", slotLeaderSubsidy.String(), "slAddrs", slAddrs)
+		log.Error("Incentive slotLeaderAllocate error", "slotLeaderSubsidy", slotLeaderSubsidy.String(), "slAddrs", act.slAddrs)
 		log.SyslogErr("Incentive slotLeaderAllocate error")
-		return false
+		return nil, nil, err
 	}
 	finalIncentive = append(finalIncentive, incentives...)
 	remainsAll.Add(remainsAll, remains)
@@ -119,20 +168,10 @@ func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64, bl
 	if !checkTotalValue(total, sumPay, remainsAll) {
 		log.Error("Incentive checkTotalValue error", "sumPay", sumPay.String(), "remainsAll", remainsAll.String(), "total", total.String())
 		log.SyslogErr("Incentive checkTotalValue error")
-		return false
+		return nil, nil, errors.New("incentive checkTotalValue error")
 	}
 
-	addRemainIncentivePool(stateDb, epochID, remainsAll)
-	saveRemain(epochID, remainsAll)
-
-	pay(finalIncentive, stateDb)
-
-	setStakerInfo(epochID, finalIncentive)
-	saveIncentiveHistory(epochID, finalIncentive)
-
-	finished(stateDb, epochID)
-	log.Info("--------Incentive Run Success Finish----------", "epochID", epochID)
-	return true
+	return finalIncentive, remainsAll, nil
 }
 
 func getIncentivePrecompileAddress() common.Address {
@@ -158,7 +197,7 @@ func finished(stateDb *state.StateDB, epochID uint64) {
 
 // protocalRunerAllocate use to calc the subsidy of protocal Participant (Epoch leader and Random proposer)
 func protocalRunerAllocate(funds *big.Int, addrs []common.Address, acts []int,
-	epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
+	epochID uint64, getInfo GetStakerInfoFn) ([][]vm.ClientIncentive, *big.Int, error) {
 	remains := big.NewInt(0)
 	count := len(addrs)
 	if count == 0 {
@@ -186,7 +225,7 @@ func protocalRunerAllocate(funds *big.Int, addrs []common.Address, acts []int,
 		}
 	}
 
-	finalIncentive, subRemain, err := delegate(fundAddrs, fundValues, epochID)
+	finalIncentive, subRemain, err := delegate(fundAddrs, fundValues, epochID, getInfo)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -197,19 +236,19 @@ func protocalRunerAllocate(funds *big.Int, addrs []common.Address, acts []int,
 
 // epochLeaderAllocate input funds, address and activity returns address and its amount allocate and remaining funds.
 func epochLeaderAllocate(funds *big.Int, addrs []common.Address, acts []int,
-	epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
-	return protocalRunerAllocate(funds, addrs, acts, epochID)
+	epochID uint64, getInfo GetStakerInfoFn) ([][]vm.ClientIncentive, *big.Int, error) {
+	return protocalRunerAllocate(funds, addrs, acts, epochID, getInfo)
 }
 
 //randomProposerAllocate input funds, address and activity returns address and its amount allocate and remaining funds.
 func randomProposerAllocate(funds *big.Int, addrs []common.Address, acts []int,
-	epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
-	return protocalRunerAllocate(funds, addrs, acts, epochID)
+	epochID uint64, getInfo GetStakerInfoFn) ([][]vm.ClientIncentive, *big.Int, error) {
+	return protocalRunerAllocate(funds, addrs, acts, epochID, getInfo)
 }
 
 //slotLeaderAllocate input funds, address, blocks and activity returns address and its amount allocate and remaining funds.
 func slotLeaderAllocate(funds *big.Int, addrs []common.Address, blocks []int,
-	act float64, slotCount int, epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
+	act float64, slotCount int, epochID uint64, getInfo GetStakerInfoFn) ([][]vm.ClientIncentive, *big.Int, error) {
 	remains := big.NewInt(0)
 
 	scale := 100000.0
@@ -230,7 +269,7 @@ func slotLeaderAllocate(funds *big.Int, addrs []common.Address, blocks []int,
 		fundValues = append(fundValues, big.NewInt(0).Mul(incentiveActive, big.NewInt(int64(blocks[i]))))
 	}
 
-	finalIncentive, subRemain, err := delegate(fundAddrs, fundValues, epochID)
+	finalIncentive, subRemain, err := delegate(fundAddrs, fundValues, epochID, getInfo)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -250,7 +289,7 @@ func sumToPay(readyToPay [][]vm.ClientIncentive) *big.Int {
 }
 
 func checkTotalValue(total *big.Int, sumPay, remain *big.Int) bool {
-	fmt.Println("Total:", total, "payout:", sumPay.String(), "remains:", remain)
+	log.Debug("Incentive check total value", "total", total, "payout", sumPay, "remains", remain)
 
 	sum := big.NewInt(0).Add(sumPay, remain)
 	if total.Cmp(sum) == -1 {
@@ -273,7 +312,7 @@ func saveIncentiveIncome(total, foundation, gasPool *big.Int) {
 }
 
 func saveIncentiveDivide(ep, rp, sl *big.Int) {
-	fmt.Println("ep:", ep, "rp:", rp, "sl:", sl)
+	log.Debug("Incentive divide", "ep", ep, "rp", rp, "sl", sl)
 }
 
 func getExtraRemain(total, sumPay, remain *big.Int) *big.Int {
@@ -25,7 +25,7 @@ func TestRun(t *testing.T) {
 	testTimes := 1
 
 	for i := 0; i < testTimes; i++ {
-		for m := 0; m < posconfig.SlotCount; m++ {
+		for m := 0; m < int(posconfig.SlotCount); m++ {
 			if !Run(&TestChainReader{}, statedb, uint64(i), uint64(m)) {
 				t.FailNow()
 			}
@@ -57,7 +57,7 @@ func generateTestAddrs() {
 
 	for i := 0; i < addrsCount; i++ {
 		slAddrs[i] = epAddrs[i]
-		slBlks[i] = posconfig.SlotCount / addrsCount
+		slBlks[i] = int(posconfig.SlotCount) / addrsCount
 	}
 }
 
@@ -0,0 +1,184 @@
+package incentive
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+// SimClient is a hypothetical delegator of a SimStaker
+type SimClient struct {
+	Address common.Address
+	Amount  *big.Int
+}
+
+// SimStaker is a hypothetical staker used by Simulate
+type SimStaker struct {
+	Address    common.Address
+	Amount     *big.Int
+	LockEpochs uint64
+	FeeRate    uint64
+	Clients    []SimClient
+}
+
+// SimInput is the staker set and activity of a what-if epoch.
+// Foundation nil means use the subsidy schedule of EpochID.
+type SimInput struct {
+	EpochID    uint64
+	Foundation *big.Int
+	GasPool    *big.Int
+	Stakers    []SimStaker
+	Activity
+}
+
+// SimResult is the incentive a SimInput produces
+type SimResult struct {
+	EpochID    uint64
+	Total      *big.Int
+	Foundation *big.Int
+	GasPool    *big.Int
+	Remain     *big.Int
+	Rewards    map[common.Address]*big.Int
+	// APR is the annual rate (0.1 is 10%) of each staker and client if the epoch repeats for a year
+	APR map[common.Address]float64
+}
+
+var (
+	errSimStaker   = errors.New("simulate staker is invalid")
+	errSimUnknown  = errors.New("simulate activity address is not a staker")
+	errSimActivity = errors.New("simulate activity length mismatch")
+)
+
+// Simulate runs the incentive allocation of one epoch over a hypothetical staker
+// set, nothing is read from or written to the chain.
+func Simulate(input *SimInput) (*SimResult, error) {
+	if input == nil {
+		return nil, errors.New("simulate input is nil")
+	}
+
+	stakers := make(map[common.Address]*SimStaker, len(input.Stakers))
+	for i := range input.Stakers {
+		s := &input.Stakers[i]
+		if s.Amount == nil || s.Amount.Sign() <= 0 || s.FeeRate > 100 {
+			return nil, errSimStaker
+		}
+		for _, c := range s.Clients {
+			if c.Amount == nil || c.Amount.Sign() <= 0 {
+				return nil, errSimStaker
+			}
+		}
+		stakers[s.Address] = s
+	}
+
+	act := &epochActivity{
+		epAddrs: input.EpLeader,
+		epAct:   input.EpActivity,
+		rpAddrs: input.RpLeader,
+		rpAct:   input.RpActivity,
+		slAddrs: input.SltLeader,
+		slBlk:   input.SlBlocks,
+		slAct:   input.SlActivity,
+	}
+	if len(act.epAddrs) != len(act.epAct) || len(act.rpAddrs) != len(act.rpAct) ||
+		len(act.slAddrs) != len(act.slBlk) {
+		return nil, errSimActivity
+	}
+	for _, addrs := range [][]common.Address{act.epAddrs, act.rpAddrs, act.slAddrs} {
+		for _, addr := range addrs {
+			if _, ok := stakers[addr]; !ok {
+				return nil, errSimUnknown
+			}
+		}
+	}
+
+	foundation := input.Foundation
+	if foundation == nil {
+		db, _ := ethdb.NewMemDatabase()
+		stateDb, err := state.New(common.Hash{}, state.NewDatabase(db))
+		if err != nil {
+			return nil, err
+		}
+		foundation = calcWanFromFoundation(stateDb, input.EpochID)
+	}
+	gasPool := input.GasPool
+	if gasPool == nil {
+		gasPool = big.NewInt(0)
+	}
+	total := big.NewInt(0).Add(foundation, gasPool)
+
+	getInfo := func(epochID uint64, addr common.Address) ([]vm.ClientProbability, uint64, *big.Int, error) {
+		s, ok := stakers[addr]
+		if !ok {
+			return nil, 0, nil, errSimUnknown
+		}
+		return simProbability(s)
+	}
+
+	payments, remain, err := allocate(total, act, input.EpochID, getInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SimResult{
+		EpochID:    input.EpochID,
+		Total:      total,
+		Foundation: foundation,
+		GasPool:    gasPool,
+		Remain:     remain,
+		Rewards:    make(map[common.Address]*big.Int),
+		APR:        make(map[common.Address]float64),
+	}
+	for i := 0; i < len(payments); i++ {
+		for m := 0; m < len(payments[i]); m++ {
+			addr := payments[i][m].Addr
+			if _, ok := result.Rewards[addr]; !ok {
+				result.Rewards[addr] = big.NewInt(0)
+			}
+			result.Rewards[addr].Add(result.Rewards[addr], payments[i][m].Incentive)
+		}
+	}
+
+	for _, s := range input.Stakers {
+		result.APR[s.Address] = simAPR(result.Rewards[s.Address], s.Amount)
+		for _, c := range s.Clients {
+			result.APR[c.Address] = simAPR(result.Rewards[c.Address], c.Amount)
+		}
+	}
+
+	return result, nil
+}
+
+// simProbability returns the same staker info as the epoch leader selection does,
+// the staker is weighted by its lock time and the clients are not.
+func simProbability(s *SimStaker) ([]vm.ClientProbability, uint64, *big.Int, error) {
+	infos := make([]vm.ClientProbability, 1, len(s.Clients)+1)
+	infos[0].Addr = s.Address
+	infos[0].Probability = big.NewInt(0).Mul(s.Amount, big.NewInt(int64(vm.CalLocktimeWeight(s.LockEpochs))))
+	totalProbability := big.NewInt(0).Set(infos[0].Probability)
+
+	weight := big.NewInt(int64(vm.CalLocktimeWeight(0)))
+	for _, c := range s.Clients {
+		info := vm.ClientProbability{
+			Addr:        c.Address,
+			Probability: big.NewInt(0).Mul(c.Amount, weight),
+		}
+		totalProbability.Add(totalProbability, info.Probability)
+		infos = append(infos, info)
+	}
+	return infos, s.FeeRate, totalProbability, nil
+}
+
+// simAPR returns reward/amount scaled from one epoch to a year
+func simAPR(reward, amount *big.Int) float64 {
+	if reward == nil || amount == nil || amount.Sign() == 0 {
+		return 0
+	}
+	epochsPerYear := float64(365*24*3600) / float64(posconfig.SlotTime*posconfig.SlotCount)
+	r, _ := new(big.Float).Quo(new(big.Float).SetInt(reward), new(big.Float).SetInt(amount)).Float64()
+	return r * epochsPerYear
+}
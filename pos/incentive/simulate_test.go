@@ -0,0 +1,117 @@
+package incentive
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+func simTestInput() *SimInput {
+	wan := big.NewInt(1e18)
+	a := common.HexToAddress("0x01")
+	b := common.HexToAddress("0x02")
+	c := common.HexToAddress("0x03")
+
+	slBlk := int(posconfig.SlotCount) / 2
+	return &SimInput{
+		EpochID: 10,
+		GasPool: big.NewInt(0).Mul(big.NewInt(3), wan),
+		Stakers: []SimStaker{
+			{Address: a, Amount: big.NewInt(0).Mul(big.NewInt(100000), wan), LockEpochs: 90, FeeRate: 10,
+				Clients: []SimClient{{Address: c, Amount: big.NewInt(0).Mul(big.NewInt(50000), wan)}}},
+			{Address: b, Amount: big.NewInt(0).Mul(big.NewInt(100000), wan), LockEpochs: 7, FeeRate: 100},
+		},
+		Activity: Activity{
+			EpLeader:   []common.Address{a, b},
+			EpActivity: []int{1, 1},
+			RpLeader:   []common.Address{a, b},
+			RpActivity: []int{1, 0},
+			SltLeader:  []common.Address{a, b},
+			SlBlocks:   []int{slBlk, int(posconfig.SlotCount) - slBlk},
+			SlActivity: 1,
+		},
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	input := simTestInput()
+	result, err := Simulate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, _ := ethdb.NewMemDatabase()
+	stateDb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	if result.Foundation.Cmp(calcWanFromFoundation(stateDb, input.EpochID)) != 0 {
+		t.Fatal("foundation mismatch", result.Foundation)
+	}
+	if result.Total.Cmp(big.NewInt(0).Add(result.Foundation, input.GasPool)) != 0 {
+		t.Fatal("total mismatch", result.Total)
+	}
+
+	sum := big.NewInt(0).Set(result.Remain)
+	for _, v := range result.Rewards {
+		sum.Add(sum, v)
+	}
+	if sum.Cmp(result.Total) != 0 {
+		t.Fatal("rewards and remain mismatch total", sum, result.Total)
+	}
+
+	a, b, c := input.Stakers[0].Address, input.Stakers[1].Address, input.Stakers[0].Clients[0].Address
+	if result.Rewards[c] == nil || result.Rewards[c].Sign() <= 0 {
+		t.Fatal("client has no reward")
+	}
+	// b missed its random proposer work and keeps all of its fee, a shares with c
+	if result.Rewards[a].Cmp(result.Rewards[b]) <= 0 {
+		t.Fatal("reward of a should be greater than b", result.Rewards[a], result.Rewards[b])
+	}
+	if result.APR[a] <= 0 || result.APR[c] <= 0 {
+		t.Fatal("apr should be positive", result.APR)
+	}
+}
+
+func TestSimulateFoundation(t *testing.T) {
+	input := simTestInput()
+	input.Foundation = big.NewInt(0)
+	input.GasPool = big.NewInt(0)
+	result, err := Simulate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total.Sign() != 0 || len(result.Rewards) == 0 {
+		t.Fatal("empty pool should pay zero", result.Total)
+	}
+	for _, v := range result.Rewards {
+		if v.Sign() != 0 {
+			t.Fatal("empty pool should pay zero", v)
+		}
+	}
+}
+
+func TestSimulateInvalid(t *testing.T) {
+	if _, err := Simulate(nil); err == nil {
+		t.Fatal("nil input should fail")
+	}
+
+	input := simTestInput()
+	input.EpActivity = input.EpActivity[:1]
+	if _, err := Simulate(input); err != errSimActivity {
+		t.Fatal("activity length mismatch should fail", err)
+	}
+
+	input = simTestInput()
+	input.RpLeader[0] = common.HexToAddress("0x09")
+	if _, err := Simulate(input); err != errSimUnknown {
+		t.Fatal("unknown address should fail", err)
+	}
+
+	input = simTestInput()
+	input.Stakers[0].FeeRate = 101
+	if _, err := Simulate(input); err != errSimStaker {
+		t.Fatal("fee rate over 100 should fail", err)
+	}
+}
@@ -0,0 +1,83 @@
+package incentive
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+// SlashRun moves the wan slashed by the double sign evidences into the remain incentive pool,
+// and slashes the random proposers of epochID who skipped the dkg2 or signature stage into it.
+// It should be called in Finalize of consensus. The random proposers are not slashed before
+// posconfig.RBSlashEpoch.
+func SlashRun(stateDb *state.StateDB, epochID uint64) bool {
+	if stateDb == nil {
+		log.SyslogErr("incentive SlashRun input param error (stateDb == nil)")
+		return false
+	}
+
+	if pool := vm.TakeSlashPool(stateDb); pool.Sign() > 0 {
+		addRemainIncentivePool(stateDb, epochID, pool)
+		log.Info("--------Incentive Double Sign Slash----------", "epochID", epochID, "total", pool)
+	}
+
+	if posconfig.RBSlashEpoch == 0 || epochID < posconfig.RBSlashEpoch {
+		return true
+	}
+
+	if vm.SlashIsFinished(stateDb, epochID) {
+		return true
+	}
+
+	total := big.NewInt(0)
+	if rate := posconfig.Cfg().RBSlashRate; rate != 0 {
+		amount, err := slashRandomProposers(stateDb, epochID, rate)
+		if err != nil {
+			return false
+		}
+		total.Add(total, amount)
+	}
+
+	if total.Sign() > 0 {
+		addRemainIncentivePool(stateDb, epochID, total)
+	}
+
+	vm.SlashSetEpoch(stateDb, epochID)
+	log.Info("--------Incentive Slash Finish----------", "epochID", epochID, "total", total)
+	return true
+}
+
+// slashRandomProposers slashes the random proposers of epochID who skipped a stage, returns the wan slashed
+func slashRandomProposers(stateDb *state.StateDB, epochID uint64, rate uint64) (*big.Int, error) {
+	if getRandomProposerAddress == nil {
+		log.Error("incentive SlashRun getRandomProposerAddress == nil", "epochID", epochID)
+		return nil, errors.New("getRandomProposerAddress == nil")
+	}
+
+	leaders := getRandomProposerAddress(epochID)
+	if len(leaders) == 0 {
+		log.Error("incentive SlashRun getRandomProposerAddress error", "epochID", epochID)
+		return nil, errors.New("random proposers not found")
+	}
+
+	total := big.NewInt(0)
+	for i := 0; i < len(leaders); i++ {
+		kind := vm.RBSlashKind(stateDb, epochID, uint32(i))
+		if kind == 0 {
+			continue
+		}
+
+		amount, err := vm.SlashStaker(stateDb, epochID, uint32(i), leaders[i].SecAddr, kind, rate)
+		if err != nil {
+			// the proposers in white list have no stake
+			log.Warn("incentive SlashRun slash staker failed", "epochID", epochID, "proposerId", i, "address", leaders[i].SecAddr, "err", err)
+			continue
+		}
+		total.Add(total, amount)
+	}
+	return total, nil
+}
@@ -0,0 +1,165 @@
+package incentive
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+func testStoreStaker(addr common.Address, amount *big.Int) {
+	staker := &vm.StakerInfo{
+		Address:     addr,
+		Amount:      new(big.Int).Set(amount),
+		StakeAmount: new(big.Int).Mul(amount, big.NewInt(int64(vm.CalLocktimeWeight(10)))),
+		LockEpochs:  10,
+	}
+	buf, _ := rlp.EncodeToBytes(staker)
+	statedb.SetStateByteArray(vm.StakersInfoAddr, vm.GetStakeInKeyHash(addr), buf)
+}
+
+func testGetStakerAmount(t *testing.T, addr common.Address) *big.Int {
+	buf := statedb.GetStateByteArray(vm.StakersInfoAddr, vm.GetStakeInKeyHash(addr))
+	var staker vm.StakerInfo
+	if err := rlp.DecodeBytes(buf, &staker); err != nil {
+		t.Fatal(err)
+	}
+	return staker.Amount
+}
+
+func TestSlashRun(t *testing.T) {
+	statedb.Reset(common.Hash{})
+	generateTestAddrs()
+	setRBAddressInterface(testGetRBAddress)
+	posconfig.RBSlashEpoch = 5
+	defer func() { posconfig.RBSlashEpoch = 0 }()
+
+	epochID := uint64(5)
+	amount := new(big.Int).Mul(big.NewInt(100000), big.NewInt(1e18))
+	for i := 0; i < len(rpAddrs); i++ {
+		testStoreStaker(rpAddrs[i], amount)
+	}
+	statedb.AddBalance(vm.WanCscPrecompileAddr, new(big.Int).Mul(amount, big.NewInt(int64(len(rpAddrs)))))
+
+	// proposer 0 skips every stage, proposer 1 joins dkg2 only
+	randomBeaconPrecompileAddr := common.BytesToAddress(big.NewInt(610).Bytes())
+	statedb.SetStateByteArray(randomBeaconPrecompileAddr, *vm.GetRBKeyHash([]byte{101}, epochID, 1), []byte{1, 2, 3})
+	for i := 2; i < len(rpAddrs); i++ {
+		testSimulateData(epochID, uint32(i))
+	}
+
+	if !SlashRun(statedb, epochID) {
+		t.Fatal("SlashRun failed")
+	}
+
+	slash := new(big.Int).Mul(amount, big.NewInt(int64(posconfig.Cfg().RBSlashRate)))
+	slash.Div(slash, big.NewInt(posconfig.RBSlashRateBase))
+	left := new(big.Int).Sub(amount, slash)
+
+	kinds := []uint64{vm.SlashKindDkg, vm.SlashKindSig}
+	for i := 0; i < len(rpAddrs); i++ {
+		evidence, err := vm.GetSlashEvidence(statedb, epochID, uint32(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < len(kinds) {
+			if testGetStakerAmount(t, rpAddrs[i]).Cmp(left) != 0 {
+				t.Fatal("staker should be slashed", i)
+			}
+			if evidence == nil || evidence.Kind != kinds[i] || evidence.Amount.Cmp(slash) != 0 || evidence.Address != rpAddrs[i] {
+				t.Fatal("slash evidence mismatch", i, evidence)
+			}
+			continue
+		}
+		if testGetStakerAmount(t, rpAddrs[i]).Cmp(amount) != 0 || evidence != nil {
+			t.Fatal("active staker should not be slashed", i)
+		}
+	}
+
+	total := new(big.Int).Mul(slash, big.NewInt(int64(len(kinds))))
+	if remain := getRemainIncentivePool(statedb, epochID+subsidyReductionInterval()); remain.Cmp(total) != 0 {
+		t.Fatal("slashed wan should go into the remain pool", remain, total)
+	}
+	balance := new(big.Int).Mul(amount, big.NewInt(int64(len(rpAddrs))))
+	balance.Sub(balance, total)
+	if statedb.GetBalance(vm.WanCscPrecompileAddr).Cmp(balance) != 0 {
+		t.Fatal("slashed wan should be burned from the staking contract")
+	}
+
+	// an epoch is slashed only once
+	if !SlashRun(statedb, epochID) {
+		t.Fatal("SlashRun failed")
+	}
+	if testGetStakerAmount(t, rpAddrs[0]).Cmp(left) != 0 {
+		t.Fatal("staker should not be slashed twice")
+	}
+}
+
+func TestSlashRunBeforeEpoch(t *testing.T) {
+	statedb.Reset(common.Hash{})
+	generateTestAddrs()
+	setRBAddressInterface(testGetRBAddress)
+
+	epochID := uint64(5)
+	amount := new(big.Int).Mul(big.NewInt(100000), big.NewInt(1e18))
+	for i := 0; i < len(rpAddrs); i++ {
+		testStoreStaker(rpAddrs[i], amount)
+	}
+	root := statedb.IntermediateRoot(false)
+
+	// every proposer skipped the stages, but the slashing is disabled or not started
+	for _, slashEpoch := range []uint64{0, epochID + 1} {
+		posconfig.RBSlashEpoch = slashEpoch
+		if !SlashRun(statedb, epochID) {
+			t.Fatal("SlashRun failed")
+		}
+		if statedb.IntermediateRoot(false) != root {
+			t.Fatal("state changed before the slash epoch", slashEpoch)
+		}
+	}
+	posconfig.RBSlashEpoch = 0
+}
+
+func TestSlashRunDoubleSignPool(t *testing.T) {
+	statedb.Reset(common.Hash{})
+	pool := new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18))
+
+	// the double sign slashes go into the remain pool even if the random proposers are never slashed
+	for _, slashEpoch := range []uint64{0, 10} {
+		posconfig.RBSlashEpoch = slashEpoch
+		vm.StoreInfo(statedb, vm.StakingCommonAddr, vm.SlashPoolKeyHash, pool.Bytes())
+		if !SlashRun(statedb, 5) {
+			t.Fatal("SlashRun failed")
+		}
+		if left := vm.TakeSlashPool(statedb); left.Sign() != 0 {
+			t.Fatal("double sign slash pool should be emptied", slashEpoch, left)
+		}
+	}
+	posconfig.RBSlashEpoch = 0
+
+	total := new(big.Int).Mul(pool, big.NewInt(2))
+	if remain := getRemainIncentivePool(statedb, 5+subsidyReductionInterval()); remain.Cmp(total) != 0 {
+		t.Fatal("double sign slashes should go into the remain pool", remain, total)
+	}
+}
+
+func TestSlashRunFail(t *testing.T) {
+	if SlashRun(nil, 0) {
+		t.Fatal("SlashRun should fail without state")
+	}
+
+	statedb.Reset(common.Hash{})
+	tmp := getRandomProposerAddress
+	getRandomProposerAddress = nil
+	posconfig.RBSlashEpoch = 1
+	defer func() {
+		getRandomProposerAddress = tmp
+		posconfig.RBSlashEpoch = 0
+	}()
+	if SlashRun(statedb, 1) {
+		t.Fatal("SlashRun should fail without random proposers")
+	}
+}
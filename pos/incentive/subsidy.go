@@ -35,16 +35,16 @@ func getBaseSubsidyTotalForSlot(stateDb *state.StateDB, epochID uint64) *big.Int
 
 	// 2500000 wan coin for first year
 	year := big.NewInt(0).Mul(big.NewInt(2.5e6), big.NewInt(1e18))
-	baseSubsidy := calcBaseSubsidy(year, posconfig.SlotTime)
+	baseSubsidy := calcBaseSubsidy(year, int64(posconfig.SlotTime))
 
-	redutionRateNow := math.Pow(redutionRateBase, float64(epochID/subsidyReductionInterval))
+	redutionRateNow := math.Pow(redutionRateBase, float64(epochID/subsidyReductionInterval()))
 	baseSubsidyReduction := calcPercent(baseSubsidy, redutionRateNow*100.0)
 
 	// If 1 years later, need add the remain incentive pool value of last 1 years
-	if (epochID / subsidyReductionInterval) >= 1 {
+	if (epochID / subsidyReductionInterval()) >= 1 {
 		remainLastPeriod := getRemainIncentivePool(stateDb, epochID)
 		remainLastPerYears := remainLastPeriod.Div(remainLastPeriod, big.NewInt(int64(redutionYears)))
-		baseRemain := calcBaseSubsidy(remainLastPerYears, posconfig.SlotTime)
+		baseRemain := calcBaseSubsidy(remainLastPerYears, int64(posconfig.SlotTime))
 		baseSubsidyReduction.Add(baseSubsidyReduction, baseRemain)
 	}
 
@@ -59,7 +59,7 @@ func calcWanFromFoundation(stateDb *state.StateDB, epochID uint64) *big.Int {
 	}
 
 	subsidyOfSlot := getBaseSubsidyTotalForSlot(stateDb, epochID)
-	subsidyOfEpoch := big.NewInt(0).Mul(subsidyOfSlot, big.NewInt(posconfig.SlotCount))
+	subsidyOfEpoch := big.NewInt(0).Mul(subsidyOfSlot, new(big.Int).SetUint64(posconfig.SlotCount))
 	return subsidyOfEpoch
 }
 
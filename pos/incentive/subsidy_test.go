@@ -40,11 +40,11 @@ func TestCalcBaseSubsidy(t *testing.T) {
 func TestGetBaseSubsidyTotalForSlot(t *testing.T) {
 	statedb.Reset(common.Hash{})
 	year := big.NewInt(0).Mul(big.NewInt(2.5e6), big.NewInt(1e18))
-	base := calcBaseSubsidy(year, posconfig.SlotTime)
-	fmt.Println(subsidyReductionInterval)
+	base := calcBaseSubsidy(year, int64(posconfig.SlotTime))
+	fmt.Println(subsidyReductionInterval())
 
 	for i := uint64(1); i < uint64(500); i++ {
-		subsidy := getBaseSubsidyTotalForSlot(statedb, subsidyReductionInterval*i)
+		subsidy := getBaseSubsidyTotalForSlot(statedb, subsidyReductionInterval()*i)
 		if subsidy.Uint64() == 0 {
 			fmt.Println("finish", i)
 			return
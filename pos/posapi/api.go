@@ -4,7 +4,6 @@ import (
 	"encoding/hex"
 	"fmt"
 	"sort"
-	"time"
 
 	"github.com/wanchain/go-wanchain/pos/cfm"
 
@@ -25,6 +24,7 @@ import (
 	"encoding/binary"
 
 	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/internal/ethapi"
@@ -33,6 +33,7 @@ import (
 	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/pos/slotleader"
 	"github.com/wanchain/go-wanchain/rpc"
+	"github.com/wanchain/go-wanchain/trie"
 )
 
 type PosApi struct {
@@ -145,6 +146,31 @@ func (a PosApi) Random(epochId uint64, blockNr int64) (*big.Int, error) {
 	return r, nil
 }
 
+// GetRandomBeaconData returns the random beacon data stored in epochId, which is all
+// needed to verify the random of epochId+1 offline.
+func (a PosApi) GetRandomBeaconData(epochId uint64) (*vm.RbEpochData, error) {
+	state, err := a.latestState()
+	if err != nil {
+		return nil, err
+	}
+
+	epocher := epochLeader.GetEpocher()
+	if epocher == nil {
+		return nil, errors.New("epocher is not initialized")
+	}
+	return vm.GetRbEpochData(state, epochId, epocher.GetRBProposerG1(epochId))
+}
+
+// VerifyRandom re-checks the random of epochId+1 from the data stored in epochId,
+// and reports which proposers were valid or excluded.
+func (a PosApi) VerifyRandom(epochId uint64) (*vm.RbVerifyResult, error) {
+	data, err := a.GetRandomBeaconData(epochId)
+	if err != nil {
+		return nil, err
+	}
+	return vm.VerifyRandom(data)
+}
+
 func (a PosApi) GetReorg(epochid uint64,slotid uint64) ([]uint64, error) {
 	reOrgDb := posdb.GetDbByName("forkdb")
 	if reOrgDb == nil {
@@ -225,6 +251,10 @@ type StakerJson struct {
 	FeeRate      uint64
 	NextFeeRate  uint64
 	Clients      []vm.ClientInfo
+
+	QuitEpoch uint64
+	OutAmount *big.Int
+	OutEpoch  uint64
 }
 
 // this is the static snap of stekers by the block Number.
@@ -262,6 +292,10 @@ func (a PosApi) GetStakerInfo(targetBlkNum uint64) ([]StakerJson, error) {
 		stakeJson.FeeRate = staker.FeeRate
 		stakeJson.NextFeeRate = staker.NextFeeRate
 		stakeJson.Clients = staker.Clients
+		out := staker.GetStakeOut()
+		stakeJson.QuitEpoch = out.QuitEpoch
+		stakeJson.OutAmount = out.OutAmount
+		stakeJson.OutEpoch = out.OutEpoch
 		stakeJson.PubSec256 = hexutil.Encode(staker.PubSec256)
 		stakeJson.PubBn256 = hexutil.Encode(staker.PubBn256)
 		stakers = append(stakers, stakeJson)
@@ -270,6 +304,101 @@ func (a PosApi) GetStakerInfo(targetBlkNum uint64) ([]StakerJson, error) {
 	return stakers, nil
 }
 
+// StakerProof is the StakerInfo storage entry of a staker together with
+// the merkle proofs which link it to the state root of a block.
+type StakerProof struct {
+	Address      common.Address
+	BlockNumber  uint64
+	StateRoot    common.Hash
+	StorageRoot  common.Hash
+	StorageKey   common.Hash
+	Value        hexutil.Bytes // rlp encoded vm.StakerInfo, empty if the staker doesn't exist
+	AccountProof []hexutil.Bytes
+	StorageProof []hexutil.Bytes
+}
+
+// GetStakerProof returns the StakerInfo of the staker addr at the block blockNr with
+// the account proof of vm.StakersInfoAddr and the storage proof of the staker entry.
+func (a PosApi) GetStakerProof(addr common.Address, blockNr int64) (*StakerProof, error) {
+	state, header, err := a.backend.StateAndHeaderByNumber(context.Background(), rpc.BlockNumber(blockNr))
+	if state == nil || err != nil {
+		return nil, err
+	}
+
+	accountProof, err := state.GetProof(vm.StakersInfoAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	key := vm.GetStakeInKeyHash(addr)
+	storageProof, err := state.GetStorageProof(vm.StakersInfoAddr, key)
+	if err != nil {
+		return nil, err
+	}
+
+	proof := &StakerProof{
+		Address:      addr,
+		BlockNumber:  header.Number.Uint64(),
+		StateRoot:    header.Root,
+		StorageRoot:  state.StorageTrie(vm.StakersInfoAddr).Hash(),
+		StorageKey:   key,
+		Value:        state.GetStateByteArray(vm.StakersInfoAddr, key),
+		AccountProof: toHexProof(accountProof),
+		StorageProof: toHexProof(storageProof),
+	}
+	return proof, nil
+}
+
+// VerifyStakerProof checks the proof against the state root, and returns the proven staker info.
+// It returns nil staker info if the proof proves the staker doesn't exist.
+func VerifyStakerProof(stateRoot common.Hash, proof *StakerProof) (*vm.StakerInfo, error) {
+	enc, err := trie.VerifyProof(stateRoot, crypto.Keccak256(vm.StakersInfoAddr[:]), fromHexProof(proof.AccountProof))
+	if err != nil {
+		return nil, err
+	}
+	if enc == nil {
+		return nil, errors.New("staker info account doesn't exist")
+	}
+	var account state.Account
+	if err := rlp.DecodeBytes(enc, &account); err != nil {
+		return nil, err
+	}
+
+	key := vm.GetStakeInKeyHash(proof.Address)
+	value, err := trie.VerifyProof(account.Root, crypto.Keccak256(key[:]), fromHexProof(proof.StorageProof))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	staker := vm.StakerInfo{}
+	if err := rlp.DecodeBytes(value, &staker); err != nil {
+		return nil, err
+	}
+	if staker.Address != proof.Address {
+		return nil, errors.New("proven staker address mismatch")
+	}
+	return &staker, nil
+}
+
+func toHexProof(proof []rlp.RawValue) []hexutil.Bytes {
+	ret := make([]hexutil.Bytes, len(proof))
+	for i := range proof {
+		ret[i] = hexutil.Bytes(proof[i])
+	}
+	return ret
+}
+
+func fromHexProof(proof []hexutil.Bytes) []rlp.RawValue {
+	ret := make([]rlp.RawValue, len(proof))
+	for i := range proof {
+		ret[i] = rlp.RawValue(proof[i])
+	}
+	return ret
+}
+
 func (a PosApi) GetEpochStakerInfoAll(epochID uint64) ([]StakerInfo, error) {
 	targetBlkNum := epochLeader.GetEpocher().GetTargetBlkNumber(epochID)
 	epocherInst := epochLeader.GetEpocher()
@@ -320,20 +449,52 @@ func biToString(value *big.Int, err error) (string, error) {
 	}
 	return value.String(), err
 }
+// the incentive totals are in the chain state, the latest state contains all the paid epochs.
+func (a PosApi) latestState() (*state.StateDB, error) {
+	state, _, err := a.backend.StateAndHeaderByNumber(context.Background(), rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, errors.New("can not get the latest state")
+	}
+	return state, nil
+}
+
+// stateAt returns the state of the block of number, the states of the old blocks
+// are only kept by the archive nodes.
+func (a PosApi) stateAt(number uint64) (*state.StateDB, error) {
+	state, _, err := a.backend.StateAndHeaderByNumber(context.Background(), rpc.BlockNumber(number))
+	if state == nil || err != nil {
+		return nil, fmt.Errorf("can not get the state of block %d", number)
+	}
+	return state, nil
+}
+
+// the payment detail is replayed on the state of the chain
 func (a PosApi) GetEpochIncentivePayDetail(epochID uint64) ([][]vm.ClientIncentive, error) {
-	return incentive.GetEpochPayDetail(epochID)
+	return incentive.GetEpochPayDetail(a.chain, a.stateAt, epochID)
 }
 
 func (a PosApi) GetTotalIncentive() (string, error) {
-	return biToString(incentive.GetTotalIncentive())
+	state, err := a.latestState()
+	if err != nil {
+		return "", err
+	}
+	return biToString(incentive.GetTotalIncentive(state))
 }
 
 func (a PosApi) GetEpochIncentive(epochID uint64) (string, error) {
-	return biToString(incentive.GetEpochIncentive(epochID))
+	state, err := a.latestState()
+	if err != nil {
+		return "", err
+	}
+	return biToString(incentive.GetEpochIncentive(state, epochID))
 }
 
 func (a PosApi) GetEpochRemain(epochID uint64) (string, error) {
-	return biToString(incentive.GetEpochRemain(epochID))
+	state, err := a.latestState()
+	if err != nil {
+		return "", err
+	}
+	return biToString(incentive.GetEpochRemain(state, epochID))
 }
 func (a PosApi) GetWhiteListConfig() ([]vm.UpgradeWhiteEpochLeaderParam, error) {
 	epocherInst := epochLeader.GetEpocher()
@@ -364,11 +525,19 @@ func (a PosApi) GetWhiteListbyEpochID(epochID uint64) ([]string, error) {
 	return epocherInst.GetWhiteByEpochId(epochID)
 }
 func (a PosApi) GetTotalRemain() (string, error) {
-	return biToString(incentive.GetTotalRemain())
+	state, err := a.latestState()
+	if err != nil {
+		return "", err
+	}
+	return biToString(incentive.GetTotalRemain(state))
 }
 
 func (a PosApi) GetIncentiveRunTimes() (string, error) {
-	return biToString(incentive.GetRunTimes())
+	state, err := a.latestState()
+	if err != nil {
+		return "", err
+	}
+	return biToString(incentive.GetRunTimes(state))
 }
 
 func (a PosApi) GetEpochGasPool(epochID uint64) (string, error) {
@@ -410,21 +579,21 @@ func (a PosApi) GetActivity(epochID uint64) (*incentive.Activity, error) {
 }
 
 func (a PosApi) GetEpochID() uint64 {
-	ep, _ := util.CalEpochSlotID(uint64(time.Now().Unix()))
+	ep, _ := util.CalEpochSlotID(util.NowUnix())
 	return ep
 }
 
 func (a PosApi) GetSlotID() uint64 {
-	_, sl := util.CalEpochSlotID(uint64(time.Now().Unix()))
+	_, sl := util.CalEpochSlotID(util.NowUnix())
 	return sl
 }
 
 func (a PosApi) GetSlotCount() int {
-	return posconfig.SlotCount
+	return int(posconfig.SlotCount)
 }
 
 func (a PosApi) GetSlotTime() int {
-	return posconfig.SlotTime
+	return int(posconfig.SlotTime)
 }
 
 func (a PosApi) GetMaxStableBlkNumber() uint64 {
@@ -0,0 +1,219 @@
+package posapi
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/pos/incentive"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+// maxPerformanceEpochs bounds the epochs of a GetValidatorPerformance request, the
+// blocks of all of them are read from the chain
+const maxPerformanceEpochs = 30
+
+// ValidatorSlot is a slot assigned to a validator and the unix time it starts at
+type ValidatorSlot struct {
+	SlotID    uint64 `json:"slotId"`
+	Timestamp uint64 `json:"timestamp"`
+}
+
+// ValidatorSchedule lists the slots of an epoch assigned to a validator
+type ValidatorSchedule struct {
+	Address common.Address  `json:"address"`
+	EpochID uint64          `json:"epochId"`
+	Slots   []ValidatorSlot `json:"slots"`
+}
+
+// EpochPerformance is the work of a validator in an epoch. The slots of the
+// current epoch which have not passed yet are neither produced nor missed.
+type EpochPerformance struct {
+	EpochID          uint64   `json:"epochId"`
+	AssignedSlots    int      `json:"assignedSlots"`
+	ProducedSlots    int      `json:"producedSlots"`
+	MissedSlots      []uint64 `json:"missedSlots"`
+	EpochLeaders     int      `json:"epochLeaders"`     // seats in the epoch leader group
+	EpochLeadersWork int      `json:"epochLeadersWork"` // seats which sent their stage-2 tx
+	RBLeaders        int      `json:"rbLeaders"`        // seats in the random proposer group
+	RBLeadersWork    int      `json:"rbLeadersWork"`    // seats which took part in the random beacon
+	Reward           string   `json:"reward"`           // empty until the incentive of the epoch is paid
+}
+
+// ValidatorPerformance is the work of a validator in a range of epochs
+type ValidatorPerformance struct {
+	Address       common.Address     `json:"address"`
+	FromEpoch     uint64             `json:"fromEpoch"`
+	ToEpoch       uint64             `json:"toEpoch"`
+	AssignedSlots int                `json:"assignedSlots"`
+	ProducedSlots int                `json:"producedSlots"`
+	MissedSlots   int                `json:"missedSlots"`
+	Reward        string             `json:"reward"` // of the paid epochs
+	Epochs        []EpochPerformance `json:"epochs"`
+}
+
+// GetValidatorSchedule lists the slots of epochID assigned to the validator of
+// address. The slot leaders of the next epoch are known once its selection is
+// done, so the schedule may be looked up ahead.
+func (a PosApi) GetValidatorSchedule(address common.Address, epochID uint64) (*ValidatorSchedule, error) {
+	leaders, err := slotLeaderAddrs(epochID)
+	if err != nil {
+		return nil, err
+	}
+
+	epochTime := a.GetTimeByEpochID(epochID)
+	schedule := &ValidatorSchedule{Address: address, EpochID: epochID, Slots: make([]ValidatorSlot, 0)}
+	for slotID, leader := range leaders {
+		if leader == address {
+			schedule.Slots = append(schedule.Slots, ValidatorSlot{
+				SlotID:    uint64(slotID),
+				Timestamp: epochTime + uint64(slotID)*posconfig.SlotTime,
+			})
+		}
+	}
+	return schedule, nil
+}
+
+// GetValidatorPerformance reports the slots produced and missed by the validator
+// of address from fromEpoch to toEpoch, its activity in the epoch leader and the
+// random proposer groups, and the rewards it earned.
+func (a PosApi) GetValidatorPerformance(address common.Address, fromEpoch uint64, toEpoch uint64) (*ValidatorPerformance, error) {
+	curEpochID, curSlotID := util.CalEpochSlotID(util.NowUnix())
+	if toEpoch > curEpochID {
+		toEpoch = curEpochID
+	}
+	if fromEpoch > toEpoch {
+		return nil, errors.New("invalid epoch range")
+	}
+	if toEpoch-fromEpoch >= maxPerformanceEpochs {
+		return nil, fmt.Errorf("epoch range exceeds %d epochs", maxPerformanceEpochs)
+	}
+
+	s := slotleader.GetSlotLeaderSelection()
+	db, err := s.GetCurrentStateDb()
+	if err != nil {
+		return nil, err
+	}
+
+	produced := a.producedSlots(fromEpoch, toEpoch)
+	perf := &ValidatorPerformance{Address: address, FromEpoch: fromEpoch, ToEpoch: toEpoch,
+		Epochs: make([]EpochPerformance, 0, toEpoch-fromEpoch+1)}
+	reward := big.NewInt(0)
+	for epochID := fromEpoch; epochID <= toEpoch; epochID++ {
+		leaders, err := slotLeaderAddrs(epochID)
+		if err != nil {
+			return nil, err
+		}
+
+		ep := EpochPerformance{EpochID: epochID, MissedSlots: make([]uint64, 0)}
+		ep.countSlots(address, leaders, produced[epochID], curEpochID, curSlotID)
+
+		epAddrs, epActivity := incentive.GetEpochLeaderActivity(db, epochID)
+		ep.EpochLeaders, ep.EpochLeadersWork = countActivity(address, epAddrs, epActivity)
+		rpAddrs, rpActivity := incentive.GetEpochRBLeaderActivity(db, epochID)
+		ep.RBLeaders, ep.RBLeadersWork = countActivity(address, rpAddrs, rpActivity)
+
+		// the rewards are left empty if the epoch is not paid
+		if payment, err := incentive.GetEpochPayDetail(a.chain, a.stateAt, epochID); err == nil {
+			epochReward := big.NewInt(0)
+			for _, group := range payment {
+				for _, client := range group {
+					if client.Addr == address {
+						epochReward.Add(epochReward, client.Incentive)
+					}
+				}
+			}
+			ep.Reward = epochReward.String()
+			reward.Add(reward, epochReward)
+		}
+
+		perf.AssignedSlots += ep.AssignedSlots
+		perf.ProducedSlots += ep.ProducedSlots
+		perf.MissedSlots += len(ep.MissedSlots)
+		perf.Epochs = append(perf.Epochs, ep)
+	}
+	perf.Reward = reward.String()
+	return perf, nil
+}
+
+// countSlots counts the slots of the epoch assigned to address by leaders, and the
+// ones produced by the coinbases of produced. The slots before the current slot
+// curSlotID of curEpochID are missed if not produced, the current one is not yet.
+func (ep *EpochPerformance) countSlots(address common.Address, leaders []common.Address,
+	produced map[uint64]common.Address, curEpochID uint64, curSlotID uint64) {
+	for slotID, leader := range leaders {
+		if leader != address {
+			continue
+		}
+		ep.AssignedSlots++
+		if coinbase, ok := produced[uint64(slotID)]; ok && coinbase == address {
+			ep.ProducedSlots++
+		} else if ep.EpochID < curEpochID || uint64(slotID) < curSlotID {
+			ep.MissedSlots = append(ep.MissedSlots, uint64(slotID))
+		}
+	}
+}
+
+// producedSlots returns the coinbases of the blocks of the canonical chain from
+// fromEpoch to toEpoch, by epoch and slot. The first and last blocks of the epochs
+// are searched, so only the blocks of the epochs are read.
+func (a PosApi) producedSlots(fromEpoch uint64, toEpoch uint64) map[uint64]map[uint64]common.Address {
+	produced := make(map[uint64]map[uint64]common.Address)
+
+	first := uint64(1)
+	if fromEpoch > 0 {
+		first = util.FirstBlockAfter(a.chain, fromEpoch-1, math.MaxUint64)
+	}
+	end := util.FirstBlockAfter(a.chain, toEpoch, math.MaxUint64)
+	for number := first; number < end; number++ {
+		header := a.chain.GetHeaderByNumber(number)
+		if header == nil {
+			continue
+		}
+
+		epochID, slotID := util.CalEpSlbyTd(header.Difficulty.Uint64())
+		if produced[epochID] == nil {
+			produced[epochID] = make(map[uint64]common.Address)
+		}
+		produced[epochID][slotID] = header.Coinbase
+	}
+	return produced
+}
+
+// slotLeaderAddrs returns the addresses of the slot leaders of epochID by slot
+func slotLeaderAddrs(epochID uint64) ([]common.Address, error) {
+	addrs := make([]common.Address, posconfig.SlotCount)
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
+		buf, err := posdb.GetDb().GetWithIndex(epochID, i, slotleader.SlotLeader)
+		if err != nil {
+			return nil, fmt.Errorf("slot leaders of epoch %d are unknown: %v", epochID, err)
+		}
+		pk := crypto.ToECDSAPub(buf)
+		if pk == nil {
+			return nil, fmt.Errorf("invalid slot leader of epoch %d slot %d", epochID, i)
+		}
+		addrs[i] = crypto.PubkeyToAddress(*pk)
+	}
+	return addrs, nil
+}
+
+// countActivity returns the seats of address in a group and the active ones
+func countActivity(address common.Address, addrs []common.Address, activity []int) (int, int) {
+	seats, active := 0, 0
+	for i, addr := range addrs {
+		if addr != address {
+			continue
+		}
+		seats++
+		if i < len(activity) && activity[i] == 1 {
+			active++
+		}
+	}
+	return seats, active
+}
@@ -0,0 +1,171 @@
+package posapi
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
+)
+
+// testChain is the chain reader of the headers, the header of number n is the n-th
+type testChain struct {
+	consensus.ChainReader
+	headers []*types.Header
+	reads   int // headers read by number
+}
+
+func (c *testChain) CurrentHeader() *types.Header {
+	return c.headers[len(c.headers)-1]
+}
+
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header {
+	c.reads++
+	if number >= uint64(len(c.headers)) {
+		return nil
+	}
+	return c.headers[number]
+}
+
+// add appends the block produced by coinbase in the slot slotID of epochID
+func (c *testChain) add(epochID uint64, slotID uint64, coinbase common.Address) {
+	c.headers = append(c.headers, &types.Header{
+		Number:     big.NewInt(int64(len(c.headers))),
+		Difficulty: new(big.Int).SetUint64(epochID<<32 | slotID<<8),
+		Coinbase:   coinbase,
+	})
+}
+
+func newTestChain() *testChain {
+	return &testChain{headers: []*types.Header{{Number: big.NewInt(0), Difficulty: big.NewInt(0)}}}
+}
+
+func TestCountSlots(t *testing.T) {
+	self, other := common.Address{1}, common.Address{2}
+	leaders := []common.Address{self, other, self, self, other, self}
+	produced := map[uint64]common.Address{0: self, 1: other, 3: other, 5: self}
+
+	// all the slots of a past epoch are passed
+	ep := EpochPerformance{EpochID: 1, MissedSlots: make([]uint64, 0)}
+	ep.countSlots(self, leaders, produced, 2, 0)
+	if ep.AssignedSlots != 4 || ep.ProducedSlots != 2 || !reflect.DeepEqual(ep.MissedSlots, []uint64{2, 3}) {
+		t.Fatal("past epoch slots mismatch", ep)
+	}
+
+	// the current slot and the later ones are not missed yet
+	ep = EpochPerformance{EpochID: 2, MissedSlots: make([]uint64, 0)}
+	ep.countSlots(self, leaders, produced, 2, 3)
+	if ep.AssignedSlots != 4 || ep.ProducedSlots != 2 || !reflect.DeepEqual(ep.MissedSlots, []uint64{2}) {
+		t.Fatal("current epoch slots mismatch", ep)
+	}
+	ep = EpochPerformance{EpochID: 2, MissedSlots: make([]uint64, 0)}
+	ep.countSlots(self, leaders, produced, 2, 4)
+	if !reflect.DeepEqual(ep.MissedSlots, []uint64{2, 3}) {
+		t.Fatal("slot before the current one should be missed", ep.MissedSlots)
+	}
+	ep = EpochPerformance{EpochID: 2, MissedSlots: make([]uint64, 0)}
+	ep.countSlots(self, leaders, nil, 2, 0)
+	if ep.AssignedSlots != 4 || ep.ProducedSlots != 0 || len(ep.MissedSlots) != 0 {
+		t.Fatal("no slot should be missed at the epoch start", ep)
+	}
+}
+
+func TestCountActivity(t *testing.T) {
+	self, other := common.Address{1}, common.Address{2}
+	addrs := []common.Address{self, other, self, self}
+
+	if seats, active := countActivity(self, addrs, []int{1, 1, 0, 1}); seats != 3 || active != 2 {
+		t.Fatal("activity mismatch", seats, active)
+	}
+	// the activity of the seats out of the list is unknown
+	if seats, active := countActivity(self, addrs, []int{1}); seats != 3 || active != 1 {
+		t.Fatal("short activity mismatch", seats, active)
+	}
+	if seats, active := countActivity(common.Address{3}, addrs, []int{1, 1, 1, 1}); seats != 0 || active != 0 {
+		t.Fatal("absent address should have no seat", seats, active)
+	}
+}
+
+func TestProducedSlots(t *testing.T) {
+	a, b := common.Address{1}, common.Address{2}
+	chain := newTestChain()
+	chain.add(1, 5, a)
+	chain.add(2, 0, b)
+	chain.add(2, 3, a)
+	chain.add(3, 1, a)
+	chain.add(4, 0, b)
+	api := PosApi{chain: chain}
+
+	produced := api.producedSlots(2, 3)
+	want := map[uint64]map[uint64]common.Address{
+		2: {0: b, 3: a},
+		3: {1: a},
+	}
+	if !reflect.DeepEqual(produced, want) {
+		t.Fatal("produced slots mismatch", produced)
+	}
+
+	if produced := api.producedSlots(0, 1); !reflect.DeepEqual(produced, map[uint64]map[uint64]common.Address{1: {5: a}}) {
+		t.Fatal("produced slots from the first epoch mismatch", produced)
+	}
+	if produced := api.producedSlots(4, 4); !reflect.DeepEqual(produced, map[uint64]map[uint64]common.Address{4: {0: b}}) {
+		t.Fatal("produced slots of the head epoch mismatch", produced)
+	}
+	if produced := api.producedSlots(5, 6); len(produced) != 0 {
+		t.Fatal("future epochs should have no slot", produced)
+	}
+
+	// only the blocks of the epochs are read, not the chain from the head
+	for epochID := uint64(5); epochID < 1000; epochID++ {
+		chain.add(epochID, 0, a)
+		chain.add(epochID, 1, b)
+	}
+	chain.reads = 0
+	if produced := api.producedSlots(10, 10); !reflect.DeepEqual(produced, map[uint64]map[uint64]common.Address{10: {0: a, 1: b}}) {
+		t.Fatal("produced slots of an old epoch mismatch", produced)
+	}
+	if chain.reads > 30 {
+		t.Fatal("too many headers read", chain.reads)
+	}
+}
+
+func TestValidatorSchedule(t *testing.T) {
+	baseTime := posconfig.EpochBaseTime
+	posconfig.EpochBaseTime = 1544544000
+	defer func() { posconfig.EpochBaseTime = baseTime }()
+
+	self, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	const epochID = 100
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
+		leader := crypto.FromECDSAPub(&other.PublicKey)
+		if i%10 == 3 {
+			leader = crypto.FromECDSAPub(&self.PublicKey)
+		}
+		posdb.GetDb().PutWithIndex(epochID, i, slotleader.SlotLeader, leader)
+	}
+
+	api := PosApi{chain: newTestChain()}
+	address := crypto.PubkeyToAddress(self.PublicKey)
+	schedule, err := api.GetValidatorSchedule(address, epochID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schedule.Slots) != int(posconfig.SlotCount/10) {
+		t.Fatal("scheduled slots mismatch", len(schedule.Slots))
+	}
+	epochTime := posconfig.EpochBaseTime + epochID*posconfig.SlotCount*posconfig.SlotTime
+	if slot := schedule.Slots[1]; slot.SlotID != 13 || slot.Timestamp != epochTime+13*posconfig.SlotTime {
+		t.Fatal("scheduled slot mismatch", slot)
+	}
+
+	if _, err := api.GetValidatorSchedule(address, epochID+1); err == nil {
+		t.Fatal("schedule of an unselected epoch should fail")
+	}
+}
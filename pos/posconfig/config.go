@@ -1,13 +1,14 @@
 package posconfig
 
 import (
-	"math/big"
+	"errors"
 
-	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
 	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
 	"github.com/wanchain/go-wanchain/node"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 )
 
 var (
@@ -27,30 +28,47 @@ const (
 var EpochLeadersHold [][]byte
 
 const (
+	PosUpgradeEpochID = 2 // must send tx 2 epoch before.
+	MaxEpHold         = 30
+	MinEpHold         = 10
+
+	//Incentive should perform delay some epochs.
+	IncentiveDelayEpochs = 1
+
+	// StakeOutDelayEpochs is the count of epochs from a delegateOut, stakeOut or
+	// stakeOutPartial to its refund
+	StakeOutDelayEpochs = 3
+
+	// KCount count of each epoch
+	KCount = 12
+
+	MinimumChainQuality     = 0.5 //BlockSecurityParam / SlotSecurityParam
+	CriticalReorgThreshold  = 3
+	CriticalChainQuality    = 0.618
+	NonCriticalChainQuality = 0.8
+
+	// RBSlashRateBase is the base of the slash rates of Config
+	RBSlashRateBase = 10000
+)
+
+// The network parameters below are set from the genesis by InitParams,
+// they should not be changed after the node starts.
+var (
 	// EpochLeaderCount is count of pk in epoch leader group which is select by stake
 	EpochLeaderCount = 50
 	// RandomProperCount is count of pk in random leader group which is select by stake
 	RandomProperCount = 25
-	PosUpgradeEpochID = 2 // must send tx 2 epoch before.
-	MaxEpHold			= 30
-	MinEpHold			= 10
-)
-const (
-	// SlotTime is the time span of a slot in second, So it's 1 hours for a epoch
-	SlotTime = 10
 
-	//Incentive should perform delay some epochs.
-	IncentiveDelayEpochs = 1
-	IncentiveStartStage  = Stage2K
+	// SlotTime is the time span of a slot in second, So it's 1 hours for a epoch
+	SlotTime = uint64(10)
 
 	// K count of each epoch
-	KCount = 12
-	K      = 10
+	K = uint64(10)
 	// SlotCount is slot count in an epoch
 	SlotCount = K * KCount
 
 	// Stage1K is divde a epoch into 10 pieces
-	Stage1K  = uint64(K)
+	Stage1K  = K
 	Stage2K  = Stage1K * 2
 	Stage3K  = Stage1K * 3
 	Stage4K  = Stage1K * 4
@@ -70,37 +88,48 @@ const (
 	Sma3Start = Stage10K
 	Sma3End   = Stage12K
 
+	IncentiveStartStage = Stage2K
+
 	// parameters for security and chain quality
 	BlockSecurityParam = K
 	SlotSecurityParam  = 2 * K
 
-	MinimumChainQuality     = 0.5 //BlockSecurityParam / SlotSecurityParam
-	CriticalReorgThreshold  = 3
-	CriticalChainQuality    = 0.618
-	NonCriticalChainQuality = 0.8
+	// staking limits, the stakes are in wan
+	MinLockEpochs       = uint64(7)
+	MaxLockEpochs       = uint64(90)
+	LockWeightEpochs    = [3]uint64{15, 45, 90}
+	MinStakeholderStake = uint64(10000)
+	MinValidatorStake   = uint64(100000)
+	MinDelegatorStake   = uint64(100)
+
+	// RBSlashEpoch is the first epoch whose random proposers are slashed for a
+	// skipped stage, 0 disables the slashing
+	RBSlashEpoch = uint64(0)
 )
 
 var GenesisPK = "04dc40d03866f7335e40084e39c3446fe676b021d1fcead11f2e2715e10a399b498e8875d348ee40358545e262994318e4dcadbc865bcf9aac1fc330f22ae2c786"
 var PosOwnerAddr = common.HexToAddress("0xcf696d8eea08a311780fb89b20d4f0895198a489")
 type Config struct {
-	PolymDegree   uint
-	K             uint
-	RBThres       uint
-	EpochInterval uint64
-	PosStartTime  int64
-	MinerKey      *keystore.Key
-	Dbpath        string
-	NodeCfg       *node.Config
-	Dkg1End       uint64
-	Dkg2Begin     uint64
-	Dkg2End       uint64
-	SignBegin     uint64
-	SignEnd       uint64
+	PolymDegree         uint
+	K                   uint
+	RBThres             uint
+	EpochInterval       uint64
+	PosStartTime        int64
+	MinerSigner         possigner.Signer
+	Dbpath              string
+	NodeCfg             *node.Config
+	Dkg1End             uint64
+	Dkg2Begin           uint64
+	Dkg2End             uint64
+	SignBegin           uint64
+	SignEnd             uint64
+	RBSlashRate         uint64 // per RBSlashRateBase of the stake slashed, 0 disables the slashing
+	DoubleSignSlashRate uint64 // per RBSlashRateBase of the stake slashed for double sign
 }
 
 var DefaultConfig = Config{
 	12,
-	K,
+	uint(K),
 	13,
 	0,
 	0,
@@ -112,6 +141,8 @@ var DefaultConfig = Config{
 	Stage6K - 1,
 	Stage8K,
 	Stage10K - 1,
+	params.DefaultPosConfig.RBSlashRate,
+	params.DefaultPosConfig.DoubleSignSlashRate,
 }
 
 func Cfg() *Config {
@@ -119,27 +150,19 @@ func Cfg() *Config {
 }
 
 func (c *Config) GetMinerAddr() common.Address {
-	if c.MinerKey == nil {
+	if c.MinerSigner == nil {
 		return common.Address{}
 	}
 
-	return c.MinerKey.Address
+	return c.MinerSigner.Address()
 }
 
 func (c *Config) GetMinerBn256PK() *bn256.G1 {
-	if c.MinerKey == nil {
+	if c.MinerSigner == nil {
 		return nil
 	}
 
-	return new(bn256.G1).Set(c.MinerKey.PrivateKey3.PublicKeyBn256.G1)
-}
-
-func (c *Config) GetMinerBn256SK() *big.Int {
-	if c.MinerKey == nil {
-		return nil
-	}
-
-	return new(big.Int).Set(c.MinerKey.PrivateKey3.D)
+	return c.MinerSigner.Bn256PublicKey()
 }
 
 func Init(nodeCfg *node.Config) {
@@ -149,3 +172,66 @@ func Init(nodeCfg *node.Config) {
 	}
 	DefaultConfig.NodeCfg = nodeCfg
 }
+
+var errPosParams = errors.New("invalid pos parameters")
+
+// InitParams sets the network parameters from the pos section of the genesis,
+// nil means params.DefaultPosConfig. It should be called before the chain is loaded.
+func InitParams(p *params.PosConfig) error {
+	if p == nil {
+		p = params.DefaultPosConfig
+	}
+	if p.SlotTime == 0 || p.K == 0 || p.EpochLeaderCount == 0 || p.RandomProperCount < 2 ||
+		p.MinLockEpochs == 0 || p.MinLockEpochs > p.MaxLockEpochs ||
+		p.LockWeightEpochs[0] > p.LockWeightEpochs[1] || p.LockWeightEpochs[1] > p.LockWeightEpochs[2] ||
+		p.MinValidatorStake < p.MinStakeholderStake || p.MinStakeholderStake < p.MinDelegatorStake ||
+		p.RBSlashRate > RBSlashRateBase || p.DoubleSignSlashRate > RBSlashRateBase {
+		return errPosParams
+	}
+
+	EpochLeaderCount = int(p.EpochLeaderCount)
+	RandomProperCount = int(p.RandomProperCount)
+	SlotTime = p.SlotTime
+
+	K = p.K
+	SlotCount = K * KCount
+	Stage1K = K
+	Stage2K = Stage1K * 2
+	Stage3K = Stage1K * 3
+	Stage4K = Stage1K * 4
+	Stage5K = Stage1K * 5
+	Stage6K = Stage1K * 6
+	Stage7K = Stage1K * 7
+	Stage8K = Stage1K * 8
+	Stage9K = Stage1K * 9
+	Stage10K = Stage1K * 10
+	Stage11K = Stage1K * 11
+	Stage12K = Stage1K * 12
+	Sma1Start, Sma1End = Stage2K, Stage4K
+	Sma2Start, Sma2End = Stage6K, Stage8K
+	Sma3Start, Sma3End = Stage10K, Stage12K
+	IncentiveStartStage = Stage2K
+	BlockSecurityParam = K
+	SlotSecurityParam = 2 * K
+
+	MinLockEpochs = p.MinLockEpochs
+	MaxLockEpochs = p.MaxLockEpochs
+	LockWeightEpochs = p.LockWeightEpochs
+	MinStakeholderStake = p.MinStakeholderStake
+	MinValidatorStake = p.MinValidatorStake
+	MinDelegatorStake = p.MinDelegatorStake
+	RBSlashEpoch = p.RBSlashEpoch
+	DefaultConfig.RBSlashRate = p.RBSlashRate
+	DefaultConfig.DoubleSignSlashRate = p.DoubleSignSlashRate
+
+	// the random beacon threshold is the majority of the random proposers
+	DefaultConfig.K = uint(K)
+	DefaultConfig.PolymDegree = uint(RandomProperCount-1) / 2
+	DefaultConfig.RBThres = DefaultConfig.PolymDegree + 1
+	DefaultConfig.Dkg1End = Stage2K - 1
+	DefaultConfig.Dkg2Begin = Stage4K
+	DefaultConfig.Dkg2End = Stage6K - 1
+	DefaultConfig.SignBegin = Stage8K
+	DefaultConfig.SignEnd = Stage10K - 1
+	return nil
+}
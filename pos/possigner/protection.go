@@ -0,0 +1,152 @@
+package possigner
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"math/big"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/crypto"
+	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+)
+
+var (
+	sealPrefix  = []byte("possigner-seal-") // sealPrefix + epochID + slotID -> seal hash
+	lastSealKey = []byte("possigner-lastSeal")
+
+	proofPrefix  = []byte("possigner-proof-") // proofPrefix + epochID + slotID -> hash of the proof inputs
+	lastProofKey = []byte("possigner-lastProof")
+
+	smaPrefix  = []byte("possigner-sma-") // smaPrefix + epochID -> hash of the pieces
+	lastSMAKey = []byte("possigner-lastSMA")
+
+	rbPrefix  = []byte("possigner-rb-") // rbPrefix + epochID -> random beacon message
+	lastRBKey = []byte("possigner-lastRB")
+)
+
+// ProtectedSigner is a Signer keeping the slashing protection records of the
+// seals in a database. A block is sealed only once per slot and never before the
+// last slot signed, so the validator is not slashed for double sign even if its
+// node is restarted or runs twice.
+//
+// The other requests are bound to the epoch or the slot they're made for the same
+// way, so the keys only ever serve the inputs of one epoch or slot: the pieces of
+// an epoch's security message, the message of an epoch's random beacon and the
+// inputs of a slot's leader proof.
+type ProtectedSigner struct {
+	Signer
+
+	db ethdb.Database
+	mu sync.Mutex // serialises the check and the update of the records
+}
+
+// NewProtectedSigner wraps s with the slashing protection records of db
+func NewProtectedSigner(s Signer, db ethdb.Database) *ProtectedSigner {
+	return &ProtectedSigner{Signer: s, db: db}
+}
+
+// SignSeal signs the seal hash if the slot is not signed yet, or was signed with
+// the same hash. The record is written before the signature is returned.
+func (p *ProtectedSigner) SignSeal(epochID uint64, slotID uint64, sealHash []byte) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := p.bind(sealPrefix, lastSealKey, encodeSlot(epochID, slotID), sealHash, ErrDoubleSeal, ErrSlotExpired)
+	if err != nil {
+		log.Warn("Refused to seal", "epochID", epochID, "slotID", slotID, "err", err)
+		return nil, err
+	}
+	return p.Signer.SignSeal(epochID, slotID, sealHash)
+}
+
+// GenerateSMA generates the security message if the epoch has no other pieces
+// signed, and is not before the last epoch signed.
+func (p *ProtectedSigner) GenerateSMA(epochID uint64, pieces []*ecdsa.PublicKey) ([]*ecdsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := p.bind(smaPrefix, lastSMAKey, encodeEpoch(epochID), hashPks(pieces), ErrDoubleRequest, ErrEpochExpired)
+	if err != nil {
+		log.Warn("Refused to generate security message", "epochID", epochID, "err", err)
+		return nil, err
+	}
+	return p.Signer.GenerateSMA(epochID, pieces)
+}
+
+// SlotLeaderProof generates the proof if the slot has no other inputs proved, and
+// is not before the last slot proved.
+func (p *ProtectedSigner) SlotLeaderProof(epochID uint64, slotID uint64, sma []*ecdsa.PublicKey,
+	epochLeaders []*ecdsa.PublicKey, rb []byte) ([]*ecdsa.PublicKey, []*big.Int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	inputs := crypto.Keccak256(hashPks(sma), hashPks(epochLeaders), rb)
+	err := p.bind(proofPrefix, lastProofKey, encodeSlot(epochID, slotID), inputs, ErrDoubleRequest, ErrSlotExpired)
+	if err != nil {
+		log.Warn("Refused to prove slot leader", "epochID", epochID, "slotID", slotID, "err", err)
+		return nil, nil, err
+	}
+	return p.Signer.SlotLeaderProof(epochID, slotID, sma, epochLeaders, rb)
+}
+
+// SignRBShare signs the share if the epoch has no other message signed, and is
+// not before the last epoch signed. The shares of the proposers of the validator
+// in the epoch differ, so they are not recorded.
+func (p *ProtectedSigner) SignRBShare(epochID uint64, shares []*bn256.G1, m *big.Int) (*bn256.G1, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := p.bind(rbPrefix, lastRBKey, encodeEpoch(epochID), m.Bytes(), ErrDoubleRequest, ErrEpochExpired)
+	if err != nil {
+		log.Warn("Refused to sign random beacon share", "epochID", epochID, "err", err)
+		return nil, err
+	}
+	return p.Signer.SignRBShare(epochID, shares, m)
+}
+
+// bind records digest as the request made at position at, the positions are the
+// big endian encoded epochs or slots. It fails with errDouble if another digest is
+// recorded at, and with errExpired if at is before the last position recorded.
+func (p *ProtectedSigner) bind(prefix, lastKey, at, digest []byte, errDouble, errExpired error) error {
+	key := make([]byte, 0, len(prefix)+len(at))
+	key = append(append(key, prefix...), at...)
+	if recorded, err := p.db.Get(key); err == nil {
+		if !bytes.Equal(recorded, digest) {
+			return errDouble
+		}
+		return nil
+	}
+
+	if last, err := p.db.Get(lastKey); err == nil && bytes.Compare(at, last) < 0 {
+		return errExpired
+	}
+
+	if err := p.db.Put(key, digest); err != nil {
+		return err
+	}
+	return p.db.Put(lastKey, at)
+}
+
+func encodeEpoch(epochID uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, epochID)
+	return buf
+}
+
+func encodeSlot(epochID uint64, slotID uint64) []byte {
+	buf := make([]byte, 16)
+	binary.BigEndian.PutUint64(buf[:8], epochID)
+	binary.BigEndian.PutUint64(buf[8:], slotID)
+	return buf
+}
+
+func hashPks(pks []*ecdsa.PublicKey) []byte {
+	buf := make([][]byte, len(pks))
+	for i, pk := range pks {
+		buf[i] = crypto.FromECDSAPub(pk)
+	}
+	return crypto.Keccak256(buf...)
+}
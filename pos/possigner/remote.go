@@ -0,0 +1,342 @@
+package possigner
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/rlp"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// rpcTimeout bounds a request to the remote signer, a slot is lost anyway once
+// it is exceeded
+const rpcTimeout = 5 * time.Second
+
+var errInvalidPoint = errors.New("invalid curve point")
+
+// ValidatorKeys are the public keys of the validator served by a remote signer
+type ValidatorKeys struct {
+	Address        common.Address `json:"address"`
+	PublicKey      hexutil.Bytes  `json:"publicKey"`
+	Bn256PublicKey hexutil.Bytes  `json:"bn256PublicKey"`
+}
+
+// SlotLeaderProof is the slot leader proof returned by a remote signer
+type SlotLeaderProof struct {
+	ProofMeg []hexutil.Bytes `json:"proofMeg"`
+	Proof    []*hexutil.Big  `json:"proof"`
+}
+
+// Service is the rpc api of a Signer served to the nodes by a remote signer,
+// registered under the possigner namespace.
+//
+// GenerateSMA and SignRBShare multiply the points of the request by the secp256k1
+// and the inverse of the bn256 secret keys, they are Diffie-Hellman oracles of the
+// keys to whoever reaches the socket. The socket must be reachable by the node of
+// the validator only, and the signer be a ProtectedSigner so each epoch is served
+// the inputs of one request only.
+type Service struct {
+	signer Signer
+}
+
+// NewService creates the rpc api of s
+func NewService(s Signer) *Service {
+	return &Service{signer: s}
+}
+
+func (api *Service) Keys() *ValidatorKeys {
+	keys := &ValidatorKeys{
+		Address:   api.signer.Address(),
+		PublicKey: crypto.FromECDSAPub(api.signer.PublicKey()),
+	}
+	if pk := api.signer.Bn256PublicKey(); pk != nil {
+		keys.Bn256PublicKey = pk.Marshal()
+	}
+	return keys
+}
+
+func (api *Service) SignSeal(epochID hexutil.Uint64, slotID hexutil.Uint64, sealHash hexutil.Bytes) (hexutil.Bytes, error) {
+	return api.signer.SignSeal(uint64(epochID), uint64(slotID), sealHash)
+}
+
+// GenerateSMA returns the pieces multiplied by the secp256k1 secret key, it's an
+// unrestricted Diffie-Hellman oracle but for the protection of the signer.
+func (api *Service) GenerateSMA(epochID hexutil.Uint64, pieces []hexutil.Bytes) ([]hexutil.Bytes, error) {
+	piecesPk, err := decodePks(pieces)
+	if err != nil {
+		return nil, err
+	}
+	sma, err := api.signer.GenerateSMA(uint64(epochID), piecesPk)
+	if err != nil {
+		return nil, err
+	}
+	return encodePks(sma), nil
+}
+
+func (api *Service) SlotLeaderProof(epochID hexutil.Uint64, slotID hexutil.Uint64, sma []hexutil.Bytes,
+	epochLeaders []hexutil.Bytes, rb hexutil.Bytes) (*SlotLeaderProof, error) {
+	smaPk, err := decodePks(sma)
+	if err != nil {
+		return nil, err
+	}
+	leadersPk, err := decodePks(epochLeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	proofMeg, proof, err := api.signer.SlotLeaderProof(uint64(epochID), uint64(slotID), smaPk, leadersPk, rb)
+	if err != nil {
+		return nil, err
+	}
+	res := &SlotLeaderProof{ProofMeg: encodePks(proofMeg), Proof: make([]*hexutil.Big, len(proof))}
+	for i, p := range proof {
+		res.Proof[i] = (*hexutil.Big)(p)
+	}
+	return res, nil
+}
+
+// SignRBShare returns the sum of the shares multiplied by the inverse of the bn256
+// secret key and by m, it's an unrestricted oracle of the inverse key but for the
+// protection of the signer.
+func (api *Service) SignRBShare(epochID hexutil.Uint64, shares []hexutil.Bytes, m *hexutil.Big) (hexutil.Bytes, error) {
+	if m == nil {
+		return nil, errors.New("missing message")
+	}
+	sharesG1 := make([]*bn256.G1, len(shares))
+	for i, share := range shares {
+		g1, err := decodeG1(share)
+		if err != nil {
+			return nil, err
+		}
+		sharesG1[i] = g1
+	}
+
+	sig, err := api.signer.SignRBShare(uint64(epochID), sharesG1, m.ToInt())
+	if err != nil {
+		return nil, err
+	}
+	return sig.Marshal(), nil
+}
+
+// SignTx signs the rlp encoded pos protocol transaction tx for the chain chainID,
+// and returns the signed transaction rlp encoded.
+func (api *Service) SignTx(tx hexutil.Bytes, chainID *hexutil.Big) (hexutil.Bytes, error) {
+	if chainID == nil {
+		return nil, errors.New("missing chain id")
+	}
+	unsigned := new(types.Transaction)
+	if err := rlp.DecodeBytes(tx, unsigned); err != nil {
+		return nil, err
+	}
+
+	signed, err := api.signer.SignTx(unsigned, chainID.ToInt())
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(signed)
+}
+
+// Serve serves s on the unix socket endpoint, until the returned listener is
+// closed.
+func Serve(endpoint string, s Signer) (net.Listener, error) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("possigner", NewService(s)); err != nil {
+		return nil, err
+	}
+	l, err := rpc.CreateIPCListener(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	go server.ServeListener(l)
+	return l, nil
+}
+
+// RemoteSigner is the Signer of a remote signer reachable on a unix socket, the
+// keys of the validator never enter the node.
+type RemoteSigner struct {
+	client *rpc.Client
+
+	address common.Address
+	pk      *ecdsa.PublicKey
+	bn256Pk *bn256.G1
+}
+
+// DialRemote connects to the remote signer listening on the unix socket endpoint
+func DialRemote(endpoint string) (*RemoteSigner, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+
+	client, err := rpc.DialIPC(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys ValidatorKeys
+	if err := client.CallContext(ctx, &keys, "possigner_keys"); err != nil {
+		client.Close()
+		return nil, err
+	}
+	r := &RemoteSigner{client: client, address: keys.Address}
+	if r.pk, err = decodePk(keys.PublicKey); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("remote signer public key: %v", err)
+	}
+	if len(keys.Bn256PublicKey) != 0 {
+		if r.bn256Pk, err = decodeG1(keys.Bn256PublicKey); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("remote signer bn256 public key: %v", err)
+		}
+	}
+	return r, nil
+}
+
+// Close disconnects from the remote signer
+func (r *RemoteSigner) Close() {
+	r.client.Close()
+}
+
+func (r *RemoteSigner) Address() common.Address {
+	return r.address
+}
+
+func (r *RemoteSigner) PublicKey() *ecdsa.PublicKey {
+	return r.pk
+}
+
+func (r *RemoteSigner) Bn256PublicKey() *bn256.G1 {
+	if r.bn256Pk == nil {
+		return nil
+	}
+	return new(bn256.G1).Set(r.bn256Pk)
+}
+
+func (r *RemoteSigner) SignSeal(epochID uint64, slotID uint64, sealHash []byte) ([]byte, error) {
+	var sig hexutil.Bytes
+	err := r.call(&sig, "possigner_signSeal", hexutil.Uint64(epochID), hexutil.Uint64(slotID), hexutil.Bytes(sealHash))
+	return sig, err
+}
+
+func (r *RemoteSigner) GenerateSMA(epochID uint64, pieces []*ecdsa.PublicKey) ([]*ecdsa.PublicKey, error) {
+	var sma []hexutil.Bytes
+	if err := r.call(&sma, "possigner_generateSMA", hexutil.Uint64(epochID), encodePks(pieces)); err != nil {
+		return nil, err
+	}
+	return decodePks(sma)
+}
+
+func (r *RemoteSigner) SlotLeaderProof(epochID uint64, slotID uint64, sma []*ecdsa.PublicKey,
+	epochLeaders []*ecdsa.PublicKey, rb []byte) ([]*ecdsa.PublicKey, []*big.Int, error) {
+	var res SlotLeaderProof
+	err := r.call(&res, "possigner_slotLeaderProof", hexutil.Uint64(epochID), hexutil.Uint64(slotID),
+		encodePks(sma), encodePks(epochLeaders), hexutil.Bytes(rb))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proofMeg, err := decodePks(res.ProofMeg)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof := make([]*big.Int, len(res.Proof))
+	for i, p := range res.Proof {
+		if p == nil {
+			return nil, nil, errors.New("missing proof")
+		}
+		proof[i] = p.ToInt()
+	}
+	return proofMeg, proof, nil
+}
+
+func (r *RemoteSigner) SignRBShare(epochID uint64, shares []*bn256.G1, m *big.Int) (*bn256.G1, error) {
+	sharesBuf := make([]hexutil.Bytes, len(shares))
+	for i, share := range shares {
+		sharesBuf[i] = share.Marshal()
+	}
+
+	var sig hexutil.Bytes
+	if err := r.call(&sig, "possigner_signRBShare", hexutil.Uint64(epochID), sharesBuf, (*hexutil.Big)(m)); err != nil {
+		return nil, err
+	}
+	return decodeG1(sig)
+}
+
+func (r *RemoteSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	// refused here too, so the transactions the remote signer would refuse aren't sent
+	if !isProtocolTx(tx) {
+		return nil, ErrNotProtocolTx
+	}
+	buf, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res hexutil.Bytes
+	if err := r.call(&res, "possigner_signTx", hexutil.Bytes(buf), (*hexutil.Big)(chainID)); err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := rlp.DecodeBytes(res, signed); err != nil {
+		return nil, err
+	}
+	signer := types.NewEIP155Signer(chainID)
+	if signer.Hash(signed) != signer.Hash(tx) {
+		return nil, errors.New("remote signer signed another transaction")
+	}
+	if from, err := types.Sender(signer, signed); err != nil || from != r.address {
+		return nil, errors.New("remote signer signed with another key")
+	}
+	return signed, nil
+}
+
+func (r *RemoteSigner) call(result interface{}, method string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+
+	return r.client.CallContext(ctx, result, method, args...)
+}
+
+func encodePks(pks []*ecdsa.PublicKey) []hexutil.Bytes {
+	buf := make([]hexutil.Bytes, len(pks))
+	for i, pk := range pks {
+		buf[i] = crypto.FromECDSAPub(pk)
+	}
+	return buf
+}
+
+func decodePks(buf []hexutil.Bytes) ([]*ecdsa.PublicKey, error) {
+	pks := make([]*ecdsa.PublicKey, len(buf))
+	for i, b := range buf {
+		pk, err := decodePk(b)
+		if err != nil {
+			return nil, err
+		}
+		pks[i] = pk
+	}
+	return pks, nil
+}
+
+func decodePk(b []byte) (*ecdsa.PublicKey, error) {
+	pk := crypto.ToECDSAPub(b)
+	if pk == nil {
+		return nil, errInvalidPoint
+	}
+	return pk, nil
+}
+
+func decodeG1(b []byte) (*bn256.G1, error) {
+	g1 := new(bn256.G1)
+	if _, err := g1.Unmarshal(b); err != nil {
+		return nil, err
+	}
+	return g1, nil
+}
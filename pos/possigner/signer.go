@@ -0,0 +1,154 @@
+// Package possigner provides the signer of the validator keys of the pos engine.
+// The block seals, the slot leader proofs, the random beacon signature shares and
+// the pos protocol transactions are produced through a Signer, so the keys may be
+// held in a separate process.
+package possigner
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/uleaderselection"
+)
+
+var (
+	ErrNoKey       = errors.New("validator key is missing")
+	ErrNoBn256Key  = errors.New("validator bn256 key is missing")
+	ErrDoubleSeal  = errors.New("refused to seal another block in a signed slot")
+	ErrSlotExpired = errors.New("refused to sign for a slot before the last signed one")
+
+	ErrDoubleRequest = errors.New("refused to sign other inputs for a signed epoch or slot")
+	ErrEpochExpired  = errors.New("refused to sign for an epoch before the last signed one")
+	ErrNotProtocolTx = errors.New("refused to sign a transaction other than a pos protocol one")
+)
+
+var (
+	// MaxProtocolTxGas is the most gas of a transaction signed by a Signer, far above
+	// the intrinsic gas of the protocol transactions
+	MaxProtocolTxGas = big.NewInt(1000000)
+	// MaxProtocolTxGasPrice is the highest gas price of a transaction signed by a
+	// Signer, ten times the default price of the node
+	MaxProtocolTxGasPrice = new(big.Int).Mul(big.NewInt(180*params.Shannon), params.WanGasTimesFactor)
+
+	// the pos precompiled contracts taking the protocol transactions of the validators,
+	// core/vm can't be imported here
+	slotLeaderAddr   = common.BytesToAddress(big.NewInt(600).Bytes())
+	randomBeaconAddr = common.BytesToAddress(big.NewInt(610).Bytes())
+)
+
+// Signer holds the keys of a validator and signs the pos protocol messages with
+// them.
+type Signer interface {
+	// Address returns the address of the validator
+	Address() common.Address
+	// PublicKey returns the secp256k1 public key of the validator
+	PublicKey() *ecdsa.PublicKey
+	// Bn256PublicKey returns the bn256 public key of the validator, nil if it has none
+	Bn256PublicKey() *bn256.G1
+
+	// SignSeal signs the seal hash of the block of the slot slotID of epochID
+	SignSeal(epochID uint64, slotID uint64, sealHash []byte) ([]byte, error)
+	// GenerateSMA generates the security message of epochID+1 from the pieces of
+	// the stage-2 transactions of epochID
+	GenerateSMA(epochID uint64, pieces []*ecdsa.PublicKey) ([]*ecdsa.PublicKey, error)
+	// SlotLeaderProof generates the proof of the validator being the leader of the
+	// slot slotID of epochID
+	SlotLeaderProof(epochID uint64, slotID uint64, sma []*ecdsa.PublicKey, epochLeaders []*ecdsa.PublicKey,
+		rb []byte) ([]*ecdsa.PublicKey, []*big.Int, error)
+	// SignRBShare computes the random beacon signature share of m from the dkg
+	// shares encrypted to the validator
+	SignRBShare(epochID uint64, shares []*bn256.G1, m *big.Int) (*bn256.G1, error)
+	// SignTx signs the pos protocol transaction tx of the validator for the chain
+	// chainID, a POS_TX of no value to the slot leader or the random beacon contract
+	// within MaxProtocolTxGas and MaxProtocolTxGasPrice, other ones are refused
+	SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// KeySigner is the Signer of an unlocked keystore key in process
+type KeySigner struct {
+	key *keystore.Key
+}
+
+// NewKeySigner creates the Signer of an unlocked key
+func NewKeySigner(key *keystore.Key) *KeySigner {
+	return &KeySigner{key: key}
+}
+
+func (s *KeySigner) Address() common.Address {
+	return s.key.Address
+}
+
+func (s *KeySigner) PublicKey() *ecdsa.PublicKey {
+	if s.key.PrivateKey == nil {
+		return nil
+	}
+	return &s.key.PrivateKey.PublicKey
+}
+
+func (s *KeySigner) Bn256PublicKey() *bn256.G1 {
+	if s.key.PrivateKey3 == nil {
+		return nil
+	}
+	return new(bn256.G1).Set(s.key.PrivateKey3.PublicKeyBn256.G1)
+}
+
+func (s *KeySigner) SignSeal(epochID uint64, slotID uint64, sealHash []byte) ([]byte, error) {
+	if s.key.PrivateKey == nil {
+		return nil, ErrNoKey
+	}
+	return crypto.Sign(sealHash, s.key.PrivateKey)
+}
+
+func (s *KeySigner) GenerateSMA(epochID uint64, pieces []*ecdsa.PublicKey) ([]*ecdsa.PublicKey, error) {
+	return uleaderselection.GenerateSMA(s.key.PrivateKey, pieces)
+}
+
+func (s *KeySigner) SlotLeaderProof(epochID uint64, slotID uint64, sma []*ecdsa.PublicKey,
+	epochLeaders []*ecdsa.PublicKey, rb []byte) ([]*ecdsa.PublicKey, []*big.Int, error) {
+	return uleaderselection.GenerateSlotLeaderProof2(s.key.PrivateKey, sma, epochLeaders, rb, slotID, epochID)
+}
+
+func (s *KeySigner) SignRBShare(epochID uint64, shares []*bn256.G1, m *big.Int) (*bn256.G1, error) {
+	if s.key.PrivateKey3 == nil {
+		return nil, ErrNoBn256Key
+	}
+
+	// gskshare = (sk^-1)*(enshare[1][i]+...+enshare[Nr][i])
+	gskshare := new(bn256.G1).ScalarBaseMult(big.NewInt(0))
+	skinver := new(big.Int).ModInverse(s.key.PrivateKey3.D, bn256.Order)
+	for _, share := range shares {
+		gskshare.Add(gskshare, new(bn256.G1).ScalarMult(share, skinver))
+	}
+
+	return new(bn256.G1).ScalarMult(gskshare, m), nil
+}
+
+func (s *KeySigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if s.key.PrivateKey == nil {
+		return nil, ErrNoKey
+	}
+	if !isProtocolTx(tx) {
+		return nil, ErrNotProtocolTx
+	}
+	return types.SignTx(tx, types.NewEIP155Signer(chainID), s.key.PrivateKey)
+}
+
+// isProtocolTx reports whether tx may be a pos protocol transaction, a POS_TX of
+// no value to the slot leader or the random beacon contract whose fee is capped.
+// The pool checks the type against the recipient only, so the signer checks all.
+func isProtocolTx(tx *types.Transaction) bool {
+	if tx.Txtype() != types.POS_TX || tx.Value().Sign() != 0 {
+		return false
+	}
+	if to := tx.To(); to == nil || (*to != slotLeaderAddr && *to != randomBeaconAddr) {
+		return false
+	}
+	return tx.Gas().Cmp(MaxProtocolTxGas) <= 0 && tx.GasPrice().Cmp(MaxProtocolTxGasPrice) <= 0
+}
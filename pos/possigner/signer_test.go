@@ -0,0 +1,254 @@
+package possigner
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	accBn256 "github.com/wanchain/go-wanchain/accounts/keystore/bn256"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/uleaderselection"
+)
+
+func newTestKey(t *testing.T) *keystore.Key {
+	sk, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk3, err := accBn256.GenerateBn256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &keystore.Key{Address: crypto.PubkeyToAddress(sk.PublicKey), PrivateKey: sk, PrivateKey3: sk3}
+}
+
+func TestProtectedSigner(t *testing.T) {
+	key := newTestKey(t)
+	db, _ := ethdb.NewMemDatabase()
+	s := NewProtectedSigner(NewKeySigner(key), db)
+
+	hash1, hash2 := crypto.Keccak256([]byte("block1")), crypto.Keccak256([]byte("block2"))
+	sig, err := s.SignSeal(1, 2, hash1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := crypto.SigToPub(hash1, sig)
+	if err != nil || crypto.PubkeyToAddress(*pub) != key.Address {
+		t.Fatal("seal should be signed by the validator", err)
+	}
+
+	// the same block may be sealed again, another one is refused
+	if _, err := s.SignSeal(1, 2, hash1); err != nil {
+		t.Fatal("resealing the same block failed", err)
+	}
+	if _, err := s.SignSeal(1, 2, hash2); err != ErrDoubleSeal {
+		t.Fatal("double seal should be refused", err)
+	}
+	if _, err := s.SignSeal(1, 1, hash2); err != ErrSlotExpired {
+		t.Fatal("seal of an earlier slot should be refused", err)
+	}
+	if _, err := s.SignSeal(2, 0, hash2); err != nil {
+		t.Fatal("seal of the next epoch failed", err)
+	}
+
+	// the records are kept by the database
+	s = NewProtectedSigner(NewKeySigner(key), db)
+	if _, err := s.SignSeal(1, 2, hash2); err != ErrDoubleSeal {
+		t.Fatal("double seal should be refused after restart", err)
+	}
+}
+
+func TestProtectedSignerEpochs(t *testing.T) {
+	key := newTestKey(t)
+	db, _ := ethdb.NewMemDatabase()
+	s := NewProtectedSigner(NewKeySigner(key), db)
+
+	pieces1, pieces2 := testPks(3), testPks(3)
+	if _, err := s.GenerateSMA(2, pieces1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GenerateSMA(2, pieces1); err != nil {
+		t.Fatal("same pieces should be served again", err)
+	}
+	if _, err := s.GenerateSMA(2, pieces2); err != ErrDoubleRequest {
+		t.Fatal("other pieces of a served epoch should be refused", err)
+	}
+	if _, err := s.GenerateSMA(1, pieces2); err != ErrEpochExpired {
+		t.Fatal("pieces of an earlier epoch should be refused", err)
+	}
+
+	shares := []*bn256.G1{new(bn256.G1).ScalarBaseMult(big.NewInt(3))}
+	if _, err := s.SignRBShare(2, shares, big.NewInt(5)); err != nil {
+		t.Fatal(err)
+	}
+	shares = []*bn256.G1{new(bn256.G1).ScalarBaseMult(big.NewInt(7))}
+	if _, err := s.SignRBShare(2, shares, big.NewInt(5)); err != nil {
+		t.Fatal("shares of another proposer should be signed", err)
+	}
+	if _, err := s.SignRBShare(2, shares, big.NewInt(6)); err != ErrDoubleRequest {
+		t.Fatal("other message of a signed epoch should be refused", err)
+	}
+	if _, err := s.SignRBShare(1, shares, big.NewInt(6)); err != ErrEpochExpired {
+		t.Fatal("message of an earlier epoch should be refused", err)
+	}
+
+	leaders := []*ecdsa.PublicKey{&key.PrivateKey.PublicKey}
+	sma, _ := s.Signer.GenerateSMA(2, pieces1)
+	rb1, rb2 := crypto.Keccak256([]byte("rb1")), crypto.Keccak256([]byte("rb2"))
+	if _, _, err := s.SlotLeaderProof(2, 3, sma, leaders, rb1); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.SlotLeaderProof(2, 3, sma, leaders, rb2); err != ErrDoubleRequest {
+		t.Fatal("other inputs of a proved slot should be refused", err)
+	}
+	if _, _, err := s.SlotLeaderProof(2, 2, sma, leaders, rb1); err != ErrSlotExpired {
+		t.Fatal("proof of an earlier slot should be refused", err)
+	}
+}
+
+func TestSignTx(t *testing.T) {
+	key := newTestKey(t)
+	s := NewKeySigner(key)
+	chainID := big.NewInt(3)
+
+	tx := types.NewTransaction(1, slotLeaderAddr, new(big.Int), big.NewInt(100000), big.NewInt(1), []byte{1})
+	if _, err := s.SignTx(tx, chainID); err != ErrNotProtocolTx {
+		t.Fatal("normal transaction should be refused", err)
+	}
+	tx.SetTxtype(types.POS_TX)
+	signed, err := s.SignTx(tx, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := types.Sender(types.NewEIP155Signer(chainID), signed); err != nil || from != key.Address {
+		t.Fatal("transaction should be signed by the validator", err)
+	}
+
+	refused := []*types.Transaction{
+		// value
+		types.NewTransaction(1, slotLeaderAddr, big.NewInt(1), big.NewInt(100000), big.NewInt(1), nil),
+		// other contracts
+		types.NewTransaction(1, common.Address{1}, new(big.Int), big.NewInt(100000), big.NewInt(1), []byte{1}),
+		types.NewTransaction(1, common.BytesToAddress(big.NewInt(606).Bytes()), new(big.Int), big.NewInt(100000), big.NewInt(1), nil),
+		types.NewContractCreation(1, new(big.Int), big.NewInt(100000), big.NewInt(1), []byte{1}),
+		// fee above the caps
+		types.NewTransaction(1, randomBeaconAddr, new(big.Int), new(big.Int).Add(MaxProtocolTxGas, big.NewInt(1)), big.NewInt(1), nil),
+		types.NewTransaction(1, randomBeaconAddr, new(big.Int), big.NewInt(100000), new(big.Int).Add(MaxProtocolTxGasPrice, big.NewInt(1)), nil),
+	}
+	for i, tx := range refused {
+		tx.SetTxtype(types.POS_TX)
+		if _, err := s.SignTx(tx, chainID); err != ErrNotProtocolTx {
+			t.Fatalf("transaction %d should be refused: %v", i, err)
+		}
+	}
+	capped := types.NewTransaction(1, randomBeaconAddr, new(big.Int), MaxProtocolTxGas, MaxProtocolTxGasPrice, nil)
+	capped.SetTxtype(types.POS_TX)
+	if _, err := s.SignTx(capped, chainID); err != nil {
+		t.Fatal("transaction at the caps should be signed", err)
+	}
+}
+
+func testPks(n int) []*ecdsa.PublicKey {
+	pks := make([]*ecdsa.PublicKey, n)
+	for i := range pks {
+		sk, _ := crypto.GenerateKey()
+		pks[i] = &sk.PublicKey
+	}
+	return pks
+}
+
+func TestRemoteSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "possigner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := newTestKey(t)
+	local := NewKeySigner(key)
+	l, err := Serve(filepath.Join(dir, "signer.ipc"), local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	remote, err := DialRemote(filepath.Join(dir, "signer.ipc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+
+	if remote.Address() != key.Address || !uleaderselection.PublicKeyEqual(remote.PublicKey(), local.PublicKey()) {
+		t.Fatal("remote keys mismatch", remote.Address().Hex())
+	}
+	if remote.Bn256PublicKey().String() != local.Bn256PublicKey().String() {
+		t.Fatal("remote bn256 key mismatch")
+	}
+
+	hash := crypto.Keccak256([]byte("block"))
+	sig, err := remote.SignSeal(1, 2, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := local.SignSeal(1, 2, hash); !bytes.Equal(sig, want) {
+		t.Fatal("remote seal mismatch")
+	}
+
+	pieces := testPks(3)
+	sma, err := remote.GenerateSMA(1, pieces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := local.GenerateSMA(1, pieces)
+	for i := range want {
+		if !uleaderselection.PublicKeyEqual(sma[i], want[i]) {
+			t.Fatal("remote sma mismatch", i)
+		}
+	}
+
+	leaders := []*ecdsa.PublicKey{local.PublicKey()}
+	rb := crypto.Keccak256([]byte("rb"))
+	proofMeg, proof, err := remote.SlotLeaderProof(1, 2, sma, leaders, rb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !uleaderselection.VerifySlotLeaderProof(proof, proofMeg, leaders, rb) {
+		t.Fatal("remote slot leader proof is invalid")
+	}
+
+	shares := make([]*bn256.G1, 3)
+	for i := range shares {
+		shares[i] = new(bn256.G1).ScalarBaseMult(big.NewInt(int64(10*i + 1)))
+	}
+	m := big.NewInt(12345)
+	gsig, err := remote.SignRBShare(1, shares, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantSig, _ := local.SignRBShare(1, shares, m); gsig.String() != wantSig.String() {
+		t.Fatal("remote rb share mismatch")
+	}
+
+	tx := types.NewTransaction(1, randomBeaconAddr, new(big.Int), big.NewInt(100000), big.NewInt(1), []byte{1})
+	tx.SetTxtype(types.POS_TX)
+	signed, err := remote.SignTx(tx, big.NewInt(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := local.SignTx(tx, big.NewInt(3)); signed.Hash() != want.Hash() {
+		t.Fatal("remote transaction signature mismatch")
+	}
+	tx.SetTxtype(types.NORMAL_TX)
+	if _, err := remote.SignTx(tx, big.NewInt(3)); err != ErrNotProtocolTx {
+		t.Fatal("remote normal transaction should be refused", err)
+	}
+}
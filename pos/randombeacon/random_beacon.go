@@ -7,10 +7,7 @@ import (
 	"sync"
 
 	"github.com/wanchain/go-wanchain/common"
-	"github.com/wanchain/go-wanchain/common/hexutil"
-	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
-	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/log"
 
 	"math/big"
@@ -18,9 +15,9 @@ import (
 	"github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
-	"github.com/wanchain/go-wanchain/rpc"
 )
 
 type RbEnsDataCollector struct {
@@ -40,7 +37,7 @@ type GetRBMFunc func(db vm.StateDB, epochId uint64) ([]byte, error)
 
 type LoopEvent struct {
 	statedb vm.StateDB
-	rc      *rpc.Client
+	sender  util.ProtocolTxSender
 	eid     uint64
 	sid     uint64
 }
@@ -65,7 +62,7 @@ type RandomBeacon struct {
 
 	statedb   vm.StateDB
 	epocher   *epochLeader.Epocher
-	rpcClient *rpc.Client
+	sender    util.ProtocolTxSender
 
 	wg sync.WaitGroup
 
@@ -102,7 +99,7 @@ func (rb *RandomBeacon) Init(epocher *epochLeader.Epocher) {
 	rb.epochStage = vm.RbDkg1Stage
 	rb.epochId = maxUint64
 	rb.polys = make(PolyMap)
-	rb.rpcClient = nil
+	rb.sender = nil
 
 	rb.epocher = epocher
 
@@ -128,7 +125,7 @@ func (rb *RandomBeacon) Stop() {
 	rb.loopEvents = nil
 }
 
-func (rb *RandomBeacon) Loop(statedb vm.StateDB, rc *rpc.Client, eid uint64, sid uint64) (err error) {
+func (rb *RandomBeacon) Loop(statedb vm.StateDB, sender util.ProtocolTxSender, eid uint64, sid uint64) (err error) {
 	defer func() {
 		if e := recover(); e != nil {
 			err = e.(error)
@@ -136,12 +133,12 @@ func (rb *RandomBeacon) Loop(statedb vm.StateDB, rc *rpc.Client, eid uint64, sid
 		}
 	}()
 
-	if statedb == nil || rc == nil {
+	if statedb == nil || sender == nil {
 		log.SyslogErr("invalid RB loop input param")
 		return errInvalidInParam
 	}
 
-	rb.loopEvents <- &LoopEvent{statedb, rc, eid, sid}
+	rb.loopEvents <- &LoopEvent{statedb, sender, eid, sid}
 	return
 }
 
@@ -154,7 +151,7 @@ func (rb *RandomBeacon) LoopRoutine() {
 			break
 		}
 
-		rb.doLoop(event.statedb, event.rc, event.eid, event.sid)
+		rb.doLoop(event.statedb, event.sender, event.eid, event.sid)
 	}
 }
 
@@ -174,10 +171,10 @@ func (rb *RandomBeacon) updateStage(stage int) {
 	rb.taskTags = nil
 }
 
-func (rb *RandomBeacon) doLoop(statedb vm.StateDB, rc *rpc.Client, epochId uint64, slotId uint64) error {
+func (rb *RandomBeacon) doLoop(statedb vm.StateDB, sender util.ProtocolTxSender, epochId uint64, slotId uint64) error {
 	log.SyslogInfo("rb doLoop begin", "epochId", epochId, "slotId", slotId, "self epochId", rb.epochId)
 	rb.statedb = statedb
-	rb.rpcClient = rc
+	rb.sender = sender
 
 	if rb.epochId != maxUint64 && rb.epochId > epochId {
 		log.SyslogErr("RB doloop fail", "err", errEpochIdRollback.Error())
@@ -477,7 +474,11 @@ func (rb *RandomBeacon) doSIG(proposerId uint32) error {
 }
 
 func (rb *RandomBeacon) generateSIG(proposerId uint32) (*vm.RbSIGTxPayload, error) {
-	prikey := posconfig.Cfg().GetMinerBn256SK()
+	valSigner := posconfig.Cfg().MinerSigner
+	if valSigner == nil {
+		return nil, possigner.ErrNoBn256Key
+	}
+
 	datas := make([]RbEnsDataCollector, 0)
 
 	for id, pk := range rb.proposerPks {
@@ -498,18 +499,11 @@ func (rb *RandomBeacon) generateSIG(proposerId uint32) (*vm.RbSIGTxPayload, erro
 	}
 
 	// Compute Group Secret Key Share
-	// Random proposers get information from the blockchain and compute its group secret share.
-
-	//set zero
-	gskshare := new(bn256.G1).ScalarBaseMult(big.NewInt(int64(0)))
-
-	// sk^-1
-	skinver := new(big.Int).ModInverse(prikey, bn256.Order)
+	// Random proposers get information from the blockchain and compute its group secret share,
+	// the signer computes gskshare[i] = (sk^-1)*(enshare[1][i]+...+enshare[Nr][i]) with the bn256 key.
+	shares := make([]*bn256.G1, dkgCount)
 	for i := 0; i < dkgCount; i++ {
-		temp := new(bn256.G1).ScalarMult(datas[i].ens[proposerId], skinver)
-
-		// gskshare[i] = (sk^-1)*(enshare[1][i]+...+enshare[Nr][i])
-		gskshare.Add(gskshare, temp)
+		shares[i] = datas[i].ens[proposerId]
 	}
 
 	// Signing Stage
@@ -522,7 +516,11 @@ func (rb *RandomBeacon) generateSIG(proposerId uint32) (*vm.RbSIGTxPayload, erro
 	m := new(big.Int).SetBytes(mBuf)
 
 	// Compute signature share
-	gsigshare := new(bn256.G1).ScalarMult(gskshare, m)
+	gsigshare, err := valSigner.SignRBShare(rb.epochId, shares, m)
+	if err != nil {
+		log.SyslogErr("sign rb share fail", "err", err.Error())
+		return nil, err
+	}
 	return &vm.RbSIGTxPayload{rb.epochId, proposerId, gsigshare}, nil
 }
 
@@ -557,21 +555,12 @@ func (rb *RandomBeacon) sendSIG(payloadObj *vm.RbSIGTxPayload) error {
 }
 
 func (rb *RandomBeacon) doSendRBTx(payload []byte) error {
-	to := vm.GetRBAddress()
-	data := hexutil.Bytes(payload)
-	gas := core.IntrinsicGas(data, &to, true)
-
-	arg := map[string]interface{}{}
-	arg["from"] = rb.getTxFrom()
-	arg["to"] = to
-	arg["value"] = (*hexutil.Big)(big.NewInt(0))
-	arg["gas"] = (*hexutil.Big)(gas)
-	arg["txType"] = types.POS_TX
-	arg["data"] = data
-
+	if rb.sender == nil {
+		return util.ErrNoProtocolTxSender
+	}
 
 	log.SyslogInfo("do send rb tx", "payload len", len(payload))
-	_, err := util.SendTx(rb.rpcClient, arg)
+	_, err := rb.sender.SendProtocolTx(rb.getTxFrom(), vm.GetRBAddress(), payload)
 	return err
 }
 
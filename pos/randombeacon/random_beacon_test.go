@@ -8,6 +8,7 @@ import (
 	"github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 	"github.com/wanchain/go-wanchain/pos/rbselection"
 	"math/big"
 	"testing"
@@ -50,8 +51,8 @@ func TestInit(t *testing.T) {
 		t.Error("invalid rb epocher")
 	}
 
-	if rb.rpcClient != nil {
-		t.Error("invalid rb rpc client")
+	if rb.sender != nil {
+		t.Error("invalid rb protocol tx sender")
 	}
 }
 
@@ -76,7 +77,7 @@ func TestGetMyRBProposerId(t *testing.T) {
 	}
 
 	selfPrivate = key.PrivateKey3
-	posconfig.Cfg().MinerKey = &key
+	posconfig.Cfg().MinerSigner = possigner.NewKeySigner(&key)
 
 	commityPrivate, err = accBn256.GenerateBn256()
 	if err != nil {
@@ -121,7 +122,7 @@ func TestDoGenerateDKG1(t *testing.T) {
 
 	selfPrivate = key.PrivateKey3
 	commityPrivate = selfPrivate
-	posconfig.Cfg().MinerKey = &key
+	posconfig.Cfg().MinerSigner = possigner.NewKeySigner(&key)
 
 	rb.Init(&epocher)
 	rb.getRBProposerGroupF = tmpGetRBProposerGroup
@@ -191,7 +192,7 @@ func TestGenerateDKG2(t *testing.T) {
 
 	selfPrivate = key.PrivateKey3
 	commityPrivate = selfPrivate
-	posconfig.Cfg().MinerKey = &key
+	posconfig.Cfg().MinerSigner = possigner.NewKeySigner(&key)
 
 	rb.Init(&epocher)
 	rb.getRBProposerGroupF = tmpGetRBProposerGroup
@@ -285,7 +286,7 @@ func TestGenerateSIG(t *testing.T) {
 
 	selfPrivate = key.PrivateKey3
 	commityPrivate = selfPrivate
-	posconfig.Cfg().MinerKey = &key
+	posconfig.Cfg().MinerSigner = possigner.NewKeySigner(&key)
 
 	rb.Init(&epocher)
 	rb.getRBProposerGroupF = tmpGetRBProposerGroup
@@ -13,6 +13,7 @@ import (
 
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/pos/uleaderselection"
@@ -112,8 +113,8 @@ func (s *SLS) VerifySlotProof(block *types.Block, epochID uint64, slotID uint64,
 	return uleaderselection.VerifySlotLeaderProof(Proof[:], ProofMeg[:], epochLeadersPtrPre[:], rbBytes[:])
 }
 
-func (s *SLS) PackSlotProof(epochID uint64, slotID uint64, prvKey *ecdsa.PrivateKey) ([]byte, error) {
-	proofMeg, proof, err := s.getSlotLeaderProof(prvKey, epochID, slotID)
+func (s *SLS) PackSlotProof(epochID uint64, slotID uint64, valSigner possigner.Signer) ([]byte, error) {
+	proofMeg, proof, err := s.getSlotLeaderProof(valSigner, epochID, slotID)
 	if err != nil {
 		return nil, err
 	}
@@ -136,7 +137,7 @@ func (s *SLS) GetInfoFromHeadExtra(epochID uint64, input []byte) ([]*big.Int, []
 	return convert.ByteArrayToBigIntArray(info.Proof), convert.ByteArrayToPkArray(info.ProofMeg), nil
 }
 
-func (s *SLS) getSlotLeaderProofByGenesis(PrivateKey *ecdsa.PrivateKey, epochID uint64,
+func (s *SLS) getSlotLeaderProofByGenesis(valSigner possigner.Signer, epochID uint64,
 	slotID uint64) ([]*ecdsa.PublicKey, []*big.Int, error) {
 	//1. SMA PRE
 	smaPiecesPtr := s.smaGenesis
@@ -146,12 +147,12 @@ func (s *SLS) getSlotLeaderProofByGenesis(PrivateKey *ecdsa.PrivateKey, epochID
 	log.Debug("getSlotLeaderProofByGenesis", "epochID", epochID, "slotID", slotID)
 	log.Debug("getSlotLeaderProofByGenesis", "epochID", epochID, "slotID", slotID, "slotLeaderRb",
 		hex.EncodeToString(rbBytes[:]))
-	profMeg, proof, err := uleaderselection.GenerateSlotLeaderProof2(PrivateKey, smaPiecesPtr[:],
-		epochLeadersPtrPre[:], rbBytes[:], slotID, epochID)
+	profMeg, proof, err := valSigner.SlotLeaderProof(epochID, slotID, smaPiecesPtr[:],
+		epochLeadersPtrPre[:], rbBytes[:])
 	return profMeg, proof, err
 }
 
-func (s *SLS) getSlotLeaderProof(PrivateKey *ecdsa.PrivateKey, epochID uint64,
+func (s *SLS) getSlotLeaderProof(valSigner possigner.Signer, epochID uint64,
 	slotID uint64) ([]*ecdsa.PublicKey, []*big.Int, error) {
 
 	epochLeadersPtrPre, err := s.getPreEpochLeadersPK(epochID)
@@ -159,13 +160,13 @@ func (s *SLS) getSlotLeaderProof(PrivateKey *ecdsa.PrivateKey, epochID uint64,
 		if err != nil {
 			log.Warn("getSlotLeaderProof", "getPreEpochLeadersPK error", err.Error())
 		}
-		return s.getSlotLeaderProofByGenesis(PrivateKey, epochID, slotID)
+		return s.getSlotLeaderProofByGenesis(valSigner, epochID, slotID)
 	}
 
 	//SMA PRE
 	smaPiecesPtr, isGenesis, _ := s.getSMAPieces(epochID)
 	if isGenesis {
-		return s.getSlotLeaderProofByGenesis(PrivateKey, epochID, slotID)
+		return s.getSlotLeaderProofByGenesis(valSigner, epochID, slotID)
 	}
 
 	//RB PRE
@@ -193,8 +194,8 @@ func (s *SLS) getSlotLeaderProof(PrivateKey *ecdsa.PrivateKey, epochID uint64,
 	}
 	log.Debug("getSlotLeaderProof", "epochID", epochID, "slotID", slotID, "smaPiecesHexStr", smaPiecesHexStr)
 
-	profMeg, proof, err := uleaderselection.GenerateSlotLeaderProof2(PrivateKey, smaPiecesPtr, epochLeadersPtrPre,
-		rbBytes[:], slotID, epochID)
+	profMeg, proof, err := valSigner.SlotLeaderProof(epochID, slotID, smaPiecesPtr, epochLeadersPtrPre,
+		rbBytes[:])
 
 	return profMeg, proof, err
 }
@@ -276,9 +277,11 @@ func (s *SLS) getSkGtFromTrans(epochLeadersPtrPre []*ecdsa.PublicKey, epochID ui
 	return skGt
 }
 
-func (s *SLS) getStageTwoFromTrans(epochID uint64) (validEpochLeadersIndex [posconfig.EpochLeaderCount]bool,
-	stageTwoAlphaPKi [posconfig.EpochLeaderCount][posconfig.EpochLeaderCount]*ecdsa.PublicKey, err error) {
+func (s *SLS) getStageTwoFromTrans(epochID uint64) (validEpochLeadersIndex []bool,
+	stageTwoAlphaPKi [][]*ecdsa.PublicKey, err error) {
 
+	validEpochLeadersIndex = make([]bool, posconfig.EpochLeaderCount)
+	stageTwoAlphaPKi = newPkMatrix(posconfig.EpochLeaderCount)
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
 		validEpochLeadersIndex[i] = true
 	}
@@ -1,42 +1,17 @@
 package slotleader
 
 import (
-	"errors"
-	"math/big"
-
-	"github.com/wanchain/go-wanchain/common"
-	"github.com/wanchain/go-wanchain/common/hexutil"
-	"github.com/wanchain/go-wanchain/core"
-	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/log"
-	"github.com/wanchain/go-wanchain/rpc"
-)
-
-var (
-	errRCNotReady = errors.New("rc is not ready")
+	"github.com/wanchain/go-wanchain/pos/util"
 )
 
-type SendTxFn func(rc *rpc.Client, tx map[string]interface{}) (common.Hash, error)
-
-func (s *SLS) sendSlotTx(payload []byte, posSender SendTxFn) error {
-	if s.rc == nil {
-		return errRCNotReady
+func (s *SLS) sendSlotTx(payload []byte) error {
+	if s.sender == nil {
+		return util.ErrNoProtocolTxSender
 	}
 
-	to := vm.GetSlotLeaderSCAddress()
-	data := hexutil.Bytes(payload)
-	gas := core.IntrinsicGas(data, &to, true)
-
-	arg := map[string]interface{}{}
-	arg["from"] = s.key.Address
-	arg["to"] = vm.GetSlotLeaderSCAddress()
-	arg["value"] = (*hexutil.Big)(big.NewInt(0))
-	arg["gas"] = (*hexutil.Big)(gas)
-	arg["txType"] = types.POS_TX
-	arg["data"] = data
-	log.Debug("Write data of payload", "length", len(data))
-
-	_, err := posSender(s.rc, arg)
+	log.Debug("Write data of payload", "length", len(payload))
+	_, err := s.sender.SendProtocolTx(s.signer.Address(), vm.GetSlotLeaderSCAddress(), payload)
 	return err
 }
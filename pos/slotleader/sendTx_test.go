@@ -1,35 +1,70 @@
 package slotleader
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/wanchain/go-wanchain/accounts/keystore"
-
 	"github.com/wanchain/go-wanchain/common"
-	"github.com/wanchain/go-wanchain/rpc"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/possigner"
+	"github.com/wanchain/go-wanchain/pos/util"
 )
 
-func testInit() {
-	SlsInit()
-	GetSlotLeaderSelection().Init(nil, &rpc.Client{}, &keystore.Key{})
+// testSender is the ProtocolTxSender recording the transactions instead of sending
+type testSender struct {
+	err   error
+	fails int // number of the next transactions failing
+	sent  [][]byte
 }
 
-func testSender(rc *rpc.Client, tx map[string]interface{}) (common.Hash, error) {
+func (t *testSender) SendProtocolTx(from common.Address, to common.Address, data []byte) (common.Hash, error) {
+	if to != vm.GetSlotLeaderSCAddress() {
+		return common.Hash{}, errors.New("not to slot leader contract")
+	}
+	if t.err != nil {
+		return common.Hash{}, t.err
+	}
+	if t.fails > 0 {
+		t.fails--
+		return common.Hash{}, errors.New("pool is full")
+	}
+	t.sent = append(t.sent, data)
 	return common.Hash{}, nil
 }
 
+func testInit() *testSender {
+	sender := &testSender{}
+	SlsInit()
+	GetSlotLeaderSelection().Init(nil, sender, possigner.NewKeySigner(&keystore.Key{}))
+	return sender
+}
+
 func TestSendStage1Tx(t *testing.T) {
-	testInit()
-	err := GetSlotLeaderSelection().sendSlotTx(nil, testSender)
-	if err != nil {
+	sender := testInit()
+	err := GetSlotLeaderSelection().sendSlotTx([]byte{1})
+	if err != nil || len(sender.sent) != 1 {
 		t.FailNow()
 	}
 }
 
 func TestSendStage2Tx(t *testing.T) {
 	testInit()
-	err := GetSlotLeaderSelection().sendSlotTx(nil, testSender)
+	err := GetSlotLeaderSelection().sendSlotTx(nil)
 	if err != nil {
 		t.FailNow()
 	}
 }
+
+func TestSendTxFailure(t *testing.T) {
+	sender := testInit()
+	sender.err = errors.New("pool is full")
+	if err := GetSlotLeaderSelection().sendSlotTx(nil); err != sender.err {
+		t.Fatal("send failure should be reported", err)
+	}
+
+	GetSlotLeaderSelection().Init(nil, nil, possigner.NewKeySigner(&keystore.Key{}))
+	if err := GetSlotLeaderSelection().sendSlotTx(nil); err != util.ErrNoProtocolTxSender {
+		t.Fatal("send without sender should fail", err)
+	}
+}
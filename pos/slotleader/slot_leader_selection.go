@@ -10,7 +10,6 @@ import (
 
 	"github.com/wanchain/go-wanchain/consensus"
 
-	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/types"
@@ -19,10 +18,10 @@ import (
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 
 	lru "github.com/hashicorp/golang-lru"
-	"github.com/wanchain/go-wanchain/rpc"
 
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/pos/uleaderselection"
@@ -55,36 +54,34 @@ var (
 type SLS struct {
 	workingEpochID uint64
 	workStage      int
-	rc             *rpc.Client
-	key            *keystore.Key
+	sender         util.ProtocolTxSender
+	signer         possigner.Signer
 	stateDbTest    *state.StateDB
 
 	epochLeadersArray []string            // len(pki)=65 hex.EncodeToString
 	epochLeadersMap   map[string][]uint64 // key: pki value: []uint64 the indexes of this pki. hex.EncodeToString
 
-	slotLeadersPtrArray  [posconfig.SlotCount]*ecdsa.PublicKey
-	slotLeadersIndex     [posconfig.SlotCount]uint64
-	epochLeadersPtrArray [posconfig.EpochLeaderCount]*ecdsa.PublicKey
+	slotLeadersPtrArray  []*ecdsa.PublicKey
+	slotLeadersIndex     []uint64
+	epochLeadersPtrArray []*ecdsa.PublicKey
 	// true: can be used to slot leader false: can not be used to slot leader
-	validEpochLeadersIndex [posconfig.EpochLeaderCount]bool
+	validEpochLeadersIndex []bool
 
-	stageOneMi       [posconfig.EpochLeaderCount]*ecdsa.PublicKey
-	stageTwoAlphaPKi [posconfig.EpochLeaderCount][posconfig.EpochLeaderCount]*ecdsa.PublicKey
-	stageTwoProof    [posconfig.EpochLeaderCount][StageTwoProofCount]*big.Int //[0]: e; [1]:Z
+	stageOneMi       []*ecdsa.PublicKey
+	stageTwoAlphaPKi [][]*ecdsa.PublicKey
+	stageTwoProof    [][StageTwoProofCount]*big.Int //[0]: e; [1]:Z
 
 	slotCreateStatus       map[uint64]bool
 	slotCreateStatusLockCh chan int
 
 	blockChain *core.BlockChain
 
-	epochLeadersPtrArrayGenesis [posconfig.EpochLeaderCount]*ecdsa.PublicKey
-	stageOneMiGenesis           [posconfig.EpochLeaderCount]*ecdsa.PublicKey
-	stageTwoAlphaPKiGenesis     [posconfig.EpochLeaderCount][posconfig.EpochLeaderCount]*ecdsa.PublicKey
-	stageTwoProofGenesis        [posconfig.EpochLeaderCount][StageTwoProofCount]*big.Int //[0]: e; [1]:Z
+	epochLeadersPtrArrayGenesis []*ecdsa.PublicKey
+	stageOneMiGenesis           []*ecdsa.PublicKey
+	stageTwoAlphaPKiGenesis     [][]*ecdsa.PublicKey
+	stageTwoProofGenesis        [][StageTwoProofCount]*big.Int //[0]: e; [1]:Z
 	randomGenesis               *big.Int
-	smaGenesis                  [posconfig.EpochLeaderCount]*ecdsa.PublicKey
-
-	sendTransactionFn SendTxFn
+	smaGenesis                  []*ecdsa.PublicKey
 }
 
 var slotLeaderSelection *SLS
@@ -135,7 +132,7 @@ func (s *SLS) GetSlotLeader(epochID uint64, slotID uint64) (slotLeader *ecdsa.Pu
 		return s.slotLeadersPtrArray[slotID], nil
 	}
 	// read from local db
-	for i := 0; i < posconfig.SlotCount; i++ {
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
 		pkByte, err := posdb.GetDb().GetWithIndex(epochID, uint64(i), SlotLeader)
 		if err != nil {
 			return nil, vm.ErrSlotLeaderGroupNotReady
@@ -165,6 +162,7 @@ func SlsInit() {
 	slotLeaderSelection.slotCreateStatus = make(map[uint64]bool)
 	slotLeaderSelection.slotCreateStatusLockCh = make(chan int, 1)
 	s := slotLeaderSelection
+	s.alloc()
 	s.randomGenesis = big.NewInt(1)
 	epoch0Leaders := s.getEpoch0LeadersPK()
 	for index, value := range epoch0Leaders {
@@ -224,11 +222,37 @@ func SlsInit() {
 
 }
 
+// alloc makes the arrays of the slots and epoch leaders, their lengths are set from the genesis
+func (s *SLS) alloc() {
+	s.slotLeadersPtrArray = make([]*ecdsa.PublicKey, posconfig.SlotCount)
+	s.slotLeadersIndex = make([]uint64, posconfig.SlotCount)
+	s.epochLeadersPtrArray = make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+	s.validEpochLeadersIndex = make([]bool, posconfig.EpochLeaderCount)
+
+	s.stageOneMi = make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+	s.stageTwoAlphaPKi = newPkMatrix(posconfig.EpochLeaderCount)
+	s.stageTwoProof = make([][StageTwoProofCount]*big.Int, posconfig.EpochLeaderCount)
+
+	s.epochLeadersPtrArrayGenesis = make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+	s.stageOneMiGenesis = make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+	s.stageTwoAlphaPKiGenesis = newPkMatrix(posconfig.EpochLeaderCount)
+	s.stageTwoProofGenesis = make([][StageTwoProofCount]*big.Int, posconfig.EpochLeaderCount)
+	s.smaGenesis = make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+}
+
+func newPkMatrix(n int) [][]*ecdsa.PublicKey {
+	m := make([][]*ecdsa.PublicKey, n)
+	for i := range m {
+		m[i] = make([]*ecdsa.PublicKey, n)
+	}
+	return m
+}
+
 func (s *SLS) getSlotLeaderStage2TxIndexes(epochID uint64) (indexesSentTran []bool, err error) {
-	var ret [posconfig.EpochLeaderCount]bool
+	ret := make([]bool, posconfig.EpochLeaderCount)
 	stateDb, err := s.getCurrentStateDb()
 	if err != nil {
-		return ret[:], err
+		return ret, err
 	}
 
 	slotLeaderPrecompileAddr := vm.GetSlotLeaderSCAddress()
@@ -245,8 +269,8 @@ func (s *SLS) getSlotLeaderStage2TxIndexes(epochID uint64) (indexesSentTran []bo
 	}
 
 	err = rlp.DecodeBytes(data, &ret)
-	if err != nil {
-		return ret[:], vm.ErrNoTx2TransInDB
+	if err != nil || len(ret) != posconfig.EpochLeaderCount {
+		return make([]bool, posconfig.EpochLeaderCount), vm.ErrNoTx2TransInDB
 	}
 	return ret[:], nil
 }
@@ -266,14 +290,10 @@ func (s *SLS) getAlpha(epochID uint64, selfIndex uint64) (*big.Int, error) {
 }
 
 func (s *SLS) getLocalPublicKey() (*ecdsa.PublicKey, error) {
-	if s.key == nil || s.key.PrivateKey == nil {
+	if s.signer == nil || s.signer.PublicKey() == nil {
 		return nil, vm.ErrInvalidLocalPublicKey
 	}
-	return &s.key.PrivateKey.PublicKey, nil
-}
-
-func (s *SLS) getLocalPrivateKey() (*ecdsa.PrivateKey, error) {
-	return s.key.PrivateKey, nil
+	return s.signer.PublicKey(), nil
 }
 
 func (s *SLS) getEpochLeaders(epochID uint64) [][]byte {
@@ -419,11 +439,11 @@ func (s *SLS) clearData() {
 		}
 	}
 
-	for i := 0; i < posconfig.SlotCount; i++ {
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
 		s.slotLeadersPtrArray[i] = nil
 	}
 
-	for i := 0; i < posconfig.SlotCount; i++ {
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
 		s.slotLeadersIndex[i] = 0
 	}
 }
@@ -724,10 +744,11 @@ func (s *SLS) collectStagesData(epochID uint64) (err error) {
 	return nil
 }
 
-func (s *SLS) generateSecurityMsg(epochID uint64, PrivateKey *ecdsa.PrivateKey) error {
+func (s *SLS) generateSecurityMsg(epochID uint64) error {
 	if !s.isLocalPkInCurrentEpochLeaders() {
+		localPk, _ := s.getLocalPublicKey()
 		log.Debug("generateSecurityMsg", "input public key",
-			hex.EncodeToString(crypto.FromECDSAPub(&PrivateKey.PublicKey)))
+			hex.EncodeToString(crypto.FromECDSAPub(localPk)))
 		return vm.ErrPkNotInCurrentEpochLeadersGroup
 	}
 	// collect data
@@ -746,7 +767,7 @@ func (s *SLS) generateSecurityMsg(epochID uint64, PrivateKey *ecdsa.PrivateKey)
 	smasPtr := make([]*ecdsa.PublicKey, 0)
 	var smasBytes bytes.Buffer
 
-	smasPtr, err = uleaderselection.GenerateSMA(PrivateKey, ArrayPiece)
+	smasPtr, err = s.signer.GenerateSMA(epochID, ArrayPiece)
 	if err != nil {
 		log.Warn("generateSecurityMsg:GenerateSMA", "error", err.Error())
 		return err
@@ -17,8 +17,8 @@ import (
 	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 	"github.com/wanchain/go-wanchain/pos/uleaderselection"
-	"github.com/wanchain/go-wanchain/rpc"
 
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
@@ -215,7 +215,7 @@ func TestCRSave(t *testing.T) {
 func TestArraySave(t *testing.T) {
 
 	fmt.Printf("TestArraySave\n\n\n")
-	var sendtrans [posconfig.EpochLeaderCount]bool
+	sendtrans := make([]bool, posconfig.EpochLeaderCount)
 	for index := range sendtrans {
 		sendtrans[index] = false
 	}
@@ -232,7 +232,7 @@ func TestArraySave(t *testing.T) {
 	db := posdb.NewDb("testArraySave")
 	db.Put(uint64(0), "TestArraySave", bytes)
 
-	var sendtransGet [posconfig.EpochLeaderCount]bool
+	var sendtransGet []bool
 	bytesGet, err := db.Get(uint64(0), "TestArraySave")
 	if err != nil {
 		t.Error(err.Error())
@@ -384,8 +384,8 @@ func TestGetSlotLeader(t *testing.T) {
 		t.Fail()
 	}
 
-	for i := 0; i < posconfig.SlotCount; i++ {
-		_, err = posdb.GetDb().PutWithIndex(1, uint64(i), SlotLeader, pkGenesisBytes)
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
+		_, err = posdb.GetDb().PutWithIndex(1, i, SlotLeader, pkGenesisBytes)
 		if err != nil {
 			t.Error(err.Error())
 			t.Fail()
@@ -415,19 +415,19 @@ func TestGetSlotLeader(t *testing.T) {
 func TestGetLocalPublicKey(t *testing.T) {
 	SlsInit()
 	s := GetSlotLeaderSelection()
-	s.Init(nil, &rpc.Client{}, &keystore.Key{})
+	s.Init(nil, &testSender{}, possigner.NewKeySigner(&keystore.Key{}))
 	key, err := crypto.GenerateKey()
 	if err != nil {
 		t.Fail()
 	}
-	s.key.PrivateKey = key
+	s.signer = possigner.NewKeySigner(&keystore.Key{PrivateKey: key})
 	// GetLocalPublicKey
 	keyGot, err := s.GetLocalPublicKey()
 	if err != nil {
 		t.Fail()
 	}
 
-	if !uleaderselection.PublicKeyEqual(keyGot, &s.key.PrivateKey.PublicKey) {
+	if !uleaderselection.PublicKeyEqual(keyGot, s.signer.PublicKey()) {
 		t.Fail()
 	}
 	// getLocalPublicKey
@@ -436,16 +436,7 @@ func TestGetLocalPublicKey(t *testing.T) {
 		t.Fail()
 	}
 
-	if !uleaderselection.PublicKeyEqual(keyGot1, &s.key.PrivateKey.PublicKey) {
-		t.Fail()
-	}
-	//getLocalPrivateKey
-	prvKeyGot, err := s.getLocalPrivateKey()
-	if err != nil {
-		t.Fail()
-	}
-
-	if prvKeyGot != key {
+	if !uleaderselection.PublicKeyEqual(keyGot1, s.signer.PublicKey()) {
 		t.Fail()
 	}
 
@@ -567,7 +558,7 @@ func TestGetAlpha(t *testing.T) {
 func TestIsLocalPKInPreEpochLeaders(t *testing.T) {
 	SlsInit()
 	s := GetSlotLeaderSelection()
-	s.Init(nil, &rpc.Client{}, &keystore.Key{})
+	s.Init(nil, &testSender{}, possigner.NewKeySigner(&keystore.Key{}))
 	posconfig.SelfTestMode = true
 
 	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
@@ -597,7 +588,7 @@ func TestIsLocalPKInPreEpochLeaders(t *testing.T) {
 	if err != nil {
 		t.Fail()
 	}
-	s.key.PrivateKey = key
+	s.signer = possigner.NewKeySigner(&keystore.Key{PrivateKey: key})
 
 	//isLocalPkInPreEpochLeaders
 	inOrNot, err := s.isLocalPkInPreEpochLeaders(2)
@@ -615,7 +606,7 @@ func TestIsLocalPKInPreEpochLeaders(t *testing.T) {
 		t.Fail()
 	}
 
-	s.key.PrivateKey = prvKeyExist
+	s.signer = possigner.NewKeySigner(&keystore.Key{PrivateKey: prvKeyExist})
 	//isLocalPkInPreEpochLeaders
 	inOrNot, err = s.isLocalPkInPreEpochLeaders(2)
 	if err != nil {
@@ -639,7 +630,7 @@ func TestIsLocalPKInPreEpochLeaders(t *testing.T) {
 func TestBuildEpochLeaderGroup(t *testing.T) {
 	SlsInit()
 	s := GetSlotLeaderSelection()
-	s.Init(nil, &rpc.Client{}, &keystore.Key{})
+	s.Init(nil, &testSender{}, possigner.NewKeySigner(&keystore.Key{}))
 	posconfig.SelfTestMode = true
 
 	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
@@ -707,7 +698,7 @@ func TestGetChainReader(t *testing.T) {
 
 	SlsInit()
 	s := GetSlotLeaderSelection()
-	s.Init(nil, &rpc.Client{}, &keystore.Key{})
+	s.Init(nil, &testSender{}, possigner.NewKeySigner(&keystore.Key{}))
 	if s.blockChain != nil {
 		t.Fail()
 	}
@@ -743,7 +734,7 @@ func TestGetRandom(t *testing.T) {
 func TestBuildStage2TxPayload(t *testing.T) {
 	SlsInit()
 	s := GetSlotLeaderSelection()
-	s.Init(nil, &rpc.Client{}, &keystore.Key{})
+	s.Init(nil, &testSender{}, possigner.NewKeySigner(&keystore.Key{}))
 	posconfig.SelfTestMode = true
 
 	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
@@ -777,12 +768,12 @@ func TestBuildStage2TxPayload(t *testing.T) {
 func TestBuildSecurityPieces(t *testing.T) {
 	SlsInit()
 	s := GetSlotLeaderSelection()
-	s.Init(nil, &rpc.Client{}, &keystore.Key{})
+	s.Init(nil, &testSender{}, possigner.NewKeySigner(&keystore.Key{}))
 	key, err := crypto.GenerateKey()
 	if err != nil {
 		t.Fail()
 	}
-	s.key.PrivateKey = key
+	s.signer = possigner.NewKeySigner(&keystore.Key{PrivateKey: key})
 
 	// getLocalPublicKey
 	keyGot1, err := s.getLocalPublicKey()
@@ -790,7 +781,7 @@ func TestBuildSecurityPieces(t *testing.T) {
 		t.Fail()
 	}
 
-	if !uleaderselection.PublicKeyEqual(keyGot1, &s.key.PrivateKey.PublicKey) {
+	if !uleaderselection.PublicKeyEqual(keyGot1, s.signer.PublicKey()) {
 		t.Fail()
 	}
 
@@ -851,12 +842,12 @@ func TestGenerateSecurityMsg(t *testing.T) {
 	}
 
 	// build local key
-	s.Init(chain, &rpc.Client{}, &keystore.Key{})
+	s.Init(chain, &testSender{}, possigner.NewKeySigner(&keystore.Key{}))
 	key, err := crypto.GenerateKey()
 	if err != nil {
 		t.Fail()
 	}
-	s.key.PrivateKey = key
+	s.signer = possigner.NewKeySigner(&keystore.Key{PrivateKey: key})
 
 	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
 	os.RemoveAll(path.Join(dir, "sl_leader_test"))
@@ -909,7 +900,7 @@ func TestGenerateSecurityMsg(t *testing.T) {
 	}
 
 	indexKeyHash := vm.GetSlotLeaderStage2IndexesKeyHash(convert.Uint64ToBytes(epochID))
-	var sendtrans [posconfig.EpochLeaderCount]bool
+	sendtrans := make([]bool, posconfig.EpochLeaderCount)
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
 		sendtrans[i] = true
 	}
@@ -927,7 +918,7 @@ func TestGenerateSecurityMsg(t *testing.T) {
 	// build security pieces
 	//pieces,_:= s.buildSecurityPieces(epochID)
 	// create SMA
-	err = s.generateSecurityMsg(epochID, key)
+	err = s.generateSecurityMsg(epochID)
 	if err != nil {
 		t.Logf("generate security message error. err:%v \n", err.Error())
 		t.Fail()
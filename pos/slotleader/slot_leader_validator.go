@@ -52,7 +52,7 @@ func (s *SLS) GetAllSlotLeaders(epochID uint64) (slotLeader []*ecdsa.PublicKey)
 
 	slotLeadersPtrArray := make([]*ecdsa.PublicKey,0)
 	// read from local db
-	for i := 0; i < posconfig.SlotCount; i++ {
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
 		pkByte, err := posdb.GetDb().GetWithIndex(epochID, uint64(i), SlotLeader)
 		if err != nil {
 			return nil
@@ -5,12 +5,12 @@ import (
 	"testing"
 
 	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 
 	"github.com/wanchain/go-wanchain/consensus/ethash"
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/ethdb"
-	"github.com/wanchain/go-wanchain/rpc"
 )
 
 var s *SLS
@@ -26,10 +26,7 @@ func testInitSlotleader() {
 	ce := ethash.NewFaker(db)
 	bc, _ := core.NewBlockChain(db, gspec.Config, ce, vm.Config{})
 
-	s.Init(bc, &rpc.Client{}, &keystore.Key{})
-
-	s.sendTransactionFn = testSender
-
+	s.Init(bc, &testSender{}, possigner.NewKeySigner(&keystore.Key{}))
 }
 
 func TestGetCurrentStateDb(t *testing.T) {
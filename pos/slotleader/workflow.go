@@ -11,17 +11,16 @@ import (
 	"github.com/wanchain/go-wanchain/core/vm"
 
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 
-	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/functrace"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/pos/uleaderselection"
-	"github.com/wanchain/go-wanchain/rpc"
 )
 
 var (
@@ -29,23 +28,21 @@ var (
 )
 
 // Init use to initial slotleader module and input some params.
-func (s *SLS) Init(blockChain *core.BlockChain, rc *rpc.Client, key *keystore.Key) {
+func (s *SLS) Init(blockChain *core.BlockChain, sender util.ProtocolTxSender, valSigner possigner.Signer) {
 	s.blockChain = blockChain
-	s.rc = rc
-	s.key = key
+	s.sender = sender
+	s.signer = valSigner
 	if blockChain != nil {
 		log.Info("SLS init success")
 	}
-
-	s.sendTransactionFn = util.SendTx
 }
 
 //Loop check work every Slot time. Called by backend loop.
 //It's all slotLeaderSelection's main workflow loop.
 //It does not loop at all, it is loop called by the backend.
-func (s *SLS) Loop(rc *rpc.Client, key *keystore.Key, epochID uint64, slotID uint64) {
-	s.rc = rc
-	s.key = key
+func (s *SLS) Loop(sender util.ProtocolTxSender, valSigner possigner.Signer, epochID uint64, slotID uint64) {
+	s.sender = sender
+	s.signer = valSigner
 
 	log.Info("Now epchoID and slotID:", "epochID", convert.Uint64ToString(epochID), "slotID",
 		convert.Uint64ToString(slotID))
@@ -76,9 +73,12 @@ func (s *SLS) Loop(rc *rpc.Client, key *keystore.Key, epochID uint64, slotID uin
 			s.setWorkStage(epochID, slotLeaderSelectionStageFinished)
 		}
 
-		err := s.startStage1Work()
+		// the stage2 work goes on for the commitments sent, even if others failed
+		sent, err := s.startStage1Work()
 		if err != nil {
 			log.SyslogErr(err.Error())
+		}
+		if err != nil && sent == 0 {
 			s.setWorkStage(epochID, slotLeaderSelectionStage3)
 		} else {
 			s.setWorkStage(epochID, slotLeaderSelectionStage2)
@@ -100,7 +100,7 @@ func (s *SLS) Loop(rc *rpc.Client, key *keystore.Key, epochID uint64, slotID uin
 			break
 		}
 
-		err := s.generateSecurityMsg(epochID, s.key.PrivateKey)
+		err := s.generateSecurityMsg(epochID)
 		if err != nil {
 			log.Warn(err.Error())
 		} else {
@@ -132,34 +132,46 @@ func (s *SLS) doInit(epochID uint64) {
 	}
 }
 
-func (s *SLS) startStage1Work() error {
+// startStage1Work sends the commitments of the local node for each of its epoch
+// leader indexes, it returns how many are sent and the indexes failed if any.
+func (s *SLS) startStage1Work() (int, error) {
 	selfPublicKey, err := s.getLocalPublicKey()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	selfPublicKeyIndex, inEpochLeaders := s.epochLeadersMap[hex.EncodeToString(crypto.FromECDSAPub(selfPublicKey))]
-	if inEpochLeaders {
-		log.Debug(fmt.Sprintf("Local node in epoch leaders times: %d", len(selfPublicKeyIndex)))
+	if !inEpochLeaders {
+		log.Debug("Local node is not in epoch leaders")
+		return 0, nil
+	}
+	log.Debug(fmt.Sprintf("Local node in epoch leaders times: %d", len(selfPublicKeyIndex)))
 
-		workingEpochID := s.getWorkingEpochID()
+	workingEpochID := s.getWorkingEpochID()
 
-		for i := 0; i < len(selfPublicKeyIndex); i++ {
-			data, err := s.generateCommitment(selfPublicKey, workingEpochID, selfPublicKeyIndex[i])
-			if err != nil {
-				log.Error("generateCommitment error", "error", err.Error())
-				continue
-			}
-			err = s.sendSlotTx(data, s.sendTransactionFn)
-			if err != nil {
-				log.Error("sendSlotTx error", "error", err.Error())
-				continue
-			}
+	sent := 0
+	failed := make([]uint64, 0)
+	var lastErr error
+	for i := 0; i < len(selfPublicKeyIndex); i++ {
+		data, err := s.generateCommitment(selfPublicKey, workingEpochID, selfPublicKeyIndex[i])
+		if err != nil {
+			log.Error("generateCommitment error", "index", selfPublicKeyIndex[i], "error", err.Error())
+			failed, lastErr = append(failed, selfPublicKeyIndex[i]), err
+			continue
 		}
-	} else {
-		log.Debug("Local node is not in epoch leaders")
+		err = s.sendSlotTx(data)
+		if err != nil {
+			log.Error("sendSlotTx error", "index", selfPublicKeyIndex[i], "error", err.Error())
+			failed, lastErr = append(failed, selfPublicKeyIndex[i]), err
+			continue
+		}
+		sent++
+	}
+	if len(failed) > 0 {
+		return sent, fmt.Errorf("fail to send commitments of epoch %d at epoch leader indexes %v: %v",
+			workingEpochID, failed, lastErr)
 	}
-	return nil
+	return sent, nil
 }
 
 func doStage2Work() {
@@ -180,6 +192,7 @@ func (s *SLS) startStage2Work() error {
 	selfPublicKeyIndex := make([]uint64, 0)
 	var inEpochLeaders bool
 	selfPublicKeyIndex, inEpochLeaders = s.epochLeadersMap[hex.EncodeToString(crypto.FromECDSAPub(selfPublicKey))]
+	var sendErr error
 	if inEpochLeaders {
 		for i := 0; i < len(selfPublicKeyIndex); i++ {
 			workingEpochID := s.getWorkingEpochID()
@@ -188,15 +201,16 @@ func (s *SLS) startStage2Work() error {
 				log.Error("buildStage2TxPayload error", "error", err.Error())
 				continue
 			}
-			err = s.sendSlotTx(data, s.sendTransactionFn)
+			err = s.sendSlotTx(data)
 			if err != nil {
 				log.Error("sendSlotTx error", "error", err.Error())
+				sendErr = err
 				continue
 			}
 		}
 	}
 	functrace.Exit()
-	return nil
+	return sendErr
 }
 
 //generateCommitment generate a commitment and send it by tx message
@@ -4,6 +4,7 @@ import (
 	"crypto/ecdsa"
 	"encoding/hex"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -17,8 +18,8 @@ import (
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/possigner"
 	"github.com/wanchain/go-wanchain/rlp"
-	"github.com/wanchain/go-wanchain/rpc"
 )
 
 var (
@@ -63,15 +64,16 @@ func TestLoop(t *testing.T) {
 	key := &keystore.Key{}
 	key.PrivateKey, _ = crypto.GenerateKey()
 	key.PrivateKey.PublicKey = *epPks[0]
+	valSigner := possigner.NewKeySigner(key)
 
 	epochIDStart := time.Now().Second()
 
-	for i := 0; i < posconfig.SlotCount; i++ {
-		s.Loop(&rpc.Client{}, key, uint64(epochIDStart+0), uint64(i))
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
+		s.Loop(&testSender{}, valSigner, uint64(epochIDStart+0), i)
 	}
 
-	for i := 0; i < posconfig.SlotCount; i++ {
-		s.Loop(&rpc.Client{}, key, uint64(epochIDStart+1), uint64(i))
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
+		s.Loop(&testSender{}, valSigner, uint64(epochIDStart+1), i)
 	}
 }
 
@@ -167,8 +169,41 @@ func TestGenerateCommitmentFailed(t *testing.T) {
 func TestStartStage1Work(t *testing.T) {
 	TestLoop(t)
 
-	err := s.startStage1Work()
-	if err != nil {
+	sent, err := s.startStage1Work()
+	if err != nil || sent != 1 {
 		t.FailNow()
 	}
 }
+
+func TestStartStage1WorkFailure(t *testing.T) {
+	TestLoop(t)
+
+	pk := hex.EncodeToString(crypto.FromECDSAPub(epPks[0]))
+	s.epochLeadersMap[pk] = []uint64{0, 1, 2}
+
+	sender := &testSender{fails: 1}
+	s.sender = sender
+	sent, err := s.startStage1Work()
+	if err == nil || sent != 2 || len(sender.sent) != 2 {
+		t.Fatal("failed commitment should be reported", sent, err)
+	}
+	if !strings.Contains(err.Error(), "[0]") {
+		t.Fatal("failed index should be reported", err)
+	}
+
+	// the workflow goes on to stage2 once a commitment is sent
+	epochID := s.getWorkingEpochID()
+	sender.fails = 1
+	s.setWorkStage(epochID, slotLeaderSelectionStage1)
+	s.Loop(sender, s.signer, epochID, posconfig.Sma1Start+1)
+	if stage := s.getWorkStage(epochID); stage != slotLeaderSelectionStage2 {
+		t.Fatal("stage2 should follow a partial stage1", stage)
+	}
+
+	sender.fails = 3
+	s.setWorkStage(epochID, slotLeaderSelectionStage1)
+	s.Loop(sender, s.signer, epochID, posconfig.Sma1Start+1)
+	if stage := s.getWorkStage(epochID); stage != slotLeaderSelectionStage3 {
+		t.Fatal("stage3 should follow a failed stage1", stage)
+	}
+}
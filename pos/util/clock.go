@@ -0,0 +1,51 @@
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of the time of the pos engine. The miner, the slot leader
+// selection, the random beacon and the confirmation of the blocks follow the slot
+// computed from it, so a SimulatedClock runs them faster than the wall clock.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+}
+
+// SystemClock is the Clock of the wall clock
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time                         { return time.Now() }
+func (SystemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (SystemClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+var (
+	clockMu sync.RWMutex
+	clock   Clock = SystemClock{}
+)
+
+// SetClock replaces the clock of the pos engine and returns the previous one,
+// it should be called before the engine is started.
+func SetClock(c Clock) Clock {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+
+	prev := clock
+	clock = c
+	return prev
+}
+
+// GetClock returns the clock of the pos engine
+func GetClock() Clock {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+
+	return clock
+}
+
+// NowUnix returns the unix time of the clock of the pos engine in seconds
+func NowUnix() uint64 {
+	return uint64(GetClock().Now().Unix())
+}
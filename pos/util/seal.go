@@ -0,0 +1,64 @@
+package util
+
+import (
+	"errors"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/crypto/sha3"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// ExtraSeal is the number of extra-data suffix bytes reserved for the slot leader seal
+const ExtraSeal = 65
+
+var errMissingSeal = errors.New("extra-data 65 byte suffix signature missing")
+
+// SigHash returns the hash which is used as input for the slot leader seal.
+// It is the hash of the entire header apart from the 65 byte signature
+// contained at the end of the extra data.
+//
+// Note, the method requires the extra data to be at least 65 bytes, otherwise it
+// panics. This is done to avoid accidentally using both forms (signature present
+// or not), which could be abused to produce different hashes for the same header.
+func SigHash(header *types.Header) (hash common.Hash) {
+	hasher := sha3.NewKeccak256()
+
+	rlp.Encode(hasher, []interface{}{
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra[:len(header.Extra)-ExtraSeal], // Yes, this will panic if extra is too short
+		header.MixDigest,
+		header.Nonce,
+	})
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+// RecoverSealer extracts the address of the slot leader who sealed the header
+func RecoverSealer(header *types.Header) (common.Address, error) {
+	if len(header.Extra) < ExtraSeal {
+		return common.Address{}, errMissingSeal
+	}
+	signature := header.Extra[len(header.Extra)-ExtraSeal:]
+
+	pubkey, err := crypto.Ecrecover(SigHash(header).Bytes(), signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	return signer, nil
+}
@@ -0,0 +1,96 @@
+package util
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SimulatedClock is a Clock the time of which moves only when Run is called.
+// The goroutines sleeping on it are woken up in the order of their deadlines, so
+// the slots of whole epochs pass as fast as the code running in them.
+type SimulatedClock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*simTimer // ascending by deadline
+}
+
+type simTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewSimulatedClock creates a SimulatedClock starting at now
+func NewSimulatedClock(now time.Time) *SimulatedClock {
+	c := &SimulatedClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the current simulated time
+func (c *SimulatedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// After returns a channel receiving the simulated time once Run moves it by d
+func (c *SimulatedClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	t := &simTimer{at: c.now.Add(d), ch: ch}
+	i := sort.Search(len(c.timers), func(i int) bool { return c.timers[i].at.After(t.at) })
+	c.timers = append(c.timers, nil)
+	copy(c.timers[i+1:], c.timers[i:])
+	c.timers[i] = t
+	c.cond.Broadcast()
+	return ch
+}
+
+// Sleep blocks until Run moves the simulated time by d
+func (c *SimulatedClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Run moves the simulated time by d, firing the timers due in order
+func (c *SimulatedClock) Run(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+	for len(c.timers) > 0 && !c.timers[0].at.After(end) {
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		c.now = t.at
+		t.ch <- t.at
+	}
+	c.now = end
+}
+
+// ActiveTimers returns the number of goroutines waiting for the simulated time
+func (c *SimulatedClock) ActiveTimers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
+
+// WaitForTimers blocks until n goroutines at least are waiting for the simulated
+// time, so the time is moved once they are done with the current slot.
+func (c *SimulatedClock) WaitForTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+func TestSimulatedClockTimers(t *testing.T) {
+	start := time.Unix(1544544000, 0)
+	c := NewSimulatedClock(start)
+
+	late, early := c.After(2*time.Second), c.After(time.Second)
+	if c.ActiveTimers() != 2 {
+		t.Fatal("timers should be waiting", c.ActiveTimers())
+	}
+	select {
+	case <-early:
+		t.Fatal("timer fired before its deadline")
+	default:
+	}
+
+	c.Run(3 * time.Second)
+	if at := <-early; !at.Equal(start.Add(time.Second)) {
+		t.Fatal("early timer fired at", at)
+	}
+	if at := <-late; !at.Equal(start.Add(2 * time.Second)) {
+		t.Fatal("late timer fired at", at)
+	}
+	if !c.Now().Equal(start.Add(3*time.Second)) || c.ActiveTimers() != 0 {
+		t.Fatal("clock should move to the end of the run", c.Now(), c.ActiveTimers())
+	}
+}
+
+// Tests that the slots of a whole epoch are passed with a simulated clock like
+// the slot loop of the miner does.
+func TestSimulatedClockEpoch(t *testing.T) {
+	c := NewSimulatedClock(time.Unix(1544544000, 0))
+	prev := SetClock(c)
+	baseTime := posconfig.EpochBaseTime
+	defer func() {
+		SetClock(prev)
+		posconfig.EpochBaseTime = baseTime
+	}()
+	posconfig.EpochBaseTime = NowUnix()
+
+	slots := make(chan uint64)
+	go func() {
+		for i := uint64(0); i <= posconfig.SlotCount; i++ {
+			CalEpochSlotIDByNow()
+			epochID, slotID := GetEpochSlotID()
+			slots <- epochID*posconfig.SlotCount + slotID
+			if i < posconfig.SlotCount {
+				GetClock().Sleep(time.Duration(posconfig.SlotTime) * time.Second)
+			}
+		}
+	}()
+
+	begin := time.Now()
+	for i := uint64(0); i <= posconfig.SlotCount; i++ {
+		if slot := <-slots; slot != i {
+			t.Fatal("slot mismatch", slot, i)
+		}
+		if i < posconfig.SlotCount {
+			c.WaitForTimers(1)
+			c.Run(time.Duration(posconfig.SlotTime) * time.Second)
+		}
+	}
+	if epochID, slotID := GetEpochSlotID(); epochID != 1 || slotID != 0 {
+		t.Fatal("epoch should be passed", epochID, slotID)
+	}
+	if time.Since(begin) > 10*time.Second {
+		t.Fatal("simulated epoch took", time.Since(begin))
+	}
+}
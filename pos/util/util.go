@@ -4,8 +4,8 @@ import (
 	"crypto/ecdsa"
 	"encoding/hex"
 	"errors"
+	"sort"
 	"strings"
-	"time"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/wanchain/go-wanchain/accounts/abi"
@@ -42,7 +42,7 @@ func CalEpochSlotIDByNow() {
 	if posconfig.EpochBaseTime == 0 {
 		return
 	}
-	timeUnix := uint64(time.Now().Unix())
+	timeUnix := NowUnix()
 	epochTimeSpan := uint64(posconfig.SlotTime * posconfig.SlotCount)
 	curEpochId = uint64((timeUnix - posconfig.EpochBaseTime) / epochTimeSpan)
 	curSlotId = uint64((timeUnix - posconfig.EpochBaseTime) / posconfig.SlotTime % posconfig.SlotCount)
@@ -98,6 +98,29 @@ func CalEpSlbyTd(blkTd uint64)(epochID uint64, slotID uint64) {
 	slotID = ((blkTd & 0xffffffff) >> 8)
 	return epochID,slotID
 }
+
+// HeaderReader reads the headers of the canonical chain
+type HeaderReader interface {
+	CurrentHeader() *types.Header
+	GetHeaderByNumber(number uint64) *types.Header
+}
+
+// FirstBlockAfter returns the number of the first block of the canonical chain in a
+// slot after the slot slotID of epochID, the number after the head if there's none.
+// It searches the epoch and slot in the difficulty of the blocks, which grow with
+// the block number.
+func FirstBlockAfter(chain HeaderReader, epochID uint64, slotID uint64) uint64 {
+	head := chain.CurrentHeader().Number.Uint64()
+	// the genesis has no slot, the search is of the blocks 1 to head
+	return 1 + uint64(sort.Search(int(head), func(i int) bool {
+		header := chain.GetHeaderByNumber(uint64(i) + 1)
+		if header == nil {
+			return true
+		}
+		ep, sl := CalEpSlbyTd(header.Difficulty.Uint64())
+		return ep > epochID || (ep == epochID && sl > slotID)
+	}))
+}
 func UpdateEpochBlock( block *types.Block) {
 	blkTd := block.Difficulty().Uint64()
 	epochID,slotID := CalEpSlbyTd(blkTd)
@@ -3,11 +3,50 @@ package util
 import (
 	"encoding/hex"
 	"fmt"
+	"math/big"
 	"testing"
 
+	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/crypto"
 )
 
+type testHeaders []*types.Header
+
+func (h testHeaders) CurrentHeader() *types.Header { return h[len(h)-1] }
+
+func (h testHeaders) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(h)) {
+		return nil
+	}
+	return h[number]
+}
+
+func TestFirstBlockAfter(t *testing.T) {
+	chain := testHeaders{{Number: big.NewInt(0), Difficulty: big.NewInt(1)}}
+	for _, es := range [][2]uint64{{0, 1}, {0, 5}, {1, 0}, {1, 3}, {3, 2}, {3, 7}} {
+		chain = append(chain, &types.Header{
+			Number:     big.NewInt(int64(len(chain))),
+			Difficulty: new(big.Int).SetUint64(es[0]<<32 | es[1]<<8 | 1),
+		})
+	}
+
+	tests := []struct{ epochID, slotID, want uint64 }{
+		{0, 0, 1},
+		{0, 1, 2},
+		{0, 9, 3},
+		{1, 0, 4},
+		{2, 0, 5},
+		{3, 2, 6},
+		{3, 7, 7},
+		{9, 0, 7},
+	}
+	for _, test := range tests {
+		if got := FirstBlockAfter(chain, test.epochID, test.slotID); got != test.want {
+			t.Errorf("after epoch %d slot %d: got block %d, want %d", test.epochID, test.slotID, got, test.want)
+		}
+	}
+}
+
 func TestGetEpochSlotID(t *testing.T) {
 	epochID, slotID := GetEpochSlotID()
 	fmt.Println("epochID:", epochID, " slotID:", slotID)
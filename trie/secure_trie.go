@@ -21,6 +21,7 @@ import (
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/rlp"
 )
 
 var secureKeyPrefix = []byte("secure-key-")
@@ -148,6 +149,13 @@ func (t *SecureTrie) Commit() (root common.Hash, err error) {
 	return t.CommitTo(t.trie.db)
 }
 
+// Prove constructs a merkle proof for key. The key is hashed the same way
+// as in Get and Update, so the proof can be verified by VerifyProof with
+// the hashed key.
+func (t *SecureTrie) Prove(key []byte) []rlp.RawValue {
+	return t.trie.Prove(t.hashKey(key))
+}
+
 func (t *SecureTrie) Hash() common.Hash {
 	return t.trie.Hash()
 }